@@ -116,7 +116,13 @@ func scanEndpoint(tiupHome string) ([]*endpoint, error) {
 		if !isInstanceAlive(tiupHome, file.Name()) {
 			continue
 		}
-		endpoints = append(endpoints, readDsn(path.Join(tiupHome, localdata.DataParentDir, file.Name()), file.Name())...)
+		dir := path.Join(tiupHome, localdata.DataParentDir, file.Name())
+		// the DSN file is read if it's not a playground or its display fails
+		eps, err := readPlaygroundEndpoints(dir, file.Name())
+		if err != nil {
+			eps = readDsn(dir, file.Name())
+		}
+		endpoints = append(endpoints, eps...)
 	}
 	return endpoints, nil
 }
@@ -0,0 +1,86 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// playgroundInstance is an instance in the result of `tiup playground display --format json`
+type playgroundInstance struct {
+	Role   string `json:"role"`
+	Addr   string `json:"addr"`
+	Status string `json:"status"`
+}
+
+// readPlaygroundEndpoints returns the TiDB instances up in the playground
+// running in the dir, they are queried by the display command through the
+// port of the playground
+func readPlaygroundEndpoints(dir, component string) ([]*endpoint, error) {
+	data, err := ioutil.ReadFile(path.Join(dir, "port"))
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := json.Marshal(map[string]string{
+		"CommandType": "display",
+		"Format":      "json",
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(fmt.Sprintf("http://127.0.0.1:%d/command", port), "application/json", bytes.NewReader(cmd))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("display playground: %s", resp.Status)
+	}
+
+	var instances []playgroundInstance
+	if err := json.NewDecoder(resp.Body).Decode(&instances); err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint{}
+	for _, inst := range instances {
+		if inst.Role != "tidb" || inst.Status != "Up" {
+			continue
+		}
+		addr := inst.Addr
+		if host, port, err := net.SplitHostPort(addr); err == nil && host == "0.0.0.0" {
+			addr = net.JoinHostPort("localhost", port)
+		}
+		endpoints = append(endpoints, &endpoint{
+			component: component,
+			dsn:       fmt.Sprintf("mysql://root@%s", addr),
+		})
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,309 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type applyOptions struct {
+	scaleOutOptions
+	version string // the version to upgrade the cluster to
+}
+
+func newApplyCmd() *cobra.Command {
+	opt := applyOptions{
+		scaleOutOptions: scaleOutOptions{
+			identityFile: filepath.Join(tiuputils.UserHome(), ".ssh", "id_rsa"),
+		},
+	}
+	cmd := &cobra.Command{
+		Use:   "apply <cluster-name> <topology.yaml>",
+		Short: "Reconcile a TiDB cluster to the topology file",
+		Long: `Reconcile a TiDB cluster to the topology file.
+
+The topology file is compared with the current topology of the cluster, the
+instances only in the file are scaled out, the instances not in the file are
+scaled in, and the instances whose configuration is changed are reloaded.
+
+The cluster is upgraded to the tidb_version at the top level of the file if
+it's specified, the --version flag takes precedence over it.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			logger.EnableAuditLog()
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if err := validateHealthGateFlags(); err != nil {
+				return err
+			}
+			return apply(clusterName, args[1], opt)
+		},
+	}
+
+	cmd.Flags().StringVar(&opt.user, "user", tiuputils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().StringVar(&opt.version, "version", "", "Upgrade the cluster to the specified version, overriding the tidb_version of the topology file")
+	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	addHealthGateFlags(cmd)
+
+	return requireLock(cmd, lockExisting)
+}
+
+func apply(clusterName, topoFile string, opt applyOptions) error {
+	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return errors.Errorf("cannot apply to non-exists cluster %s", clusterName)
+	}
+
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	var applyTopo applyTopology
+	if err := clusterutil.ParseTopologyYaml(topoFile, &applyTopo); err != nil {
+		return err
+	}
+	desired := applyTopo.Topology
+	if opt.version == "" {
+		opt.version = applyTopo.Version
+	}
+
+	if data, err := ioutil.ReadFile(topoFile); err == nil {
+		teleTopology = string(data)
+	}
+
+	diff, err := meta.DiffTopology(metadata.Topology, &desired)
+	if err != nil {
+		return err
+	}
+
+	upgradeVersion := opt.version != "" && opt.version != metadata.Version
+	if upgradeVersion {
+		if err := versionCompare(metadata.Version, opt.version); err != nil {
+			return err
+		}
+	}
+
+	if diff.Empty() && !upgradeVersion {
+		log.Infof("Cluster `%s` is already up to date", clusterName)
+		return nil
+	}
+
+	scaleOutNeeded := false
+	diff.Added.IterInstance(func(meta.Instance) {
+		scaleOutNeeded = true
+	})
+	patchedComponents := set.NewStringSet()
+	if scaleOutNeeded {
+		if _, err := validateScaleOutTopology(clusterName, metadata, diff.Added); err != nil {
+			return err
+		}
+		patchedComponents = scaleOutPatchedComponents(clusterName, diff.Added)
+	}
+
+	if !skipConfirm {
+		if err := confirmApplyPlan(clusterName, metadata, diff, opt.version); err != nil {
+			return err
+		}
+	}
+
+	var sshConnProps *cliutil.SSHConnectionProps
+	if scaleOutNeeded {
+		if sshConnProps, err = cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword); err != nil {
+			return err
+		}
+	}
+
+	if len(diff.Removed) > 0 {
+		options := gOpt
+		options.Nodes = diff.Removed
+		if err := scaleIn(clusterName, options); err != nil {
+			return err
+		}
+	}
+
+	if len(diff.Reconfigured) > 0 || diff.HooksChanged || upgradeVersion {
+		// the metadata is changed by the scale-in
+		if metadata, err = meta.ClusterMetadata(clusterName); err != nil {
+			return err
+		}
+		// the configuration is saved before the upgrade, which reads it from
+		// the metadata, and it's restored if the instances fail to take it
+		previous, err := meta.ClusterMetadata(clusterName)
+		if err != nil {
+			return err
+		}
+		metadata.Topology.PatchConfig(&desired)
+		if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
+			return errors.Trace(err)
+		}
+
+		// the upgrade refreshes the configuration and restarts all instances
+		if upgradeVersion {
+			err = upgrade(clusterName, opt.version, gOpt, false)
+		} else if len(diff.Reconfigured) > 0 {
+			err = applyConfig(clusterName, metadata, diff.Reconfigured)
+		}
+		if err != nil {
+			if rerr := meta.SaveClusterMeta(clusterName, previous); rerr != nil {
+				log.Errorf("Failed to restore the metadata of cluster `%s`: %s", clusterName, rerr)
+			}
+			return err
+		}
+	}
+
+	if scaleOutNeeded {
+		if metadata, err = meta.ClusterMetadata(clusterName); err != nil {
+			return err
+		}
+		mergedTopo := metadata.Topology.Merge(diff.Added)
+		if err := executeScaleOut(clusterName, metadata, mergedTopo, diff.Added, patchedComponents, opt.scaleOutOptions, sshConnProps); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Applied topology to cluster `%s` successfully", clusterName)
+
+	return nil
+}
+
+// applyTopology is the topology file of apply, which may specify the version
+// of the cluster by tidb_version as the metadata does
+type applyTopology struct {
+	Version  string
+	Topology meta.TopologySpecification
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface, the topology is
+// parsed strictly without the version
+func (t *applyTopology) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items yaml.MapSlice
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	topo := yaml.MapSlice{}
+	for _, item := range items {
+		if item.Key == "tidb_version" {
+			t.Version = fmt.Sprint(item.Value)
+			continue
+		}
+		topo = append(topo, item)
+	}
+
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalStrict(data, &t.Topology)
+}
+
+// applyConfig reloads the reconfigured instances
+func applyConfig(clusterName string, metadata *meta.ClusterMeta, nodes []string) error {
+	options := gOpt
+	options.Nodes = nodes
+	t, err := buildReloadTask(clusterName, metadata, options)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Execute(task.NewContext()); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// confirmApplyPlan prints the changes to the cluster and asks for confirmation
+func confirmApplyPlan(clusterName string, metadata *meta.ClusterMeta, diff *meta.TopologyDiff, version string) error {
+	log.Infof("Please confirm the changes:")
+
+	cyan := color.New(color.FgCyan, color.Bold)
+	fmt.Printf("TiDB Cluster: %s\n", cyan.Sprint(clusterName))
+	if version != "" && version != metadata.Version {
+		fmt.Printf("TiDB Version: %s -> %s\n", cyan.Sprint(metadata.Version), cyan.Sprint(version))
+	} else {
+		fmt.Printf("TiDB Version: %s\n", cyan.Sprint(metadata.Version))
+	}
+
+	planTable := [][]string{
+		// Header
+		{"Action", "ID", "Role", "Host", "Ports"},
+	}
+
+	removed := set.NewStringSet(diff.Removed...)
+	reconfigured := set.NewStringSet(diff.Reconfigured...)
+	metadata.Topology.IterInstance(func(instance meta.Instance) {
+		action := ""
+		switch {
+		case removed.Exist(instance.ID()):
+			action = color.RedString("remove")
+		case reconfigured.Exist(instance.ID()):
+			action = color.YellowString("reconfigure")
+		default:
+			return
+		}
+		planTable = append(planTable, []string{
+			action,
+			instance.ID(),
+			instance.ComponentName(),
+			instance.GetHost(),
+			clusterutil.JoinInt(instance.UsedPorts(), "/"),
+		})
+	})
+	diff.Added.IterInstance(func(instance meta.Instance) {
+		planTable = append(planTable, []string{
+			color.GreenString("add"),
+			instance.ID(),
+			instance.ComponentName(),
+			instance.GetHost(),
+			clusterutil.JoinInt(instance.UsedPorts(), "/"),
+		})
+	})
+
+	cliutil.PrintTable(planTable, true)
+	if diff.HooksChanged {
+		fmt.Println("The hooks are changed.")
+	}
+
+	if len(diff.Removed) > 0 {
+		log.Warnf("Attention:")
+		log.Warnf("    The removed instances and all their data will be deleted.")
+	}
+
+	return cliutil.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: ")
+}
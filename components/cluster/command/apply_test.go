@@ -0,0 +1,43 @@
+package command
+
+import (
+	"github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+type applySuite struct{}
+
+var _ = check.Suite(&applySuite{})
+
+func (s *applySuite) TestApplyTopology(c *check.C) {
+	var topo applyTopology
+	err := yaml.UnmarshalStrict([]byte(`
+tidb_version: v4.0.2
+global:
+  user: tidb
+pd_servers:
+  - host: 172.16.5.140
+`), &topo)
+	c.Assert(err, check.IsNil)
+	c.Assert(topo.Version, check.Equals, "v4.0.2")
+	c.Assert(topo.Topology.GlobalOptions.User, check.Equals, "tidb")
+	c.Assert(topo.Topology.PDServers, check.HasLen, 1)
+	c.Assert(topo.Topology.PDServers[0].ClientPort, check.Equals, 2379)
+
+	// the version is optional
+	topo = applyTopology{}
+	err = yaml.UnmarshalStrict([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+`), &topo)
+	c.Assert(err, check.IsNil)
+	c.Assert(topo.Version, check.Equals, "")
+
+	// the topology is still parsed strictly
+	err = yaml.UnmarshalStrict([]byte(`
+tidb_version: v4.0.2
+unknown_servers:
+  - host: 172.16.5.140
+`), &applyTopology{})
+	c.Assert(err, check.NotNil)
+}
@@ -14,31 +14,76 @@
 package command
 
 import (
-	"bufio"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"sort"
+	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/pingcap/errors"
-	"github.com/pingcap/tiup/pkg/base52"
 	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/audit"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/logger/log"
 	tiuputils "github.com/pingcap/tiup/pkg/utils"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
+// envNameAuditRetainDays is the environment variable of the retention policy
+// of the audit logs, the logs older than the days are removed after the
+// commands at most once a day if it is set
+const envNameAuditRetainDays = "TIUP_CLUSTER_AUDIT_RETAIN_DAYS"
+
+// auditRetentionStamp is the file in the audit dir touched by each removing
+// of the expired audit logs, it's not decoded as an audit log
+const auditRetentionStamp = ".retention"
+
+// auditRetentionInterval is the minimum interval of removing the expired
+// audit logs after the commands
+const auditRetentionInterval = 24 * time.Hour
+
+// auditCluster is the name of the cluster operated by the command, which is
+// recorded in the audit log
+var auditCluster string
+
 func newAuditCmd() *cobra.Command {
+	var (
+		filter       audit.Filter
+		since, until string
+	)
 	cmd := &cobra.Command{
 		Use:   "audit [audit-id]",
 		Short: "Show audit log of cluster operation",
+		Long: `Show audit log of cluster operation.
+
+The audit logs are listed if no audit ID is specified, they can be searched by
+the cluster, the time and whether the operation failed. The time is either
+relative like 12h or 7d, or absolute like 2006-01-02 or RFC3339.
+
+The audit logs older than the days set by ` + envNameAuditRetainDays + ` are
+removed after the operations at most once a day, or use the cleanup command to
+remove them.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			switch len(args) {
 			case 0:
-				return showAuditList()
+				now := time.Now()
+				var err error
+				if since != "" {
+					if filter.Since, err = audit.ParseTime(since, now); err != nil {
+						return err
+					}
+				}
+				if until != "" {
+					if filter.Until, err = audit.ParseTime(until, now); err != nil {
+						return err
+					}
+				}
+				return showAuditList(filter)
 			case 1:
 				return showAuditLog(args[0])
 			default:
@@ -46,79 +91,193 @@ func newAuditCmd() *cobra.Command {
 			}
 		},
 	}
+
+	cmd.Flags().StringVar(&filter.Cluster, "cluster", "", "Only show the audit logs of the cluster")
+	cmd.Flags().StringVar(&since, "since", "", "Only show the audit logs since the time, e.g. 7d or 2006-01-02")
+	cmd.Flags().StringVar(&until, "until", "", "Only show the audit logs before the time, e.g. 1d or 2006-01-02")
+	cmd.Flags().BoolVar(&filter.Failed, "failed", false, "Only show the audit logs of the failed operations")
+
+	cmd.AddCommand(
+		newAuditReplayCmd(),
+		newAuditCleanupCmd(),
+	)
 	return cmd
 }
 
-func showAuditList() error {
-	firstLine := func(fileName string) (string, error) {
-		file, err := os.Open(meta.ProfilePath(meta.TiOpsAuditDir, fileName))
-		if err != nil {
-			return "", errors.Trace(err)
-		}
-		defer file.Close()
+func newAuditReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay <audit-id>",
+		Short: "Run the command recorded in the audit log again",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			return replayAuditLog(args[0])
+		},
+	}
+	return cmd
+}
 
-		scanner := bufio.NewScanner(file)
-		if scanner.Scan() {
-			return scanner.Text(), nil
-		}
-		return "", errors.New("unknown audit log format")
+func newAuditCleanupCmd() *cobra.Command {
+	var retainDays int
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Remove the audit logs older than the retention days",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if retainDays < 0 {
+				return errors.New("the retention days should not be negative")
+			}
+			removed, err := audit.Cleanup(meta.ProfilePath(meta.TiOpsAuditDir), time.Now().AddDate(0, 0, -retainDays))
+			if err != nil {
+				return err
+			}
+			log.Infof("%d audit logs older than %d days are removed", len(removed), retainDays)
+			return nil
+		},
 	}
+	cmd.Flags().IntVar(&retainDays, "retain-days", 90, "The days to retain the audit logs")
+	return cmd
+}
 
-	auditDir := meta.ProfilePath(meta.TiOpsAuditDir)
-	// Header
-	clusterTable := [][]string{{"ID", "Time", "Command"}}
-	fileInfos, err := ioutil.ReadDir(auditDir)
-	if err != nil && !os.IsNotExist(err) {
+// clusterNameArg returns the cluster name if it is the first argument of the command
+func clusterNameArg(cmd *cobra.Command, args []string) string {
+	if fields := strings.Fields(cmd.Use); len(args) > 0 && len(fields) > 1 && fields[1] == "<cluster-name>" {
+		return args[0]
+	}
+	return ""
+}
+
+// applyAuditRetention removes the audit logs out of the retention policy set
+// by the environment variable, it's skipped if the expired logs have been
+// removed in the last day
+func applyAuditRetention() {
+	v := os.Getenv(envNameAuditRetainDays)
+	if v == "" {
+		return
+	}
+	days, err := strconv.Atoi(v)
+	if err != nil || days < 0 {
+		zap.L().Warn("Invalid retention days of the audit logs", zap.String("env", envNameAuditRetainDays), zap.String("value", v))
+		return
+	}
+
+	dir := meta.ProfilePath(meta.TiOpsAuditDir)
+	stamp := filepath.Join(dir, auditRetentionStamp)
+	if fi, err := os.Stat(stamp); err == nil && time.Since(fi.ModTime()) < auditRetentionInterval {
+		return
+	}
+	if _, err := audit.Cleanup(dir, time.Now().AddDate(0, 0, -days)); err != nil {
+		zap.L().Warn("Remove the expired audit logs failed", zap.Error(err))
+		return
+	}
+	if err := tiuputils.CreateDir(dir); err != nil {
+		zap.L().Warn("Create the audit dir failed", zap.Error(err))
+		return
+	}
+	if err := ioutil.WriteFile(stamp, nil, 0644); err != nil {
+		zap.L().Warn("Record the removing of the expired audit logs failed", zap.Error(err))
+	}
+}
+
+func showAuditList(filter audit.Filter) error {
+	records, err := audit.List(meta.ProfilePath(meta.TiOpsAuditDir))
+	if err != nil {
 		return err
 	}
-	for _, fi := range fileInfos {
-		if fi.IsDir() {
-			continue
+	matched := []*audit.Record{}
+	for _, r := range records {
+		if filter.Match(r) {
+			matched = append(matched, r)
 		}
-		ts, err := base52.Decode(fi.Name())
-		if err != nil {
-			continue
-		}
-		t := time.Unix(ts, 0)
-		cmd, err := firstLine(fi.Name())
-		if err != nil {
-			continue
-		}
-		clusterTable = append(clusterTable, []string{
-			fi.Name(),
-			t.Format(time.RFC3339),
-			cmd,
-		})
 	}
 
-	sort.Slice(clusterTable[1:], func(i, j int) bool {
-		return clusterTable[i+1][1] > clusterTable[j+1][1]
-	})
+	if cliutil.IsStructuredOutput() {
+		return cliutil.PrintResult(struct {
+			AuditLogs []*audit.Record `json:"audit_logs" yaml:"audit_logs"`
+		}{matched})
+	}
 
+	// Header
+	clusterTable := [][]string{{"ID", "Time", "Cluster", "User", "Exit Code", "Command"}}
+	for _, r := range matched {
+		cluster, user, code := "-", "-", "-"
+		if r.Structured() {
+			code = strconv.Itoa(r.ExitCode)
+			if r.Cluster != "" {
+				cluster = r.Cluster
+			}
+			user = r.User
+		}
+		clusterTable = append(clusterTable, []string{r.ID, r.StartTime.Format(time.RFC3339), cluster, user, code, r.Command})
+	}
 	cliutil.PrintTable(clusterTable, true)
 	return nil
 }
 
 func showAuditLog(auditID string) error {
-	path := meta.ProfilePath(meta.TiOpsAuditDir, auditID)
-	if tiuputils.IsNotExist(path) {
-		return errors.Errorf("cannot find the audit log '%s'", auditID)
-	}
-
-	ts, err := base52.Decode(auditID)
+	record, content, err := audit.Read(meta.ProfilePath(meta.TiOpsAuditDir), auditID)
 	if err != nil {
-		return errors.Annotatef(err, "unrecognized audit id '%s'", auditID)
+		return err
 	}
 
-	content, err := ioutil.ReadFile(path)
-	if err != nil {
-		return errors.Trace(err)
+	if cliutil.IsStructuredOutput() {
+		return cliutil.PrintResult(struct {
+			audit.Record `yaml:",inline"`
+			Content      string `json:"content" yaml:"content"`
+		}{*record, string(content)})
 	}
-
-	t := time.Unix(ts, 0)
-	hint := fmt.Sprintf("- OPERATION TIME: %s -", t.Format("2006-01-02T15:04:05"))
+	hint := fmt.Sprintf("- OPERATION TIME: %s -", record.StartTime.Format("2006-01-02T15:04:05"))
 	line := strings.Repeat("-", len(hint))
 	_, _ = os.Stdout.WriteString(color.MagentaString("%s\n%s\n%s\n", line, hint, line))
+	_, _ = os.Stdout.WriteString(record.Command + "\n")
+	if record.Structured() {
+		fmt.Printf("Cluster:   %s\n", record.Cluster)
+		fmt.Printf("User:      %s\n", record.User)
+		fmt.Printf("Directory: %s\n", record.Dir)
+		fmt.Printf("Duration:  %s\n", record.EndTime.Sub(record.StartTime).Round(time.Second))
+		fmt.Printf("Exit Code: %d\n", record.ExitCode)
+		fmt.Printf("Hosts:     %s\n", strings.Join(record.Hosts, ", "))
+		if record.DebugLog != "" {
+			fmt.Printf("Debug Log: %s\n", record.DebugLog)
+		}
+		fmt.Println()
+	}
 	_, _ = os.Stdout.Write(content)
 	return nil
 }
+
+// replayAuditLog runs the command recorded in the audit log again with the
+// args and in the working directory of it
+func replayAuditLog(auditID string) error {
+	record, _, err := audit.Read(meta.ProfilePath(meta.TiOpsAuditDir), auditID)
+	if err != nil {
+		return err
+	}
+	if len(record.Args) == 0 {
+		return errors.Errorf("the audit log '%s' is written by an older version without the args, it can't be replayed", auditID)
+	}
+
+	if !skipConfirm {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"The command will be run again in %s:\n    %s\nDo you want to continue? [y/N]: ",
+			record.Dir, color.HiYellowString(record.Command)); err != nil {
+			return err
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	c := exec.Command(exe, record.Args...)
+	if tiuputils.IsExist(record.Dir) {
+		c.Dir = record.Dir
+	}
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return errors.Annotatef(err, "failed to replay the audit log '%s'", auditID)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/base52"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/localdata"
+)
+
+type auditSuite struct{}
+
+var _ = check.Suite(&auditSuite{})
+
+func (s *auditSuite) TestAuditRetention(c *check.C) {
+	dir := c.MkDir()
+	c.Assert(os.Setenv(localdata.EnvNameComponentDataDir, dir), check.IsNil)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+	c.Assert(meta.Initialize("cluster"), check.IsNil)
+	c.Assert(os.Setenv(envNameAuditRetainDays, "1"), check.IsNil)
+	defer os.Unsetenv(envNameAuditRetainDays)
+
+	// the audit log of 3 days ago in the legacy format
+	auditDir := filepath.Join(dir, meta.TiOpsAuditDir)
+	c.Assert(os.MkdirAll(auditDir, 0755), check.IsNil)
+	expired := filepath.Join(auditDir, base52.Encode(time.Now().AddDate(0, 0, -3).Unix()))
+	writeExpired := func() {
+		c.Assert(ioutil.WriteFile(expired, []byte("tiup cluster display\nlog\n"), 0644), check.IsNil)
+	}
+
+	writeExpired()
+	applyAuditRetention()
+	_, err := os.Stat(expired)
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+
+	// skipped in the same day
+	writeExpired()
+	applyAuditRetention()
+	_, err = os.Stat(expired)
+	c.Assert(err, check.IsNil)
+
+	// removed again a day later
+	stamp := filepath.Join(auditDir, auditRetentionStamp)
+	ts := time.Now().Add(-auditRetentionInterval - time.Minute)
+	c.Assert(os.Chtimes(stamp, ts, ts), check.IsNil)
+	applyAuditRetention()
+	_, err = os.Stat(expired)
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+}
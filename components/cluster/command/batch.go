@@ -0,0 +1,248 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// batchFlags are the flags selecting the clusters, they are not passed to
+// the command run on each cluster
+var batchFlags = set.NewStringSet("all", "label", "concurrency")
+
+// batchOptions select the clusters a command runs on, the command is run on
+// each cluster in a sub-process of its own
+type batchOptions struct {
+	all         bool
+	labels      []string
+	concurrency int
+	confirm     bool // ask for confirmation before running the command
+}
+
+// batchResult is the result of the command run on a cluster
+type batchResult struct {
+	Cluster  string      `json:"cluster" yaml:"cluster"`
+	ExitCode int         `json:"exit_code" yaml:"exit_code"`
+	Duration string      `json:"duration" yaml:"duration"`
+	Result   interface{} `json:"result,omitempty" yaml:"result,omitempty"` // the structured result of the command
+	Stdout   string      `json:"stdout,omitempty" yaml:"stdout,omitempty"`
+	Stderr   string      `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+}
+
+// addBatchFlags adds the flags to select the clusters, confirm is true if
+// the command modifies the clusters
+func addBatchFlags(cmd *cobra.Command, confirm bool) *batchOptions {
+	opt := &batchOptions{confirm: confirm}
+	cmd.Flags().BoolVar(&opt.all, "all", false, "Run on all the clusters and omit the cluster name, a glob pattern like 'prod-*' can also be used as the name to run on multiple clusters")
+	cmd.Flags().StringSliceVarP(&opt.labels, "label", "l", nil, "Run on the clusters with the labels, in the form of key=value or key, the cluster name is omitted")
+	cmd.Flags().IntVar(&opt.concurrency, "concurrency", 5, "The max number of clusters to run on at the same time")
+	return opt
+}
+
+// enabled returns true if the command runs on multiple clusters, which is
+// the case if the cluster name is a glob pattern or the clusters are
+// selected by the flags
+func (b *batchOptions) enabled(args []string) bool {
+	return b.all || len(b.labels) > 0 || (len(args) > 0 && meta.HasGlob(args[0]))
+}
+
+// run runs the command on the clusters selected, args are the arguments of
+// the command with the cluster name replaced by the pattern or omitted
+func (b *batchOptions) run(cmd *cobra.Command, args []string) error {
+	if b.concurrency < 1 {
+		return errors.New("the concurrency should be at least 1")
+	}
+
+	selector := meta.ClusterSelector{}
+	if len(args) > 0 && meta.HasGlob(args[0]) {
+		selector.Pattern = args[0]
+		args = args[1:]
+	} else if !b.all && len(b.labels) == 0 {
+		return cmd.Help()
+	}
+	labels, err := meta.ParseLabels(b.labels)
+	if err != nil {
+		return err
+	}
+	selector.Labels = labels
+
+	clusters, err := meta.SelectClusters(selector)
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return errors.New("no cluster is selected")
+	}
+
+	if b.confirm && !skipConfirm && !dryRun {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"The command will run on %d clusters: %s\nDo you want to continue? [y/N]: ",
+			len(clusters), color.HiYellowString(strings.Join(clusters, ", "))); err != nil {
+			return err
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	results := make([]batchResult, len(clusters))
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		limit = make(chan struct{}, b.concurrency)
+	)
+	for i, name := range clusters {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, name string) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			results[i] = runOnCluster(exe, batchArgs(cmd, name, args))
+			results[i].Cluster = name
+
+			if cliutil.IsStructuredOutput() {
+				// the command prints the result in the same format
+				if v, err := cliutil.ParseResult([]byte(results[i].Stdout)); err == nil && results[i].Stdout != "" {
+					results[i].Result = v
+					results[i].Stdout = ""
+				}
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			printBatchResult(&results[i])
+		}(i, name)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			failed++
+		}
+	}
+
+	if cliutil.IsStructuredOutput() {
+		if err := cliutil.PrintResult(struct {
+			Results []batchResult `json:"results" yaml:"results"`
+		}{results}); err != nil {
+			return err
+		}
+	} else {
+		resultTable := [][]string{{"Cluster", "Result", "Duration", "Message"}}
+		for _, r := range results {
+			result, message := color.GreenString("ok"), ""
+			if r.ExitCode != 0 {
+				result, message = color.RedString("failed"), lastLine(r.Stderr)
+			}
+			resultTable = append(resultTable, []string{r.Cluster, result, r.Duration, message})
+		}
+		fmt.Println()
+		cliutil.PrintTable(resultTable, true)
+	}
+
+	if failed > 0 {
+		return errors.Errorf("the command failed on %d of %d clusters", failed, len(clusters))
+	}
+	return nil
+}
+
+// batchArgs returns the arguments to run the command on the cluster, the
+// flags set are passed except the ones selecting the clusters, and the
+// confirmation is done before running on the clusters
+func batchArgs(cmd *cobra.Command, clusterName string, args []string) []string {
+	// the root is "cluster" which is the program itself
+	cmdArgs := append(getParentNames(cmd)[1:], clusterName)
+	cmdArgs = append(cmdArgs, args...)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if batchFlags.Exist(f.Name) || f.Name == "yes" {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			values := sv.GetSlice()
+			if len(values) == 0 {
+				cmdArgs = append(cmdArgs, fmt.Sprintf("--%s=", f.Name))
+			}
+			for _, v := range values {
+				cmdArgs = append(cmdArgs, fmt.Sprintf("--%s=%s", f.Name, v))
+			}
+			return
+		}
+		cmdArgs = append(cmdArgs, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return append(cmdArgs, "--yes")
+}
+
+// runOnCluster runs the program with the args and returns the result
+func runOnCluster(exe string, args []string) batchResult {
+	var stdout, stderr bytes.Buffer
+	c := exec.Command(exe, args...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	start := time.Now()
+	err := c.Run()
+	result := batchResult{
+		Duration: time.Since(start).Round(time.Second).String(),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	}
+	if err != nil {
+		result.ExitCode = 1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.Stderr += err.Error()
+		}
+	}
+	return result
+}
+
+func printBatchResult(r *batchResult) {
+	status := color.GreenString("ok")
+	if r.ExitCode != 0 {
+		status = color.RedString("failed with exit code %d", r.ExitCode)
+	}
+	fmt.Printf("%s %s (%s, %s)\n", color.CyanString("==>"), color.CyanString(r.Cluster), status, r.Duration)
+	if r.Stdout != "" {
+		fmt.Print(r.Stdout)
+	}
+	if r.Stderr != "" {
+		fmt.Fprint(os.Stderr, r.Stderr)
+	}
+}
+
+// lastLine returns the last non-empty line of the output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
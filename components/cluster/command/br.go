@@ -0,0 +1,385 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+type brOptions struct {
+	storage     string
+	host        string
+	db          string
+	table       string
+	from        string // the ID of the backup job to restore from
+	rateLimit   uint
+	concurrency uint
+	s3Endpoint  string
+	s3Region    string
+	timeout     int64
+}
+
+func newBackupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up the data of a cluster with BR",
+	}
+
+	cmd.AddCommand(
+		newBackupCreateCmd(),
+		newBackupListCmd(),
+	)
+	return cmd
+}
+
+func newBackupCreateCmd() *cobra.Command {
+	opt := brOptions{}
+	cmd := &cobra.Command{
+		Use:   "create <cluster-name>",
+		Short: "Back up the cluster, a database or a table to the storage",
+		Long: `Back up the cluster, a database or a table to the storage by running BR
+on a host of the cluster, the PD host by default. The storage is a local path
+or a URL of S3 compatible or GCS storage, e.g.:
+
+  /data/backup (the same as local:///data/backup)
+  s3://bucket/prefix?endpoint=http://minio:9000
+
+NOTE: the files of a local storage are written by each TiKV to the path on
+its own host, mount a shared filesystem to the path to keep a full backup.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			storage, err := meta.ParseBRStorage(opt.storage)
+			if err != nil {
+				return err
+			}
+
+			job := meta.NewBRJob(meta.BRJobBackup, storage)
+			job.DB, job.Table = opt.db, opt.table
+			return runBRJob(clusterName, job, &opt)
+		},
+	}
+
+	addBRFlags(cmd, &opt)
+	cmd.Flags().UintVar(&opt.rateLimit, "ratelimit", 0, "The rate limit in MiB/s of the backup on each TiKV, unlimited if not set")
+
+	return requireLock(cmd, lockExisting)
+}
+
+func newBackupListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list <cluster-name>",
+		Short: "List the backup and restore jobs of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cluster %s does not exist", clusterName)
+			}
+
+			jobs, err := meta.ListBRJobs(clusterName)
+			if err != nil {
+				return err
+			}
+
+			if cliutil.IsStructuredOutput() {
+				if jobs == nil {
+					jobs = []*meta.BRJob{}
+				}
+				return cliutil.PrintResult(struct {
+					Jobs []*meta.BRJob `json:"jobs" yaml:"jobs"`
+				}{jobs})
+			}
+
+			jobTable := [][]string{
+				{"ID", "Type", "Scope", "Storage", "Host", "Status", "Start Time", "Duration"},
+			}
+			for _, job := range jobs {
+				scope := job.Scope()
+				switch {
+				case job.Table != "":
+					scope = fmt.Sprintf("%s.%s", job.DB, job.Table)
+				case job.DB != "":
+					scope = job.DB
+				}
+				duration := "-"
+				if !job.EndTime.IsZero() {
+					duration = job.EndTime.Sub(job.StartTime).Round(time.Second).String()
+				}
+				jobTable = append(jobTable, []string{
+					job.ID,
+					job.Type,
+					scope,
+					job.Storage,
+					job.Host,
+					formatBRJobStatus(job.Status),
+					job.StartTime.Format(time.RFC3339),
+					duration,
+				})
+			}
+			cliutil.PrintTable(jobTable, true)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newRestoreCmd() *cobra.Command {
+	opt := brOptions{}
+	cmd := &cobra.Command{
+		Use:   "restore <cluster-name>",
+		Short: "Restore the data of a cluster from a backup with BR",
+		Long: `Restore the data of a cluster from a backup with BR. The backup is either a
+storage specified by --storage or a backup job of the cluster specified by
+--from, the database and table of the backup job are restored by default.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if opt.from != "" && opt.storage != "" {
+				return errors.New("--from and --storage can not be used at the same time")
+			}
+
+			var job *meta.BRJob
+			if opt.from != "" {
+				source, err := meta.GetBRJob(clusterName, opt.from)
+				if err != nil {
+					return err
+				}
+				if source.Type != meta.BRJobBackup || source.Status != meta.BRJobSuccess {
+					return errors.Errorf("job %s is not a successful backup job", source.ID)
+				}
+				job = meta.NewBRJob(meta.BRJobRestore, source.Storage)
+				job.Source = source.ID
+				job.DB, job.Table = source.DB, source.Table
+			} else {
+				storage, err := meta.ParseBRStorage(opt.storage)
+				if err != nil {
+					return err
+				}
+				job = meta.NewBRJob(meta.BRJobRestore, storage)
+			}
+			if opt.db != "" {
+				job.DB, job.Table = opt.db, opt.table
+			}
+
+			if !skipConfirm {
+				if err := cliutil.PromptForConfirmOrAbortError(
+					"The data of cluster %s will be restored from %s, the tables restored must not exist.\nDo you want to continue? [y/N]: ",
+					color.HiYellowString(clusterName), color.HiYellowString(meta.ScrubBRStorage(job.Storage))); err != nil {
+					return err
+				}
+			}
+			return runBRJob(clusterName, job, &opt)
+		},
+	}
+
+	addBRFlags(cmd, &opt)
+	cmd.Flags().StringVar(&opt.from, "from", "", "The ID of the backup job to restore from, the credentials of its storage are not recorded, use --storage if they are required")
+
+	return requireLock(cmd, lockExisting)
+}
+
+func addBRFlags(cmd *cobra.Command, opt *brOptions) {
+	cmd.Flags().StringVar(&opt.storage, "storage", "", "The storage of the backup, a local path or a URL of local://, s3:// or gcs://")
+	cmd.Flags().StringVar(&opt.host, "host", "", "The host of the cluster to run BR on, the host of the first PD by default")
+	cmd.Flags().StringVar(&opt.db, "db", "", "Only back up or restore the database")
+	cmd.Flags().StringVar(&opt.table, "table", "", "Only back up or restore the table of the database specified by --db")
+	cmd.Flags().UintVar(&opt.concurrency, "concurrency", 0, "The concurrency of the tasks on each TiKV, the default of BR is used if not set")
+	cmd.Flags().StringVar(&opt.s3Endpoint, "s3.endpoint", "", "The endpoint of the S3 compatible storage")
+	cmd.Flags().StringVar(&opt.s3Region, "s3.region", "", "The region of the S3 storage")
+	cmd.Flags().Int64Var(&opt.timeout, "br-timeout", 86400, "Timeout in seconds to wait for BR to finish")
+}
+
+// runBRJob runs BR on the host of the cluster and records the job
+func runBRJob(clusterName string, job *meta.BRJob, opt *brOptions) error {
+	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return errors.Errorf("cluster %s does not exist", clusterName)
+	}
+	if job.Table != "" && job.DB == "" {
+		return errors.New("the database of the table must be specified by --db")
+	}
+
+	logger.EnableAuditLog()
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+	topo := metadata.Topology
+	if len(topo.PDServers) == 0 {
+		return errors.Errorf("cluster %s has no pd server", clusterName)
+	}
+
+	host := opt.host
+	if host == "" {
+		host = topo.PDServers[0].Host
+	}
+	var target meta.Instance
+	topo.IterInstance(func(inst meta.Instance) {
+		if target == nil && inst.GetHost() == host {
+			target = inst
+		}
+	})
+	if target == nil {
+		return errors.Errorf("host %s is not a host of cluster %s", host, clusterName)
+	}
+
+	remoteDir := filepath.Join(task.CheckToolsPathDir, job.ID)
+	job.Host = host
+	job.Version = metadata.Version
+	// the log is kept with the logs of the instance, the temporary dir of the
+	// binary is removed after the job
+	job.LogFile = filepath.Join(clusterutil.Abs(metadata.User, target.LogDir()), fmt.Sprintf("br-%s.log", job.ID))
+	if job.Type == meta.BRJobBackup && strings.HasPrefix(job.Storage, "local://") {
+		log.Warnf("The backup files are written by each TiKV to %s on its own host", strings.TrimPrefix(job.Storage, "local://"))
+	}
+	// the credentials in the storage URL are passed to BR but not recorded
+	storage := job.Storage
+	job.Storage = meta.ScrubBRStorage(storage)
+
+	b := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		UserSSH(host, target.GetSSHPort(), metadata.User, gOpt.SSHTimeout).
+		Download(meta.ComponentBR, target.OS(), target.Arch(), metadata.Version).
+		Mkdir(metadata.User, host, filepath.Join(remoteDir, "bin")).
+		CopyComponent(meta.ComponentBR, target.OS(), target.Arch(), metadata.Version, host, remoteDir)
+	tlsDir := ""
+	if topo.GlobalOptions.TLSEnabled {
+		tlsDir = filepath.Join(remoteDir, meta.TLSCertKeyDir)
+		b.Mkdir(metadata.User, host, tlsDir)
+		for _, f := range []string{meta.TLSCACert, meta.TLSClientCert, meta.TLSClientKey} {
+			b.CopyFile(meta.ClusterPath(clusterName, meta.TLSCertKeyDir, f), filepath.Join(tlsDir, f), host, false)
+		}
+	}
+
+	command := brCommand(filepath.Join(remoteDir, "bin", "br"), job, storage, topo, tlsDir, opt)
+	t := b.Func("RunBR", func(ctx *task.Context) error {
+		exec, found := ctx.GetExecutor(host)
+		if !found {
+			return task.ErrNoExecutor
+		}
+		log.Infof("Run %s on %s, it may take a long time", color.CyanString(job.ID), host)
+		stdout, stderr, err := exec.Execute(command, false, time.Duration(opt.timeout)*time.Second)
+		ctx.SetOutputs(host, stdout, stderr)
+		if err != nil {
+			return errors.Annotatef(err, "stderr: %s, see %s:%s for details", strings.TrimSpace(string(stderr)), host, job.LogFile)
+		}
+		return nil
+	}).Build()
+
+	if err := meta.SaveBRJob(clusterName, job); err != nil {
+		return err
+	}
+	ctx := task.NewContext()
+	err = t.Execute(ctx)
+	// the binary is removed whether BR succeeds or not, the log is kept
+	_ = task.NewBuilder().Rmdir(host, remoteDir).Build().Execute(ctx)
+
+	job.Finish(err)
+	if saveErr := meta.SaveBRJob(clusterName, job); saveErr != nil {
+		log.Errorf("Failed to save the record of job %s: %s", job.ID, saveErr)
+	}
+	if err != nil {
+		log.Errorf("Job %s failed", job.ID)
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	log.Infof("Job %s of cluster %s finished successfully, the log is %s:%s",
+		color.CyanString(job.ID), clusterName, host, job.LogFile)
+	return nil
+}
+
+// brCommand returns the command line running the job with the BR binary
+func brCommand(bin string, job *meta.BRJob, storage string, topo *meta.TopologySpecification, tlsDir string, opt *brOptions) string {
+	var pdAddrs []string
+	for _, pd := range topo.PDServers {
+		pdAddrs = append(pdAddrs, fmt.Sprintf("%s:%d", pd.Host, pd.ClientPort))
+	}
+
+	args := []string{
+		bin, job.Type, job.Scope(),
+		"--pd", strings.Join(pdAddrs, ","),
+		"--storage", tiuputils.ShellQuote(storage),
+		"--log-file", job.LogFile,
+	}
+	if job.DB != "" {
+		args = append(args, "--db", tiuputils.ShellQuote(job.DB))
+	}
+	if job.Table != "" {
+		args = append(args, "--table", tiuputils.ShellQuote(job.Table))
+	}
+	if opt.rateLimit > 0 {
+		args = append(args, "--ratelimit", fmt.Sprint(opt.rateLimit))
+	}
+	if opt.concurrency > 0 {
+		args = append(args, "--concurrency", fmt.Sprint(opt.concurrency))
+	}
+	if opt.s3Endpoint != "" {
+		args = append(args, "--s3.endpoint", tiuputils.ShellQuote(opt.s3Endpoint))
+	}
+	if opt.s3Region != "" {
+		args = append(args, "--s3.region", tiuputils.ShellQuote(opt.s3Region))
+	}
+	if tlsDir != "" {
+		args = append(args,
+			"--ca", filepath.Join(tlsDir, meta.TLSCACert),
+			"--cert", filepath.Join(tlsDir, meta.TLSClientCert),
+			"--key", filepath.Join(tlsDir, meta.TLSClientKey),
+		)
+	}
+	return strings.Join(args, " ")
+}
+
+func formatBRJobStatus(status string) string {
+	switch status {
+	case meta.BRJobSuccess:
+		return color.GreenString(status)
+	case meta.BRJobFailed:
+		return color.RedString(status)
+	default:
+		return color.YellowString(status)
+	}
+}
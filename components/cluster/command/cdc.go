@@ -0,0 +1,284 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newCDCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cdc",
+		Short: "Manage the TiCDC of a cluster",
+	}
+
+	cmd.AddCommand(newCDCChangefeedCmd())
+	return cmd
+}
+
+func newCDCChangefeedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "changefeed",
+		Short: "Manage the changefeeds of TiCDC",
+		Long: `Manage the changefeeds of TiCDC.
+
+The open API of TiCDC is used since v5.3.0. For the earlier versions, the
+changefeeds are listed and created by running 'cdc cli' on the host of the
+first TiCDC server, and they are queried and administered through the API of
+the owner.`,
+	}
+
+	cmd.AddCommand(
+		newChangefeedCreateCmd(),
+		newChangefeedListCmd(),
+		newChangefeedActionCmd("pause", "Pause a changefeed", api.CDCChangefeedClient.PauseChangefeed),
+		newChangefeedActionCmd("resume", "Resume a paused changefeed", api.CDCChangefeedClient.ResumeChangefeed),
+		newChangefeedActionCmd("remove", "Remove a changefeed", api.CDCChangefeedClient.RemoveChangefeed),
+	)
+	return cmd
+}
+
+func newChangefeedCreateCmd() *cobra.Command {
+	cfg := api.CDCChangefeedConfig{}
+	cmd := &cobra.Command{
+		Use:   "create <cluster-name> <changefeed-id>",
+		Short: "Create a changefeed replicating to the sink",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if cfg.SinkURI == "" {
+				return errors.New("the sink URI is required, specify it by --sink-uri")
+			}
+
+			client, err := cdcClient(clusterName)
+			if err != nil {
+				return err
+			}
+			cfg.ID = args[1]
+			if err := client.CreateChangefeed(&cfg); err != nil {
+				return err
+			}
+			log.Infof("Changefeed %s of cluster %s is created", cfg.ID, clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&cfg.SinkURI, "sink-uri", "", "The URI of the downstream, e.g. mysql://root:@127.0.0.1:3306/ or kafka://127.0.0.1:9092/topic")
+	cmd.Flags().Uint64Var(&cfg.StartTS, "start-ts", 0, "The TSO to start replicating from, the current TSO is used if not set")
+	cmd.Flags().Uint64Var(&cfg.TargetTS, "target-ts", 0, "The TSO to stop replicating at, it replicates endlessly if not set")
+	cmd.Flags().StringSliceVar(&cfg.FilterRules, "filter", nil, "The table filter rules, e.g. 'db1.*,!db1.tmp'")
+	cmd.Flags().BoolVar(&cfg.ForceReplicate, "force-replicate", false, "Replicate the tables without a valid index")
+	cmd.Flags().BoolVar(&cfg.IgnoreIneligibleTable, "ignore-ineligible-table", false, "Ignore the tables which can not be replicated")
+
+	return requireLock(cmd, lockExisting)
+}
+
+func newChangefeedListCmd() *cobra.Command {
+	state := ""
+	cmd := &cobra.Command{
+		Use:   "list <cluster-name>",
+		Short: "List the changefeeds",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			client, err := cdcClient(clusterName)
+			if err != nil {
+				return err
+			}
+			changefeeds, err := client.ListChangefeeds(state)
+			if err != nil {
+				return err
+			}
+
+			if cliutil.IsStructuredOutput() {
+				return cliutil.PrintResult(struct {
+					Changefeeds []api.CDCChangefeed `json:"changefeeds" yaml:"changefeeds"`
+				}{changefeeds})
+			}
+
+			clusterTable := [][]string{
+				{"ID", "State", "Checkpoint TSO", "Checkpoint Time", "Error"},
+			}
+			for _, cf := range changefeeds {
+				errMsg := "-"
+				if cf.Error != nil {
+					errMsg = fmt.Sprintf("%s: %s", cf.Error.Code, cf.Error.Message)
+				}
+				clusterTable = append(clusterTable, []string{
+					cf.ID,
+					cf.State,
+					fmt.Sprint(cf.CheckpointTSO),
+					cf.CheckpointTime,
+					errMsg,
+				})
+			}
+			cliutil.PrintTable(clusterTable, true)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&state, "state", "all", "Only list the changefeeds in the state, one of normal, stopped, error, failed, finished and all")
+
+	return cmd
+}
+
+func newChangefeedActionCmd(action, short string, fn func(api.CDCChangefeedClient, string) error) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s <cluster-name> <changefeed-id>", action),
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName, id := args[0], args[1]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if action == "remove" && !skipConfirm {
+				if err := cliutil.PromptForConfirmOrAbortError(
+					fmt.Sprintf("The changefeed %s will be removed and can not be resumed.\nDo you want to continue? [y/N]: ", id)); err != nil {
+					return err
+				}
+			}
+
+			client, err := cdcClient(clusterName)
+			if err != nil {
+				return err
+			}
+			if err := fn(client, id); err != nil {
+				return err
+			}
+			log.Infof("Changefeed %s of cluster %s is %sd", id, clusterName, action)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// cdcClient returns the client of the changefeeds of the TiCDC of the cluster
+func cdcClient(clusterName string) (api.CDCChangefeedClient, error) {
+	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return nil, errors.Errorf("cluster %s does not exist", clusterName)
+	}
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	for _, s := range metadata.Topology.CDCServers {
+		addrs = append(addrs, fmt.Sprintf("%s:%d", s.Host, s.Port))
+	}
+	if len(addrs) == 0 {
+		return nil, errors.Errorf("cluster %s has no cdc server", clusterName)
+	}
+
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(gOpt.OptTimeout) * time.Second
+	return api.NewCDCChangefeedClient(metadata.Version, addrs, timeout, tlsCfg, cdcCliRunner(clusterName, metadata)), nil
+}
+
+// cdcCliRunner returns the runner of 'cdc cli' on the host of the first cdc
+// server, it's used by the TiCDC earlier than v5.3.0 without the open API
+func cdcCliRunner(clusterName string, metadata *meta.ClusterMeta) api.CDCCliRunner {
+	topo := metadata.Topology
+	cdc := topo.CDCServers[0]
+	deployDir := clusterutil.Abs(metadata.User, cdc.DeployDir)
+
+	scheme := "http"
+	if topo.GlobalOptions.TLSEnabled {
+		scheme = "https"
+	}
+	var pdAddrs []string
+	for _, pd := range topo.PDServers {
+		pdAddrs = append(pdAddrs, fmt.Sprintf("%s://%s:%d", scheme, pd.Host, pd.ClientPort))
+	}
+	command := []string{filepath.Join(deployDir, "bin", "cdc"), "cli", "--pd=" + strings.Join(pdAddrs, ",")}
+	if topo.GlobalOptions.TLSEnabled {
+		tlsDir := filepath.Join(deployDir, meta.TLSCertKeyDir)
+		command = append(command,
+			"--ca="+filepath.Join(tlsDir, meta.TLSCACert),
+			"--cert="+filepath.Join(tlsDir, meta.ComponentCDC+".crt"),
+			"--key="+filepath.Join(tlsDir, meta.ComponentCDC+".pem"),
+		)
+	}
+
+	return func(args []string, config []byte) ([]byte, error) {
+		cmd := append([]string{}, command...)
+		for _, arg := range args {
+			cmd = append(cmd, tiuputils.ShellQuote(arg))
+		}
+		configPath := filepath.Join(deployDir, "changefeed.toml")
+		if len(config) > 0 {
+			cmd = append(cmd, "--config="+configPath)
+		}
+
+		var stdout []byte
+		t := task.NewBuilder().
+			SSHKeySet(
+				meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+				meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+			UserSSH(cdc.Host, cdc.SSHPort, metadata.User, gOpt.SSHTimeout).
+			Func("RunCDCCli", func(ctx *task.Context) error {
+				exec, found := ctx.GetExecutor(cdc.Host)
+				if !found {
+					return task.ErrNoExecutor
+				}
+				if len(config) > 0 {
+					write := fmt.Sprintf("printf '%%s' %s > %s", tiuputils.ShellQuote(string(config)), configPath)
+					if _, stderr, err := exec.Execute(write, false); err != nil {
+						return errors.Annotatef(err, "stderr: %s", strings.TrimSpace(string(stderr)))
+					}
+					defer func() {
+						_, _, _ = exec.Execute(fmt.Sprintf("rm -f %s", configPath), false)
+					}()
+				}
+				out, stderr, err := exec.Execute(strings.Join(cmd, " "), false)
+				if err != nil {
+					return errors.Annotatef(err, "stderr: %s", strings.TrimSpace(string(stderr)))
+				}
+				stdout = out
+				return nil
+			}).
+			Build()
+		if err := t.Execute(task.NewContext()); err != nil {
+			return nil, err
+		}
+		return stdout, nil
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/telemetry"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+type collectOptions struct {
+	since      time.Duration
+	dmesgLines int
+	timeout    int64
+	output     string
+}
+
+func newCollectCmd() *cobra.Command {
+	opt := collectOptions{}
+	cmd := &cobra.Command{
+		Use:   "collect <cluster-name>",
+		Short: "Collect the logs and configs of the instances to a bundle for troubleshooting",
+		Long: `Collect the logs, rendered configs, systemd status of the instances and the
+dmesg of the hosts in parallel, and package them with the scrubbed meta of the
+cluster and a manifest to one tarball for troubleshooting.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot collect files of non-exists cluster %s", clusterName)
+			}
+
+			logger.EnableAuditLog()
+			return collectBundle(clusterName, &opt)
+		},
+	}
+
+	cmd.Flags().DurationVar(&opt.since, "since", 0, "Only collect the logs modified in the duration, e.g. 2h, all the logs are collected if not set")
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only collect the files of the instances of the roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only collect the files of the instances of the nodes")
+	cmd.Flags().IntVar(&opt.dmesgLines, "dmesg-lines", 1000, "The number of the last lines of dmesg collected from each host, 0 to skip")
+	cmd.Flags().Int64Var(&opt.timeout, "collect-timeout", 600, "Timeout in seconds to collect the files on each host")
+	cmd.Flags().StringVarP(&opt.output, "output", "o", "", "The path of the bundle, <cluster-name>-diag-<time>.tar.gz in the current directory by default")
+
+	return cmd
+}
+
+func collectBundle(clusterName string, opt *collectOptions) error {
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	filterRoles := set.NewStringSet(gOpt.Roles...)
+	filterNodes := set.NewStringSet(gOpt.Nodes...)
+	var hosts []string
+	instances := make(map[string][]meta.Instance) // host -> instances
+	metadata.Topology.IterInstance(func(inst meta.Instance) {
+		if len(gOpt.Roles) > 0 && !filterRoles.Exist(inst.Role()) {
+			return
+		}
+		if len(gOpt.Nodes) > 0 && !filterNodes.Exist(inst.ID()) {
+			return
+		}
+		if _, found := instances[inst.GetHost()]; !found {
+			hosts = append(hosts, inst.GetHost())
+		}
+		instances[inst.GetHost()] = append(instances[inst.GetHost()], inst)
+	})
+	if len(hosts) == 0 {
+		return errors.New("no instance matches the roles and nodes specified")
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-diag-%s", clusterName, now.Format("20060102150405"))
+	output := opt.output
+	if output == "" {
+		output = name + ".tar.gz"
+	}
+	localDir, err := ioutil.TempDir("", "tiup-collect")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.RemoveAll(localDir)
+
+	collectOpt := operator.CollectOptions{
+		DeployUser: metadata.User,
+		Since:      opt.since,
+		DmesgLines: opt.dmesgLines,
+		Timeout:    time.Duration(opt.timeout) * time.Second,
+	}
+	var (
+		mu      sync.Mutex
+		results = make(map[string]error)
+		tasks   []*task.StepDisplay
+	)
+	for _, host := range hosts {
+		host := host
+		t := task.NewBuilder().
+			Func("Collect", func(ctx *task.Context) error {
+				exec, found := ctx.GetExecutor(host)
+				err := task.ErrNoExecutor
+				if found {
+					err = operator.CollectHost(exec, instances[host],
+						filepath.Join(task.CheckToolsPathDir, name), filepath.Join(localDir, host+".tar.gz"), collectOpt)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				results[host] = err
+				// the bundle is still built if some of the hosts fail
+				return nil
+			}).
+			BuildAsStep(fmt.Sprintf("  - Collecting files on %s", host))
+		tasks = append(tasks, t)
+	}
+
+	t := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
+		ParallelStep("+ Collect the files of the instances", tasks...).
+		Build()
+	if err := t.Execute(task.NewContext()); err != nil {
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	manifest := operator.CollectManifest{
+		Cluster:   clusterName,
+		Version:   metadata.Version,
+		CreatedAt: now,
+		Roles:     gOpt.Roles,
+		Nodes:     gOpt.Nodes,
+	}
+	if opt.since > 0 {
+		manifest.Since = opt.since.String()
+	}
+
+	w, err := operator.NewBundleWriter(output, name)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, host := range hosts {
+		collected := operator.CollectedHost{Host: host}
+		for _, inst := range instances[host] {
+			collected.Instances = append(collected.Instances, inst.ID())
+		}
+		err := results[host]
+		if err == nil {
+			collected.Files, err = w.AddArchive(host, filepath.Join(localDir, host+".tar.gz"))
+		}
+		if err != nil {
+			failed++
+			collected.Error = err.Error()
+			log.Warnf("Failed to collect the files on %s: %s", host, err)
+		}
+		sort.Strings(collected.Files)
+		manifest.Hosts = append(manifest.Hosts, collected)
+	}
+
+	if err := addScrubbedMeta(w, clusterName); err != nil {
+		log.Warnf("Failed to add the meta of the cluster: %s", err)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = w.AddFile("manifest.json", data)
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed == len(hosts) {
+		return errors.Errorf("failed to collect the files on all the hosts, see %s for details", output)
+	}
+	if failed > 0 {
+		log.Warnf("The files on %d of %d hosts are not collected", failed, len(hosts))
+	}
+
+	bundleTable := [][]string{{"Host", "Instances", "Files", "Status"}}
+	for _, h := range manifest.Hosts {
+		status := color.GreenString("ok")
+		if h.Error != "" {
+			status = color.RedString("failed")
+		}
+		bundleTable = append(bundleTable, []string{h.Host, fmt.Sprint(len(h.Instances)), fmt.Sprint(len(h.Files)), status})
+	}
+	cliutil.PrintTable(bundleTable, true)
+	log.Infof("The bundle of cluster %s is written to %s", clusterName, color.CyanString(output))
+	return nil
+}
+
+// addScrubbedMeta adds the meta of the cluster scrubbed of the sensitive
+// values to the bundle
+func addScrubbedMeta(w *operator.BundleWriter, clusterName string) error {
+	data, err := ioutil.ReadFile(meta.ClusterPath(clusterName, meta.MetaFileName))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	scrubbed, err := telemetry.ScrubYaml(data, map[string]struct{}{"host": {}})
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	return w.AddFile(meta.MetaFileName, scrubbed)
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/edit"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	errNSConfig    = errNS.NewSubNamespace("config")
+	errConfigDrift = errNSConfig.NewType("drift", errutil.ErrTraitPreCheck)
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration of the instances",
+	}
+
+	cmd.AddCommand(newConfigDiffCmd())
+	return cmd
+}
+
+func newConfigDiffCmd() *cobra.Command {
+	check := false
+	cmd := &cobra.Command{
+		Use:   "diff <cluster-name>",
+		Short: "Show the changes of the config files and scripts on the hosts",
+		Long: `Render the config files, scripts and systemd units of the instances from the
+topology the same way as reload, and compare them with the files on the hosts.
+The changes made on the hosts directly are overwritten by the next reload, they
+should be imported into the topology by edit-config if they are wanted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot diff config of non-exists cluster %s", clusterName)
+			}
+
+			drifts, err := diffConfig(clusterName)
+			if err != nil {
+				return err
+			}
+			if cliutil.IsStructuredOutput() {
+				if drifts == nil {
+					drifts = []operator.ConfigDrift{}
+				}
+				if err := cliutil.PrintResult(struct {
+					Drifts []operator.ConfigDrift `json:"drifts" yaml:"drifts"`
+				}{drifts}); err != nil {
+					return err
+				}
+			} else {
+				printConfigDrifts(clusterName, drifts)
+			}
+
+			if check && len(drifts) > 0 {
+				return errConfigDrift.New("%d files of cluster %s differ from the topology", len(drifts), clusterName)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "Exit with a non-zero code if any file differs, e.g. to be run by cron")
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only compare the files of the instances of the roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only compare the files of the instances of the nodes")
+
+	return cmd
+}
+
+// diffConfig returns the files of the instances differing from the ones
+// rendered from the topology
+func diffConfig(clusterName string) ([]operator.ConfigDrift, error) {
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheDir, err := ioutil.TempDir("", "tiup-config-diff")
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	filterRoles := set.NewStringSet(gOpt.Roles...)
+	filterNodes := set.NewStringSet(gOpt.Nodes...)
+	var hosts []string
+	instances := make(map[string][]meta.Instance)  // host -> instances
+	rendered := make(map[string]map[string][]byte) // instance -> path -> content
+	var renderErr error
+	metadata.Topology.IterInstance(func(inst meta.Instance) {
+		if renderErr != nil {
+			return
+		}
+		if len(gOpt.Roles) > 0 && !filterRoles.Exist(inst.Role()) {
+			return
+		}
+		if len(gOpt.Nodes) > 0 && !filterNodes.Exist(inst.ID()) {
+			return
+		}
+
+		files, err := operator.RenderConfigs(inst, clusterName, metadata.Version, metadata.User, meta.DirPaths{
+			Deploy: clusterutil.Abs(metadata.User, inst.DeployDir()),
+			Data:   clusterutil.MultiDirAbs(metadata.User, inst.DataDir()),
+			Log:    clusterutil.Abs(metadata.User, inst.LogDir()),
+			Cache:  cacheDir,
+		})
+		if err != nil {
+			renderErr = err
+			return
+		}
+		rendered[inst.ID()] = files
+		if _, found := instances[inst.GetHost()]; !found {
+			hosts = append(hosts, inst.GetHost())
+		}
+		instances[inst.GetHost()] = append(instances[inst.GetHost()], inst)
+	})
+	if renderErr != nil {
+		return nil, renderErr
+	}
+	if len(hosts) == 0 {
+		return nil, errors.New("no instance matches the roles and nodes specified")
+	}
+
+	var (
+		mu     sync.Mutex
+		drifts []operator.ConfigDrift
+		tasks  []task.Task
+	)
+	for _, host := range hosts {
+		host := host
+		tasks = append(tasks, task.NewBuilder().
+			Func("DiffConfig", func(ctx *task.Context) error {
+				exec, found := ctx.GetExecutor(host)
+				if !found {
+					return task.ErrNoExecutor
+				}
+				var files []string
+				for _, inst := range instances[host] {
+					for path := range rendered[inst.ID()] {
+						files = append(files, path)
+					}
+				}
+				sort.Strings(files)
+				actual, err := operator.ReadRemoteFiles(exec, files)
+				if err != nil {
+					return errors.Annotatef(err, "read config files on %s", host)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				for _, inst := range instances[host] {
+					drifts = append(drifts, operator.DiffConfigs(inst.ID(), rendered[inst.ID()], actual)...)
+				}
+				return nil
+			}).
+			Build())
+	}
+
+	t := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
+		Parallel(tasks...).
+		Build()
+	if err := t.Execute(task.NewContext()); err != nil {
+		if errorx.Cast(err) != nil {
+			return nil, err
+		}
+		return nil, errors.Trace(err)
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Instance < drifts[j].Instance
+	})
+	return drifts, nil
+}
+
+func printConfigDrifts(clusterName string, drifts []operator.ConfigDrift) {
+	if len(drifts) == 0 {
+		log.Infof("The config files of cluster %s are the same as the topology", clusterName)
+		return
+	}
+
+	fmt.Printf("The changes from the files on the hosts (%s) to the ones rendered from the topology (%s):\n",
+		color.RedString("red"), color.GreenString("green"))
+	for _, d := range drifts {
+		fmt.Println()
+		if d.Missing {
+			fmt.Println(color.CyanString("%s %s (missing)", d.Instance, d.Path))
+			continue
+		}
+		fmt.Println(color.CyanString("%s %s", d.Instance, d.Path))
+		edit.ShowDiff(d.Actual, d.Expected, os.Stdout)
+	}
+	fmt.Println()
+
+	instances := set.NewStringSet()
+	for _, d := range drifts {
+		instances.Insert(d.Instance)
+	}
+	log.Warnf("%d files of %d instances differ from the topology, the changes on the hosts will be overwritten by reload", len(drifts), len(instances))
+	log.Infof("To keep the changes, import them into the topology by `%s`", color.YellowString("tiup cluster edit-config %s", clusterName))
+}
@@ -32,6 +32,7 @@ import (
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/report"
 	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/crypto"
 	"github.com/pingcap/tiup/pkg/errutil"
 	"github.com/pingcap/tiup/pkg/logger"
 	"github.com/pingcap/tiup/pkg/logger/log"
@@ -64,6 +65,8 @@ type (
 		user         string // username to login to the SSH server
 		identityFile string // path to the private key file
 		usePassword  bool   // use password instead of identity file for ssh connection
+		resume       bool   // skip the tasks finished by the previous interrupted deploy
+		noRollback   bool   // keep the work finished by this run if the deploy fails
 	}
 
 	hostInfo struct {
@@ -92,7 +95,9 @@ func newDeploy() *cobra.Command {
 				return nil
 			}
 
-			logger.EnableAuditLog()
+			if !dryRun {
+				logger.EnableAuditLog()
+			}
 			clusterName := args[0]
 			version := args[1]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
@@ -104,8 +109,11 @@ func newDeploy() *cobra.Command {
 	cmd.Flags().StringVar(&opt.user, "user", tiuputils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
 	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.resume, "resume", false, "Resume the previous interrupted deploy, the finished tasks are skipped.")
+	cmd.Flags().BoolVar(&opt.noRollback, "no-rollback", false, "Do not revert the tasks finished by this run if the deploy fails, the tasks finished by the previous runs are never reverted.")
+	addDryRunFlag(cmd)
 
-	return cmd
+	return requireLock(cmd, lockNew)
 }
 
 func confirmTopology(clusterName, version string, topo *meta.ClusterSpecification, patchedRoles set.StringSet) error {
@@ -173,21 +181,41 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		return err
 	}
 
-	if !skipConfirm {
+	if !skipConfirm && !dryRun {
 		if err := confirmTopology(clusterName, clusterVersion, &topo, set.NewStringSet()); err != nil {
 			return err
 		}
 	}
 
-	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword)
+	sshConnProps, err := readSSHConnectionProps(opt.identityFile, opt.usePassword)
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(meta.ClusterPath(clusterName), 0755); err != nil {
-		return errorx.InitializationFailed.
-			Wrap(err, "Failed to create cluster metadata directory '%s'", meta.ClusterPath(clusterName)).
-			WithProperty(cliutil.SuggestionFromString("Please check file system permissions and try again."))
+	var ca *crypto.CertificateAuthority
+	if dryRun {
+		// nothing is written in dry run, the CA is only used to build the tasks
+		if topo.GlobalOptions.TLSEnabled {
+			if ca, err = crypto.NewCA(clusterName); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := os.MkdirAll(meta.ClusterPath(clusterName), 0755); err != nil {
+			return errorx.InitializationFailed.
+				Wrap(err, "Failed to create cluster metadata directory '%s'", meta.ClusterPath(clusterName)).
+				WithProperty(cliutil.SuggestionFromString("Please check file system permissions and try again."))
+		}
+
+		if topo.GlobalOptions.TLSEnabled {
+			tlsDir := meta.ClusterPath(clusterName, meta.TLSCertKeyDir)
+			if err := os.MkdirAll(tlsDir, 0700); err != nil {
+				return errors.AddStack(err)
+			}
+			if ca, err = meta.GenClusterCA(clusterName, tlsDir); err != nil {
+				return err
+			}
+		}
 	}
 
 	var (
@@ -261,7 +289,7 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		// log dir will always be with values, but might not used by the component
 		logDir := clusterutil.Abs(globalOptions.User, inst.LogDir())
 		// Deploy component
-		t := task.NewBuilder().
+		tb := task.NewBuilder().
 			UserSSH(inst.GetHost(), inst.GetSSHPort(), globalOptions.User, gOpt.SSHTimeout).
 			Mkdir(globalOptions.User, inst.GetHost(),
 				deployDir, logDir,
@@ -276,20 +304,25 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 				version,
 				inst.GetHost(),
 				deployDir,
-			).
-			InitConfig(
-				clusterName,
-				clusterVersion,
-				inst,
-				globalOptions.User,
-				meta.DirPaths{
-					Deploy: deployDir,
-					Data:   dataDirs,
-					Log:    logDir,
-					Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
-				},
-			).
-			BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", inst.ComponentName(), inst.GetHost()))
+			)
+		if ca != nil {
+			tb.TLSCert(inst, ca, globalOptions.User, meta.DirPaths{
+				Deploy: deployDir,
+				Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
+			})
+		}
+		t := tb.InitConfig(
+			clusterName,
+			clusterVersion,
+			inst,
+			globalOptions.User,
+			meta.DirPaths{
+				Deploy: deployDir,
+				Data:   dataDirs,
+				Log:    logDir,
+				Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
+			},
+		).BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", inst.ComponentName(), inst.GetHost()))
 		deployCompTasks = append(deployCompTasks, t)
 	})
 
@@ -315,7 +348,12 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		deployCompTasks = append(deployCompTasks, nodeInfoTask)
 	}
 
+	// the hooks before the deployment can only run on the control machine
+	hookOpt := hookOptions(clusterName, clusterVersion, meta.HookOperationDeploy)
+	instances := operatedInstances(topo.ComponentsByStartOrder(), operator.Options{})
+
 	builder := task.NewBuilder().
+		Hook(globalOptions.Hooks, hookOpt, true, instances).
 		Step("+ Generate SSH keys",
 			task.NewBuilder().SSHKeyGen(meta.ClusterPath(clusterName, "ssh", "id_rsa")).Build()).
 		ParallelStep("+ Download TiDB components", downloadCompTasks...).
@@ -325,10 +363,32 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 	if report.Enable() {
 		builder.ParallelStep("+ Check status", nodeInfoTask)
 	}
+	builder.Hook(globalOptions.Hooks, hookOpt, false, instances)
 
 	t := builder.Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if dryRun {
+		plan := task.BuildPlan(t)
+		plan.Add(task.PlanHostLocal, task.PlanActionMeta, "save meta of cluster %s", clusterName)
+		return printPlan(clusterName, "deploy", plan)
+	}
+
+	cp, err := task.NewCheckPoint(meta.ClusterPath(clusterName, meta.CheckPointFileName("deploy")), opt.resume)
+	if err != nil {
+		return err
+	}
+	ctx := task.NewContext()
+	ctx.SetCheckPoint(cp)
+	if !opt.noRollback {
+		ctx.EnableRollback()
+	}
+	if err := t.Execute(ctx); err != nil {
+		if !opt.noRollback {
+			rollbackTask(t, ctx)
+		}
+		// the checkpoint is kept to resume the deploy, the tasks finished
+		// by the previous runs are not rolled back
+		_ = cp.Close()
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -342,14 +402,39 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		Topology: &topo,
 	})
 	if err != nil {
+		_ = cp.Close()
 		return errors.Trace(err)
 	}
+	if err := cp.Remove(); err != nil {
+		return err
+	}
 
 	hint := color.New(color.Bold).Sprintf("%s start %s", cliutil.OsArgs0(), clusterName)
 	log.Infof("Deployed cluster `%s` successfully, you can start the cluster via `%s`", clusterName, hint)
 	return nil
 }
 
+// rollbackTask reverts the executed tasks of a failed deploy or scale-out and
+// reports the changes which could not be reverted
+func rollbackTask(t task.Task, ctx *task.Context) {
+	log.Warnf("Rolling back the finished tasks...")
+	err := t.Rollback(ctx)
+	if err == nil {
+		log.Infof("Rolled back successfully")
+		return
+	}
+
+	rbErr, ok := err.(*task.RollbackError)
+	if !ok {
+		log.Errorf("Failed to rollback: %s", err)
+		return
+	}
+	log.Errorf("The following changes could not be reverted, please check and clean them up manually:")
+	for _, f := range rbErr.Failures {
+		log.Errorf("  - %s: %s", f.Task, f.Err)
+	}
+}
+
 func buildMonitoredDeployTask(
 	clusterName string,
 	uniqueHosts map[string]hostInfo, // host -> ssh-port, os, arch
@@ -382,7 +467,7 @@ func buildMonitoredDeployTask(
 			// log dir will always be with values, but might not used by the component
 			logDir := clusterutil.Abs(globalOptions.User, monitoredOptions.LogDir)
 			// Deploy component
-			t := task.NewBuilder().
+			tb := task.NewBuilder().
 				UserSSH(host, info.ssh, globalOptions.User, gOpt.SSHTimeout).
 				Mkdir(globalOptions.User, host,
 					deployDir, dataDir, logDir,
@@ -396,21 +481,31 @@ func buildMonitoredDeployTask(
 					version,
 					host,
 					deployDir,
-				).
-				MonitoredConfig(
-					clusterName,
-					comp,
-					host,
-					globalOptions.ResourceControl,
-					monitoredOptions,
-					globalOptions.User,
-					meta.DirPaths{
-						Deploy: deployDir,
-						Data:   []string{dataDir},
-						Log:    logDir,
-						Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
-					},
-				).
+				)
+			// the blackbox exporter probes the TLS ports with the client
+			// certificate of the cluster
+			if globalOptions.TLSEnabled && comp == meta.ComponentBlackboxExporter {
+				tlsDir := filepath.Join(deployDir, meta.TLSCertKeyDir)
+				tb.Mkdir(globalOptions.User, host, tlsDir)
+				for _, f := range []string{meta.TLSCACert, meta.TLSClientCert, meta.TLSClientKey} {
+					tb.CopyFile(meta.ClusterPath(clusterName, meta.TLSCertKeyDir, f), filepath.Join(tlsDir, f), host, false)
+				}
+			}
+			t := tb.MonitoredConfig(
+				clusterName,
+				comp,
+				host,
+				globalOptions.ResourceControl,
+				monitoredOptions,
+				globalOptions.TLSEnabled,
+				globalOptions.User,
+				meta.DirPaths{
+					Deploy: deployDir,
+					Data:   []string{dataDir},
+					Log:    logDir,
+					Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
+				},
+			).
 				BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", comp, host))
 			deployCompTasks = append(deployCompTasks, t)
 		}
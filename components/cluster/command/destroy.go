@@ -44,13 +44,20 @@ func newDestroyCmd() *cobra.Command {
 				return errors.Errorf("cannot destroy non-exists cluster %s", clusterName)
 			}
 
-			logger.EnableAuditLog()
+			if !dryRun {
+				logger.EnableAuditLog()
+			}
 			metadata, err := meta.ClusterMetadata(clusterName)
 			if err != nil {
 				return err
 			}
 
-			if !skipConfirm {
+			tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+			if err != nil {
+				return err
+			}
+
+			if !skipConfirm && !dryRun {
 				if err := cliutil.PromptForConfirmOrAbortError(
 					"This operation will destroy TiDB %s cluster %s and its data.\nDo you want to continue? [y/N]:",
 					color.HiYellowString(metadata.Version),
@@ -60,15 +67,27 @@ func newDestroyCmd() *cobra.Command {
 				log.Infof("Destroying cluster...")
 			}
 
+			hookOpt := hookOptions(clusterName, metadata.Version, meta.HookOperationDestroy)
+			instances := operatedInstances(metadata.Topology.ComponentsByStopOrder(), operator.Options{})
+			globalHooks := metadata.Topology.GlobalOptions.Hooks
+
 			t := task.NewBuilder().
 				SSHKeySet(
 					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 				ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-				ClusterOperate(metadata.Topology, operator.StopOperation, operator.Options{}).
-				ClusterOperate(metadata.Topology, operator.DestroyOperation, operator.Options{}).
+				Hook(globalHooks, hookOpt, true, instances).
+				ClusterOperate(metadata.Topology, operator.StopOperation, operator.Options{}, tlsCfg).
+				ClusterOperate(metadata.Topology, operator.DestroyOperation, operator.Options{}, tlsCfg).
+				Hook(globalHooks, hookOpt, false, instances).
 				Build()
 
+			if dryRun {
+				plan := task.BuildPlan(t)
+				plan.Add(task.PlanHostLocal, task.PlanActionMeta, "remove %s", meta.ClusterPath(clusterName))
+				return printPlan(clusterName, "destroy", plan)
+			}
+
 			if err := t.Execute(task.NewContext()); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
@@ -85,5 +104,7 @@ func newDestroyCmd() *cobra.Command {
 		},
 	}
 
-	return cmd
+	addDryRunFlag(cmd)
+
+	return requireLock(cmd, lockExisting)
 }
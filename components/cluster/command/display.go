@@ -39,10 +39,14 @@ func newDisplayCmd() *cobra.Command {
 		clusterName       string
 		showDashboardOnly bool
 	)
+	var batch *batchOptions
 	cmd := &cobra.Command{
 		Use:   "display <cluster-name>",
 		Short: "Display information of a TiDB cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if batch.enabled(args) {
+				return batch.run(cmd, args)
+			}
 			if len(args) != 1 {
 				return cmd.Help()
 			}
@@ -58,17 +62,28 @@ func newDisplayCmd() *cobra.Command {
 				return displayDashboardInfo(clusterName)
 			}
 
-			if err := displayClusterMeta(clusterName, &gOpt); err != nil {
-				return err
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return errors.AddStack(err)
 			}
-			if err := displayClusterTopology(clusterName, &gOpt); err != nil {
+			instances, err := clusterInstances(clusterName, &gOpt)
+			if err != nil {
 				return err
 			}
 
-			metadata, err := meta.ClusterMetadata(clusterName)
-			if err != nil {
-				return errors.AddStack(err)
+			if cliutil.IsStructuredOutput() {
+				if err := cliutil.PrintResult(clusterDisplayInfo{
+					ClusterName:    clusterName,
+					ClusterVersion: metadata.Version,
+					Instances:      instances,
+				}); err != nil {
+					return err
+				}
+			} else {
+				displayClusterMeta(clusterName, metadata)
+				displayClusterTopology(instances)
 			}
+
 			return destroyTombstoneIfNeed(clusterName, metadata, gOpt)
 		},
 	}
@@ -76,6 +91,7 @@ func newDisplayCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only display specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only display specified nodes")
 	cmd.Flags().BoolVar(&showDashboardOnly, "dashboard", false, "Only display TiDB Dashboard information")
+	batch = addBatchFlags(cmd, false)
 
 	return cmd
 }
@@ -85,13 +101,17 @@ func displayDashboardInfo(clusterName string) error {
 	if err != nil {
 		return err
 	}
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
 
 	pdEndpoints := make([]string, 0)
 	for _, pd := range metadata.Topology.PDServers {
 		pdEndpoints = append(pdEndpoints, fmt.Sprintf("%s:%d", pd.Host, pd.ClientPort))
 	}
 
-	pdAPI := api.NewPDClient(pdEndpoints, 2*time.Second, nil)
+	pdAPI := api.NewPDClient(pdEndpoints, 2*time.Second, tlsCfg)
 	dashboardAddr, err := pdAPI.GetDashboardAddress()
 	if err != nil {
 		return fmt.Errorf("failed to retrieve TiDB Dashboard instance from PD: %s", err)
@@ -108,23 +128,40 @@ func displayDashboardInfo(clusterName string) error {
 	}
 
 	u.Path = "/dashboard/"
+	if cliutil.IsStructuredOutput() {
+		return cliutil.PrintResult(struct {
+			DashboardURL string `json:"dashboard_url" yaml:"dashboard_url"`
+		}{u.String()})
+	}
 	fmt.Println(u.String())
 
 	return nil
 }
 
-func displayClusterMeta(clusterName string, opt *operator.Options) error {
-	clsMeta, err := meta.ClusterMetadata(clusterName)
-	if err != nil {
-		return err
-	}
+// clusterDisplayInfo is the structured output of display
+type clusterDisplayInfo struct {
+	ClusterName    string     `json:"cluster_name" yaml:"cluster_name"`
+	ClusterVersion string     `json:"cluster_version" yaml:"cluster_version"`
+	Instances      []InstInfo `json:"instances" yaml:"instances"`
+}
 
+// InstInfo represents the status of an instance
+type InstInfo struct {
+	ID        string `json:"id" yaml:"id"`
+	Role      string `json:"role" yaml:"role"`
+	Host      string `json:"host" yaml:"host"`
+	Ports     string `json:"ports" yaml:"ports"`
+	OsArch    string `json:"os_arch" yaml:"os_arch"`
+	Status    string `json:"status" yaml:"status"`
+	DataDir   string `json:"data_dir" yaml:"data_dir"`
+	DeployDir string `json:"deploy_dir" yaml:"deploy_dir"`
+}
+
+func displayClusterMeta(clusterName string, clsMeta *meta.ClusterMeta) {
 	cyan := color.New(color.FgCyan, color.Bold)
 
 	fmt.Printf("TiDB Cluster: %s\n", cyan.Sprint(clusterName))
 	fmt.Printf("TiDB Version: %s\n", cyan.Sprint(clsMeta.Version))
-
-	return nil
 }
 
 func destroyTombstoneIfNeed(clusterName string, metadata *meta.ClusterMeta, opt operator.Options) error {
@@ -134,8 +171,13 @@ func destroyTombstoneIfNeed(clusterName string, metadata *meta.ClusterMeta, opt
 		return nil
 	}
 
+	tlsCfg, err := topo.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
 	ctx := task.NewContext()
-	err := ctx.SetSSHKeySet(meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+	err = ctx.SetSSHKeySet(meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 		meta.ClusterPath(clusterName, "ssh", "id_rsa.pub"))
 	if err != nil {
 		return errors.AddStack(err)
@@ -146,7 +188,7 @@ func destroyTombstoneIfNeed(clusterName string, metadata *meta.ClusterMeta, opt
 		return errors.AddStack(err)
 	}
 
-	nodes, err := operator.DestroyTombstone(ctx, topo, true /* returnNodesOnly */, opt)
+	nodes, err := operator.DestroyTombstone(ctx, topo, true /* returnNodesOnly */, opt, tlsCfg)
 	if err != nil {
 		return errors.AddStack(err)
 	}
@@ -157,7 +199,7 @@ func destroyTombstoneIfNeed(clusterName string, metadata *meta.ClusterMeta, opt
 
 	log.Infof("Start destroy Tombstone nodes: %v ...", nodes)
 
-	_, err = operator.DestroyTombstone(ctx, topo, false /* returnNodesOnly */, opt)
+	_, err = operator.DestroyTombstone(ctx, topo, false /* returnNodesOnly */, opt, tlsCfg)
 	if err != nil {
 		return errors.AddStack(err)
 	}
@@ -167,31 +209,32 @@ func destroyTombstoneIfNeed(clusterName string, metadata *meta.ClusterMeta, opt
 	return meta.SaveClusterMeta(clusterName, metadata)
 }
 
-func displayClusterTopology(clusterName string, opt *operator.Options) error {
+// clusterInstances returns the status of the instances sorted by role, host and ports
+func clusterInstances(clusterName string, opt *operator.Options) ([]InstInfo, error) {
 	metadata, err := meta.ClusterMetadata(clusterName)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return nil, err
 	}
 
 	topo := metadata.Topology
 
-	clusterTable := [][]string{
-		// Header
-		{"ID", "Role", "Host", "Ports", "OS/Arch", "Status", "Data Dir", "Deploy Dir"},
-	}
-
 	ctx := task.NewContext()
 	err = ctx.SetSSHKeySet(meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 		meta.ClusterPath(clusterName, "ssh", "id_rsa.pub"))
 	if err != nil {
-		return errors.AddStack(err)
+		return nil, errors.AddStack(err)
 	}
 
 	err = ctx.SetClusterSSH(topo, metadata.User, gOpt.SSHTimeout)
 	if err != nil {
-		return errors.AddStack(err)
+		return nil, errors.AddStack(err)
 	}
 
+	var instances []InstInfo
 	filterRoles := set.NewStringSet(opt.Roles...)
 	filterNodes := set.NewStringSet(opt.Nodes...)
 	pdList := topo.GetPDList()
@@ -213,7 +256,7 @@ func displayClusterTopology(clusterName string, opt *operator.Options) error {
 				dataDir = insDirs[1]
 			}
 
-			status := ins.Status(pdList...)
+			status := ins.Status(tlsCfg, pdList...)
 			// Query the service status
 			if status == "-" {
 				e, found := ctx.GetExecutor(ins.GetHost())
@@ -228,35 +271,53 @@ func displayClusterTopology(clusterName string, opt *operator.Options) error {
 					}
 				}
 			}
-			clusterTable = append(clusterTable, []string{
-				color.CyanString(ins.ID()),
-				ins.Role(),
-				ins.GetHost(),
-				clusterutil.JoinInt(ins.UsedPorts(), "/"),
-				cliutil.OsArch(ins.OS(), ins.Arch()),
-				formatInstanceStatus(status),
-				dataDir,
-				deployDir,
+			instances = append(instances, InstInfo{
+				ID:        ins.ID(),
+				Role:      ins.Role(),
+				Host:      ins.GetHost(),
+				Ports:     clusterutil.JoinInt(ins.UsedPorts(), "/"),
+				OsArch:    cliutil.OsArch(ins.OS(), ins.Arch()),
+				Status:    status,
+				DataDir:   dataDir,
+				DeployDir: deployDir,
 			})
-
 		}
 	}
 
 	// Sort by role,host,ports
-	sort.Slice(clusterTable[1:], func(i, j int) bool {
-		lhs, rhs := clusterTable[i+1], clusterTable[j+1]
-		// column: 1 => role, 2 => host, 3 => ports
-		for _, col := range []int{1, 2} {
-			if lhs[col] != rhs[col] {
-				return lhs[col] < rhs[col]
-			}
+	sort.Slice(instances, func(i, j int) bool {
+		lhs, rhs := instances[i], instances[j]
+		if lhs.Role != rhs.Role {
+			return lhs.Role < rhs.Role
+		}
+		if lhs.Host != rhs.Host {
+			return lhs.Host < rhs.Host
 		}
-		return lhs[3] < rhs[3]
+		return lhs.Ports < rhs.Ports
 	})
 
-	cliutil.PrintTable(clusterTable, true)
+	return instances, nil
+}
 
-	return nil
+func displayClusterTopology(instances []InstInfo) {
+	clusterTable := [][]string{
+		// Header
+		{"ID", "Role", "Host", "Ports", "OS/Arch", "Status", "Data Dir", "Deploy Dir"},
+	}
+	for _, ins := range instances {
+		clusterTable = append(clusterTable, []string{
+			color.CyanString(ins.ID),
+			ins.Role,
+			ins.Host,
+			ins.Ports,
+			ins.OsArch,
+			formatInstanceStatus(ins.Status),
+			ins.DataDir,
+			ins.DeployDir,
+		})
+	}
+
+	cliutil.PrintTable(clusterTable, true)
 }
 
 func formatInstanceStatus(status string) string {
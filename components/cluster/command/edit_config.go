@@ -56,7 +56,7 @@ func newEditConfigCmd() *cobra.Command {
 		},
 	}
 
-	return cmd
+	return requireLock(cmd, lockExisting)
 }
 
 // 1. Write Topology to a temporary file.
@@ -14,9 +14,12 @@
 package command
 
 import (
+	"sort"
+
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
 	"github.com/pingcap/tiup/pkg/cluster/task"
 	"github.com/pingcap/tiup/pkg/logger"
@@ -31,12 +34,23 @@ type execOptions struct {
 	sudo    bool
 }
 
+// execOutput is the structured output of the command on a host
+type execOutput struct {
+	Host   string `json:"host" yaml:"host"`
+	Stdout string `json:"stdout" yaml:"stdout"`
+	Stderr string `json:"stderr" yaml:"stderr"`
+}
+
 func newExecCmd() *cobra.Command {
 	opt := execOptions{}
+	var batch *batchOptions
 	cmd := &cobra.Command{
 		Use:   "exec <cluster-name>",
 		Short: "Run shell command on host in the tidb cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if batch.enabled(args) {
+				return batch.run(cmd, args)
+			}
 			if len(args) != 1 {
 				return cmd.Help()
 			}
@@ -96,6 +110,28 @@ func newExecCmd() *cobra.Command {
 				return errors.Trace(err)
 			}
 
+			if cliutil.IsStructuredOutput() {
+				outputs := []execOutput{}
+				for host := range uniqueHosts {
+					stdout, stderr, ok := execCtx.GetOutputs(host)
+					if !ok {
+						continue
+					}
+					outputs = append(outputs, execOutput{
+						Host:   host,
+						Stdout: string(stdout),
+						Stderr: string(stderr),
+					})
+				}
+				sort.Slice(outputs, func(i, j int) bool {
+					return outputs[i].Host < outputs[j].Host
+				})
+				return cliutil.PrintResult(struct {
+					Command string       `json:"command" yaml:"command"`
+					Outputs []execOutput `json:"outputs" yaml:"outputs"`
+				}{opt.command, outputs})
+			}
+
 			// print outputs
 			for host := range uniqueHosts {
 				stdout, stderr, ok := execCtx.GetOutputs(host)
@@ -121,6 +157,7 @@ func newExecCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&opt.sudo, "sudo", false, "use root permissions (default false)")
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only exec on host with specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only exec on host with specified nodes")
+	batch = addBatchFlags(cmd, true)
 
 	return cmd
 }
@@ -0,0 +1,40 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// hookOptions returns the options of the hooks run around the operation
+func hookOptions(clusterName, clusterVersion, operation string) operator.HookOptions {
+	return operator.HookOptions{
+		ClusterName:    clusterName,
+		ClusterVersion: clusterVersion,
+		Operation:      operation,
+	}
+}
+
+// operatedInstances returns the instances of the components selected by the
+// roles and nodes of the options, in the order of the components
+func operatedInstances(comps []meta.Component, options operator.Options) []meta.Instance {
+	var instances []meta.Instance
+	nodes := set.NewStringSet(options.Nodes...)
+	for _, comp := range operator.FilterComponent(comps, set.NewStringSet(options.Roles...)) {
+		instances = append(instances, operator.FilterInstance(comp.Instances(), nodes)...)
+	}
+	return instances
+}
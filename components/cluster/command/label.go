@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newLabelCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "label",
+		Short: "Set or remove the labels of a cluster",
+		Long: `Set or remove the labels of a cluster.
+
+The labels are used to select the clusters by --label in the commands running
+on multiple clusters, e.g. exec, restart, check, display and upgrade.`,
+	}
+
+	cmd.AddCommand(
+		newLabelSetCmd(),
+		newLabelRemoveCmd(),
+	)
+	return cmd
+}
+
+func newLabelSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "set <cluster-name> <key=value>...",
+		Short:   "Set the labels of a cluster",
+		Example: "  tiup cluster label set prod-1 env=prod region=us-west",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return cmd.Help()
+			}
+			for _, l := range args[1:] {
+				if !strings.Contains(l, "=") {
+					return errors.Errorf("invalid label '%s', it should be in the form of key=value", l)
+				}
+			}
+			labels, err := meta.ParseLabels(args[1:])
+			if err != nil {
+				return err
+			}
+			return updateClusterLabels(args[0], func(current map[string]string) {
+				for k, v := range labels {
+					current[k] = v
+				}
+			})
+		},
+	}
+	return requireLock(cmd, lockExisting)
+}
+
+func newLabelRemoveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rm <cluster-name> <key>...",
+		Short: "Remove the labels of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return cmd.Help()
+			}
+			return updateClusterLabels(args[0], func(current map[string]string) {
+				for _, k := range args[1:] {
+					delete(current, k)
+				}
+			})
+		},
+	}
+	return requireLock(cmd, lockExisting)
+}
+
+// updateClusterLabels updates the labels of the cluster and saves the meta
+func updateClusterLabels(clusterName string, update func(map[string]string)) error {
+	teleCommand = append(teleCommand, scrubClusterName(clusterName))
+	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return errors.Errorf("cannot label non-exists cluster %s", clusterName)
+	}
+
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+	if metadata.Labels == nil {
+		metadata.Labels = make(map[string]string)
+	}
+	update(metadata.Labels)
+	if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
+		return err
+	}
+
+	log.Infof("Labels of cluster `%s`: %s", clusterName, formatLabels(metadata.Labels))
+	return nil
+}
+
+// formatLabels returns the labels in the form of key=value, sorted by the keys
+func formatLabels(labels map[string]string) string {
+	kvs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	return strings.Join(kvs, ",")
+}
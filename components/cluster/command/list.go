@@ -14,55 +14,79 @@
 package command
 
 import (
-	"io/ioutil"
-	"os"
-
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cliutil"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
-	tiuputils "github.com/pingcap/tiup/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 func newListCmd() *cobra.Command {
+	var labels []string
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all clusters",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return listCluster()
+			return listCluster(labels)
 		},
 	}
+	cmd.Flags().StringSliceVarP(&labels, "label", "l", nil, "Only list the clusters with the labels, in the form of key=value or key")
 	return cmd
 }
 
-func listCluster() error {
-	clusterDir := meta.ProfilePath(meta.TiOpsClusterDir)
-	clusterTable := [][]string{
-		// Header
-		{"Name", "User", "Version", "Path", "PrivateKey"},
+// clusterInfo is the structured output of list
+type clusterInfo struct {
+	Name       string            `json:"name" yaml:"name"`
+	User       string            `json:"user" yaml:"user"`
+	Version    string            `json:"version" yaml:"version"`
+	Path       string            `json:"path" yaml:"path"`
+	PrivateKey string            `json:"private_key" yaml:"private_key"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+func listCluster(labels []string) error {
+	selector := meta.ClusterSelector{}
+	var err error
+	if selector.Labels, err = meta.ParseLabels(labels); err != nil {
+		return err
 	}
-	fileInfos, err := ioutil.ReadDir(clusterDir)
-	if err != nil && !os.IsNotExist(err) {
+	names, err := meta.ListClusterNames()
+	if err != nil {
 		return err
 	}
-	for _, fi := range fileInfos {
-		if tiuputils.IsNotExist(meta.ClusterPath(fi.Name(), meta.MetaFileName)) {
-			continue
-		}
-		metadata, err := meta.ClusterMetadata(fi.Name())
+
+	clusters := []clusterInfo{}
+	for _, name := range names {
+		metadata, err := meta.ClusterMetadata(name)
 		if err != nil {
 			return errors.Trace(err)
 		}
+		if !selector.Match(name, metadata.Labels) {
+			continue
+		}
 
-		clusterTable = append(clusterTable, []string{
-			fi.Name(),
-			metadata.User,
-			metadata.Version,
-			meta.ClusterPath(fi.Name()),
-			meta.ClusterPath(fi.Name(), "ssh", "id_rsa"),
+		clusters = append(clusters, clusterInfo{
+			Name:       name,
+			User:       metadata.User,
+			Version:    metadata.Version,
+			Path:       meta.ClusterPath(name),
+			PrivateKey: meta.ClusterPath(name, "ssh", "id_rsa"),
+			Labels:     metadata.Labels,
 		})
 	}
 
+	if cliutil.IsStructuredOutput() {
+		return cliutil.PrintResult(struct {
+			Clusters []clusterInfo `json:"clusters" yaml:"clusters"`
+		}{clusters})
+	}
+
+	clusterTable := [][]string{
+		// Header
+		{"Name", "User", "Version", "Path", "PrivateKey", "Labels"},
+	}
+	for _, c := range clusters {
+		clusterTable = append(clusterTable, []string{c.Name, c.User, c.Version, c.Path, c.PrivateKey, formatLabels(c.Labels)})
+	}
 	cliutil.PrintTable(clusterTable, true)
 	return nil
 }
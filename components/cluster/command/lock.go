@@ -0,0 +1,223 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/clientv3"
+)
+
+const (
+	// lockAnnotation is the annotation of the commands which modify the cluster
+	// and must hold the operation lock of it, the cluster name is the first argument
+	lockAnnotation = "lock"
+	// lockExisting locks the cluster only if it exists, the commands report the
+	// non-exist cluster by themselves
+	lockExisting = "existing"
+	// lockNew locks the cluster created by the command, e.g. deploy
+	lockNew = "new"
+)
+
+var (
+	// useEtcdLock means the lock is also held in the etcd of PD
+	useEtcdLock bool
+	// clusterLock is the lock held by the running command
+	clusterLock *meta.ClusterLock
+)
+
+func newLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Show or break the operation lock of a cluster",
+	}
+
+	cmd.AddCommand(
+		newLockStatusCmd(),
+		newLockBreakCmd(),
+	)
+	return cmd
+}
+
+func newLockStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status <cluster-name>",
+		Short: "Show the holder of the operation lock of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName)) {
+				return errors.Errorf("cluster %s does not exist", clusterName)
+			}
+
+			holder, err := meta.ClusterLockStatus(clusterName)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Local lock: %s\n", formatLockHolder(holder))
+
+			cli, err := clusterEtcdClient(clusterName)
+			if err != nil {
+				fmt.Printf("Etcd lock:  %s\n", color.YellowString("unknown (%s)", err))
+				return nil
+			}
+			defer cli.Close()
+			if holder, err = meta.EtcdLockStatus(cli); err != nil {
+				fmt.Printf("Etcd lock:  %s\n", color.YellowString("unknown (%s)", err))
+				return nil
+			}
+			fmt.Printf("Etcd lock:  %s\n", formatLockHolder(holder))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newLockBreakCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "break <cluster-name>",
+		Short: "Break the operation lock of a cluster",
+		Long: `Break the operation lock of a cluster. It is used to clean up a stale lock,
+the process holding the lock is NOT stopped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName)) {
+				return errors.Errorf("cluster %s does not exist", clusterName)
+			}
+
+			if !skipConfirm {
+				if err := cliutil.PromptForConfirmOrAbortError(
+					"Breaking the lock may corrupt the cluster if the operation holding it is still running.\nDo you want to continue? [y/N]: "); err != nil {
+					return err
+				}
+			}
+
+			if err := meta.BreakClusterLock(clusterName); err != nil {
+				return err
+			}
+			if cli, err := clusterEtcdClient(clusterName); err == nil {
+				defer cli.Close()
+				if err := meta.BreakEtcdLock(cli); err != nil {
+					log.Warnf("Failed to break the etcd lock: %s", err)
+				}
+			}
+
+			log.Infof("Broke the lock of cluster `%s`", clusterName)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// requireLock makes the command hold the operation lock of the cluster named
+// by its first argument, the mode is lockExisting or lockNew
+func requireLock(cmd *cobra.Command, mode string) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[lockAnnotation] = mode
+	return cmd
+}
+
+// lockClusterIfNeed acquires the operation lock if the command modifies the
+// cluster, nothing is modified in dry run
+func lockClusterIfNeed(cmd *cobra.Command, args []string) error {
+	mode := cmd.Annotations[lockAnnotation]
+	if mode == "" || len(args) == 0 || dryRun {
+		return nil
+	}
+	clusterName := args[0]
+	if mode == lockNew {
+		if clusterutil.ValidateClusterNameOrError(clusterName) != nil {
+			return nil
+		}
+	} else if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return nil
+	}
+
+	info := meta.NewLockInfo(cliutil.OsArgs())
+	lock, err := meta.LockCluster(clusterName, info)
+	if err != nil {
+		return err
+	}
+	clusterLock = lock
+
+	// the etcd of a new cluster is not up yet
+	if !useEtcdLock || mode == lockNew {
+		return nil
+	}
+	cli, err := clusterEtcdClient(clusterName)
+	if err == nil {
+		if err = lock.LockEtcd(cli, info); err != nil {
+			cli.Close()
+		}
+	}
+	if err != nil {
+		if errorx.IsOfType(err, meta.ErrClusterLocked) {
+			return err
+		}
+		log.Warnf("Failed to acquire the etcd lock, only the local lock is held: %s", err)
+	}
+	return nil
+}
+
+// unlockCluster releases the operation lock held by the command
+func unlockCluster() {
+	if clusterLock == nil {
+		return
+	}
+	if err := clusterLock.Unlock(); err != nil {
+		log.Warnf("Failed to release the lock: %s", err)
+	}
+	clusterLock = nil
+}
+
+func clusterEtcdClient(clusterName string) (*clientv3.Client, error) {
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Topology.GetEtcdClient(tlsCfg)
+}
+
+func formatLockHolder(holder *meta.LockInfo) string {
+	if holder == nil {
+		return color.GreenString("not locked")
+	}
+	return color.YellowString(holder.String())
+}
@@ -0,0 +1,317 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+type logsOptions struct {
+	follow bool
+	lines  int
+	grep   string
+	level  string
+	since  string
+	until  string
+}
+
+// instanceColors are the colors of the prefixes of the instances
+var instanceColors = []func(format string, a ...interface{}) string{
+	color.CyanString,
+	color.GreenString,
+	color.YellowString,
+	color.BlueString,
+	color.MagentaString,
+	color.HiCyanString,
+	color.HiGreenString,
+	color.HiYellowString,
+	color.HiBlueString,
+	color.HiMagentaString,
+}
+
+func newLogsCmd() *cobra.Command {
+	opt := logsOptions{}
+	cmd := &cobra.Command{
+		Use:   "logs <cluster-name>",
+		Short: "Show or follow the logs of the instances",
+		Long: `Show the last lines of the logs of the instances merged in the order of time,
+or follow the logs of the instances with -f. The lines are prefixed with the ID
+of the instance and can be filtered by the level, time and a regular expression.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot show logs of non-exists cluster %s", clusterName)
+			}
+
+			filter, err := opt.filter(time.Now())
+			if err != nil {
+				return err
+			}
+			return showLogs(clusterName, &opt, filter)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opt.follow, "follow", "f", false, "Follow the logs, the lines appended are printed until interrupted")
+	cmd.Flags().IntVarP(&opt.lines, "lines", "n", 100, "The number of the last lines of each log read, all the lines are read if it is 0")
+	cmd.Flags().StringVar(&opt.grep, "grep", "", "Only show the lines matching the regular expression")
+	cmd.Flags().StringVar(&opt.level, "level", "", "Only show the lines of the level or above, one of debug, info, warn, error and fatal")
+	cmd.Flags().StringVar(&opt.since, "since", "", "Only show the lines since the time, e.g. '2h' or '2020-10-17 12:00:00'")
+	cmd.Flags().StringVar(&opt.until, "until", "", "Only show the lines until the time, e.g. '30m' or '2020-10-17 14:00:00'")
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only show the logs of the instances of the roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only show the logs of the instances of the nodes")
+
+	return cmd
+}
+
+// filter builds the filter of the log lines from the options
+func (opt *logsOptions) filter(now time.Time) (*operator.LogFilter, error) {
+	filter := &operator.LogFilter{}
+	var err error
+	if opt.level != "" {
+		if filter.Level, err = operator.ParseLogLevel(opt.level); err != nil {
+			return nil, err
+		}
+	}
+	if opt.grep != "" {
+		if filter.Pattern, err = regexp.Compile(opt.grep); err != nil {
+			return nil, errors.Annotatef(err, "invalid pattern %s", opt.grep)
+		}
+	}
+	if opt.since != "" {
+		if filter.Since, err = parseLogTime(opt.since, now); err != nil {
+			return nil, err
+		}
+	}
+	if opt.until != "" {
+		if filter.Until, err = parseLogTime(opt.until, now); err != nil {
+			return nil, err
+		}
+	}
+	return filter, nil
+}
+
+// parseLogTime parses a time in the local time zone or a duration before now
+func parseLogTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid time '%s', it must be a duration like 2h or a time like '2020-10-17 12:00:00'", s)
+}
+
+func showLogs(clusterName string, opt *logsOptions, filter *operator.LogFilter) error {
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	filterRoles := set.NewStringSet(gOpt.Roles...)
+	filterNodes := set.NewStringSet(gOpt.Nodes...)
+	var hosts []string
+	files := make(map[string]map[string]string) // host -> file -> instance
+	prefixes := make(map[string]string)         // instance -> colored prefix
+	width := 0
+	metadata.Topology.IterInstance(func(inst meta.Instance) {
+		if len(gOpt.Roles) > 0 && !filterRoles.Exist(inst.Role()) {
+			return
+		}
+		if len(gOpt.Nodes) > 0 && !filterNodes.Exist(inst.ID()) {
+			return
+		}
+		if _, found := files[inst.GetHost()]; !found {
+			hosts = append(hosts, inst.GetHost())
+			files[inst.GetHost()] = make(map[string]string)
+		}
+		files[inst.GetHost()][operator.LogFile(inst, metadata.User)] = inst.ID()
+		prefixes[inst.ID()] = ""
+		if len(inst.ID()) > width {
+			width = len(inst.ID())
+		}
+	})
+	if len(hosts) == 0 {
+		return errors.New("no instance matches the roles and nodes specified")
+	}
+	ids := make([]string, 0, len(prefixes))
+	for id := range prefixes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for i, id := range ids {
+		prefixes[id] = instanceColors[i%len(instanceColors)]("%-*s", width+2, "["+id+"]")
+	}
+
+	ctx := task.NewContext()
+	t := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	// the lines are printed as soon as they arrive when following, or sorted
+	// by the time before printing otherwise
+	var (
+		mu    sync.Mutex
+		lines []*operator.LogLine
+	)
+	print := func(l *operator.LogLine) {
+		text := l.Text
+		if l.Level >= operator.LogLevelError {
+			text = color.RedString("%s", text)
+		}
+		fmt.Printf("%s%s\n", prefixes[l.Instance], text)
+	}
+	handle := func(l *operator.LogLine) {
+		if !filter.Match(l) {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if opt.follow {
+			print(l)
+			return
+		}
+		lines = append(lines, l)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if opt.follow {
+		sc := make(chan os.Signal, 1)
+		signal.Notify(sc, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sc)
+		go func() {
+			select {
+			case <-sc:
+				cancel()
+			case <-streamCtx.Done():
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	failed := 0
+	for _, host := range hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return task.ErrNoExecutor
+		}
+		streamer, ok := e.(executor.StreamExecutor)
+		if !ok {
+			return errors.Errorf("the executor of %s can't stream the logs", host)
+		}
+
+		var paths []string
+		for f := range files[host] {
+			paths = append(paths, f)
+		}
+		sort.Strings(paths)
+		cmd := operator.TailLogCommand(paths, opt.lines, opt.follow)
+
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			stdout := operator.NewLogStream(files[host], handle)
+			stderr := &hostLogWriter{host: host}
+			err := streamer.ExecuteStream(streamCtx, cmd, false, stdout, stderr)
+			stdout.Flush()
+			stderr.flush()
+			if err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				log.Warnf("Failed to read the logs on %s: %s", host, err)
+			}
+		}(host)
+	}
+	wg.Wait()
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Time.Before(lines[j].Time)
+	})
+	for _, l := range lines {
+		print(l)
+	}
+	if failed == len(hosts) && streamCtx.Err() == nil {
+		return errors.New("failed to read the logs on all the hosts")
+	}
+	return nil
+}
+
+// hostLogWriter prints the error messages of tail on a host, e.g., the log
+// file doesn't exist
+type hostLogWriter struct {
+	host string
+	buf  bytes.Buffer
+}
+
+func (w *hostLogWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// put back the incomplete line
+			w.buf.WriteString(line)
+			break
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			log.Warnf("%s: %s", w.host, line)
+		}
+	}
+	return len(p), nil
+}
+
+func (w *hostLogWriter) flush() {
+	if line := strings.TrimSpace(w.buf.String()); line != "" {
+		log.Warnf("%s: %s", w.host, line)
+	}
+	w.buf.Reset()
+}
@@ -0,0 +1,276 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v2"
+)
+
+// envNameMetaPassphrase is the environment variable of the passphrase to
+// encrypt and decrypt the meta archives, it is prompted for if not set
+const envNameMetaPassphrase = "TIUP_CLUSTER_META_PASSPHRASE"
+
+func newMetaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "meta",
+		Short: "Backup, restore or rebuild the meta of a cluster",
+		Long: `Backup, restore or rebuild the meta of a cluster.
+
+All that tiup knows about a cluster, the meta, SSH keys, TLS certificates,
+patches and backups, is stored in the local directory of the cluster. The
+backup archive is encrypted with a passphrase read from the environment
+variable ` + envNameMetaPassphrase + ` or prompted for.`,
+	}
+
+	cmd.AddCommand(
+		newMetaBackupCmd(),
+		newMetaRestoreCmd(),
+		newMetaRebuildCmd(),
+	)
+	return cmd
+}
+
+func newMetaBackupCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "backup <cluster-name>",
+		Short: "Backup the meta of a cluster to an encrypted archive",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if output == "" {
+				output = fmt.Sprintf("tiup-cluster-%s-%s.meta", clusterName, time.Now().Format("20060102150405"))
+			}
+			if tiuputils.IsExist(output) {
+				return errors.Errorf("the file %s already exists", output)
+			}
+
+			passphrase, err := readMetaPassphrase(true)
+			if err != nil {
+				return err
+			}
+			archive, err := meta.ArchiveCluster(clusterName, passphrase)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(output, archive, 0600); err != nil {
+				return errors.Trace(err)
+			}
+
+			log.Infof("Meta of cluster `%s` is saved to %s", clusterName, color.CyanString(output))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "The path of the archive, tiup-cluster-<cluster-name>-<time>.meta in the current directory by default")
+	return cmd
+}
+
+func newMetaRestoreCmd() *cobra.Command {
+	var overwrite bool
+	cmd := &cobra.Command{
+		Use:   "restore <cluster-name> <archive>",
+		Short: "Restore the meta of a cluster from an encrypted archive",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			exist := tiuputils.IsExist(meta.ClusterPath(clusterName, meta.MetaFileName))
+			if exist && !overwrite {
+				return errDeployNameDuplicate.
+					New("Cluster name '%s' is duplicated", clusterName).
+					WithProperty(cliutil.SuggestionFromFormat("Please specify --overwrite to replace the meta of the existing cluster"))
+			}
+
+			archive, err := ioutil.ReadFile(args[1])
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if exist && !skipConfirm {
+				if err := cliutil.PromptForConfirmOrAbortError(
+					"The meta of cluster %s will be replaced by the archive.\nDo you want to continue? [y/N]: ", clusterName); err != nil {
+					return err
+				}
+			}
+			passphrase, err := readMetaPassphrase(false)
+			if err != nil {
+				return err
+			}
+
+			logger.EnableAuditLog()
+			if err := meta.RestoreCluster(clusterName, archive, passphrase, overwrite); err != nil {
+				return err
+			}
+
+			log.Infof("Meta of cluster `%s` is restored", clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace the meta of the cluster if it exists")
+	return requireLock(cmd, lockNew)
+}
+
+func newMetaRebuildCmd() *cobra.Command {
+	var (
+		opt          operator.RebuildOptions
+		pdAddrs      []string
+		identityFile = filepath.Join(tiuputils.UserHome(), ".ssh", "id_rsa")
+		overwrite    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "rebuild <cluster-name>",
+		Short: "Rebuild the meta of a cluster from the live cluster",
+		Long: `Rebuild the meta of a cluster from the live cluster.
+
+The members and stores are queried from PD, and the systemd units of the
+instances are scanned on the hosts of them and the hosts specified by --host,
+which is needed for the hosts running TiDB or monitoring components only. The
+hosts are connected as the user running the instances, and the identity file
+is saved as the SSH key of the cluster.
+
+The server configs are not rebuilt, the config of every instance is kept on
+its host, and TLS clusters can't be rebuilt as the certificates are lost.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if err := clusterutil.ValidateClusterNameOrError(clusterName); err != nil {
+				return err
+			}
+			if tiuputils.IsExist(meta.ClusterPath(clusterName, meta.MetaFileName)) && !overwrite {
+				return errDeployNameDuplicate.
+					New("Cluster name '%s' is duplicated", clusterName).
+					WithProperty(cliutil.SuggestionFromFormat("Please specify --overwrite to replace the meta of the existing cluster"))
+			}
+			if len(pdAddrs) == 0 {
+				return errors.New("the address of PD is required")
+			}
+
+			opt.PDAddrs = pdAddrs
+			opt.SSH.KeyFile = identityFile
+			opt.SSH.Timeout = time.Second * time.Duration(gOpt.SSHTimeout)
+			metadata, err := operator.RebuildMeta(opt)
+			if err != nil {
+				return err
+			}
+			if metadata.User != opt.SSH.User {
+				return errors.Errorf("the instances are run by %s, please connect the hosts as it with --user", metadata.User)
+			}
+
+			if !skipConfirm {
+				data, err := yaml.Marshal(metadata)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				fmt.Println(string(data))
+				if err := cliutil.PromptForConfirmOrAbortError(
+					"The meta of cluster %s is rebuilt as above.\nDo you want to save it? [y/N]: ", clusterName); err != nil {
+					return err
+				}
+			}
+
+			logger.EnableAuditLog()
+			if err := saveClusterSSHKey(clusterName, identityFile); err != nil {
+				return err
+			}
+			if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
+				return err
+			}
+
+			log.Infof("Meta of cluster `%s` is rebuilt", clusterName)
+			fmt.Printf("Try `%s` to check the instances of the cluster.\n",
+				color.HiYellowString("%s display %s", cliutil.OsArgs0(), clusterName))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&pdAddrs, "pd", nil, "The client addresses of PD, in the form of host:port")
+	cmd.Flags().StringSliceVar(&opt.Hosts, "host", nil, "The hosts to scan besides the hosts of PD and the stores")
+	cmd.Flags().StringVar(&opt.Version, "version", "", "The version of the cluster, detected from PD if not set")
+	cmd.Flags().StringVar(&opt.SSH.User, "user", "tidb", "The user running the instances to login via SSH")
+	cmd.Flags().IntVar(&opt.SSH.Port, "ssh-port", 22, "The SSH port of the hosts")
+	cmd.Flags().StringVarP(&identityFile, "identity_file", "i", identityFile, "The path of the SSH identity file of the user, it is saved as the SSH key of the cluster")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace the meta of the cluster if it exists")
+	return requireLock(cmd, lockNew)
+}
+
+// readMetaPassphrase reads the passphrase of the meta archive from the
+// environment variable or the terminal, it is asked twice when creating
+func readMetaPassphrase(confirm bool) ([]byte, error) {
+	if passphrase := os.Getenv(envNameMetaPassphrase); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	passphrase := cliutil.PromptForPassword("Input the passphrase of the archive: ")
+	if passphrase == "" {
+		return nil, errors.New("the passphrase should not be empty")
+	}
+	if confirm && cliutil.PromptForPassword("Input the passphrase again: ") != passphrase {
+		return nil, errors.New("the passphrases don't match")
+	}
+	return []byte(passphrase), nil
+}
+
+// saveClusterSSHKey saves the identity file as the SSH key of the cluster,
+// the public key is derived from the private key if it does not exist
+func saveClusterSSHKey(clusterName, identityFile string) error {
+	privKey, err := ioutil.ReadFile(identityFile)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	pubKey, err := ioutil.ReadFile(identityFile + ".pub")
+	if err != nil {
+		signer, err := ssh.ParsePrivateKey(privKey)
+		if err != nil {
+			return errors.Annotatef(err, "failed to parse the identity file %s", identityFile)
+		}
+		pubKey = ssh.MarshalAuthorizedKey(signer.PublicKey())
+	}
+
+	if err := tiuputils.CreateDir(meta.ClusterPath(clusterName, "ssh")); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(meta.ClusterPath(clusterName, "ssh", "id_rsa"), privKey, 0600); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(meta.ClusterPath(clusterName, "ssh", "id_rsa.pub"), pubKey, 0644))
+}
@@ -59,7 +59,8 @@ func newPatchCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Specify the nodes")
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Specify the role")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
-	return cmd
+	addDryRunFlag(cmd)
+	return requireLock(cmd, lockExisting)
 }
 
 func patch(clusterName, packagePath string, options operator.Options, overwrite bool) error {
@@ -76,13 +77,21 @@ func patch(clusterName, packagePath string, options operator.Options, overwrite
 		return err
 	}
 
-	insts, err := instancesToPatch(metadata, options)
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
 	if err != nil {
 		return err
 	}
-	if err := checkPackage(clusterName, insts[0].ComponentName(), insts[0].OS(), insts[0].Arch(), packagePath); err != nil {
+
+	insts, err := instancesToPatch(metadata, options)
+	if err != nil {
 		return err
 	}
+	// the package is checked against the repository, which is skipped in dry run
+	if !dryRun {
+		if err := checkPackage(clusterName, insts[0].ComponentName(), insts[0].OS(), insts[0].Arch(), packagePath); err != nil {
+			return err
+		}
+	}
 
 	var replacePackageTasks []task.Task
 	for _, inst := range insts {
@@ -99,9 +108,17 @@ func patch(clusterName, packagePath string, options operator.Options, overwrite
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
 		Parallel(replacePackageTasks...).
-		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options).
+		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options, tlsCfg).
 		Build()
 
+	if dryRun {
+		plan := task.BuildPlan(t)
+		if overwrite {
+			plan.Add(task.PlanHostLocal, task.PlanActionMeta, "save %s as the package of %s for future scale-out", packagePath, insts[0].ComponentName())
+		}
+		return printPlan(clusterName, "patch", plan)
+	}
+
 	if err := t.Execute(task.NewContext()); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
@@ -0,0 +1,73 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/spf13/cobra"
+)
+
+// dryRun is set by the --dry-run flag, the commands print the plan of their
+// tasks instead of executing them
+var dryRun bool
+
+func addDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the operations to be performed, grouped by host, without connecting to any host")
+}
+
+// readSSHConnectionProps reads the identity file or password for the SSH
+// connections, nothing is read or prompted in dry run
+func readSSHConnectionProps(identityFile string, usePassword bool) (*cliutil.SSHConnectionProps, error) {
+	if dryRun {
+		return &cliutil.SSHConnectionProps{IdentityFile: identityFile}, nil
+	}
+	return cliutil.ReadIdentityFileOrPassword(identityFile, usePassword)
+}
+
+// planHost is the operations on a host in the structured output
+type planHost struct {
+	Host  string          `json:"host" yaml:"host"`
+	Steps []task.PlanStep `json:"steps" yaml:"steps"`
+}
+
+// printPlan prints the plan of a command, the operations are grouped by host
+// in the order the hosts are first operated
+func printPlan(clusterName, command string, plan *task.Plan) error {
+	var hosts []planHost
+	for _, host := range plan.Hosts() {
+		hosts = append(hosts, planHost{Host: host, Steps: plan.HostSteps(host)})
+	}
+
+	if cliutil.IsStructuredOutput() {
+		return cliutil.PrintResult(struct {
+			Cluster string     `json:"cluster" yaml:"cluster"`
+			Command string     `json:"command" yaml:"command"`
+			Hosts   []planHost `json:"hosts" yaml:"hosts"`
+		}{clusterName, command, hosts})
+	}
+
+	cyan := color.New(color.FgCyan, color.Bold)
+	fmt.Printf("Plan of %s for cluster %s (dry run, nothing is executed):\n", command, cyan.Sprint(clusterName))
+	for _, h := range hosts {
+		fmt.Printf("\n%s\n", cyan.Sprint(h.Host))
+		for i, s := range h.Steps {
+			fmt.Printf("  %3d. %-8s %s\n", i+1, s.Action, s.Detail)
+		}
+	}
+	return nil
+}
@@ -45,7 +45,9 @@ func newReloadCmd() *cobra.Command {
 				return errors.Errorf("cannot start non-exists cluster %s", clusterName)
 			}
 
-			logger.EnableAuditLog()
+			if !dryRun {
+				logger.EnableAuditLog()
+			}
 			metadata, err := meta.ClusterMetadata(clusterName)
 			if err != nil {
 				return err
@@ -56,6 +58,10 @@ func newReloadCmd() *cobra.Command {
 				return err
 			}
 
+			if dryRun {
+				return printPlan(clusterName, "reload", task.BuildPlan(t))
+			}
+
 			if err := t.Execute(task.NewContext()); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
@@ -73,8 +79,9 @@ func newReloadCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only start specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only start specified nodes")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	addDryRunFlag(cmd)
 
-	return cmd
+	return requireLock(cmd, lockExisting)
 }
 
 func buildReloadTask(
@@ -83,6 +90,11 @@ func buildReloadTask(
 	options operator.Options,
 ) (task.Task, error) {
 
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return nil, err
+	}
+
 	var refreshConfigTasks []task.Task
 
 	topo := metadata.Topology
@@ -121,7 +133,7 @@ func buildReloadTask(
 	})
 
 	// handle dir scheme changes
-	if hasImported {
+	if hasImported && !dryRun {
 		if err := meta.HandleImportPathMigration(clusterName); err != nil {
 			return task.NewBuilder().Build(), err
 		}
@@ -133,7 +145,7 @@ func buildReloadTask(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
 		Parallel(refreshConfigTasks...).
-		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options).
+		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options, tlsCfg).
 		Build()
 
 	return t, nil
@@ -26,10 +26,14 @@ import (
 )
 
 func newRestartCmd() *cobra.Command {
+	var batch *batchOptions
 	cmd := &cobra.Command{
 		Use:   "restart <cluster-name>",
 		Short: "Restart a TiDB cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if batch.enabled(args) {
+				return batch.run(cmd, args)
+			}
 			if len(args) != 1 {
 				return cmd.Help()
 			}
@@ -50,12 +54,17 @@ func newRestartCmd() *cobra.Command {
 				return err
 			}
 
+			tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+			if err != nil {
+				return err
+			}
+
 			t := task.NewBuilder().
 				SSHKeySet(
 					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 				ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-				ClusterOperate(metadata.Topology, operator.RestartOperation, gOpt).
+				ClusterOperate(metadata.Topology, operator.RestartOperation, gOpt, tlsCfg).
 				Build()
 
 			if err := t.Execute(task.NewContext()); err != nil {
@@ -74,6 +83,7 @@ func newRestartCmd() *cobra.Command {
 
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only restart specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only restart specified nodes")
+	batch = addBatchFlags(cmd, true)
 
-	return cmd
+	return requireLock(cmd, lockExisting)
 }
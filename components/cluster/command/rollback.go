@@ -0,0 +1,208 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newRollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback <cluster-name> [version]",
+		Short: "Roll back a specified TiDB cluster to the previous version",
+		Long: `Roll back a specified TiDB cluster to the previous version.
+
+The binaries backed up on the hosts by the upgrade or patch are restored, the
+configs are regenerated for the version, and the instances are restarted one
+by one in the reverse order of the upgrade. The version is the one before the
+last upgrade by default, specify the current version to back out of a patch.
+
+The rollback is refused if the data format has changed since the version.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 && len(args) != 2 {
+				return cmd.Help()
+			}
+
+			if !dryRun {
+				logger.EnableAuditLog()
+			}
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			version := ""
+			if len(args) == 2 {
+				version = args[1]
+				teleCommand = append(teleCommand, version)
+			}
+			if err := validateHealthGateFlags(); err != nil {
+				return err
+			}
+			return rollback(clusterName, version, gOpt)
+		},
+	}
+	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force rollback won't transfer leader and check the health gates")
+	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	addHealthGateFlags(cmd)
+	addDryRunFlag(cmd)
+
+	return requireLock(cmd, lockExisting)
+}
+
+// rollback the cluster to the version, the previous version if it is empty
+func rollback(clusterName, clusterVersion string, opt operator.Options) error {
+	if utils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return errors.Errorf("cannot roll back non-exists cluster %s", clusterName)
+	}
+
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
+	if clusterVersion == "" {
+		if clusterVersion, err = meta.PreviousClusterVersion(clusterName); err != nil {
+			return err
+		}
+	}
+	if err := meta.CheckDowngrade(metadata.Version, clusterVersion); err != nil {
+		return err
+	}
+
+	// the configuration is rolled back with the version, the instances added
+	// since then keep theirs
+	backup, err := meta.ClusterMetaBackup(clusterName, clusterVersion)
+	if err != nil {
+		return err
+	}
+	if backup != nil && backup.Topology != nil {
+		metadata.Topology.PatchConfig(backup.Topology)
+	} else {
+		log.Warnf("No backup of the metadata of %s is found, the current configuration is kept", clusterVersion)
+	}
+
+	var (
+		downloadCompTasks []task.Task // tasks which are used to download components
+		restoreCompTasks  []task.Task // tasks which are used to restore components on remote host
+
+		uniqueComps = map[string]struct{}{}
+	)
+
+	for _, comp := range operator.ComponentsByDowngradeOrder(metadata.Topology) {
+		for _, inst := range comp.Instances() {
+			version := meta.ComponentVersion(inst.ComponentName(), clusterVersion)
+			deployDir := clusterutil.Abs(metadata.User, inst.DeployDir())
+			// data dir would be empty for components which don't need it
+			dataDirs := clusterutil.MultiDirAbs(metadata.User, inst.DataDir())
+			// log dir will always be with values, but might not used by the component
+			logDir := clusterutil.Abs(metadata.User, inst.LogDir())
+
+			tb := task.NewBuilder()
+			switch inst.ComponentName() {
+			case meta.ComponentPrometheus, meta.ComponentGrafana, meta.ComponentAlertManager:
+				// the imported monitoring components are not backed up by the upgrade
+				if inst.IsImported() {
+					key := fmt.Sprintf("%s-%s-%s-%s", inst.ComponentName(), version, inst.OS(), inst.Arch())
+					if _, found := uniqueComps[key]; !found {
+						uniqueComps[key] = struct{}{}
+						t := task.NewBuilder().
+							Download(inst.ComponentName(), inst.OS(), inst.Arch(), version).
+							Build()
+						downloadCompTasks = append(downloadCompTasks, t)
+					}
+					tb.CopyComponent(inst.ComponentName(), inst.OS(), inst.Arch(), version, inst.GetHost(), deployDir)
+					break
+				}
+				fallthrough
+			default:
+				tb.RestoreComponent(inst.ComponentName(), metadata.Version, clusterVersion, inst.GetHost(), deployDir)
+			}
+			tb.InitConfig(
+				clusterName,
+				clusterVersion,
+				inst,
+				metadata.User,
+				meta.DirPaths{
+					Deploy: deployDir,
+					Data:   dataDirs,
+					Log:    logDir,
+					Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
+				},
+			)
+			restoreCompTasks = append(restoreCompTasks, tb.Build())
+		}
+	}
+
+	t := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
+		Parallel(downloadCompTasks...).
+		Parallel(restoreCompTasks...).
+		ClusterOperate(metadata.Topology, operator.DowngradeOperation, opt, tlsCfg).
+		Build()
+
+	if dryRun {
+		plan := task.BuildPlan(t)
+		plan.Add(task.PlanHostLocal, task.PlanActionMeta, "set version of cluster %s to %s, restore its configuration and remove the patches", clusterName, clusterVersion)
+		return printPlan(clusterName, "rollback", plan)
+	}
+
+	if !skipConfirm {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"Cluster %s will be rolled back from %s to %s.\nDo you want to continue? [y/N]: ",
+			color.HiYellowString(clusterName), color.HiYellowString(metadata.Version), color.HiYellowString(clusterVersion)); err != nil {
+			return err
+		}
+	}
+
+	if err := t.Execute(task.NewContext()); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	metadata.Version = clusterVersion
+	if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
+		return errors.Trace(err)
+	}
+	// the patched packages saved for scale-out are for the version rolled back from
+	if err := os.RemoveAll(meta.ClusterPath(clusterName, "patch")); err != nil {
+		return errors.Trace(err)
+	}
+
+	log.Infof("Rolled back cluster `%s` to %s successfully", clusterName, clusterVersion)
+
+	return nil
+}
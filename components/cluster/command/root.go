@@ -25,6 +25,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/joomcode/errorx"
 	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/audit"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
 	"github.com/pingcap/tiup/pkg/cluster/flags"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -46,6 +48,8 @@ var (
 	errNS       = errorx.NewNamespace("cmd")
 	rootCmd     *cobra.Command
 	gOpt        operator.Options
+	sshOpt      executor.SSHOptions
+	format      string
 	skipConfirm bool
 )
 
@@ -85,9 +89,15 @@ func init() {
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 			var env *tiupmeta.Environment
+			if err = cliutil.SetOutputFormat(format); err != nil {
+				return err
+			}
 			if err = meta.Initialize("cluster"); err != nil {
 				return err
 			}
+			if err = executor.SetDefaultSSHOptions(sshOpt); err != nil {
+				return err
+			}
 			// Running in other OS/ARCH Should be fine we only download manifest file.
 			env, err = tiupmeta.InitEnv(repository.Options{
 				GOOS:   "linux",
@@ -99,8 +109,9 @@ func init() {
 			tiupmeta.SetGlobalEnv(env)
 
 			teleCommand = getParentNames(cmd)
+			auditCluster = clusterNameArg(cmd, args)
 
-			return nil
+			return lockClusterIfNeed(cmd, args)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
@@ -115,27 +126,46 @@ func init() {
 	rootCmd.PersistentFlags().Int64Var(&gOpt.SSHTimeout, "ssh-timeout", 5, "Timeout in seconds to connect host via SSH, ignored for operations that don't need an SSH connection.")
 	rootCmd.PersistentFlags().Int64Var(&gOpt.OptTimeout, "wait-timeout", 60, "Timeout in seconds to wait for an operation to complete, ignored for operations that don't fit.")
 	rootCmd.PersistentFlags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip all confirmations and assumes 'yes'")
+	rootCmd.PersistentFlags().StringVar(&format, "format", cliutil.OutputFormatText, "The format of the results, support values: text, json, yaml (display, list, audit, check, exec, config diff, cdc changefeed list and backup list only)")
+	rootCmd.PersistentFlags().StringVar((*string)(&sshOpt.Type), "ssh", string(executor.SSHTypeEasySSH), "The executor used to connect hosts, support values: easyssh, native, none (run on the local host only)")
+	rootCmd.PersistentFlags().StringVar(&sshOpt.ProxyJump, "ssh-proxy-jump", "", "Connect hosts through the jump hosts, in the form of [user@]host[:port][,...] (native SSH only)")
+	rootCmd.PersistentFlags().StringVar(&sshOpt.ProxyKeyFile, "ssh-proxy-identity-file", "", "The private key file of the jump hosts, the key of the target host is used by default (native SSH only)")
+	rootCmd.PersistentFlags().StringVar(&sshOpt.KnownHostsFile, "ssh-known-hosts", "", "Verify host keys against the known_hosts file, host keys are not verified if not set (native SSH only)")
+	rootCmd.PersistentFlags().BoolVar(&sshOpt.UseAgent, "ssh-agent", false, "Authenticate with the keys of ssh-agent (native SSH only)")
+	rootCmd.PersistentFlags().BoolVar(&useEtcdLock, "etcd-lock", false, "Also hold the operation lock in the etcd of PD to exclude operators on other control machines")
 
 	rootCmd.AddCommand(
 		newCheckCmd(),
+		newTemplateCmd(),
 		newDeploy(),
 		newStartCmd(),
 		newStopCmd(),
 		newRestartCmd(),
 		newScaleInCmd(),
 		newScaleOutCmd(),
+		newApplyCmd(),
 		newDestroyCmd(),
 		newUpgradeCmd(),
+		newRollbackCmd(),
 		newExecCmd(),
 		newDisplayCmd(),
 		newListCmd(),
+		newLabelCmd(),
 		newAuditCmd(),
+		newCollectCmd(),
+		newLogsCmd(),
 		newImportCmd(),
 		newEditConfigCmd(),
+		newConfigCmd(),
 		newReloadCmd(),
 		newPatchCmd(),
+		newCDCCmd(),
+		newBackupCmd(),
+		newRestoreCmd(),
 		newTestCmd(), // hidden command for test internally
 		newTelemetryCmd(),
+		newLockCmd(),
+		newMetaCmd(),
 	)
 }
 
@@ -217,10 +247,13 @@ func Execute() {
 
 	start := time.Now()
 	code := 0
+	debugLog := ""
 	err := rootCmd.Execute()
 	if err != nil {
 		code = 1
 	}
+	executor.CloseSSHConnections()
+	unlockCluster()
 
 	zap.L().Info("Execute command finished", zap.Int("code", code), zap.Error(err))
 
@@ -269,7 +302,7 @@ func Execute() {
 		}
 
 		if !errorx.HasTrait(err, errutil.ErrTraitPreCheck) {
-			logger.OutputDebugLog()
+			debugLog = logger.OutputDebugLog()
 		}
 
 		if errx := errorx.Cast(err); errx != nil {
@@ -279,7 +312,13 @@ func Execute() {
 		}
 	}
 
-	logger.OutputAuditLogIfEnabled()
+	logger.OutputAuditLogIfEnabled(&audit.Record{
+		Cluster:  auditCluster,
+		ExitCode: code,
+		Hosts:    executor.TouchedHosts(),
+		DebugLog: debugLog,
+	})
+	applyAuditRetention()
 
 	color.Unset()
 
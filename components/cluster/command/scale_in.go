@@ -42,7 +42,7 @@ func newScaleInCmd() *cobra.Command {
 
 			clusterName := args[0]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
-			if !skipConfirm {
+			if !skipConfirm && !dryRun {
 				if err := cliutil.PromptForConfirmOrAbortError(
 					"This operation will delete the %s nodes in `%s` and all their data.\nDo you want to continue? [y/N]:",
 					strings.Join(gOpt.Nodes, ","),
@@ -61,7 +61,9 @@ func newScaleInCmd() *cobra.Command {
 				log.Infof("Scale-in nodes...")
 			}
 
-			logger.EnableAuditLog()
+			if !dryRun {
+				logger.EnableAuditLog()
+			}
 			return scaleIn(clusterName, gOpt)
 		},
 	}
@@ -70,9 +72,11 @@ func newScaleInCmd() *cobra.Command {
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
 	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force just try stop and destroy instance before removing the instance from topo")
 
+	addDryRunFlag(cmd)
+
 	_ = cmd.MarkFlagRequired("node")
 
-	return cmd
+	return requireLock(cmd, lockExisting)
 }
 
 func scaleIn(clusterName string, options operator.Options) error {
@@ -85,6 +89,11 @@ func scaleIn(clusterName string, options operator.Options) error {
 		return err
 	}
 
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
 	// Regenerate configuration
 	var regenConfigTasks []task.Task
 	hasImported := false
@@ -128,29 +137,40 @@ func scaleIn(clusterName string, options operator.Options) error {
 	}
 
 	// handle dir scheme changes
-	if hasImported {
+	if hasImported && !dryRun {
 		if err := meta.HandleImportPathMigration(clusterName); err != nil {
 			return err
 		}
 	}
 
+	hookOpt := hookOptions(clusterName, metadata.Version, meta.HookOperationScaleIn)
+	instances := operatedInstances(metadata.Topology.ComponentsByStopOrder(), options)
+	globalHooks := metadata.Topology.GlobalOptions.Hooks
+
 	b := task.NewBuilder().
 		SSHKeySet(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
-		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout)
+		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
+		Hook(globalHooks, hookOpt, true, instances)
 
 	if !options.Force {
-		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
+		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options, tlsCfg).
 			UpdateMeta(clusterName, metadata, operator.AsyncNodes(metadata.Topology, options.Nodes, false)).
 			UpdateTopology(clusterName, metadata, operator.AsyncNodes(metadata.Topology, options.Nodes, false))
 	} else {
-		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
+		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options, tlsCfg).
 			UpdateMeta(clusterName, metadata, options.Nodes).
 			UpdateTopology(clusterName, metadata, options.Nodes)
 	}
 
-	t := b.Parallel(regenConfigTasks...).Build()
+	t := b.Parallel(regenConfigTasks...).
+		Hook(globalHooks, hookOpt, false, instances).
+		Build()
+
+	if dryRun {
+		return printPlan(clusterName, "scale-in", task.BuildPlan(t))
+	}
 
 	if err := t.Execute(task.NewContext()); err != nil {
 		if errorx.Cast(err) != nil {
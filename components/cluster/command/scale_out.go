@@ -28,6 +28,7 @@ import (
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/report"
 	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/crypto"
 	"github.com/pingcap/tiup/pkg/logger"
 	"github.com/pingcap/tiup/pkg/logger/log"
 	"github.com/pingcap/tiup/pkg/set"
@@ -39,6 +40,8 @@ type scaleOutOptions struct {
 	user         string // username to login to the SSH server
 	identityFile string // path to the private key file
 	usePassword  bool   // use password instead of identity file for ssh connection
+	resume       bool   // skip the tasks finished by the previous interrupted scale-out
+	noRollback   bool   // keep the work finished by this run if the scale-out fails
 }
 
 func newScaleOutCmd() *cobra.Command {
@@ -54,7 +57,9 @@ func newScaleOutCmd() *cobra.Command {
 				return cmd.Help()
 			}
 
-			logger.EnableAuditLog()
+			if !dryRun {
+				logger.EnableAuditLog()
+			}
 			clusterName := args[0]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 			return scaleOut(args[0], args[1], opt)
@@ -64,8 +69,11 @@ func newScaleOutCmd() *cobra.Command {
 	cmd.Flags().StringVar(&opt.user, "user", tiuputils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
 	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.resume, "resume", false, "Resume the previous interrupted scale-out, the finished tasks are skipped.")
+	cmd.Flags().BoolVar(&opt.noRollback, "no-rollback", false, "Do not revert the tasks finished by this run if the scale-out fails, the tasks finished by the previous runs are never reverted.")
+	addDryRunFlag(cmd)
 
-	return cmd
+	return requireLock(cmd, lockExisting)
 }
 
 func scaleOut(clusterName, topoFile string, opt scaleOutOptions) error {
@@ -93,50 +101,101 @@ func scaleOut(clusterName, topoFile string, opt scaleOutOptions) error {
 		teleTopology = string(data)
 	}
 
+	mergedTopo, err := validateScaleOutTopology(clusterName, metadata, &newPart)
+	if err != nil {
+		return err
+	}
+
+	patchedComponents := scaleOutPatchedComponents(clusterName, &newPart)
+	if !skipConfirm && !dryRun {
+		// patchedComponents are components that have been patched and overwrited
+		if err := confirmTopology(clusterName, metadata.Version, &newPart, patchedComponents); err != nil {
+			return err
+		}
+	}
+
+	sshConnProps, err := readSSHConnectionProps(opt.identityFile, opt.usePassword)
+	if err != nil {
+		return err
+	}
+
+	return executeScaleOut(clusterName, metadata, mergedTopo, &newPart, patchedComponents, opt, sshConnProps)
+}
+
+// validateScaleOutTopology returns the merged topology if the new part can be
+// added to the cluster
+func validateScaleOutTopology(clusterName string, metadata *meta.ClusterMeta, newPart *meta.TopologySpecification) (*meta.ClusterSpecification, error) {
 	// Abort scale out operation if the merged topology is invalid
-	mergedTopo := metadata.Topology.Merge(&newPart)
+	mergedTopo := metadata.Topology.Merge(newPart)
 	if err := mergedTopo.Validate(); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := prepare.CheckClusterPortConflict(clusterName, mergedTopo); err != nil {
-		return err
+		return nil, err
 	}
 	if err := prepare.CheckClusterDirConflict(clusterName, mergedTopo); err != nil {
-		return err
+		return nil, err
 	}
+	return mergedTopo, nil
+}
 
+// scaleOutPatchedComponents returns the components of the new part which have
+// been patched and overwrited
+func scaleOutPatchedComponents(clusterName string, newPart *meta.TopologySpecification) set.StringSet {
 	patchedComponents := set.NewStringSet()
 	newPart.IterInstance(func(instance meta.Instance) {
 		if exists := tiuputils.IsExist(meta.ClusterPath(clusterName, meta.PatchDirName, instance.ComponentName()+".tar.gz")); exists {
 			patchedComponents.Insert(instance.ComponentName())
 		}
 	})
-	if !skipConfirm {
-		// patchedComponents are components that have been patched and overwrited
-		if err := confirmTopology(clusterName, metadata.Version, &newPart, patchedComponents); err != nil {
-			return err
-		}
-	}
+	return patchedComponents
+}
 
-	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword)
+func executeScaleOut(
+	clusterName string,
+	metadata *meta.ClusterMeta,
+	mergedTopo *meta.ClusterSpecification,
+	newPart *meta.TopologySpecification,
+	patchedComponents set.StringSet,
+	opt scaleOutOptions,
+	sshConnProps *cliutil.SSHConnectionProps,
+) error {
+	// Build the scale out tasks
+	t, err := buildScaleOutTask(clusterName, metadata, mergedTopo, opt, sshConnProps, newPart, patchedComponents, gOpt.OptTimeout)
 	if err != nil {
 		return err
 	}
 
-	// Build the scale out tasks
-	t, err := buildScaleOutTask(clusterName, metadata, mergedTopo, opt, sshConnProps, &newPart, patchedComponents, gOpt.OptTimeout)
+	if dryRun {
+		return printPlan(clusterName, "scale-out", task.BuildPlan(t))
+	}
+
+	cp, err := task.NewCheckPoint(meta.ClusterPath(clusterName, meta.CheckPointFileName("scale-out")), opt.resume)
 	if err != nil {
 		return err
 	}
-
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := task.NewContext()
+	ctx.SetCheckPoint(cp)
+	if !opt.noRollback {
+		ctx.EnableRollback()
+	}
+	if err := t.Execute(ctx); err != nil {
+		if !opt.noRollback {
+			rollbackTask(t, ctx)
+		}
+		// the checkpoint is kept to resume the scale-out, the tasks finished
+		// by the previous runs are not rolled back
+		_ = cp.Close()
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
 		}
 		return errors.Trace(err)
 	}
+	if err := cp.Remove(); err != nil {
+		return err
+	}
 
 	log.Infof("Scaled cluster `%s` out successfully", clusterName)
 
@@ -169,6 +228,17 @@ func buildScaleOutTask(
 		refreshConfigTasks []task.Task // tasks which are used to refresh configuration
 	)
 
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return task.NewBuilder().Build(), err
+	}
+	var ca *crypto.CertificateAuthority
+	if metadata.Topology.GlobalOptions.TLSEnabled {
+		if ca, err = meta.GenClusterCA(clusterName, meta.ClusterPath(clusterName, meta.TLSCertKeyDir)); err != nil {
+			return task.NewBuilder().Build(), err
+		}
+	}
+
 	// Initialize the environments
 	initializedHosts := set.NewStringSet()
 	metadata.Topology.IterInstance(func(instance meta.Instance) {
@@ -256,6 +326,12 @@ func buildScaleOutTask(
 		} else {
 			tb.CopyComponent(inst.ComponentName(), inst.OS(), inst.Arch(), version, inst.GetHost(), deployDir)
 		}
+		if ca != nil {
+			tb.TLSCert(inst, ca, metadata.User, meta.DirPaths{
+				Deploy: deployDir,
+				Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
+			})
+		}
 		t := tb.ScaleConfig(clusterName,
 			metadata.Version,
 			metadata.Topology,
@@ -307,7 +383,7 @@ func buildScaleOutTask(
 	})
 
 	// handle dir scheme changes
-	if hasImported {
+	if hasImported && !dryRun {
 		if err := meta.HandleImportPathMigration(clusterName); err != nil {
 			return task.NewBuilder().Build(), err
 		}
@@ -345,20 +421,31 @@ func buildScaleOutTask(
 		builder.Parallel(convertStepDisplaysToTasks([]*task.StepDisplay{nodeInfoTask})...)
 	}
 
+	hookOpt := hookOptions(clusterName, metadata.Version, meta.HookOperationScaleOut)
+	instances := operatedInstances(newPart.ComponentsByStartOrder(), operator.Options{})
+	globalHooks := metadata.Topology.GlobalOptions.Hooks
+
 	// TODO: find another way to make sure current cluster started
-	builder.ClusterOperate(metadata.Topology, operator.StartOperation, operator.Options{OptTimeout: timeout}).
+	originTopo := metadata.Topology
+	builder.ClusterOperate(metadata.Topology, operator.StartOperation, operator.Options{OptTimeout: timeout}, tlsCfg).
 		ClusterSSH(newPart, metadata.User, gOpt.SSHTimeout).
-		Func("save meta", func(_ *task.Context) error {
+		// the new instances are deployed but not started yet
+		Hook(globalHooks, hookOpt, true, instances).
+		FuncWithRollback("save meta", func(_ *task.Context) error {
 			metadata.Topology = mergedTopo
 			return meta.SaveClusterMeta(clusterName, metadata)
+		}, func(_ *task.Context) error {
+			metadata.Topology = originTopo
+			return meta.SaveClusterMeta(clusterName, metadata)
 		}).
-		ClusterOperate(newPart, operator.StartOperation, operator.Options{OptTimeout: timeout}).
+		ClusterOperate(newPart, operator.StartOperation, operator.Options{OptTimeout: timeout}, tlsCfg).
 		Parallel(refreshConfigTasks...).
 		ClusterOperate(metadata.Topology, operator.RestartOperation, operator.Options{
 			Roles:      []string{meta.ComponentPrometheus},
 			OptTimeout: timeout,
-		}).
-		UpdateTopology(clusterName, metadata, nil)
+		}, tlsCfg).
+		UpdateTopology(clusterName, metadata, nil).
+		Hook(globalHooks, hookOpt, false, instances)
 
 	return builder.Build(), nil
 
@@ -51,7 +51,7 @@ func newStartCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only start specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only start specified nodes")
 
-	return cmd
+	return requireLock(cmd, lockExisting)
 }
 
 func startCluster(clusterName string, options operator.Options) error {
@@ -62,13 +62,24 @@ func startCluster(clusterName string, options operator.Options) error {
 		return err
 	}
 
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
+	hookOpt := hookOptions(clusterName, metadata.Version, meta.HookOperationStart)
+	instances := operatedInstances(metadata.Topology.ComponentsByStartOrder(), options)
+	globalHooks := metadata.Topology.GlobalOptions.Hooks
+
 	t := task.NewBuilder().
 		SSHKeySet(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-		ClusterOperate(metadata.Topology, operator.StartOperation, options).
+		Hook(globalHooks, hookOpt, true, instances).
+		ClusterOperate(metadata.Topology, operator.StartOperation, options, tlsCfg).
 		UpdateTopology(clusterName, metadata, nil).
+		Hook(globalHooks, hookOpt, false, instances).
 		Build()
 
 	if err := t.Execute(task.NewContext()); err != nil {
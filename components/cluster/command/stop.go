@@ -50,12 +50,23 @@ func newStopCmd() *cobra.Command {
 				return err
 			}
 
+			tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+			if err != nil {
+				return err
+			}
+
+			hookOpt := hookOptions(clusterName, metadata.Version, meta.HookOperationStop)
+			instances := operatedInstances(metadata.Topology.ComponentsByStopOrder(), gOpt)
+			globalHooks := metadata.Topology.GlobalOptions.Hooks
+
 			t := task.NewBuilder().
 				SSHKeySet(
 					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 				ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-				ClusterOperate(metadata.Topology, operator.StopOperation, gOpt).
+				Hook(globalHooks, hookOpt, true, instances).
+				ClusterOperate(metadata.Topology, operator.StopOperation, gOpt, tlsCfg).
+				Hook(globalHooks, hookOpt, false, instances).
 				Build()
 
 			if err := t.Execute(task.NewContext()); err != nil {
@@ -75,5 +86,5 @@ func newStopCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only stop specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only stop specified nodes")
 
-	return cmd
+	return requireLock(cmd, lockExisting)
 }
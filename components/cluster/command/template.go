@@ -0,0 +1,413 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cliutil/prepare"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/cluster/topogen"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/tui"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// the hosts of the topology printed if no host is specified
+var exampleHosts = []string{"172.16.5.1", "172.16.5.2", "172.16.5.3"}
+
+type templateOptions struct {
+	interactive  bool   // ask for the spec
+	specFile     string // generate from the spec file
+	output       string // the file to write, stdout if empty
+	probe        bool   // probe the hardware of the hosts
+	user         string // username to login to the SSH server
+	identityFile string // path to the private key file
+	usePassword  bool   // use password instead of identity file for ssh connection
+}
+
+func newTemplateCmd() *cobra.Command {
+	opt := templateOptions{
+		identityFile: path.Join(tiuputils.UserHome(), ".ssh", "id_rsa"),
+	}
+	cmd := &cobra.Command{
+		Use:   "template [host]...",
+		Short: "Generate a topology file",
+		Long: `Generate a topology file which passes the validations and the conflict
+checks of deploy. By default the components are placed on the hosts given, or
+three example hosts if none is given. The hosts and the roles can also be
+read from a spec file with '--spec', or be asked with '--interactive'.
+
+With '--probe', the CPU, memory, disks and NUMA nodes of the hosts are probed
+over SSH to suggest the placement: TiKV and TiFlash are placed on the hosts
+with more data disks and get the disks of their own, and the instances on the
+same host are bound to the NUMA nodes.
+
+The spec file is like:
+
+  user: tidb
+  deploy_dir: /tidb-deploy
+  data_dir: /tidb-data
+  hosts:
+    - host: 172.16.5.1
+    - host: 172.16.5.2
+    - host: 172.16.5.3
+      roles: [tidb, prometheus, grafana, alertmanager]
+  roles:
+    tikv: 3
+
+The roles of a host are placed as listed, and the number of instances in
+'roles' are placed on the other hosts automatically.`,
+		Example: `  tiup cluster template > topology.yaml
+  tiup cluster template 172.16.5.1 172.16.5.2 172.16.5.3 --probe -o topology.yaml
+  tiup cluster template --spec spec.yaml -o topology.yaml
+  tiup cluster template --interactive`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opt.interactive {
+				return templateWizard(&opt)
+			}
+
+			var spec *topogen.Spec
+			if opt.specFile != "" {
+				if len(args) > 0 {
+					return errors.New("the hosts can not be specified with --spec")
+				}
+				var err error
+				if spec, err = topogen.LoadSpec(opt.specFile); err != nil {
+					return err
+				}
+			} else if len(args) > 0 {
+				spec = topogen.DefaultSpec(args...)
+			} else {
+				spec = topogen.DefaultSpec(exampleHosts...)
+			}
+
+			if err := spec.Validate(); err != nil {
+				return err
+			}
+			if opt.probe {
+				if err := probeHosts(spec, &opt); err != nil {
+					return err
+				}
+			}
+
+			data, topo, err := generateTopology(spec)
+			if err != nil {
+				return err
+			}
+			if opt.output == "" {
+				fmt.Print(string(data))
+				return nil
+			}
+			printTopologySummary(topo)
+			return writeTopology(opt.output, data)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opt.interactive, "interactive", false, "Ask for the hosts and the roles interactively")
+	cmd.Flags().StringVar(&opt.specFile, "spec", "", "Generate from the spec file of the hosts and the roles")
+	cmd.Flags().StringVarP(&opt.output, "output", "o", "", "The file to write the topology to, print to stdout if not specified")
+	cmd.Flags().BoolVar(&opt.probe, "probe", false, "Probe the hardware of the hosts over SSH to suggest the placement")
+	cmd.Flags().StringVar(&opt.user, "user", tiuputils.CurrentUser(), "The user name to login via SSH to probe the hosts. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+
+	return cmd
+}
+
+// templateWizard asks for the spec and writes the topology generated
+func templateWizard(opt *templateOptions) error {
+	p := tui.NewStdPrompter()
+	w := topogen.NewWizard(p)
+	if err := w.AskHosts(); err != nil {
+		return err
+	}
+
+	probe, err := p.Confirm("Probe the CPU, memory, disks and NUMA nodes of the hosts over SSH?", true)
+	if err != nil {
+		return err
+	}
+	if probe {
+		if opt.user, err = p.Input("User to login via SSH, it must has root (or sudo) privilege", opt.user, nil); err != nil {
+			return err
+		}
+		if err := probeHosts(w.Spec, opt); err != nil {
+			return err
+		}
+		hostTable := [][]string{{"Host", "Hardware"}}
+		for _, h := range w.Spec.Hosts {
+			hostTable = append(hostTable, []string{h.Host, h.Info.String()})
+		}
+		cliutil.PrintTable(hostTable, true)
+	}
+
+	for {
+		if err := w.AskRoles(); err != nil {
+			return err
+		}
+		data, topo, err := generateTopology(w.Spec)
+		if err != nil {
+			// ask again if the roles can not be placed
+			log.Errorf("Failed to generate the topology: %s", err)
+			continue
+		}
+
+		printTopologySummary(topo)
+		ok, err := p.Confirm("Do you want to use the placement?", true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		output := opt.output
+		if output == "" {
+			if output, err = p.Input("Write the topology to", "topology.yaml", nil); err != nil {
+				return err
+			}
+		}
+		if tiuputils.IsExist(output) {
+			if ok, err := p.Confirm(fmt.Sprintf("%s exists, overwrite it?", output), false); err != nil || !ok {
+				return err
+			}
+		}
+		return writeTopology(output, data)
+	}
+}
+
+// probeHosts probes the hardware of the hosts of the spec, the insight
+// collector of `check` is used to collect the system info
+func probeHosts(spec *topogen.Spec, opt *templateOptions) error {
+	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu    sync.Mutex
+		archs = make(map[string]string) // host -> arch
+		numas = make(map[string]int)    // host -> the number of NUMA nodes
+		infos = make(map[string]*topogen.HostInfo)
+	)
+
+	// the arch is probed first to download the collector of the arch
+	var archTasks []*task.StepDisplay
+	for _, h := range spec.Hosts {
+		host := h.Host
+		sshPort := h.SSHPort
+		if sshPort == 0 {
+			sshPort = spec.SSHPort
+		}
+		t := task.NewBuilder().
+			RootSSH(
+				host,
+				sshPort,
+				opt.user,
+				sshConnProps.Password,
+				sshConnProps.IdentityFile,
+				sshConnProps.IdentityFilePassphrase,
+				gOpt.SSHTimeout,
+			).
+			Shell(host, "uname -m", false).
+			Func("ParseArch", func(ctx *task.Context) error {
+				stdout, _, _ := ctx.GetOutputs(host)
+				mu.Lock()
+				defer mu.Unlock()
+				archs[host] = meta.NormalizeArch(strings.TrimSpace(string(stdout)))
+				return nil
+			}).
+			Shell(host, topogen.NUMAProbeCommand, false).
+			Func("ParseNUMANodes", func(ctx *task.Context) error {
+				stdout, _, _ := ctx.GetOutputs(host)
+				n, err := topogen.ParseNUMANodes(stdout)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				numas[host] = n
+				return nil
+			}).
+			BuildAsStep(fmt.Sprintf("  - Connecting to %s:%d", host, sshPort))
+		archTasks = append(archTasks, t)
+	}
+
+	ctx := task.NewContext()
+	t := task.NewBuilder().
+		ParallelStep("+ Detect the architecture of the hosts", archTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	var (
+		downloadTasks []*task.StepDisplay
+		collectTasks  []*task.StepDisplay
+		downloaded    = make(map[string]struct{})
+		insightVer    = meta.ComponentVersion(meta.ComponentCheckCollector, "")
+	)
+	for _, h := range spec.Hosts {
+		host, arch := h.Host, archs[h.Host]
+		if _, found := downloaded[arch]; !found {
+			downloaded[arch] = struct{}{}
+			downloadTasks = append(downloadTasks, task.NewBuilder().
+				Download(meta.ComponentCheckCollector, "linux", arch, insightVer).
+				BuildAsStep(fmt.Sprintf("  - Downloading check tools for linux/%s", arch)))
+		}
+
+		// the executors are connected by the previous tasks
+		t := task.NewBuilder().
+			Mkdir(opt.user, host, filepath.Join(task.CheckToolsPathDir, "bin")).
+			CopyComponent(meta.ComponentCheckCollector, "linux", arch, insightVer, host, task.CheckToolsPathDir).
+			Shell(host, filepath.Join(task.CheckToolsPathDir, "bin", "insight"), false).
+			Func("ParseHostInfo", func(ctx *task.Context) error {
+				stdout, _, _ := ctx.GetOutputs(host)
+				info, err := topogen.ParseHostInfo(stdout)
+				if err != nil {
+					return errors.Annotatef(err, "failed to probe %s", host)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				infos[host] = info
+				return nil
+			}).
+			Rmdir(host, task.CheckToolsPathDir).
+			BuildAsStep(fmt.Sprintf("  - Getting system info of %s", host))
+		collectTasks = append(collectTasks, t)
+	}
+
+	t = task.NewBuilder().
+		ParallelStep("+ Download necessary tools", downloadTasks...).
+		ParallelStep("+ Collect the hardware of the hosts", collectTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	for i := range spec.Hosts {
+		h := &spec.Hosts[i]
+		h.Info = infos[h.Host]
+		h.Info.Arch = archs[h.Host]
+		h.Info.NUMANodes = numas[h.Host]
+	}
+	return nil
+}
+
+// generateTopology generates the topology of the spec avoiding the ports
+// used by the existing clusters, it returns the content of topology.yaml
+// and the topology parsed from it
+func generateTopology(spec *topogen.Spec) ([]byte, *meta.TopologySpecification, error) {
+	used, err := usedPorts()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range spec.Hosts {
+		spec.Hosts[i].UsedPorts = used[spec.Hosts[i].Host]
+	}
+
+	generated, err := topogen.Generate(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := topogen.Encode(generated)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var topo meta.TopologySpecification
+	if err := yaml.UnmarshalStrict(data, &topo); err != nil {
+		return nil, nil, errors.AddStack(err)
+	}
+	// use a dummy cluster name, the real cluster name is set during deploy
+	if err := prepare.CheckClusterPortConflict("nonexist-dummy-tidb-cluster", &topo); err != nil {
+		return nil, nil, err
+	}
+	if err := prepare.CheckClusterDirConflict("nonexist-dummy-tidb-cluster", &topo); err != nil {
+		return nil, nil, err
+	}
+	return data, &topo, nil
+}
+
+// usedPorts returns the ports used by the existing clusters on each host
+func usedPorts() (map[string][]int, error) {
+	names, err := meta.ListClusterNames()
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string][]int)
+	for _, name := range names {
+		metadata, err := meta.ClusterMetadata(name)
+		if err != nil {
+			return nil, err
+		}
+		topo := metadata.Topology
+		topo.IterInstance(func(inst meta.Instance) {
+			used[inst.GetHost()] = append(used[inst.GetHost()], inst.UsedPorts()...)
+		})
+		topo.IterHost(func(inst meta.Instance) {
+			used[inst.GetHost()] = append(used[inst.GetHost()],
+				topo.MonitoredOptions.NodeExporterPort,
+				topo.MonitoredOptions.BlackboxExporterPort)
+		})
+	}
+	return used, nil
+}
+
+// printTopologySummary prints the instances of the topology
+func printTopologySummary(topo *meta.TopologySpecification) {
+	instTable := [][]string{{"Role", "Host", "Ports", "Data Dir"}}
+	topo.IterInstance(func(inst meta.Instance) {
+		ports := make([]string, 0, len(inst.UsedPorts()))
+		for _, port := range inst.UsedPorts() {
+			ports = append(ports, strconv.Itoa(port))
+		}
+		instTable = append(instTable, []string{
+			color.CyanString(inst.ComponentName()),
+			inst.GetHost(),
+			strings.Join(ports, "/"),
+			inst.DataDir(),
+		})
+	})
+	cliutil.PrintTable(instTable, true)
+}
+
+func writeTopology(file string, data []byte) error {
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return errors.AddStack(err)
+	}
+	log.Infof("The topology is written to %s, deploy it with `%s deploy <cluster-name> <version> %s`",
+		file, cliutil.OsArgs0(), file)
+	return nil
+}
@@ -16,9 +16,12 @@ package command
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -31,27 +34,86 @@ import (
 	"golang.org/x/mod/semver"
 )
 
+// envNameTiDBPassword is the environment variable of the password used by
+// the tidb health gate
+const envNameTiDBPassword = "TIUP_CLUSTER_TIDB_PASSWORD"
+
 func newUpgradeCmd() *cobra.Command {
+	var (
+		resume bool
+		batch  *batchOptions
+	)
 	cmd := &cobra.Command{
 		Use:   "upgrade <cluster-name> <version>",
 		Short: "Upgrade a specified TiDB cluster",
+		Long: `Upgrade a specified TiDB cluster.
+
+The instances are restarted one by one. The health gates of --health-gates,
+pd-health and stores-up by default, are checked before the upgrade and after
+restarting each instance unless --force is set, and the upgrade is halted if
+they don't pass in time. The gates are disabled by --health-gates="". The halted upgrade
+can be resumed with --resume once the cluster is healthy, the upgraded instances
+are skipped.
+
+Multiple clusters are upgraded if the cluster name is a glob pattern like
+'prod-*', or the name is omitted and the clusters are selected by --all or
+--label. They are upgraded at most --concurrency at a time.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if batch.enabled(args) {
+				return batch.run(cmd, args)
+			}
 			if len(args) != 2 {
 				return cmd.Help()
 			}
 
-			logger.EnableAuditLog()
+			if !dryRun {
+				logger.EnableAuditLog()
+			}
 			clusterName := args[0]
 			version := args[1]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 			teleCommand = append(teleCommand, version)
-			return upgrade(clusterName, version, gOpt)
+			if err := validateHealthGateFlags(); err != nil {
+				return err
+			}
+			return upgrade(clusterName, version, gOpt, resume)
 		},
 	}
-	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force upgrade won't transfer leader")
+	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force upgrade won't transfer leader and check the health gates")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Resume the previous halted upgrade, the upgraded instances are skipped")
+	addHealthGateFlags(cmd)
+	addDryRunFlag(cmd)
+	batch = addBatchFlags(cmd, true)
 
-	return cmd
+	return requireLock(cmd, lockExisting)
+}
+
+// defaultHealthGates are the health gates checked unless --health-gates is set
+var defaultHealthGates = []string{operator.HealthGatePD, operator.HealthGateStoresUp}
+
+// addHealthGateFlags adds the flags of the health gates checked between the
+// instances in the upgrade
+func addHealthGateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&gOpt.HealthGate.Gates, "health-gates", defaultHealthGates,
+		fmt.Sprintf("The health gates checked between the instances, support values: %s, none is checked if set to empty", strings.Join(operator.HealthGates, ", ")))
+	cmd.Flags().Int64Var(&gOpt.HealthGate.Timeout, "health-timeout", 600, "Timeout in seconds to wait for the health gates to pass, the upgrade is halted on timeout")
+	cmd.Flags().Int64Var(&gOpt.HealthGate.StableTime, "health-stable-time", 30, "The health gates must keep passing for the seconds after restarting an instance")
+	cmd.Flags().IntVar(&gOpt.HealthGate.MaxUnhealthyRegions, "max-unhealthy-regions", 0, "The max number of regions with down or pending peers allowed by the region-health gate")
+	cmd.Flags().Float64Var(&gOpt.HealthGate.MinLeaderRatio, "min-leader-ratio", 0.5, "The min ratio of the leaders of a store to the average allowed by the leader-balance gate")
+	cmd.Flags().StringVar(&gOpt.HealthGate.TiDBUser, "tidb-user", "root", "The user to connect TiDB for the tidb gate, the password is read from "+envNameTiDBPassword)
+}
+
+func validateHealthGateFlags() error {
+	var gates []string
+	for _, gate := range gOpt.HealthGate.Gates {
+		if gate = strings.TrimSpace(gate); gate != "" {
+			gates = append(gates, gate)
+		}
+	}
+	gOpt.HealthGate.Gates = gates
+	gOpt.HealthGate.TiDBPassword = os.Getenv(envNameTiDBPassword)
+	return operator.ValidateHealthGates(gates)
 }
 
 func versionCompare(curVersion, newVersion string) error {
@@ -70,7 +132,8 @@ func versionCompare(curVersion, newVersion string) error {
 	}
 }
 
-func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
+// upgrade the cluster, the halted upgrade to the same version is resumed if resume is true
+func upgrade(clusterName, clusterVersion string, opt operator.Options, resume bool) error {
 	if utils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
 		return errors.Errorf("cannot upgrade non-exists cluster %s", clusterName)
 	}
@@ -80,6 +143,11 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 		return err
 	}
 
+	tlsCfg, err := metadata.Topology.TLSConfig(meta.ClusterPath(clusterName, meta.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
 	var (
 		downloadCompTasks []task.Task // tasks which are used to download components
 		copyCompTasks     []task.Task // tasks which are used to copy components to remote host
@@ -151,12 +219,18 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 	}
 
 	// handle dir scheme changes
-	if hasImported {
+	if hasImported && !dryRun {
 		if err := meta.HandleImportPathMigration(clusterName); err != nil {
 			return err
 		}
 	}
 
+	// the hooks of the instances run around the restart of each instance
+	hookOpt := hookOptions(clusterName, clusterVersion, meta.HookOperationUpgrade)
+	instances := operatedInstances(metadata.Topology.ComponentsByUpdateOrder(), opt)
+	globalHooks := metadata.Topology.GlobalOptions.Hooks
+	opt.Hooks = &hookOpt
+
 	t := task.NewBuilder().
 		SSHKeySet(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
@@ -164,10 +238,39 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
 		Parallel(downloadCompTasks...).
 		Parallel(copyCompTasks...).
-		ClusterOperate(metadata.Topology, operator.UpgradeOperation, opt).
+		GlobalHook(globalHooks, hookOpt, true, instances).
+		ClusterOperate(metadata.Topology, operator.UpgradeOperation, opt, tlsCfg).
+		GlobalHook(globalHooks, hookOpt, false, instances).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if dryRun {
+		plan := task.BuildPlan(t)
+		plan.Add(task.PlanHostLocal, task.PlanActionMeta, "set version of cluster %s to %s and remove the patches", clusterName, clusterVersion)
+		return printPlan(clusterName, "upgrade", plan)
+	}
+
+	// the upgrade is recorded in the checkpoint log, so that a halted one is
+	// only resumed by the upgrade to the same version
+	cp, err := task.NewCheckPoint(meta.ClusterPath(clusterName, meta.CheckPointFileName("upgrade")), resume)
+	if err != nil {
+		return err
+	}
+	step := fmt.Sprintf("upgrade cluster %s to %s", clusterName, clusterVersion)
+	if resume && !cp.StepDone(step) {
+		_ = cp.Close()
+		return errors.Errorf("no halted upgrade of cluster %s to %s to resume", clusterName, clusterVersion)
+	}
+	if err := cp.RecordStep(step); err != nil {
+		_ = cp.Close()
+		return err
+	}
+
+	ctx := task.NewContext()
+	ctx.SetCheckPoint(cp)
+	if err := t.Execute(ctx); err != nil {
+		_ = cp.Close()
+		hint := color.New(color.Bold).Sprintf("%s upgrade %s %s --resume", cliutil.OsArgs0(), clusterName, clusterVersion)
+		log.Warnf("The upgrade is halted, run `%s` to continue from the last upgraded instance once the problem is fixed", hint)
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -177,8 +280,12 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 
 	metadata.Version = clusterVersion
 	if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
+		_ = cp.Close()
 		return errors.Trace(err)
 	}
+	if err := cp.Remove(); err != nil {
+		return err
+	}
 	if err := os.RemoveAll(meta.ClusterPath(clusterName, "patch")); err != nil {
 		return errors.Trace(err)
 	}
@@ -49,6 +49,13 @@ func newAuditCmd() *cobra.Command {
 	return cmd
 }
 
+// auditInfo is the structured output of the audit list
+type auditInfo struct {
+	ID      string `json:"id" yaml:"id"`
+	Time    string `json:"time" yaml:"time"`
+	Command string `json:"command" yaml:"command"`
+}
+
 func showAuditList() error {
 	firstLine := func(fileName string) (string, error) {
 		file, err := os.Open(meta.ProfilePath(meta.TiOpsAuditDir, fileName))
@@ -65,8 +72,7 @@ func showAuditList() error {
 	}
 
 	auditDir := meta.ProfilePath(meta.TiOpsAuditDir)
-	// Header
-	clusterTable := [][]string{{"ID", "Time", "Command"}}
+	audits := []auditInfo{}
 	fileInfos, err := ioutil.ReadDir(auditDir)
 	if err != nil && !os.IsNotExist(err) {
 		return err
@@ -84,17 +90,28 @@ func showAuditList() error {
 		if err != nil {
 			continue
 		}
-		clusterTable = append(clusterTable, []string{
-			fi.Name(),
-			t.Format(time.RFC3339),
-			cmd,
+		audits = append(audits, auditInfo{
+			ID:      fi.Name(),
+			Time:    t.Format(time.RFC3339),
+			Command: cmd,
 		})
 	}
 
-	sort.Slice(clusterTable[1:], func(i, j int) bool {
-		return clusterTable[i+1][1] > clusterTable[j+1][1]
+	sort.Slice(audits, func(i, j int) bool {
+		return audits[i].Time > audits[j].Time
 	})
 
+	if cliutil.IsStructuredOutput() {
+		return cliutil.PrintResult(struct {
+			AuditLogs []auditInfo `json:"audit_logs" yaml:"audit_logs"`
+		}{audits})
+	}
+
+	// Header
+	clusterTable := [][]string{{"ID", "Time", "Command"}}
+	for _, a := range audits {
+		clusterTable = append(clusterTable, []string{a.ID, a.Time, a.Command})
+	}
 	cliutil.PrintTable(clusterTable, true)
 	return nil
 }
@@ -116,6 +133,13 @@ func showAuditLog(auditID string) error {
 	}
 
 	t := time.Unix(ts, 0)
+	if cliutil.IsStructuredOutput() {
+		return cliutil.PrintResult(struct {
+			ID      string `json:"id" yaml:"id"`
+			Time    string `json:"time" yaml:"time"`
+			Content string `json:"content" yaml:"content"`
+		}{auditID, t.Format(time.RFC3339), string(content)})
+	}
 	hint := fmt.Sprintf("- OPERATION TIME: %s -", t.Format("2006-01-02T15:04:05"))
 	line := strings.Repeat("-", len(hint))
 	_, _ = os.Stdout.WriteString(color.MagentaString("%s\n%s\n%s\n", line, hint, line))
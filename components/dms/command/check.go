@@ -261,12 +261,7 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *meta.DMTopologySpecifi
 		return errors.Trace(err)
 	}
 
-	var checkResultTable [][]string
-	// FIXME: add fix result to output
-	checkResultTable = [][]string{
-		// Header
-		{"Node", "Check", "Result", "Message"},
-	}
+	checkResults := []checkResult{}
 	for host := range uniqueHosts {
 		tf := task.NewBuilder().
 			RootSSH(
@@ -278,17 +273,25 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *meta.DMTopologySpecifi
 				s.IdentityFilePassphrase,
 				gOpt.SSHTimeout,
 			)
-		resLines, err := handleCheckResults(ctx, host, opt, tf)
+		results, err := handleCheckResults(ctx, host, opt, tf)
 		if err != nil {
 			continue
 		}
 		applyFixTasks = append(applyFixTasks, tf.BuildAsStep(fmt.Sprintf("  - Applying changes on %s", host)))
-		checkResultTable = append(checkResultTable, resLines...)
+		checkResults = append(checkResults, results...)
 	}
 
 	// print check results *before* trying to applying checks
 	// FIXME: add fix result to output, and display the table after fixing
-	cliutil.PrintTable(checkResultTable, true)
+	if cliutil.IsStructuredOutput() {
+		if err := cliutil.PrintResult(struct {
+			Results []checkResult `json:"results" yaml:"results"`
+		}{checkResults}); err != nil {
+			return err
+		}
+	} else {
+		printCheckResults(checkResults)
+	}
 
 	if opt.applyFix {
 		tc := task.NewBuilder().
@@ -306,46 +309,70 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *meta.DMTopologySpecifi
 	return nil
 }
 
+// checkResult is the result of a check on a node
+type checkResult struct {
+	Node    string `json:"node" yaml:"node"`
+	Name    string `json:"name" yaml:"name"`
+	Status  string `json:"status" yaml:"status"` // Pass, Warn or Fail
+	Message string `json:"message" yaml:"message"`
+}
+
 // handleCheckResults parses the result of checks
-func handleCheckResults(ctx *task.Context, host string, opt *checkOptions, t *task.Builder) ([][]string, error) {
+func handleCheckResults(ctx *task.Context, host string, opt *checkOptions, t *task.Builder) ([]checkResult, error) {
 	results, _ := ctx.GetCheckResults(host)
 	if len(results) < 1 {
 		return nil, fmt.Errorf("no check results found for %s", host)
 	}
 
-	lines := make([][]string, 0)
-	//log.Infof("Check results of %s: (only errors and important info are displayed)", color.HiCyanString(host))
+	checkResults := make([]checkResult, 0)
 	for _, r := range results {
-		var line []string
+		res := checkResult{Node: host, Name: r.Name, Status: "Pass", Message: r.Msg}
 		if r.Err != nil {
+			res.Status = "Fail"
 			if r.IsWarning() {
-				line = []string{host, r.Name, color.YellowString("Warn"), r.Error()}
-			} else {
-				line = []string{host, r.Name, color.HiRedString("Fail"), r.Error()}
-			}
-			if !opt.applyFix {
-				lines = append(lines, line)
-				continue
-			}
-			msg, err := fixFailedChecks(ctx, host, r, t)
-			if err != nil {
-				log.Debugf("%s: fail to apply fix to %s (%s)", host, r.Name, err)
+				res.Status = "Warn"
 			}
-			if msg != "" {
-				// show auto fixing info
-				line[len(line)-1] = msg
+			res.Message = r.Error()
+			if opt.applyFix {
+				msg, err := fixFailedChecks(ctx, host, r, t)
+				if err != nil {
+					log.Debugf("%s: fail to apply fix to %s (%s)", host, r.Name, err)
+				}
+				if msg != "" {
+					// show auto fixing info
+					res.Message = msg
+				}
 			}
-		} else if r.Msg != "" {
-			line = []string{host, r.Name, color.GreenString("Pass"), r.Msg}
 		}
+		checkResults = append(checkResults, res)
+	}
+
+	return checkResults, nil
+}
 
-		// show errors and messages only, ignore empty lines
-		if len(line) > 0 {
-			lines = append(lines, line)
+// printCheckResults prints the errors and messages of checks, the passed
+// checks without messages are ignored
+func printCheckResults(results []checkResult) {
+	checkResultTable := [][]string{
+		// Header
+		{"Node", "Check", "Result", "Message"},
+	}
+	for _, r := range results {
+		status := r.Status
+		switch status {
+		case "Pass":
+			if r.Message == "" {
+				continue
+			}
+			status = color.GreenString(status)
+		case "Warn":
+			status = color.YellowString(status)
+		case "Fail":
+			status = color.HiRedString(status)
 		}
+		checkResultTable = append(checkResultTable, []string{r.Node, r.Name, status, r.Message})
 	}
-
-	return lines, nil
+	cliutil.PrintTable(checkResultTable, true)
 }
 
 // fixFailedChecks tries to automatically apply changes to fix failed checks
@@ -64,8 +64,8 @@ func newDestroyCmd() *cobra.Command {
 					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 				ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-				ClusterOperate(metadata.Topology, operator.StopOperation, operator.Options{}).
-				ClusterOperate(metadata.Topology, operator.DestroyOperation, operator.Options{}).
+				ClusterOperate(metadata.Topology, operator.StopOperation, operator.Options{}, nil).
+				ClusterOperate(metadata.Topology, operator.DestroyOperation, operator.Options{}, nil).
 				Build()
 
 			if err := t.Execute(task.NewContext()); err != nil {
@@ -48,17 +48,32 @@ func newDisplayCmd() *cobra.Command {
 			}
 
 			clusterName = args[0]
-			if err := displayDMMeta(clusterName, &gOpt); err != nil {
-				return err
-			}
-			if err := displayClusterTopology(clusterName, &gOpt); err != nil {
-				return err
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot display non-exists cluster %s", clusterName)
 			}
 
 			metadata, err := meta.DMMetadata(clusterName)
 			if err != nil {
 				return errors.AddStack(err)
 			}
+			instances, err := clusterInstances(clusterName, &gOpt)
+			if err != nil {
+				return err
+			}
+
+			if cliutil.IsStructuredOutput() {
+				if err := cliutil.PrintResult(clusterDisplayInfo{
+					ClusterName:    clusterName,
+					ClusterVersion: metadata.Version,
+					Instances:      instances,
+				}); err != nil {
+					return err
+				}
+			} else {
+				displayDMMeta(clusterName, metadata)
+				displayClusterTopology(instances)
+			}
+
 			return clearOutDatedEtcdInfo(clusterName, metadata, gOpt)
 		},
 	}
@@ -69,22 +84,29 @@ func newDisplayCmd() *cobra.Command {
 	return cmd
 }
 
-func displayDMMeta(clusterName string, opt *operator.Options) error {
-	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
-		return errors.Errorf("cannot display non-exists cluster %s", clusterName)
-	}
+// clusterDisplayInfo is the structured output of display
+type clusterDisplayInfo struct {
+	ClusterName    string     `json:"cluster_name" yaml:"cluster_name"`
+	ClusterVersion string     `json:"cluster_version" yaml:"cluster_version"`
+	Instances      []InstInfo `json:"instances" yaml:"instances"`
+}
 
-	clsMeta, err := meta.DMMetadata(clusterName)
-	if err != nil {
-		return err
-	}
+// InstInfo represents the status of an instance
+type InstInfo struct {
+	ID        string `json:"id" yaml:"id"`
+	Role      string `json:"role" yaml:"role"`
+	Host      string `json:"host" yaml:"host"`
+	Ports     string `json:"ports" yaml:"ports"`
+	Status    string `json:"status" yaml:"status"`
+	DataDir   string `json:"data_dir" yaml:"data_dir"`
+	DeployDir string `json:"deploy_dir" yaml:"deploy_dir"`
+}
 
+func displayDMMeta(clusterName string, clsMeta *meta.DMMeta) {
 	cyan := color.New(color.FgCyan, color.Bold)
 
 	fmt.Printf("DM Cluster: %s\n", cyan.Sprint(clusterName))
 	fmt.Printf("DM Version: %s\n", cyan.Sprint(clsMeta.Version))
-
-	return nil
 }
 
 func clearOutDatedEtcdInfo(clusterName string, metadata *meta.DMMeta, opt operator.Options) error {
@@ -154,31 +176,28 @@ func clearOutDatedEtcdInfo(clusterName string, metadata *meta.DMMeta, opt operat
 	return <-errCh
 }
 
-func displayClusterTopology(clusterName string, opt *operator.Options) error {
+// clusterInstances returns the status of the instances sorted by role, host and ports
+func clusterInstances(clusterName string, opt *operator.Options) ([]InstInfo, error) {
 	metadata, err := meta.DMMetadata(clusterName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	topo := metadata.Topology
 
-	clusterTable := [][]string{
-		// Header
-		{"ID", "Role", "Host", "Ports", "Status", "Data Dir", "Deploy Dir"},
-	}
-
 	ctx := task.NewContext()
 	err = ctx.SetSSHKeySet(meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 		meta.ClusterPath(clusterName, "ssh", "id_rsa.pub"))
 	if err != nil {
-		return errors.AddStack(err)
+		return nil, errors.AddStack(err)
 	}
 
 	err = ctx.SetClusterSSH(topo, metadata.User, gOpt.SSHTimeout)
 	if err != nil {
-		return errors.AddStack(err)
+		return nil, errors.AddStack(err)
 	}
 
+	var instances []InstInfo
 	filterRoles := set.NewStringSet(opt.Roles...)
 	filterNodes := set.NewStringSet(opt.Nodes...)
 	masterList := topo.GetMasterList()
@@ -200,7 +219,7 @@ func displayClusterTopology(clusterName string, opt *operator.Options) error {
 				dataDir = insDirs[1]
 			}
 
-			status := ins.Status(masterList...)
+			status := ins.Status(nil, masterList...)
 			// Query the service status
 			if status == "-" {
 				e, found := ctx.GetExecutor(ins.GetHost())
@@ -215,34 +234,51 @@ func displayClusterTopology(clusterName string, opt *operator.Options) error {
 					}
 				}
 			}
-			clusterTable = append(clusterTable, []string{
-				color.CyanString(ins.ID()),
-				ins.Role(),
-				ins.GetHost(),
-				clusterutil.JoinInt(ins.UsedPorts(), "/"),
-				formatInstanceStatus(status),
-				dataDir,
-				deployDir,
+			instances = append(instances, InstInfo{
+				ID:        ins.ID(),
+				Role:      ins.Role(),
+				Host:      ins.GetHost(),
+				Ports:     clusterutil.JoinInt(ins.UsedPorts(), "/"),
+				Status:    status,
+				DataDir:   dataDir,
+				DeployDir: deployDir,
 			})
-
 		}
 	}
 
 	// Sort by role,host,ports
-	sort.Slice(clusterTable[1:], func(i, j int) bool {
-		lhs, rhs := clusterTable[i+1], clusterTable[j+1]
-		// column: 1 => role, 2 => host, 3 => ports
-		for _, col := range []int{1, 2} {
-			if lhs[col] != rhs[col] {
-				return lhs[col] < rhs[col]
-			}
+	sort.Slice(instances, func(i, j int) bool {
+		lhs, rhs := instances[i], instances[j]
+		if lhs.Role != rhs.Role {
+			return lhs.Role < rhs.Role
 		}
-		return lhs[3] < rhs[3]
+		if lhs.Host != rhs.Host {
+			return lhs.Host < rhs.Host
+		}
+		return lhs.Ports < rhs.Ports
 	})
 
-	cliutil.PrintTable(clusterTable, true)
+	return instances, nil
+}
+
+func displayClusterTopology(instances []InstInfo) {
+	clusterTable := [][]string{
+		// Header
+		{"ID", "Role", "Host", "Ports", "Status", "Data Dir", "Deploy Dir"},
+	}
+	for _, ins := range instances {
+		clusterTable = append(clusterTable, []string{
+			color.CyanString(ins.ID),
+			ins.Role,
+			ins.Host,
+			ins.Ports,
+			formatInstanceStatus(ins.Status),
+			ins.DataDir,
+			ins.DeployDir,
+		})
+	}
 
-	return nil
+	cliutil.PrintTable(clusterTable, true)
 }
 
 func formatInstanceStatus(status string) string {
@@ -14,9 +14,12 @@
 package command
 
 import (
+	"sort"
+
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
 	"github.com/pingcap/tiup/pkg/cluster/task"
 	"github.com/pingcap/tiup/pkg/logger"
@@ -31,6 +34,13 @@ type execOptions struct {
 	sudo    bool
 }
 
+// execOutput is the structured output of the command on a host
+type execOutput struct {
+	Host   string `json:"host" yaml:"host"`
+	Stdout string `json:"stdout" yaml:"stdout"`
+	Stderr string `json:"stderr" yaml:"stderr"`
+}
+
 func newExecCmd() *cobra.Command {
 	opt := execOptions{}
 	cmd := &cobra.Command{
@@ -95,6 +105,28 @@ func newExecCmd() *cobra.Command {
 				return errors.Trace(err)
 			}
 
+			if cliutil.IsStructuredOutput() {
+				outputs := []execOutput{}
+				for host := range uniqueHosts {
+					stdout, stderr, ok := execCtx.GetOutputs(host)
+					if !ok {
+						continue
+					}
+					outputs = append(outputs, execOutput{
+						Host:   host,
+						Stdout: string(stdout),
+						Stderr: string(stderr),
+					})
+				}
+				sort.Slice(outputs, func(i, j int) bool {
+					return outputs[i].Host < outputs[j].Host
+				})
+				return cliutil.PrintResult(struct {
+					Command string       `json:"command" yaml:"command"`
+					Outputs []execOutput `json:"outputs" yaml:"outputs"`
+				}{opt.command, outputs})
+			}
+
 			// print outputs
 			for host := range uniqueHosts {
 				stdout, stderr, ok := execCtx.GetOutputs(host)
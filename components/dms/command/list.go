@@ -35,12 +35,18 @@ func newListCmd() *cobra.Command {
 	return cmd
 }
 
+// clusterInfo is the structured output of list
+type clusterInfo struct {
+	Name       string `json:"name" yaml:"name"`
+	User       string `json:"user" yaml:"user"`
+	Version    string `json:"version" yaml:"version"`
+	Path       string `json:"path" yaml:"path"`
+	PrivateKey string `json:"private_key" yaml:"private_key"`
+}
+
 func listCluster() error {
 	clusterDir := meta.ProfilePath(meta.TiOpsClusterDir)
-	clusterTable := [][]string{
-		// Header
-		{"Name", "User", "Version", "Path", "PrivateKey"},
-	}
+	clusters := []clusterInfo{}
 	fileInfos, err := ioutil.ReadDir(clusterDir)
 	if err != nil && !os.IsNotExist(err) {
 		return err
@@ -54,15 +60,28 @@ func listCluster() error {
 			return errors.Trace(err)
 		}
 
-		clusterTable = append(clusterTable, []string{
-			fi.Name(),
-			metadata.User,
-			metadata.Version,
-			meta.ClusterPath(fi.Name()),
-			meta.ClusterPath(fi.Name(), "ssh", "id_rsa"),
+		clusters = append(clusters, clusterInfo{
+			Name:       fi.Name(),
+			User:       metadata.User,
+			Version:    metadata.Version,
+			Path:       meta.ClusterPath(fi.Name()),
+			PrivateKey: meta.ClusterPath(fi.Name(), "ssh", "id_rsa"),
 		})
 	}
 
+	if cliutil.IsStructuredOutput() {
+		return cliutil.PrintResult(struct {
+			Clusters []clusterInfo `json:"clusters" yaml:"clusters"`
+		}{clusters})
+	}
+
+	clusterTable := [][]string{
+		// Header
+		{"Name", "User", "Version", "Path", "PrivateKey"},
+	}
+	for _, c := range clusters {
+		clusterTable = append(clusterTable, []string{c.Name, c.User, c.Version, c.Path, c.PrivateKey})
+	}
 	cliutil.PrintTable(clusterTable, true)
 	return nil
 }
@@ -99,7 +99,7 @@ func patch(clusterName, packagePath string, options operator.Options, overwrite
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
 		Parallel(replacePackageTasks...).
-		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options).
+		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options, nil).
 		Build()
 
 	if err := t.Execute(task.NewContext()); err != nil {
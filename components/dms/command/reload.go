@@ -123,7 +123,7 @@ func buildReloadTask(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
 		Parallel(refreshConfigTasks...).
-		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options).
+		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options, nil).
 		Build()
 
 	return t, nil
@@ -54,7 +54,7 @@ func newRestartCmd() *cobra.Command {
 					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 				ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-				ClusterOperate(metadata.Topology, operator.RestartOperation, gOpt).
+				ClusterOperate(metadata.Topology, operator.RestartOperation, gOpt, nil).
 				Build()
 
 			if err := t.Execute(task.NewContext()); err != nil {
@@ -114,10 +114,10 @@ func scaleIn(clusterName string, options operator.Options) error {
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout)
 
 	if !options.Force {
-		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
+		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options, nil).
 			UpdateDMMeta(clusterName, metadata, operator.AsyncNodes(metadata.Topology, options.Nodes, false))
 	} else {
-		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
+		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options, nil).
 			UpdateDMMeta(clusterName, metadata, options.Nodes)
 	}
 
@@ -299,18 +299,18 @@ func buildScaleOutTask(
 	}
 
 	// TODO: find another way to make sure current cluster started
-	builder.ClusterOperate(metadata.Topology, operator.StartOperation, operator.Options{OptTimeout: timeout}).
+	builder.ClusterOperate(metadata.Topology, operator.StartOperation, operator.Options{OptTimeout: timeout}, nil).
 		ClusterSSH(newPart, metadata.User, gOpt.SSHTimeout).
 		Func("save meta", func(_ *task.Context) error {
 			metadata.Topology = mergedTopo
 			return meta.SaveDMMeta(clusterName, metadata)
 		}).
-		ClusterOperate(newPart, operator.StartOperation, operator.Options{OptTimeout: timeout}).
+		ClusterOperate(newPart, operator.StartOperation, operator.Options{OptTimeout: timeout}, nil).
 		Parallel(refreshConfigTasks...).
 		ClusterOperate(metadata.Topology, operator.RestartOperation, operator.Options{
 			Roles:      []string{meta.ComponentPrometheus},
 			OptTimeout: timeout,
-		})
+		}, nil)
 
 	return builder.Build(), nil
 }
@@ -62,7 +62,7 @@ func startCluster(clusterName string, options operator.Options) error {
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-		ClusterOperate(metadata.Topology, operator.StartOperation, options).
+		ClusterOperate(metadata.Topology, operator.StartOperation, options, nil).
 		Build()
 
 	if err := t.Execute(task.NewContext()); err != nil {
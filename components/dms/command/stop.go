@@ -50,7 +50,7 @@ func newStopCmd() *cobra.Command {
 					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 				ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-				ClusterOperate(metadata.Topology, operator.StopOperation, gOpt).
+				ClusterOperate(metadata.Topology, operator.StopOperation, gOpt, nil).
 				Build()
 
 			if err := t.Execute(task.NewContext()); err != nil {
@@ -151,7 +151,7 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
 		Parallel(downloadCompTasks...).
 		Parallel(copyCompTasks...).
-		ClusterOperate(metadata.Topology, operator.UpgradeOperation, opt).
+		ClusterOperate(metadata.Topology, operator.UpgradeOperation, opt, nil).
 		Build()
 
 	if err := t.Execute(task.NewContext()); err != nil {
@@ -18,12 +18,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cliutil"
 	"github.com/spf13/cobra"
 )
 
@@ -35,14 +38,25 @@ const (
 	ScaleInCommandType  CommandType = "scale-in"
 	ScaleOutCommandType CommandType = "scale-out"
 	DisplayCommandType  CommandType = "display"
+
+	KillCommandType      CommandType = "kill"
+	PauseCommandType     CommandType = "pause"
+	ResumeCommandType    CommandType = "resume"
+	RestartCommandType   CommandType = "restart"
+	DelayCommandType     CommandType = "delay"
+	PartitionCommandType CommandType = "partition"
+	HealCommandType      CommandType = "heal"
 )
 
 // Command send to Playground.
 type Command struct {
 	CommandType CommandType
-	PID         int // Set when scale-in
+	PID         int // Set when scale-in or injecting faults
 	ComponentID string
 	instance.Config
+	PDLeader bool          // Set when injecting faults into the PD leader instead of PID
+	Latency  time.Duration // Set when delaying the network
+	Format   string        // Set when display, the result is in JSON if it's json
 }
 
 func buildCommands(tp CommandType, opt *bootOptions) (cmds []Command) {
@@ -100,6 +114,15 @@ func buildCommands(tp CommandType, opt *bootOptions) (cmds []Command) {
 
 		cmds = append(cmds, c)
 	}
+	for i := 0; i < opt.ticdc.Num; i++ {
+		c := Command{
+			CommandType: tp,
+			ComponentID: "ticdc",
+			Config:      opt.ticdc,
+		}
+
+		cmds = append(cmds, c)
+	}
 	return
 }
 
@@ -118,7 +141,8 @@ func newScaleOut() *cobra.Command {
 	cmd.Flags().IntVarP(&opt.pd.Num, "pd", "", opt.pd.Num, "PD instance number")
 	cmd.Flags().IntVarP(&opt.tiflash.Num, "tiflash", "", opt.tiflash.Num, "TiFlash instance number")
 	cmd.Flags().IntVarP(&opt.pump.Num, "pump", "", opt.pump.Num, "Pump instance number")
-	cmd.Flags().IntVarP(&opt.drainer.Num, "drainer", "", opt.pump.Num, "Drainer instance number")
+	cmd.Flags().IntVarP(&opt.drainer.Num, "drainer", "", opt.drainer.Num, "Drainer instance number")
+	cmd.Flags().IntVarP(&opt.ticdc.Num, "ticdc", "", opt.ticdc.Num, "TiCDC instance number")
 
 	cmd.Flags().StringVarP(&opt.tidb.Host, "db.host", "", opt.tidb.Host, "Playground TiDB host. If not provided, TiDB will still use `host` flag as its host")
 	cmd.Flags().StringVarP(&opt.pd.Host, "pd.host", "", opt.pd.Host, "Playground PD host. If not provided, PD will still use `host` flag as its host")
@@ -126,9 +150,10 @@ func newScaleOut() *cobra.Command {
 	cmd.Flags().StringVarP(&opt.tidb.ConfigPath, "db.config", "", opt.tidb.ConfigPath, "TiDB instance configuration file")
 	cmd.Flags().StringVarP(&opt.tikv.ConfigPath, "kv.config", "", opt.tikv.ConfigPath, "TiKV instance configuration file")
 	cmd.Flags().StringVarP(&opt.pd.ConfigPath, "pd.config", "", opt.pd.ConfigPath, "PD instance configuration file")
-	cmd.Flags().StringVarP(&opt.tidb.ConfigPath, "tiflash.config", "", opt.tidb.ConfigPath, "TiFlash instance configuration file")
+	cmd.Flags().StringVarP(&opt.tiflash.ConfigPath, "tiflash.config", "", opt.tiflash.ConfigPath, "TiFlash instance configuration file")
 	cmd.Flags().StringVarP(&opt.pump.ConfigPath, "pump.config", "", opt.pump.ConfigPath, "Pump instance configuration file")
 	cmd.Flags().StringVarP(&opt.drainer.ConfigPath, "drainer.config", "", opt.drainer.ConfigPath, "Drainer instance configuration file")
+	cmd.Flags().StringVarP(&opt.ticdc.ConfigPath, "ticdc.config", "", opt.ticdc.ConfigPath, "TiCDC instance configuration file")
 
 	cmd.Flags().StringVarP(&opt.tidb.BinPath, "db.binpath", "", opt.tidb.BinPath, "TiDB instance binary path")
 	cmd.Flags().StringVarP(&opt.tikv.BinPath, "kv.binpath", "", opt.tikv.BinPath, "TiKV instance binary path")
@@ -136,6 +161,7 @@ func newScaleOut() *cobra.Command {
 	cmd.Flags().StringVarP(&opt.tiflash.BinPath, "tiflash.binpath", "", opt.tiflash.BinPath, "TiFlash instance binary path")
 	cmd.Flags().StringVarP(&opt.pump.BinPath, "pump.binpath", "", opt.pump.BinPath, "Pump instance binary path")
 	cmd.Flags().StringVarP(&opt.drainer.BinPath, "drainer.binpath", "", opt.drainer.BinPath, "Drainer instance binary path")
+	cmd.Flags().StringVarP(&opt.ticdc.BinPath, "ticdc.binpath", "", opt.ticdc.BinPath, "TiCDC instance binary path")
 
 	return cmd
 }
@@ -161,13 +187,55 @@ func newScaleIn() *cobra.Command {
 }
 
 func newDisplay() *cobra.Command {
+	var format string
+
 	cmd := &cobra.Command{
 		Use:    "display",
 		Hidden: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return display(args)
+			return display(format)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", cliutil.OutputFormatText, "The format of the result, support values: text, json, yaml")
+
+	return cmd
+}
+
+// newFaultCmd returns the command injecting the fault into the instances of
+// the pids
+func newFaultCmd(tp CommandType, short string) *cobra.Command {
+	var pdLeader bool
+	var latency time.Duration
+
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s <pid>...", tp),
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !pdLeader {
+				return cmd.Help()
+			}
+
+			return injectFault(tp, args, pdLeader, latency)
 		},
+		Hidden: true,
+	}
+	switch tp {
+	case DelayCommandType, PartitionCommandType, HealCommandType:
+		cmd.Long = short + `.
+
+The network faults are injected into a proxy started in front of the instance
+on its first fault, the address of the proxy is printed. Only the clients
+connecting through the proxy are affected, the instances of the playground
+still connect to each other directly. The proxy is closed when the instance
+is scaled in.`
+	}
+
+	cmd.Flags().BoolVar(&pdLeader, "pd-leader", false, "Target the PD leader besides the pids")
+	if tp == DelayCommandType {
+		cmd.Flags().DurationVar(&latency, "latency", time.Millisecond*200, "Latency of the data forwarded in each direction")
 	}
+
 	return cmd
 }
 
@@ -201,7 +269,41 @@ func scaleOut(args []string, opt *bootOptions) error {
 	return sendCommandsAndPrintResult(cmds, addr)
 }
 
-func display(args []string) error {
+func injectFault(tp CommandType, args []string, pdLeader bool, latency time.Duration) error {
+	port, err := targetTag()
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	var cmds []Command
+	if pdLeader {
+		cmds = append(cmds, Command{
+			CommandType: tp,
+			PDLeader:    true,
+			Latency:     latency,
+		})
+	}
+	for _, arg := range args {
+		pid, err := strconv.Atoi(arg)
+		if err != nil {
+			return errors.Errorf("invalid pid: %s", arg)
+		}
+		cmds = append(cmds, Command{
+			CommandType: tp,
+			PID:         pid,
+			Latency:     latency,
+		})
+	}
+
+	addr := "127.0.0.1:" + strconv.Itoa(port)
+	return sendCommandsAndPrintResult(cmds, addr)
+}
+
+func display(format string) error {
+	if err := cliutil.SetOutputFormat(format); err != nil {
+		return err
+	}
+
 	port, err := targetTag()
 	if err != nil {
 		return errors.AddStack(err)
@@ -211,7 +313,26 @@ func display(args []string) error {
 	}
 
 	addr := "127.0.0.1:" + strconv.Itoa(port)
-	return sendCommandsAndPrintResult([]Command{c}, addr)
+	if !cliutil.IsStructuredOutput() {
+		return sendCommandsAndPrintResult([]Command{c}, addr)
+	}
+
+	// the instances are always sent in JSON and printed in the format
+	c.Format = cliutil.OutputFormatJSON
+	rc, err := requestCommand(c, addr)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	var infos []instanceInfo
+	if err := json.Unmarshal(data, &infos); err != nil {
+		return errors.Errorf("display failed: %s", bytes.TrimSpace(data))
+	}
+	return cliutil.PrintResult(infos)
 }
 
 func sendCommandsAndPrintResult(cmds []Command, addr string) error {
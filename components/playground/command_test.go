@@ -0,0 +1,37 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildScaleOutTiCDC(t *testing.T) {
+	var opt bootOptions
+	opt.tikv.Num = 1
+	opt.ticdc.Num = 2
+	opt.ticdc.ConfigPath = "/conf/cdc.toml"
+	opt.ticdc.BinPath = "/bin/cdc"
+
+	cmds := buildCommands(ScaleOutCommandType, &opt)
+	assert.Len(t, cmds, 3)
+	assert.Equal(t, "tikv", cmds[0].ComponentID)
+	for _, cmd := range cmds[1:] {
+		assert.Equal(t, ScaleOutCommandType, cmd.CommandType)
+		assert.Equal(t, "ticdc", cmd.ComponentID)
+		assert.Equal(t, opt.ticdc, cmd.Config)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	gops "github.com/shirou/gopsutil/process"
+)
+
+// instanceInfo is the information of an instance shown by display, it's also
+// the result of display in JSON consumed by other components
+type instanceInfo struct {
+	ID         int    `json:"id" yaml:"id"`
+	Role       string `json:"role" yaml:"role"`
+	Pid        int    `json:"pid" yaml:"pid"`
+	Addr       string `json:"addr" yaml:"addr"`
+	StatusAddr string `json:"status_addr,omitempty" yaml:"status_addr,omitempty"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"`
+	BinPath    string `json:"bin_path,omitempty" yaml:"bin_path,omitempty"`
+	Uptime     string `json:"uptime,omitempty" yaml:"uptime,omitempty"`
+	DataDir    string `json:"data_dir" yaml:"data_dir"`
+	Status     string `json:"status" yaml:"status"`
+}
+
+func (p *Playground) handleDisplay(w io.Writer, format string) error {
+	infos := p.instanceInfos()
+	if format == cliutil.OutputFormatJSON {
+		return errors.AddStack(json.NewEncoder(w).Encode(infos))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	t := tabby.NewCustom(tw)
+	t.AddHeader("Pid", "Role", "Addr", "Status Addr", "Version", "Uptime", "Status", "Data Dir")
+	for _, info := range infos {
+		version := info.Version
+		if info.BinPath != "" {
+			version = info.BinPath
+		}
+		t.AddLine(strconv.Itoa(info.Pid), info.Role, info.Addr, info.StatusAddr, version, info.Uptime, info.Status, info.DataDir)
+	}
+	t.Print()
+	return nil
+}
+
+// instanceInfos collects the information of the instances, the status of
+// the stores and PD members is queried from PD
+func (p *Playground) instanceInfos() []instanceInfo {
+	pdStatus := p.pdMemberStatus()
+	storeStates := p.storeStates()
+
+	infos := []instanceInfo{}
+	_ = p.WalkInstances(func(cid string, inst instance.Instance) error {
+		spec := inst.Spec()
+		info := instanceInfo{
+			ID:      spec.ID,
+			Role:    cid,
+			Pid:     inst.Pid(),
+			Addr:    net.JoinHostPort(spec.Host, strconv.Itoa(spec.Port)),
+			BinPath: spec.BinPath,
+			DataDir: p.instanceDir(cid, spec.ID),
+		}
+		if spec.StatusPort != 0 {
+			info.StatusAddr = net.JoinHostPort(spec.Host, strconv.Itoa(spec.StatusPort))
+		}
+		if spec.BinPath == "" {
+			info.Version = p.versions[componentName(cid)]
+		}
+
+		proc := instanceProcess(info.Pid)
+		if proc == nil {
+			info.Status = "Down"
+			infos = append(infos, info)
+			return nil
+		}
+		if created, err := proc.CreateTime(); err == nil {
+			info.Uptime = time.Since(time.Unix(0, created*int64(time.Millisecond))).Round(time.Second).String()
+		}
+
+		// the process of a paused instance is stopped by SIGSTOP
+		if status, err := proc.Status(); err == nil && status == "T" {
+			info.Status = "Paused"
+			infos = append(infos, info)
+			return nil
+		}
+
+		switch inst := inst.(type) {
+		case *instance.PDInstance:
+			info.Status = pdStatus[inst.Name()]
+		case *instance.TiKVInstance:
+			info.Status = storeStates[inst.StoreAddr()]
+		case *instance.TiFlashInstance:
+			info.Status = storeStates[inst.StoreAddr()]
+		case *instance.TiDBInstance:
+			info.Status = "Up"
+			if err := tryConnect(fmt.Sprintf("root:@tcp(%s)/?timeout=2s", inst.Addr())); err != nil {
+				info.Status = "Down"
+			}
+		default:
+			info.Status = "Up"
+		}
+		if info.Status == "" {
+			info.Status = "N/A"
+		}
+		infos = append(infos, info)
+		return nil
+	})
+	return infos
+}
+
+// pdMemberStatus returns the health of the PD members by names, the leader
+// is marked with |L
+func (p *Playground) pdMemberStatus() map[string]string {
+	status := make(map[string]string)
+	if len(p.pds) == 0 {
+		return status
+	}
+
+	client := p.pdClient()
+	healths, err := client.GetHealth()
+	if err != nil {
+		return status
+	}
+	leader, err := client.GetLeader()
+	if err != nil {
+		return status
+	}
+	for _, member := range healths.Healths {
+		s := "Unhealthy"
+		if member.Health {
+			s = "Healthy"
+		}
+		if member.Name == leader.Name {
+			s += "|L"
+		}
+		status[member.Name] = s
+	}
+	return status
+}
+
+// storeStates returns the states of the stores by addresses, the state of
+// the latest store is used if there are several ones of an address
+func (p *Playground) storeStates() map[string]string {
+	states := make(map[string]string)
+	if len(p.pds) == 0 {
+		return states
+	}
+
+	stores, err := p.pdClient().GetStores()
+	if err != nil {
+		return states
+	}
+	ids := make(map[string]uint64)
+	for _, store := range stores.Stores {
+		addr := store.Store.Address
+		if id, found := ids[addr]; found && id > store.Store.Id {
+			continue
+		}
+		ids[addr] = store.Store.Id
+		states[addr] = store.Store.StateName
+	}
+	return states
+}
+
+// instanceProcess returns the process of the instance started by the tiup
+// process of the pid, or the tiup process if there is none. It returns nil
+// if the process exited.
+func instanceProcess(pid int) *gops.Process {
+	proc, err := gops.NewProcess(int32(pid))
+	if err != nil {
+		return nil
+	}
+	if children, err := proc.Children(); err == nil && len(children) > 0 {
+		return children[0]
+	}
+	return proc
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceProcess(t *testing.T) {
+	// the sleep is the instance started by the shell as tiup
+	cmd := exec.Command("sh", "-c", "sleep 30; true")
+	assert.Nil(t, cmd.Start())
+	pid := cmd.Process.Pid
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	proc := instanceProcess(pid)
+	for i := 0; i < 50 && proc != nil && int(proc.Pid) == pid; i++ {
+		time.Sleep(time.Millisecond * 100)
+		proc = instanceProcess(pid)
+	}
+	assert.NotNil(t, proc)
+	assert.NotEqual(t, pid, int(proc.Pid))
+
+	assert.Nil(t, signalInstance(pid, syscall.SIGSTOP))
+	time.Sleep(time.Millisecond * 100)
+	status, err := proc.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, "T", status)
+
+	assert.Nil(t, signalInstance(pid, syscall.SIGKILL))
+	assert.Nil(t, waitProcessExit(pid, time.Second*5))
+	assert.Nil(t, instanceProcess(pid))
+	// it's an error to signal the instance which is already gone
+	assert.NotNil(t, signalInstance(pid, syscall.SIGKILL))
+}
@@ -0,0 +1,211 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/repository/v0manifest"
+	gops "github.com/shirou/gopsutil/process"
+)
+
+// findInstance returns the instance the command targets, which is the PD
+// leader if PDLeader is set, or the instance of the pid
+func (p *Playground) findInstance(cmd *Command) (cid string, inst instance.Instance, err error) {
+	if cmd.PDLeader {
+		leader, err := p.pdClient().GetLeader()
+		if err != nil {
+			return "", nil, errors.Annotate(err, "get the PD leader")
+		}
+		for _, pd := range p.pds {
+			if pd.Name() == leader.Name {
+				return "pd", pd, nil
+			}
+		}
+		return "", nil, errors.Errorf("the PD leader %s is not in the playground", leader.Name)
+	}
+
+	_ = p.WalkInstances(func(wcid string, winst instance.Instance) error {
+		if winst.Pid() == cmd.PID {
+			cid = wcid
+			inst = winst
+		}
+		return nil
+	})
+	if inst == nil {
+		return "", nil, errors.Errorf("no instance with pid: %d", cmd.PID)
+	}
+	return cid, inst, nil
+}
+
+func (p *Playground) handleFault(w io.Writer, cmd *Command) error {
+	cid, inst, err := p.findInstance(cmd)
+	if err != nil {
+		return err
+	}
+
+	pid := inst.Pid()
+	switch cmd.CommandType {
+	case KillCommandType:
+		if err := signalInstance(pid, syscall.SIGKILL); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s %d is killed, restart it by `tiup playground restart %d`\n", cid, pid, pid)
+	case PauseCommandType:
+		if err := signalInstance(pid, syscall.SIGSTOP); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s %d is paused, resume it by `tiup playground resume %d`\n", cid, pid, pid)
+	case ResumeCommandType:
+		if err := signalInstance(pid, syscall.SIGCONT); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s %d is resumed\n", cid, pid)
+	case RestartCommandType:
+		return p.restartInstance(w, cid, inst)
+	default:
+		return p.handleNetworkFault(w, cid, inst, cmd)
+	}
+	return nil
+}
+
+// restartInstance kills the instance if it's running and starts it again on
+// its data and ports
+func (p *Playground) restartInstance(w io.Writer, cid string, inst instance.Instance) error {
+	pid := inst.Pid()
+	if processExists(pid) {
+		if err := signalInstance(pid, syscall.SIGKILL); err != nil {
+			return err
+		}
+		if err := waitProcessExit(pid, time.Second*30); err != nil {
+			return err
+		}
+	}
+
+	err := inst.Start(context.Background(), v0manifest.Version(p.bootOptions.version))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	p.instanceWaiter.Go(func() error {
+		return inst.Wait()
+	})
+
+	fmt.Fprintf(w, "%s %d is restarted with pid %d\n", cid, pid, inst.Pid())
+	return nil
+}
+
+// handleNetworkFault injects the network fault into the proxy in front of the
+// instance, the proxy is started on the first fault of the instance. Only the
+// connections through the proxy are affected, the instances of the playground
+// still connect to each other directly.
+func (p *Playground) handleNetworkFault(w io.Writer, cid string, inst instance.Instance, cmd *Command) error {
+	pid := inst.Pid()
+	proxy, found := p.proxies[inst]
+	if !found {
+		if cmd.CommandType == HealCommandType {
+			fmt.Fprintf(w, "no network fault in %s %d\n", cid, pid)
+			return nil
+		}
+		var err error
+		proxy, err = newFaultProxy(p.bootOptions.host, proxyTarget(cid, inst))
+		if err != nil {
+			return err
+		}
+		p.proxies[inst] = proxy
+	}
+
+	switch cmd.CommandType {
+	case DelayCommandType:
+		proxy.delay(cmd.Latency)
+		fmt.Fprintf(w, "the network of %s %d is delayed by %s\n", cid, pid, cmd.Latency)
+	case PartitionCommandType:
+		proxy.partition()
+		fmt.Fprintf(w, "the network of %s %d is partitioned\n", cid, pid)
+	case HealCommandType:
+		proxy.heal()
+		fmt.Fprintf(w, "the network of %s %d is healed\n", cid, pid)
+		return nil
+	default:
+		return errors.Errorf("unknown command %s", cmd.CommandType)
+	}
+	fmt.Fprintf(w, "connect to %s %d through %s to be affected\n", cid, pid, proxy.Addr())
+	return nil
+}
+
+// proxyTarget returns the address the clients of the instance connect to
+func proxyTarget(cid string, inst instance.Instance) string {
+	spec := inst.Spec()
+	port := spec.Port
+	switch cid {
+	case "pd":
+		port = spec.StatusPort
+	case "tiflash":
+		port = spec.Ports["tcp"]
+	}
+	host := spec.Host
+	if host == "0.0.0.0" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// signalInstance sends the signal to the processes of the instance, which are
+// started by the tiup process of the pid. The tiup process is also killed with
+// SIGKILL, it's not stopped to be able to wait for its children.
+func signalInstance(pid int, sig syscall.Signal) error {
+	proc, err := gops.NewProcess(int32(pid))
+	if err != nil {
+		return errors.Annotatef(err, "process %d", pid)
+	}
+	children, _ := proc.Children()
+	for _, child := range children {
+		// the child may have exited since it was listed
+		if err := syscall.Kill(int(child.Pid), sig); err != nil && err != syscall.ESRCH {
+			return errors.Annotatef(err, "send %s to %d", sig, child.Pid)
+		}
+	}
+	if len(children) == 0 || sig == syscall.SIGKILL {
+		// the tiup process exits along with its children on SIGKILL, it's
+		// only an error if the process had no children to be signaled
+		if err := syscall.Kill(pid, sig); err != nil && (len(children) == 0 || err != syscall.ESRCH) {
+			return errors.Annotatef(err, "send %s to %d", sig, pid)
+		}
+	}
+	return nil
+}
+
+func processExists(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
+
+// waitProcessExit waits until the process exits, it's reaped by the waiter of
+// the instance
+func waitProcessExit(pid int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for processExists(pid) {
+		if time.Now().After(deadline) {
+			return errors.Errorf("process %d doesn't exit after %s", pid, timeout)
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	return nil
+}
@@ -26,6 +26,8 @@ type Config struct {
 	BinPath    string
 	Num        int
 	Host       string
+	Port       int // a free port is chosen if 0
+	StatusPort int
 }
 
 type instance struct {
@@ -38,6 +40,18 @@ type instance struct {
 	BinPath    string
 }
 
+// Spec describes an instance to restart it later with the same ports, the
+// data dir is derived from the ID
+type Spec struct {
+	ID         int            `json:"id"`
+	Host       string         `json:"host"`
+	Port       int            `json:"port"`
+	StatusPort int            `json:"status_port,omitempty"`
+	Ports      map[string]int `json:"ports,omitempty"` // the other ports, e.g., of TiFlash
+	ConfigPath string         `json:"config_path,omitempty"`
+	BinPath    string         `json:"bin_path,omitempty"`
+}
+
 // Instance represent running component
 type Instance interface {
 	Pid() int
@@ -45,6 +59,10 @@ type Instance interface {
 	Start(ctx context.Context, version v0manifest.Version) error
 	StatusAddrs() []string
 	Wait() error
+	// Spec returns the description of the instance to restart it.
+	Spec() Spec
+	// Restore sets the ports of the instance to the ones of the spec.
+	Restore(spec Spec)
 }
 
 func (inst *instance) StatusAddrs() (addrs []string) {
@@ -54,6 +72,22 @@ func (inst *instance) StatusAddrs() (addrs []string) {
 	return
 }
 
+func (inst *instance) Spec() Spec {
+	return Spec{
+		ID:         inst.ID,
+		Host:       inst.Host,
+		Port:       inst.Port,
+		StatusPort: inst.StatusPort,
+		ConfigPath: inst.ConfigPath,
+		BinPath:    inst.BinPath,
+	}
+}
+
+func (inst *instance) Restore(spec Spec) {
+	inst.Port = spec.Port
+	inst.StatusPort = spec.StatusPort
+}
+
 // CompVersion return the format to run specified version of a component.
 func CompVersion(comp string, version v0manifest.Version) string {
 	if version.IsEmpty() {
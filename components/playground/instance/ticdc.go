@@ -0,0 +1,177 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository/v0manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// TiCDC represent a ticdc instance.
+type TiCDC struct {
+	instance
+	pds     []*PDInstance
+	cmd     *exec.Cmd
+	version v0manifest.Version
+}
+
+var _ Instance = &TiCDC{}
+
+// NewTiCDC create a TiCDC instance.
+func NewTiCDC(binPath string, dir, host, configPath string, id int, pds []*PDInstance) *TiCDC {
+	port := utils.MustGetFreePort(host, 8300)
+	return &TiCDC{
+		instance: instance{
+			BinPath: binPath,
+			ID:      id,
+			Dir:     dir,
+			Host:    host,
+			Port:    port,
+			// the metrics are served on the same port
+			StatusPort: port,
+			ConfigPath: configPath,
+		},
+		pds: pds,
+	}
+}
+
+// Restore implements Instance interface.
+func (c *TiCDC) Restore(spec Spec) {
+	c.instance.Restore(spec)
+	c.StatusPort = c.Port
+}
+
+// Addr return the address of TiCDC.
+func (c *TiCDC) Addr() string {
+	return fmt.Sprintf("%s:%d", advertiseHost(c.Host), c.Port)
+}
+
+// Ready return nil when TiCDC is ready to serve.
+func (c *TiCDC) Ready(ctx context.Context) error {
+	url := fmt.Sprintf("http://%s/status", c.Addr())
+
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == 200 {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			// just retry
+		}
+	}
+}
+
+// pdURLs returns the URLs of the PD instances
+func (c *TiCDC) pdURLs() string {
+	var urls []string
+	for _, pd := range c.pds {
+		urls = append(urls, fmt.Sprintf("http://%s:%d", pd.Host, pd.StatusPort))
+	}
+	return strings.Join(urls, ",")
+}
+
+// startArgs returns the command line to start the TiCDC server
+func (c *TiCDC) startArgs(version v0manifest.Version) []string {
+	args := []string{
+		"tiup", fmt.Sprintf("--binpath=%s", c.BinPath),
+		CompVersion("cdc", version),
+		"server",
+		fmt.Sprintf("--addr=%s:%d", c.Host, c.Port),
+		fmt.Sprintf("--advertise-addr=%s", c.Addr()),
+		fmt.Sprintf("--pd=%s", c.pdURLs()),
+		fmt.Sprintf("--log-file=%s", filepath.Join(c.Dir, "ticdc.log")),
+	}
+	if c.ConfigPath != "" {
+		args = append(args, fmt.Sprintf("--config=%s", c.ConfigPath))
+	}
+	return args
+}
+
+// cliArgs returns the command line to run `cdc cli` against the PD instances
+func (c *TiCDC) cliArgs(version v0manifest.Version, args ...string) []string {
+	cmd := []string{
+		"tiup", fmt.Sprintf("--binpath=%s", c.BinPath),
+		CompVersion("cdc", version),
+		"cli",
+	}
+	cmd = append(cmd, args...)
+	return append(cmd, fmt.Sprintf("--pd=%s", c.pdURLs()))
+}
+
+// Start implements Instance interface.
+func (c *TiCDC) Start(ctx context.Context, version v0manifest.Version) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	c.version = version
+	args := c.startArgs(version)
+	c.cmd = exec.CommandContext(ctx, args[0], args[1:]...)
+	c.cmd.Env = append(
+		os.Environ(),
+		fmt.Sprintf("%s=%s", localdata.EnvNameInstanceDataDir, c.Dir),
+	)
+	c.cmd.Stderr = os.Stderr
+	c.cmd.Stdout = os.Stdout
+
+	return c.cmd.Start()
+}
+
+// CreateChangefeed creates the changefeed replicating to the sink by `cdc cli`,
+// which works for all the versions of TiCDC
+func (c *TiCDC) CreateChangefeed(ctx context.Context, id, sinkURI string) error {
+	args := c.cliArgs(c.version,
+		"changefeed", "create",
+		fmt.Sprintf("--changefeed-id=%s", id),
+		fmt.Sprintf("--sink-uri=%s", sinkURI),
+	)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(
+		os.Environ(),
+		fmt.Sprintf("%s=%s", localdata.EnvNameInstanceDataDir, c.Dir),
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Annotatef(err, "create changefeed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Wait implements Instance interface.
+func (c *TiCDC) Wait() error {
+	return c.cmd.Wait()
+}
+
+// Pid implements Instance interface.
+func (c *TiCDC) Pid() int {
+	return c.cmd.Process.Pid
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instance
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTiCDCArgs(t *testing.T) {
+	pd := NewPDInstance("", "/data/pd-0", "127.0.0.1", "", 0)
+	pd.StatusPort = 2379
+	cdc := NewTiCDC("/bin/cdc", "/data/ticdc-0", "0.0.0.0", "/conf/cdc.toml", 0, []*PDInstance{pd})
+	cdc.Port = 8300
+
+	assert.Equal(t, "localhost:8300", cdc.Addr())
+	assert.Equal(t, 8300, cdc.StatusPort)
+	assert.Equal(t, []string{
+		"tiup", "--binpath=/bin/cdc", "cdc:v4.0.9", "server",
+		"--addr=0.0.0.0:8300",
+		"--advertise-addr=localhost:8300",
+		"--pd=http://127.0.0.1:2379",
+		"--log-file=/data/ticdc-0/ticdc.log",
+		"--config=/conf/cdc.toml",
+	}, cdc.startArgs("v4.0.9"))
+	assert.Equal(t, []string{
+		"tiup", "--binpath=/bin/cdc", "cdc", "cli",
+		"changefeed", "create", "--sink-uri=blackhole://",
+		"--pd=http://127.0.0.1:2379",
+	}, cdc.cliArgs("", "changefeed", "create", "--sink-uri=blackhole://"))
+}
+
+func TestTiCDCReady(t *testing.T) {
+	ready := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-ready:
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"is_owner":true}`))
+	}))
+	defer srv.Close()
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	assert.Nil(t, err)
+	cdc := NewTiCDC("", "", host, "", 0, nil)
+	cdc.Port, err = strconv.Atoi(port)
+	assert.Nil(t, err)
+
+	// not ready until the server responds OK
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, cdc.Ready(ctx))
+
+	close(ready)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, cdc.Ready(ctx))
+}
@@ -69,6 +69,27 @@ func NewTiFlashInstance(binPath, dir, host, configPath string, id int, pds []*PD
 	}
 }
 
+// Spec implements Instance interface.
+func (inst *TiFlashInstance) Spec() Spec {
+	spec := inst.instance.Spec()
+	spec.Ports = map[string]int{
+		"tcp":          inst.TCPPort,
+		"service":      inst.ServicePort,
+		"proxy":        inst.ProxyPort,
+		"proxy_status": inst.ProxyStatusPort,
+	}
+	return spec
+}
+
+// Restore implements Instance interface.
+func (inst *TiFlashInstance) Restore(spec Spec) {
+	inst.instance.Restore(spec)
+	inst.TCPPort = spec.Ports["tcp"]
+	inst.ServicePort = spec.Ports["service"]
+	inst.ProxyPort = spec.Ports["proxy"]
+	inst.ProxyStatusPort = spec.Ports["proxy_status"]
+}
+
 func getFlashClusterPath(dir string) string {
 	return fmt.Sprintf("%s/flash_cluster_manager", dir)
 }
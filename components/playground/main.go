@@ -49,8 +49,14 @@ type bootOptions struct {
 	tiflash instance.Config
 	pump    instance.Config
 	drainer instance.Config
+	ticdc   instance.Config
 	host    string
 	monitor bool
+	// changefeed is the sink URI of the changefeed created once TiCDC is ready
+	changefeed string
+	// instances are declared by the topology file, they are added instead
+	// of the numbers of the components if not empty
+	instances []declaredInstance
 }
 
 func installIfMissing(profile *localdata.Profile, component, version string) error {
@@ -108,6 +114,8 @@ func execute() error {
 		monitor: false,
 		version: "",
 	}
+	resume := ""
+	topoFile := ""
 
 	rootCmd := &cobra.Command{
 		Use: "tiup playground [version]",
@@ -118,8 +126,16 @@ Examples:
   $ tiup playground nightly                         # Start a TiDB nightly version local cluster
   $ tiup playground v3.0.10 --db 3 --pd 3 --kv 3    # Start a local cluster with 10 nodes
   $ tiup playground nightly --monitor               # Start a local cluster with monitor system
+  $ tiup playground --ticdc 1 --changefeed 'kafka://127.0.0.1:9092/topic'  # Start a local cluster replicating to Kafka
   $ tiup playground --pd.config ~/config/pd.toml    # Start a local cluster with specified configuration file,
-  $ tiup playground --db.binpath /xx/tidb-server    # Start a local cluster with component binary path`,
+  $ tiup playground --db.binpath /xx/tidb-server    # Start a local cluster with component binary path
+  $ tiup playground -f playground.yaml              # Start a local cluster with the instances declared in the file
+  $ tiup --tag qa playground                        # Start a local cluster kept after it's stopped
+  $ tiup playground --resume qa                     # Restart the local cluster of the tag qa
+  $ tiup playground display --format json           # Show the instances of the running local cluster in JSON
+  $ tiup playground kill --pd-leader                # Kill the PD leader of the running local cluster
+  $ tiup playground pause 1234                      # Pause the instance of the pid 1234, resume it by 'resume 1234'
+  $ tiup playground delay 1234 --latency 500ms      # Delay the connections to the instance through a proxy`,
 		SilenceUsage: true,
 		Args: func(cmd *cobra.Command, args []string) error {
 			return nil
@@ -133,11 +149,20 @@ Examples:
 			if err != nil {
 				return errors.AddStack(err)
 			}
-			err = dumpPort(port)
+			err = dumpPort("", port)
 			p := NewPlayground(port)
 			if err != nil {
 				return errors.AddStack(err)
 			}
+			if resume != "" {
+				return p.resumeCluster(resume)
+			}
+			if topoFile != "" {
+				opt, err = topologyOptions(cmd.Flags(), getAbsolutePath(topoFile), opt, p.dataDir)
+				if err != nil {
+					return err
+				}
+			}
 			return p.bootCluster(opt)
 		},
 	}
@@ -148,18 +173,23 @@ Examples:
 	rootCmd.Flags().IntVarP(&opt.tiflash.Num, "tiflash", "", opt.tiflash.Num, "TiFlash instance number")
 	rootCmd.Flags().IntVarP(&opt.pump.Num, "pump", "", opt.pump.Num, "Pump instance number")
 	rootCmd.Flags().IntVarP(&opt.drainer.Num, "drainer", "", opt.drainer.Num, "Drainer instance number")
+	rootCmd.Flags().IntVarP(&opt.ticdc.Num, "ticdc", "", opt.ticdc.Num, "TiCDC instance number")
 
 	rootCmd.Flags().StringVarP(&opt.host, "host", "", opt.host, "Playground cluster host")
 	rootCmd.Flags().StringVarP(&opt.tidb.Host, "db.host", "", opt.tidb.Host, "Playground TiDB host. If not provided, TiDB will still use `host` flag as its host")
 	rootCmd.Flags().StringVarP(&opt.pd.Host, "pd.host", "", opt.pd.Host, "Playground PD host. If not provided, PD will still use `host` flag as its host")
 	rootCmd.Flags().BoolVar(&opt.monitor, "monitor", false, "Start prometheus component")
+	rootCmd.Flags().StringVarP(&topoFile, "file", "f", "", "Topology file declaring the instances, it can't be used with the flags of the components")
+	rootCmd.Flags().StringVar(&resume, "resume", "", "Restart the playground started by 'tiup --tag <tag> playground' on its data with the same instances, the other options are ignored")
+	rootCmd.Flags().StringVar(&opt.changefeed, "changefeed", "", "Create a changefeed replicating to the sink URI once TiCDC is ready, e.g. 'blackhole://'")
 
 	rootCmd.Flags().StringVarP(&opt.tidb.ConfigPath, "db.config", "", opt.tidb.ConfigPath, "TiDB instance configuration file")
 	rootCmd.Flags().StringVarP(&opt.tikv.ConfigPath, "kv.config", "", opt.tikv.ConfigPath, "TiKV instance configuration file")
 	rootCmd.Flags().StringVarP(&opt.pd.ConfigPath, "pd.config", "", opt.pd.ConfigPath, "PD instance configuration file")
-	rootCmd.Flags().StringVarP(&opt.tidb.ConfigPath, "tiflash.config", "", opt.tidb.ConfigPath, "TiFlash instance configuration file")
+	rootCmd.Flags().StringVarP(&opt.tiflash.ConfigPath, "tiflash.config", "", opt.tiflash.ConfigPath, "TiFlash instance configuration file")
 	rootCmd.Flags().StringVarP(&opt.pump.ConfigPath, "pump.config", "", opt.pump.ConfigPath, "Pump instance configuration file")
 	rootCmd.Flags().StringVarP(&opt.drainer.ConfigPath, "drainer.config", "", opt.drainer.ConfigPath, "Drainer instance configuration file")
+	rootCmd.Flags().StringVarP(&opt.ticdc.ConfigPath, "ticdc.config", "", opt.ticdc.ConfigPath, "TiCDC instance configuration file")
 
 	rootCmd.Flags().StringVarP(&opt.tidb.BinPath, "db.binpath", "", opt.tidb.BinPath, "TiDB instance binary path")
 	rootCmd.Flags().StringVarP(&opt.tikv.BinPath, "kv.binpath", "", opt.tikv.BinPath, "TiKV instance binary path")
@@ -167,10 +197,18 @@ Examples:
 	rootCmd.Flags().StringVarP(&opt.tiflash.BinPath, "tiflash.binpath", "", opt.tiflash.BinPath, "TiFlash instance binary path")
 	rootCmd.Flags().StringVarP(&opt.pump.BinPath, "pump.binpath", "", opt.pump.BinPath, "Pump instance binary path")
 	rootCmd.Flags().StringVarP(&opt.drainer.BinPath, "drainer.binpath", "", opt.drainer.BinPath, "Drainer instance binary path")
+	rootCmd.Flags().StringVarP(&opt.ticdc.BinPath, "ticdc.binpath", "", opt.ticdc.BinPath, "TiCDC instance binary path")
 
 	rootCmd.AddCommand(newDisplay())
 	rootCmd.AddCommand(newScaleOut())
 	rootCmd.AddCommand(newScaleIn())
+	rootCmd.AddCommand(newFaultCmd(KillCommandType, "Kill the instances"))
+	rootCmd.AddCommand(newFaultCmd(PauseCommandType, "Pause the instances by SIGSTOP"))
+	rootCmd.AddCommand(newFaultCmd(ResumeCommandType, "Resume the paused instances"))
+	rootCmd.AddCommand(newFaultCmd(RestartCommandType, "Restart the instances on their data"))
+	rootCmd.AddCommand(newFaultCmd(DelayCommandType, "Delay the network of the instances through a proxy"))
+	rootCmd.AddCommand(newFaultCmd(PartitionCommandType, "Partition the network of the instances through a proxy"))
+	rootCmd.AddCommand(newFaultCmd(HealCommandType, "Heal the network of the instances"))
 
 	return rootCmd.Execute()
 }
@@ -239,8 +277,8 @@ func getAbsolutePath(binPath string) string {
 	return binPath
 }
 
-func dumpPort(port int) error {
-	return ioutil.WriteFile("port", []byte(strconv.Itoa(port)), 0644)
+func dumpPort(dir string, port int) error {
+	return ioutil.WriteFile(filepath.Join(dir, "port"), []byte(strconv.Itoa(port)), 0644)
 }
 
 func loadPort(dir string) (port int, err error) {
@@ -26,11 +26,10 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
-	"text/tabwriter"
 	"time"
 
-	"github.com/cheynewallace/tabby"
 	"github.com/fatih/color"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
@@ -49,6 +48,10 @@ type Playground struct {
 	bootOptions *bootOptions
 	profile     *localdata.Profile
 	port        int
+	// dataDir is the dir of the data of the instances and the session
+	dataDir string
+	// session is the session resumed, the instances are restored from it
+	session *session
 
 	pds      []*instance.PDInstance
 	tikvs    []*instance.TiKVInstance
@@ -56,19 +59,31 @@ type Playground struct {
 	tiflashs []*instance.TiFlashInstance
 	pumps    []*instance.Pump
 	drainers []*instance.Drainer
+	ticdcs   []*instance.TiCDC
 
 	idAlloc        map[string]int
 	instanceWaiter errgroup.Group
+	// versions are the versions of the components resolved when the first
+	// instances are added, the instances run the latest installed version
+	// if the version is not specified
+	versions map[string]string
 
 	monitor *monitor
+	// proxies are in front of the instances with network faults
+	proxies map[instance.Instance]*faultProxy
+	// cmdMu serializes the commands, which are served concurrently
+	cmdMu sync.Mutex
 }
 
 // NewPlayground create a Playground instance.
 func NewPlayground(port int) *Playground {
 	return &Playground{
-		port:    port,
-		profile: localdata.InitProfile(),
-		idAlloc: make(map[string]int),
+		port:     port,
+		profile:  localdata.InitProfile(),
+		idAlloc:  make(map[string]int),
+		versions: make(map[string]string),
+		proxies:  make(map[instance.Instance]*faultProxy),
+		dataDir:  os.Getenv(localdata.EnvNameInstanceDataDir),
 	}
 }
 
@@ -78,30 +93,6 @@ func (p *Playground) allocID(componentID string) int {
 	return id
 }
 
-func (p *Playground) handleDisplay(r io.Writer) (err error) {
-	w := tabwriter.NewWriter(r, 0, 0, 2, ' ', 0)
-	t := tabby.NewCustom(w)
-
-	// TODO add more info.
-	header := []interface{}{"Pid", "Role"}
-	t.AddHeader(header...)
-
-	err = p.WalkInstances(func(componentID string, ins instance.Instance) error {
-		row := make([]interface{}, len(header))
-		row[0] = strconv.Itoa(ins.Pid())
-		row[1] = componentID
-		t.AddLine(row...)
-		return nil
-	})
-
-	if err != nil {
-		return errors.AddStack(err)
-	}
-
-	t.Print()
-	return nil
-}
-
 var timeoutOpt = &clusterutil.RetryOption{
 	Timeout: time.Second * 15,
 	Delay:   time.Second * 5,
@@ -125,6 +116,28 @@ func (p *Playground) pdClient() *api.PDClient {
 	return api.NewPDClient(addrs, 10*time.Second, nil)
 }
 
+// playgroundChangefeedID is the ID of the changefeed created by --changefeed
+const playgroundChangefeedID = "playground"
+
+// createChangefeed creates the changefeed replicating to the sink once all
+// the TiCDC instances are ready
+func (p *Playground) createChangefeed(sinkURI string) error {
+	for _, inst := range p.ticdcs {
+		fmt.Print(color.YellowString("Waiting for ticdc %s ready ", inst.Addr()))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := inst.Ready(ctx)
+		cancel()
+		fmt.Println()
+		if err != nil {
+			return errors.Annotatef(err, "ticdc %s is not ready", inst.Addr())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return p.ticdcs[0].CreateChangefeed(ctx, playgroundChangefeedID, sinkURI)
+}
+
 func (p *Playground) killKVIfTombstone(inst *instance.TiKVInstance) {
 	defer logIfErr(p.renderSDFile())
 
@@ -143,6 +156,7 @@ func (p *Playground) killKVIfTombstone(inst *instance.TiKVInstance) {
 						fmt.Println(err)
 					}
 					p.tikvs = append(p.tikvs[:i], p.tikvs[i+1:]...)
+					logIfErr(p.saveSession())
 					return
 				}
 			}
@@ -166,6 +180,7 @@ func (p *Playground) removePumpWhenTombstone(c *api.BinlogClient, inst *instance
 				if e == inst {
 					fmt.Printf("pump already offline %s\n", inst.Addr())
 					p.pumps = append(p.pumps[:i], p.pumps[i+1:]...)
+					logIfErr(p.saveSession())
 					return
 				}
 			}
@@ -189,6 +204,7 @@ func (p *Playground) removeDrainerWhenTombstone(c *api.BinlogClient, inst *insta
 				if e == inst {
 					fmt.Printf("drainer already offline %s\n", inst.Addr())
 					p.drainers = append(p.drainers[:i], p.drainers[i+1:]...)
+					logIfErr(p.saveSession())
 					return
 				}
 			}
@@ -216,6 +232,7 @@ func (p *Playground) killFlashIfTombstone(inst *instance.TiFlashInstance) {
 						fmt.Println(err)
 					}
 					p.tiflashs = append(p.tiflashs[:i], p.tiflashs[i+1:]...)
+					logIfErr(p.saveSession())
 					return
 				}
 			}
@@ -244,6 +261,11 @@ func (p *Playground) handleScaleIn(w io.Writer, pid int) error {
 		return nil
 	}
 
+	if proxy, found := p.proxies[inst]; found {
+		logIfErr(proxy.close())
+		delete(p.proxies, inst)
+	}
+
 	switch cid {
 	case "pd":
 		for i := 0; i < len(p.pds); i++ {
@@ -328,6 +350,12 @@ func (p *Playground) handleScaleIn(w io.Writer, pid int) error {
 				return nil
 			}
 		}
+	case "ticdc":
+		for i := 0; i < len(p.ticdcs); i++ {
+			if p.ticdcs[i].Pid() == pid {
+				p.ticdcs = append(p.ticdcs[:i], p.ticdcs[i+1:]...)
+			}
+		}
 	default:
 		fmt.Fprintf(w, "unknown component in scale in: %s", cid)
 		return nil
@@ -339,6 +367,7 @@ func (p *Playground) handleScaleIn(w io.Writer, pid int) error {
 	}
 
 	logIfErr(p.renderSDFile())
+	logIfErr(p.saveSession())
 
 	fmt.Fprintf(w, "scale in %s success\n", cid)
 
@@ -353,7 +382,7 @@ func (p *Playground) sanitizeConfig(boot instance.Config, cfg *instance.Config)
 		cfg.ConfigPath = boot.ConfigPath
 	}
 	if cfg.Host == "" {
-		cfg.Host = boot.ConfigPath
+		cfg.Host = boot.Host
 	}
 }
 
@@ -371,6 +400,8 @@ func (p *Playground) sanitizeComponentConfig(cid string, cfg *instance.Config) {
 		p.sanitizeConfig(p.bootOptions.pump, cfg)
 	case "drainer":
 		p.sanitizeConfig(p.bootOptions.drainer, cfg)
+	case "ticdc":
+		p.sanitizeConfig(p.bootOptions.ticdc, cfg)
 	default:
 		fmt.Printf("unknow %s in sanitizeConfig", cid)
 	}
@@ -394,19 +425,26 @@ func (p *Playground) handleScaleOut(w io.Writer, cmd *Command) error {
 	})
 
 	logIfErr(p.renderSDFile())
+	logIfErr(p.saveSession())
 
 	return nil
 }
 
 func (p *Playground) handleCommand(cmd *Command, w io.Writer) error {
+	p.cmdMu.Lock()
+	defer p.cmdMu.Unlock()
+
 	fmt.Printf("receive command: %s\n", cmd.CommandType)
 	switch cmd.CommandType {
 	case DisplayCommandType:
-		return p.handleDisplay(w)
+		return p.handleDisplay(w, cmd.Format)
 	case ScaleInCommandType:
 		return p.handleScaleIn(w, cmd.PID)
 	case ScaleOutCommandType:
 		return p.handleScaleOut(w, cmd)
+	case KillCommandType, PauseCommandType, ResumeCommandType, RestartCommandType,
+		DelayCommandType, PartitionCommandType, HealCommandType:
+		return p.handleFault(w, cmd)
 	}
 
 	return nil
@@ -481,6 +519,13 @@ func (p *Playground) WalkInstances(fn func(componentID string, ins instance.Inst
 		}
 	}
 
+	for _, ins := range p.ticdcs {
+		err := fn("ticdc", ins)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+	}
+
 	for _, ins := range p.tiflashs {
 		err := fn("tiflash", ins)
 		if err != nil {
@@ -495,6 +540,27 @@ func (p *Playground) enableBinlog() bool {
 }
 
 func (p *Playground) addInstance(componentID string, cfg instance.Config) (ins instance.Instance, err error) {
+	return p.newInstance(componentID, p.allocID(componentID), cfg)
+}
+
+// restoreInstance adds the instance of a resumed session with its ID and ports
+func (p *Playground) restoreInstance(spec sessionInstance) error {
+	if spec.ID >= p.idAlloc[spec.Component] {
+		p.idAlloc[spec.Component] = spec.ID + 1
+	}
+	ins, err := p.newInstance(spec.Component, spec.ID, instance.Config{
+		BinPath:    spec.BinPath,
+		ConfigPath: spec.ConfigPath,
+		Host:       spec.Host,
+	})
+	if err != nil {
+		return err
+	}
+	ins.Restore(spec.Spec)
+	return nil
+}
+
+func (p *Playground) newInstance(componentID string, id int, cfg instance.Config) (ins instance.Instance, err error) {
 	if cfg.BinPath != "" {
 		cfg.BinPath = getAbsolutePath(cfg.BinPath)
 	}
@@ -503,18 +569,24 @@ func (p *Playground) addInstance(componentID string, cfg instance.Config) (ins i
 		cfg.ConfigPath = getAbsolutePath(cfg.ConfigPath)
 	}
 
-	err = installIfMissing(p.profile, componentID, p.bootOptions.version)
+	component := componentName(componentID)
+	err = installIfMissing(p.profile, component, p.bootOptions.version)
 	if err != nil {
-		return nil, errors.Annotatef(err, "failed to install %s", componentID)
+		return nil, errors.Annotatef(err, "failed to install %s", component)
+	}
+	if _, found := p.versions[component]; !found && cfg.BinPath == "" {
+		version, err := p.profile.SelectInstalledVersion(component, v0manifest.Version(p.bootOptions.version))
+		if err != nil {
+			return nil, errors.AddStack(err)
+		}
+		p.versions[component] = version.String()
 	}
 
-	dataDir := os.Getenv(localdata.EnvNameInstanceDataDir)
-	if dataDir == "" {
+	if p.dataDir == "" {
 		return nil, fmt.Errorf("cannot read environment variable %s", localdata.EnvNameInstanceDataDir)
 	}
 
-	id := p.allocID(componentID)
-	dir := filepath.Join(dataDir, fmt.Sprintf("%s-%d", componentID, id))
+	dir := p.instanceDir(componentID, id)
 	// look more like listen ip?
 	host := p.bootOptions.host
 	if cfg.Host != "" {
@@ -554,64 +626,136 @@ func (p *Playground) addInstance(componentID string, cfg instance.Config) (ins i
 		inst := instance.NewDrainer(cfg.BinPath, dir, host, cfg.ConfigPath, id, p.pds)
 		ins = inst
 		p.drainers = append(p.drainers, inst)
+	case "ticdc":
+		inst := instance.NewTiCDC(cfg.BinPath, dir, host, cfg.ConfigPath, id, p.pds)
+		ins = inst
+		p.ticdcs = append(p.ticdcs, inst)
 	default:
 		return nil, errors.Errorf("unknow component: %s", componentID)
 	}
 
+	// the ports declared in the topology file replace the allocated ones
+	if cfg.Port != 0 || cfg.StatusPort != 0 {
+		spec := ins.Spec()
+		if cfg.Port != 0 {
+			spec.Port = cfg.Port
+		}
+		if cfg.StatusPort != 0 {
+			spec.StatusPort = cfg.StatusPort
+		}
+		ins.Restore(spec)
+	}
+
 	return
 }
 
-func (p *Playground) bootCluster(options *bootOptions) error {
-	p.bootOptions = options
-
-	if options.pd.Num < 1 || options.tidb.Num < 1 || options.tikv.Num < 1 {
-		return fmt.Errorf("all components count must be great than 0 (tidb=%v, tikv=%v, pd=%v)",
-			options.pd.Num, options.tikv.Num, options.pd.Num)
+// componentName returns the name of the component in the repository
+func componentName(componentID string) string {
+	// the component of TiCDC is named cdc in the repository
+	if componentID == "ticdc" {
+		return "cdc"
 	}
+	return componentID
+}
 
-	if options.version != "" && semver.Compare("v3.1.0", options.version) > 0 && options.tiflash.Num != 0 {
-		fmt.Println(color.YellowString("Warning: current version %s doesn't support TiFlash", options.version))
-		options.tiflash.Num = 0
-	}
+// instanceDir returns the data dir of the instance
+func (p *Playground) instanceDir(componentID string, id int) string {
+	return filepath.Join(p.dataDir, fmt.Sprintf("%s-%d", componentID, id))
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// addInstances adds the instances declared in the topology file of the
+// options, or the instances of the numbers in the options
+func (p *Playground) addInstances(options *bootOptions) error {
+	if len(options.instances) > 0 {
+		for _, inst := range options.instances {
+			// the component may be disabled for the version
+			if options.component(inst.componentID).Num == 0 {
+				continue
+			}
+			if _, err := p.addInstance(inst.componentID, inst.Config); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
 	for i := 0; i < options.pd.Num; i++ {
 		_, err := p.addInstance("pd", options.pd)
 		if err != nil {
-			return errors.AddStack(err)
+			return err
 		}
 	}
 	for i := 0; i < options.tikv.Num; i++ {
 		_, err := p.addInstance("tikv", options.tikv)
 		if err != nil {
-			return errors.AddStack(err)
+			return err
 		}
 	}
 	for i := 0; i < options.pump.Num; i++ {
 		_, err := p.addInstance("pump", options.pump)
 		if err != nil {
-			return errors.AddStack(err)
+			return err
 		}
 	}
 	for i := 0; i < options.tidb.Num; i++ {
 		_, err := p.addInstance("tidb", options.tidb)
 		if err != nil {
-			return errors.AddStack(err)
+			return err
 		}
 	}
 	for i := 0; i < options.drainer.Num; i++ {
 		_, err := p.addInstance("drainer", options.drainer)
 		if err != nil {
-			return errors.AddStack(err)
+			return err
+		}
+	}
+	for i := 0; i < options.ticdc.Num; i++ {
+		_, err := p.addInstance("ticdc", options.ticdc)
+		if err != nil {
+			return err
 		}
 	}
 	for i := 0; i < options.tiflash.Num; i++ {
 		_, err := p.addInstance("tiflash", options.tiflash)
 		if err != nil {
-			return errors.AddStack(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Playground) bootCluster(options *bootOptions) error {
+	p.bootOptions = options
+
+	if options.pd.Num < 1 || options.tidb.Num < 1 || options.tikv.Num < 1 {
+		return fmt.Errorf("all components count must be great than 0 (tidb=%v, tikv=%v, pd=%v)",
+			options.tidb.Num, options.tikv.Num, options.pd.Num)
+	}
+
+	if options.version != "" && semver.Compare("v3.1.0", options.version) > 0 && options.tiflash.Num != 0 {
+		fmt.Println(color.YellowString("Warning: current version %s doesn't support TiFlash", options.version))
+		options.tiflash.Num = 0
+	}
+
+	if options.version != "" && semver.Compare("v4.0.0", options.version) > 0 && options.ticdc.Num != 0 {
+		fmt.Println(color.YellowString("Warning: current version %s doesn't support TiCDC", options.version))
+		options.ticdc.Num = 0
+	}
+	if options.changefeed != "" && options.ticdc.Num == 0 {
+		return errors.New("the changefeed requires at least one TiCDC instance, specify it by --ticdc")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if p.session != nil {
+		for _, inst := range p.session.Instances {
+			if err := p.restoreInstance(inst); err != nil {
+				return errors.AddStack(err)
+			}
 		}
+	} else if err := p.addInstances(options); err != nil {
+		return errors.AddStack(err)
 	}
 
 	fmt.Println("Playground Bootstrapping...")
@@ -630,8 +774,7 @@ func (p *Playground) bootCluster(options *bootOptions) error {
 			return err
 		}
 
-		dataDir := os.Getenv(localdata.EnvNameInstanceDataDir)
-		promDir := filepath.Join(dataDir, "prometheus")
+		promDir := filepath.Join(p.dataDir, "prometheus")
 
 		monitor := newMonitor()
 		port, cmd, err := monitor.startMonitor(ctx, options.version, options.host, promDir)
@@ -671,9 +814,12 @@ func (p *Playground) bootCluster(options *bootOptions) error {
 			return errors.AddStack(err)
 		}
 
-		dataDir = os.Getenv(localdata.EnvNameInstanceDataDir)
-		grafanaDir := filepath.Join(dataDir, "grafana")
+		grafanaDir := filepath.Join(p.dataDir, "grafana")
 
+		// the grafana of a resumed playground is copied again
+		if err := os.RemoveAll(grafanaDir); err != nil {
+			return errors.AddStack(err)
+		}
 		cmd = exec.Command("cp", "-r", installPath, grafanaDir)
 		err = cmd.Run()
 		if err != nil {
@@ -745,6 +891,7 @@ func (p *Playground) bootCluster(options *bootOptions) error {
 	}
 
 	p.booted = true
+	logIfErr(p.saveSession())
 
 	var succ []string
 	for _, db := range p.tidbs {
@@ -810,6 +957,14 @@ func (p *Playground) bootCluster(options *bootOptions) error {
 		}
 	}
 
+	if options.changefeed != "" {
+		if err := p.createChangefeed(options.changefeed); err != nil {
+			fmt.Println(color.RedString("Failed to create the changefeed: %s", err))
+		} else {
+			fmt.Println(color.GreenString("Changefeed %s is created to replicate to %s", playgroundChangefeedID, options.changefeed))
+		}
+	}
+
 	if pdAddr := p.pds[0].Addr(); hasDashboard(pdAddr) {
 		fmt.Println(color.GreenString("To view the dashboard: http://%s/dashboard", pdAddr))
 	}
@@ -0,0 +1,147 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// faultProxy is a TCP proxy in front of a port of an instance, the
+// connections through it can be delayed or partitioned
+type faultProxy struct {
+	listener net.Listener
+	target   string
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	latency     time.Duration
+	partitioned bool
+}
+
+// newFaultProxy listens on a free port of the host and forwards the
+// connections to the target
+func newFaultProxy(host, target string) (*faultProxy, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	p := &faultProxy{
+		listener: listener,
+		target:   target,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	go p.serve()
+	return p, nil
+}
+
+// Addr returns the address to connect through the proxy
+func (p *faultProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// delay delays the data forwarded in both directions by the latency
+func (p *faultProxy) delay(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = latency
+}
+
+// partition stops forwarding data and connecting to the target until it's
+// healed, the connections hang as if the network is broken
+func (p *faultProxy) partition() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.partitioned = true
+}
+
+// heal removes the latency and the partition
+func (p *faultProxy) heal() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = 0
+	p.partitioned = false
+	p.cond.Broadcast()
+}
+
+// close stops accepting the connections and releases the partitioned ones
+func (p *faultProxy) close() error {
+	err := p.listener.Close()
+	p.heal()
+	return err
+}
+
+// wait blocks while the proxy is partitioned and returns the latency
+func (p *faultProxy) wait() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.partitioned {
+		p.cond.Wait()
+	}
+	return p.latency
+}
+
+func (p *faultProxy) serve() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *faultProxy) handle(conn net.Conn) {
+	defer conn.Close()
+
+	p.wait()
+	upstream, err := net.Dial("tcp", p.target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		p.pipe(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		p.pipe(conn, upstream)
+		done <- struct{}{}
+	}()
+	// close both connections once any direction is closed
+	<-done
+}
+
+func (p *faultProxy) pipe(dst, src net.Conn) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if latency := p.wait(); latency > 0 {
+				time.Sleep(latency)
+			}
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultProxy(t *testing.T) {
+	// an echo server as the instance
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	proxy, err := newFaultProxy("127.0.0.1", listener.Addr().String())
+	assert.Nil(t, err)
+	conn, err := net.Dial("tcp", proxy.Addr())
+	assert.Nil(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	echo := func(timeout time.Duration) (time.Duration, error) {
+		start := time.Now()
+		_ = conn.SetDeadline(start.Add(timeout))
+		if _, err := conn.Write([]byte("ping\n")); err != nil {
+			return 0, err
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		assert.Equal(t, "ping\n", line)
+		return time.Since(start), nil
+	}
+
+	_, err = echo(time.Second)
+	assert.Nil(t, err)
+
+	// the data is delayed in both directions
+	proxy.delay(time.Millisecond * 100)
+	elapsed, err := echo(time.Second)
+	assert.Nil(t, err)
+	assert.True(t, elapsed >= time.Millisecond*200, elapsed)
+
+	// the connection hangs while partitioned
+	proxy.partition()
+	_, err = echo(time.Millisecond * 200)
+	assert.NotNil(t, err)
+
+	// the new connections are established after healed
+	proxy.heal()
+	conn2, err := net.Dial("tcp", proxy.Addr())
+	assert.Nil(t, err)
+	defer conn2.Close()
+	_ = conn2.SetDeadline(time.Now().Add(time.Second))
+	_, err = conn2.Write([]byte("pong\n"))
+	assert.Nil(t, err)
+	line, err := bufio.NewReader(conn2).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "pong\n", line)
+
+	// no connection is accepted after closed
+	assert.Nil(t, proxy.close())
+	_, err = net.Dial("tcp", proxy.Addr())
+	assert.NotNil(t, err)
+}
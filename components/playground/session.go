@@ -0,0 +1,166 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/localdata"
+)
+
+// sessionFile is the file in the data dir of a playground recording its
+// instances, the playground can be resumed from it
+const sessionFile = "session.json"
+
+// sessionInstance is an instance of a playground session
+type sessionInstance struct {
+	Component string `json:"component"`
+	instance.Spec
+}
+
+// session is the persisted state of a playground
+type session struct {
+	Version   string            `json:"version"`
+	Host      string            `json:"host"`
+	Monitor   bool              `json:"monitor"`
+	Instances []sessionInstance `json:"instances"`
+}
+
+// saveSession writes the instances of the playground to its data dir, it's
+// called whenever the instances change
+func (p *Playground) saveSession() error {
+	s := session{
+		Version: p.bootOptions.version,
+		Host:    p.bootOptions.host,
+		Monitor: p.bootOptions.monitor,
+	}
+	_ = p.WalkInstances(func(cid string, inst instance.Instance) error {
+		s.Instances = append(s.Instances, sessionInstance{Component: cid, Spec: inst.Spec()})
+		return nil
+	})
+
+	data, err := json.MarshalIndent(&s, "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	// write to a temporary file first to not leave a broken session
+	tmp := filepath.Join(p.dataDir, sessionFile+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(os.Rename(tmp, filepath.Join(p.dataDir, sessionFile)))
+}
+
+// loadSession reads the session of the playground of the tag
+func loadSession(dir string) (*session, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, sessionFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Errorf("no playground to resume in %s, it must be started with `tiup --tag <tag> playground` to be kept", dir)
+		}
+		return nil, errors.AddStack(err)
+	}
+	s := &session{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, errors.Annotatef(err, "parse %s", filepath.Join(dir, sessionFile))
+	}
+	if len(s.Instances) == 0 {
+		return nil, errors.Errorf("no instance in the playground of %s", dir)
+	}
+	return s, nil
+}
+
+// bootOptions returns the options to boot the playground of the session, the
+// binpath and config of a component are the ones of its first instance and
+// used when scaling out
+func (s *session) bootOptions() *bootOptions {
+	opt := &bootOptions{
+		version: s.Version,
+		host:    s.Host,
+		monitor: s.Monitor,
+	}
+	configs := map[string]*instance.Config{
+		"pd":      &opt.pd,
+		"tikv":    &opt.tikv,
+		"tidb":    &opt.tidb,
+		"tiflash": &opt.tiflash,
+		"pump":    &opt.pump,
+		"drainer": &opt.drainer,
+		"ticdc":   &opt.ticdc,
+	}
+	for _, inst := range s.Instances {
+		cfg, found := configs[inst.Component]
+		if !found {
+			continue
+		}
+		if cfg.Num == 0 {
+			cfg.BinPath = inst.BinPath
+			cfg.ConfigPath = inst.ConfigPath
+		}
+		cfg.Num++
+	}
+	return opt
+}
+
+// resumeDir returns the data dir of the playground of the tag
+func resumeDir(tag string) string {
+	// the data dirs of all the instances are in the same parent dir
+	return filepath.Join(filepath.Dir(os.Getenv(localdata.EnvNameInstanceDataDir)), tag)
+}
+
+// checkNotRunning returns an error if the playground in the dir is running,
+// which is found by its command port
+func checkNotRunning(dir string) error {
+	port, err := loadPort(dir)
+	if err != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	if err != nil {
+		return nil
+	}
+	conn.Close()
+	return fmt.Errorf("the playground in %s is still running", dir)
+}
+
+// resumeCluster restarts the playground of the tag with the same instances
+// on the existing data
+func (p *Playground) resumeCluster(tag string) error {
+	dir := resumeDir(tag)
+	if err := checkNotRunning(dir); err != nil {
+		return err
+	}
+	s, err := loadSession(dir)
+	if err != nil {
+		return err
+	}
+
+	// the commands are sent to the port of the data dir of the tag
+	if err := dumpPort(dir, p.port); err != nil {
+		return errors.AddStack(err)
+	}
+	p.dataDir = dir
+	p.session = s
+	fmt.Printf("Resuming the playground %s with %d instances\n", tag, len(s.Instances))
+	return p.bootCluster(s.bootOptions())
+}
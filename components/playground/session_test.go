@@ -0,0 +1,62 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "play_session_test_*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	p := NewPlayground(9527)
+	p.dataDir = dir
+	p.bootOptions = &bootOptions{version: "v4.0.8", host: "127.0.0.1"}
+	p.pds = append(p.pds, instance.NewPDInstance("", dir, "127.0.0.1", "/conf/pd.toml", 0))
+	p.tikvs = append(p.tikvs,
+		instance.NewTiKVInstance("/bin/tikv-server", dir, "127.0.0.1", "", 0, p.pds),
+		instance.NewTiKVInstance("/bin/tikv-server", dir, "127.0.0.1", "", 2, p.pds))
+	p.tiflashs = append(p.tiflashs, instance.NewTiFlashInstance("", dir, "127.0.0.1", "", 0, p.pds, nil))
+	assert.Nil(t, p.saveSession())
+
+	s, err := loadSession(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, "v4.0.8", s.Version)
+	assert.Len(t, s.Instances, 4)
+	assert.Equal(t, "pd", s.Instances[0].Component)
+	assert.Equal(t, p.pds[0].Spec(), s.Instances[0].Spec)
+	assert.Equal(t, 2, s.Instances[2].ID)
+
+	opt := s.bootOptions()
+	assert.Equal(t, 1, opt.pd.Num)
+	assert.Equal(t, "/conf/pd.toml", opt.pd.ConfigPath)
+	assert.Equal(t, 2, opt.tikv.Num)
+	assert.Equal(t, "/bin/tikv-server", opt.tikv.BinPath)
+	assert.Equal(t, 0, opt.tidb.Num)
+
+	// the ports allocated for a new instance are replaced by the saved ones
+	flash := instance.NewTiFlashInstance("", dir, "127.0.0.1", "", 0, p.pds, nil)
+	flash.Restore(s.Instances[3].Spec)
+	assert.Equal(t, p.tiflashs[0].Spec(), flash.Spec())
+
+	_, err = loadSession(os.TempDir())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,302 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// topologyInstance declares an instance, or several identical instances if
+// count is greater than 1, in the topology file
+type topologyInstance struct {
+	Count      int                    `yaml:"count,omitempty"`
+	Host       string                 `yaml:"host,omitempty"`
+	Port       int                    `yaml:"port,omitempty"`
+	StatusPort int                    `yaml:"status_port,omitempty"`
+	BinPath    string                 `yaml:"binpath,omitempty"`
+	ConfigPath string                 `yaml:"config_path,omitempty"`
+	Config     map[string]interface{} `yaml:"config,omitempty"`
+}
+
+// topology is the file declaring the instances of a playground, e.g.,
+//
+//	version: v4.0.8
+//	pd:
+//	  - count: 1
+//	tikv:
+//	  - binpath: /path/to/tikv-server
+//	    config:
+//	      raftstore.sync-log: false
+//	  - count: 2
+//	tidb:
+//	  - port: 4000
+//	    config_path: tidb.toml
+//
+// The relative paths are relative to the file.
+type topology struct {
+	Version string             `yaml:"version,omitempty"`
+	Host    string             `yaml:"host,omitempty"`
+	Monitor bool               `yaml:"monitor,omitempty"`
+	PD      []topologyInstance `yaml:"pd,omitempty"`
+	TiKV    []topologyInstance `yaml:"tikv,omitempty"`
+	Pump    []topologyInstance `yaml:"pump,omitempty"`
+	TiDB    []topologyInstance `yaml:"tidb,omitempty"`
+	Drainer []topologyInstance `yaml:"drainer,omitempty"`
+	TiCDC   []topologyInstance `yaml:"ticdc,omitempty"`
+	TiFlash []topologyInstance `yaml:"tiflash,omitempty"`
+}
+
+// declaredInstance is an instance expanded from the topology file
+type declaredInstance struct {
+	componentID string
+	instance.Config
+}
+
+// topologyComponent is the instances of a component in the topology file
+type topologyComponent struct {
+	componentID string
+	instances   []topologyInstance
+}
+
+// components returns the components in the order they are added
+func (t *topology) components() []topologyComponent {
+	return []topologyComponent{
+		{"pd", t.PD},
+		{"tikv", t.TiKV},
+		{"pump", t.Pump},
+		{"tidb", t.TiDB},
+		{"drainer", t.Drainer},
+		{"ticdc", t.TiCDC},
+		{"tiflash", t.TiFlash},
+	}
+}
+
+// loadTopology reads the topology file, the relative paths in it are
+// resolved to the dir of the file
+func loadTopology(file string) (*topology, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	t := &topology{}
+	if err := yaml.UnmarshalStrict(data, t); err != nil {
+		return nil, errors.Annotatef(err, "parse topology file %s", file)
+	}
+
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "~") {
+			return path
+		}
+		return filepath.Join(filepath.Dir(file), path)
+	}
+	for _, comp := range t.components() {
+		for i := range comp.instances {
+			comp.instances[i].BinPath = resolve(comp.instances[i].BinPath)
+			comp.instances[i].ConfigPath = resolve(comp.instances[i].ConfigPath)
+		}
+	}
+	return t, nil
+}
+
+// bootOptions expands the instances of the topology, the configs declared
+// inline are written to confDir. The binpath, config and host of the first
+// instance of a component are used when scaling out.
+func (t *topology) bootOptions(confDir string) (*bootOptions, error) {
+	opt := &bootOptions{
+		version: t.Version,
+		host:    t.Host,
+		monitor: t.Monitor,
+	}
+	if opt.host == "" {
+		opt.host = "127.0.0.1"
+	}
+
+	for _, comp := range t.components() {
+		cfg := opt.component(comp.componentID)
+		for i, inst := range comp.instances {
+			count := inst.Count
+			if count == 0 {
+				count = 1
+			}
+			if count < 0 {
+				return nil, errors.Errorf("%s[%d]: invalid count %d", comp.componentID, i, inst.Count)
+			}
+			if count > 1 && (inst.Port != 0 || inst.StatusPort != 0) {
+				return nil, errors.Errorf("%s[%d]: the ports can't be shared by %d instances", comp.componentID, i, count)
+			}
+			if inst.ConfigPath != "" && len(inst.Config) > 0 {
+				return nil, errors.Errorf("%s[%d]: config and config_path can't be both specified", comp.componentID, i)
+			}
+
+			configPath := inst.ConfigPath
+			if len(inst.Config) > 0 {
+				configPath = filepath.Join(confDir, fmt.Sprintf("%s-%d.toml", comp.componentID, i))
+				if err := writeTomlConfig(configPath, inst.Config); err != nil {
+					return nil, errors.Annotatef(err, "%s[%d]", comp.componentID, i)
+				}
+			}
+
+			if cfg.Num == 0 {
+				cfg.BinPath = inst.BinPath
+				cfg.ConfigPath = configPath
+				cfg.Host = inst.Host
+			}
+			cfg.Num += count
+			for j := 0; j < count; j++ {
+				opt.instances = append(opt.instances, declaredInstance{
+					componentID: comp.componentID,
+					Config: instance.Config{
+						Num:        1,
+						Host:       inst.Host,
+						Port:       inst.Port,
+						StatusPort: inst.StatusPort,
+						BinPath:    inst.BinPath,
+						ConfigPath: configPath,
+					},
+				})
+			}
+		}
+	}
+	return opt, nil
+}
+
+// topologyOptions returns the options to boot the playground of the topology
+// file, which can't be mixed with the flags of the components
+func topologyOptions(flags *pflag.FlagSet, file string, flagOpt *bootOptions, dataDir string) (*bootOptions, error) {
+	for _, comp := range []string{"db", "kv", "pd", "tiflash", "pump", "drainer", "ticdc"} {
+		for _, suffix := range []string{"", ".host", ".config", ".binpath"} {
+			if f := flags.Lookup(comp + suffix); f != nil && f.Changed {
+				return nil, errors.Errorf("--%s can't be used with the topology file, declare it in %s instead", f.Name, file)
+			}
+		}
+	}
+	if dataDir == "" {
+		return nil, fmt.Errorf("cannot read environment variable %s", localdata.EnvNameInstanceDataDir)
+	}
+
+	t, err := loadTopology(file)
+	if err != nil {
+		return nil, err
+	}
+	opt, err := t.bootOptions(filepath.Join(dataDir, "conf"))
+	if err != nil {
+		return nil, errors.Annotatef(err, "invalid topology file %s", file)
+	}
+
+	// the options on the command line take precedence
+	if flagOpt.version != "" {
+		opt.version = flagOpt.version
+	}
+	if flags.Changed("host") {
+		opt.host = flagOpt.host
+	}
+	if flags.Changed("monitor") {
+		opt.monitor = flagOpt.monitor
+	}
+	opt.changefeed = flagOpt.changefeed
+	return opt, nil
+}
+
+// component returns the config of the component in the options
+func (o *bootOptions) component(componentID string) *instance.Config {
+	switch componentID {
+	case "pd":
+		return &o.pd
+	case "tikv":
+		return &o.tikv
+	case "tidb":
+		return &o.tidb
+	case "tiflash":
+		return &o.tiflash
+	case "pump":
+		return &o.pump
+	case "drainer":
+		return &o.drainer
+	case "ticdc":
+		return &o.ticdc
+	default:
+		panic(fmt.Sprintf("unknown component %s", componentID))
+	}
+}
+
+// writeTomlConfig writes the config declared in the topology file as toml,
+// the dotted keys, e.g., raftstore.sync-log, are expanded to tables
+func writeTomlConfig(path string, config map[string]interface{}) error {
+	result := make(map[string]interface{})
+	for key, value := range config {
+		setConfig(result, key, value)
+	}
+
+	buf := bytes.NewBufferString("# generated from the topology file of the playground\n")
+	if err := toml.NewEncoder(buf).Encode(result); err != nil {
+		return errors.AddStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(ioutil.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func setConfig(config map[string]interface{}, key string, value interface{}) {
+	if fields := strings.SplitN(key, ".", 2); len(fields) == 2 {
+		setConfig(subConfig(config, fields[0]), fields[1], value)
+		return
+	}
+	if m, ok := value.(map[interface{}]interface{}); ok {
+		sub := subConfig(config, key)
+		for k, v := range m {
+			setConfig(sub, fmt.Sprint(k), v)
+		}
+		return
+	}
+	config[key] = tomlValue(value)
+}
+
+func subConfig(config map[string]interface{}, key string) map[string]interface{} {
+	sub, ok := config[key].(map[string]interface{})
+	if !ok {
+		sub = make(map[string]interface{})
+		config[key] = sub
+	}
+	return sub
+}
+
+// tomlValue converts the maps decoded by yaml, which can't be encoded as toml
+func tomlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for k, val := range v {
+			setConfig(m, fmt.Sprint(k), val)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = tomlValue(v[i])
+		}
+	}
+	return value
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopology(t *testing.T) {
+	dir, err := ioutil.TempDir("", "play_topology_test_*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "playground.yaml")
+	err = ioutil.WriteFile(file, []byte(`
+version: v4.0.8
+pd:
+  - count: 1
+tikv:
+  - binpath: bin/tikv-server
+    config:
+      raftstore.sync-log: false
+      storage:
+        reserve-space: 0MB
+  - count: 2
+tidb:
+  - port: 4000
+    status_port: 10080
+    config_path: /conf/tidb.toml
+tiflash:
+  - host: 0.0.0.0
+`), 0644)
+	assert.Nil(t, err)
+
+	topo, err := loadTopology(file)
+	assert.Nil(t, err)
+	opt, err := topo.bootOptions(filepath.Join(dir, "conf"))
+	assert.Nil(t, err)
+	assert.Equal(t, "v4.0.8", opt.version)
+	assert.Equal(t, "127.0.0.1", opt.host)
+	assert.Equal(t, 1, opt.pd.Num)
+	assert.Equal(t, 3, opt.tikv.Num)
+	assert.Equal(t, 1, opt.tidb.Num)
+	assert.Equal(t, 0, opt.pump.Num)
+	assert.Equal(t, 1, opt.tiflash.Num)
+
+	// the instances are in the order they are added
+	var ids []string
+	for _, inst := range opt.instances {
+		ids = append(ids, inst.componentID)
+	}
+	assert.Equal(t, []string{"pd", "tikv", "tikv", "tikv", "tidb", "tiflash"}, ids)
+
+	// the custom binary is only used by the first TiKV
+	custom := opt.instances[1]
+	assert.Equal(t, filepath.Join(dir, "bin/tikv-server"), custom.BinPath)
+	assert.Equal(t, "", opt.instances[2].BinPath)
+	assert.Equal(t, custom.BinPath, opt.tikv.BinPath)
+
+	var config map[string]interface{}
+	_, err = toml.DecodeFile(custom.ConfigPath, &config)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"raftstore": map[string]interface{}{"sync-log": false},
+		"storage":   map[string]interface{}{"reserve-space": "0MB"},
+	}, config)
+
+	assert.Equal(t, 4000, opt.instances[4].Port)
+	assert.Equal(t, 10080, opt.instances[4].StatusPort)
+	assert.Equal(t, "/conf/tidb.toml", opt.instances[4].ConfigPath)
+	assert.Equal(t, "0.0.0.0", opt.instances[5].Host)
+
+	// the ports can't be shared
+	topo.TiKV[1].Port = 20160
+	_, err = topo.bootOptions(filepath.Join(dir, "conf"))
+	assert.NotNil(t, err)
+	topo.TiKV[1].Port = 0
+
+	topo.TiDB[0].Config = map[string]interface{}{"mem-quota-query": 1}
+	_, err = topo.bootOptions(filepath.Join(dir, "conf"))
+	assert.NotNil(t, err)
+
+	// unknown fields are rejected
+	err = ioutil.WriteFile(file, []byte("tikv:\n  - binary: tikv-server\n"), 0644)
+	assert.Nil(t, err)
+	_, err = loadTopology(file)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cliutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil/progress"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"gopkg.in/yaml.v2"
+)
+
+// The formats of the results printed by commands
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+	OutputFormatYAML = "yaml"
+)
+
+var (
+	outputFormat            = OutputFormatText
+	resultOutput  io.Writer = os.Stdout
+	messageOutput io.Writer = os.Stdout // the writer of the prompts
+)
+
+// SetOutputFormat sets the format of the results printed by commands. For the
+// structured formats, the logs, progress bars and prompts are written to stderr
+// so that stdout contains nothing but the result.
+func SetOutputFormat(format string) error {
+	switch format {
+	case OutputFormatText:
+	case OutputFormatJSON, OutputFormatYAML:
+		messageOutput = os.Stderr
+		log.SetOutput(os.Stderr)
+		progress.SetOutput(os.Stderr)
+		color.NoColor = true
+	default:
+		return errors.Errorf("unsupported output format '%s', supported formats are %s, %s and %s",
+			format, OutputFormatText, OutputFormatJSON, OutputFormatYAML)
+	}
+	outputFormat = format
+	return nil
+}
+
+// IsStructuredOutput returns true if the results are printed in a machine
+// readable format instead of tables and colored text
+func IsStructuredOutput() bool {
+	return outputFormat != OutputFormatText
+}
+
+// PrintResult prints the result in the structured output format
+func PrintResult(v interface{}) error {
+	switch outputFormat {
+	case OutputFormatJSON:
+		enc := json.NewEncoder(resultOutput)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return errors.AddStack(enc.Encode(v))
+	case OutputFormatYAML:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		_, err = resultOutput.Write(data)
+		return errors.AddStack(err)
+	default:
+		return errors.Errorf("the result can't be printed in the %s format", outputFormat)
+	}
+}
+
+// ParseResult parses the result printed by PrintResult in the same format, so
+// that the results of the sub-processes can be embedded in the result
+func ParseResult(data []byte) (interface{}, error) {
+	switch outputFormat {
+	case OutputFormatJSON:
+		if !json.Valid(data) {
+			return nil, errors.New("the result is not valid JSON")
+		}
+		return json.RawMessage(bytes.TrimSpace(data)), nil
+	case OutputFormatYAML:
+		var v yaml.MapSlice
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, errors.AddStack(err)
+		}
+		return v, nil
+	default:
+		return nil, errors.Errorf("the result can't be parsed in the %s format", outputFormat)
+	}
+}
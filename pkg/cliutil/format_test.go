@@ -0,0 +1,105 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cliutil
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cliutil/progress"
+	"github.com/pingcap/tiup/pkg/logger/log"
+)
+
+func TestCliUtil(t *testing.T) {
+	check.TestingT(t)
+}
+
+type formatSuite struct{}
+
+var _ = check.Suite(&formatSuite{})
+
+func (s *formatSuite) TestPrintResult(c *check.C) {
+	noColor := color.NoColor
+	defer func() {
+		color.NoColor = noColor
+		outputFormat, resultOutput, messageOutput = OutputFormatText, os.Stdout, os.Stdout
+		log.SetOutput(os.Stdout)
+		progress.SetOutput(os.Stdout)
+	}()
+
+	c.Assert(SetOutputFormat("xml"), check.NotNil)
+	c.Assert(SetOutputFormat(OutputFormatText), check.IsNil)
+	c.Assert(IsStructuredOutput(), check.IsFalse)
+	c.Assert(messageOutput, check.Equals, os.Stdout)
+
+	result := struct {
+		Name  string   `json:"name" yaml:"name"`
+		Hosts []string `json:"hosts" yaml:"hosts"`
+	}{"test", []string{"172.16.5.1"}}
+
+	// the other messages go to stderr
+	c.Assert(SetOutputFormat(OutputFormatJSON), check.IsNil)
+	c.Assert(IsStructuredOutput(), check.IsTrue)
+	c.Assert(messageOutput, check.Equals, os.Stderr)
+	buf := &bytes.Buffer{}
+	resultOutput = buf
+	c.Assert(PrintResult(result), check.IsNil)
+	c.Assert(buf.String(), check.Equals, "{\n  \"name\": \"test\",\n  \"hosts\": [\n    \"172.16.5.1\"\n  ]\n}\n")
+
+	c.Assert(SetOutputFormat(OutputFormatYAML), check.IsNil)
+	buf.Reset()
+	resultOutput = buf
+	c.Assert(PrintResult(result), check.IsNil)
+	c.Assert(buf.String(), check.Equals, "name: test\nhosts:\n- 172.16.5.1\n")
+}
+
+func (s *formatSuite) TestParseResult(c *check.C) {
+	noColor := color.NoColor
+	defer func() {
+		color.NoColor = noColor
+		outputFormat, resultOutput, messageOutput = OutputFormatText, os.Stdout, os.Stdout
+		log.SetOutput(os.Stdout)
+		progress.SetOutput(os.Stdout)
+	}()
+
+	result := struct {
+		Name    string      `json:"name" yaml:"name"`
+		Results interface{} `json:"results" yaml:"results"`
+	}{Name: "test"}
+	buf := &bytes.Buffer{}
+	for _, format := range []string{OutputFormatJSON, OutputFormatYAML} {
+		c.Assert(SetOutputFormat(format), check.IsNil)
+		_, err := ParseResult([]byte("{"))
+		c.Assert(err, check.NotNil)
+
+		v, err := ParseResult([]byte("{\"b\": 1, \"a\": [\"x\"]}\n"))
+		c.Assert(err, check.IsNil)
+		result.Results = v
+		buf.Reset()
+		resultOutput = buf
+		c.Assert(PrintResult(result), check.IsNil)
+		if format == OutputFormatJSON {
+			c.Assert(buf.String(), check.Equals, "{\n  \"name\": \"test\",\n  \"results\": {\n    \"b\": 1,\n    \"a\": [\n      \"x\"\n    ]\n  }\n}\n")
+		} else {
+			c.Assert(buf.String(), check.Equals, "name: test\nresults:\n  b: 1\n  a:\n  - x\n")
+		}
+	}
+
+	c.Assert(SetOutputFormat(OutputFormatText), check.IsNil)
+	_, err := ParseResult([]byte("{}"))
+	c.Assert(err, check.NotNil)
+}
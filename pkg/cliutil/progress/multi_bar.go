@@ -16,7 +16,6 @@ package progress
 import (
 	"bufio"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/mattn/go-runewidth"
@@ -77,11 +76,11 @@ func (b *MultiBar) StopRenderLoop() {
 
 func (b *MultiBar) preRender() {
 	// Preserve space for the bar
-	fmt.Print(strings.Repeat("\n", len(b.bars)+1))
+	fmt.Fprint(output, strings.Repeat("\n", len(b.bars)+1))
 }
 
 func (b *MultiBar) render() {
-	f := bufio.NewWriter(os.Stdout)
+	f := bufio.NewWriter(output)
 
 	y := int(termSizeHeight.Load()) - 1
 	movedY := 0
@@ -15,6 +15,7 @@ package progress
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -33,6 +34,14 @@ var (
 
 var refreshRate = time.Millisecond * 50
 
+// output is the writer the bars are rendered to
+var output io.Writer = os.Stdout
+
+// SetOutput sets the writer the bars are rendered to
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 const (
 	doneTail  = "Done"
 	errorTail = "Error"
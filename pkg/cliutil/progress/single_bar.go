@@ -17,7 +17,6 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/fatih/color"
 	"github.com/mattn/go-runewidth"
@@ -137,11 +136,11 @@ func (b *SingleBar) StopRenderLoop() {
 
 func (b *SingleBar) preRender() {
 	// Preserve space for the bar
-	fmt.Println("")
+	fmt.Fprintln(output, "")
 }
 
 func (b *SingleBar) render() {
-	f := bufio.NewWriter(os.Stdout)
+	f := bufio.NewWriter(output)
 
 	moveCursorUp(f, 1)
 	moveCursorToLineStart(f)
@@ -58,7 +58,7 @@ func Prompt(prompt string) string {
 	if prompt != "" {
 		prompt += " " // append a whitespace
 	}
-	fmt.Print(prompt)
+	fmt.Fprint(messageOutput, prompt)
 
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
@@ -89,9 +89,9 @@ func PromptForConfirmOrAbortError(format string, a ...interface{}) error {
 
 // PromptForPassword reads a password input from console
 func PromptForPassword(format string, a ...interface{}) string {
-	defer fmt.Println("")
+	defer fmt.Fprintln(messageOutput, "")
 
-	fmt.Printf(format, a...)
+	fmt.Fprintf(messageOutput, format, a...)
 
 	input, err := terminal.ReadPassword(syscall.Stdin)
 
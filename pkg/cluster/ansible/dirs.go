@@ -32,13 +32,16 @@ var (
 func parseDirs(user string, ins meta.InstanceSpec, sshTimeout int64) (meta.InstanceSpec, error) {
 	hostName, sshPort := ins.SSH()
 
-	e := executor.NewSSHExecutor(executor.SSHConfig{
+	e, err := executor.New(executor.SSHConfig{
 		Host:    hostName,
 		Port:    sshPort,
 		User:    user,
 		KeyFile: SSHKeyPath(), // ansible generated keyfile
 		Timeout: time.Second * time.Duration(sshTimeout),
 	}, false) // not using global sudo
+	if err != nil {
+		return ins, err
+	}
 	log.Debugf("Detecting deploy paths on %s...", hostName)
 
 	stdout, err := readStartScript(e, ins.Role(), hostName, ins.GetMainPort())
@@ -225,7 +228,7 @@ func parseDirs(user string, ins meta.InstanceSpec, sshTimeout int64) (meta.Insta
 	return ins, nil
 }
 
-func readStartScript(e *executor.SSHExecutor, component, host string, port int) (string, error) {
+func readStartScript(e executor.TiOpsExecutor, component, host string, port int) (string, error) {
 	serviceFile := fmt.Sprintf("%s/%s-%d.service",
 		systemdUnitPath,
 		component,
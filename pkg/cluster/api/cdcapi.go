@@ -0,0 +1,273 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+	tiupver "github.com/pingcap/tiup/pkg/version"
+	"golang.org/x/mod/semver"
+)
+
+// CDCChangefeedClient manages the changefeeds of a TiCDC cluster
+type CDCChangefeedClient interface {
+	// ListChangefeeds queries the changefeeds in the state, the state is one of
+	// normal, stopped, error, failed, finished and all, empty means normal
+	ListChangefeeds(state string) ([]CDCChangefeed, error)
+	// CreateChangefeed creates a changefeed
+	CreateChangefeed(cfg *CDCChangefeedConfig) error
+	// PauseChangefeed pauses a changefeed
+	PauseChangefeed(id string) error
+	// ResumeChangefeed resumes a paused changefeed
+	ResumeChangefeed(id string) error
+	// RemoveChangefeed removes a changefeed
+	RemoveChangefeed(id string) error
+}
+
+// cdcOpenAPIVersion is the first version of TiCDC serving the open API
+const cdcOpenAPIVersion = "v5.3.0"
+
+// NewCDCChangefeedClient returns the client of the changefeeds of the TiCDC in
+// the version, the open API is used since v5.3.0 and the owner API with
+// `cdc cli` run by cli is used by the earlier versions
+func NewCDCChangefeedClient(version string, addrs []string, timeout time.Duration, tlsConfig *tls.Config, cli CDCCliRunner) CDCChangefeedClient {
+	if version == tiupver.NightlyVersion || semver.Compare(version, cdcOpenAPIVersion) >= 0 {
+		return NewCDCOpenAPIClient(addrs, timeout, tlsConfig)
+	}
+	return NewCDCOwnerAPIClient(addrs, timeout, tlsConfig, cli)
+}
+
+// CDCOpenAPIClient is an HTTP client of the open API of TiCDC, the requests
+// are sent to the owner capture first and then to the others
+type CDCOpenAPIClient struct {
+	addrs      []string
+	tlsEnabled bool
+	httpClient *utils.HTTPClient
+}
+
+// NewCDCOpenAPIClient returns a new CDCOpenAPIClient
+func NewCDCOpenAPIClient(addrs []string, timeout time.Duration, tlsConfig *tls.Config) *CDCOpenAPIClient {
+	return &CDCOpenAPIClient{
+		addrs:      addrs,
+		tlsEnabled: tlsConfig != nil,
+		httpClient: utils.NewHTTPClient(timeout, tlsConfig),
+	}
+}
+
+// GetURL builds the the client URL of CDCOpenAPIClient
+func (c *CDCOpenAPIClient) GetURL(addr string) string {
+	httpPrefix := "http"
+	if c.tlsEnabled {
+		httpPrefix = "https"
+	}
+	return fmt.Sprintf("%s://%s", httpPrefix, addr)
+}
+
+var (
+	cdcCapturesURI    = "api/v1/captures"
+	cdcChangefeedsURI = "api/v1/changefeeds"
+)
+
+// CDCCapture is a capture (TiCDC server) in the TiCDC cluster
+type CDCCapture struct {
+	ID      string `json:"id" yaml:"id"`
+	IsOwner bool   `json:"is_owner" yaml:"is_owner"`
+	Address string `json:"address" yaml:"address"`
+}
+
+// CDCRunningError is the error occurred in a running changefeed
+type CDCRunningError struct {
+	Addr    string `json:"addr" yaml:"addr"`
+	Code    string `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// CDCChangefeed is the brief status of a changefeed
+type CDCChangefeed struct {
+	ID             string           `json:"id" yaml:"id"`
+	State          string           `json:"state" yaml:"state"`
+	CheckpointTSO  uint64           `json:"checkpoint_tso" yaml:"checkpoint_tso"`
+	CheckpointTime string           `json:"checkpoint_time" yaml:"checkpoint_time"`
+	Error          *CDCRunningError `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// CDCChangefeedConfig is the config to create a changefeed
+type CDCChangefeedConfig struct {
+	ID                    string   `json:"changefeed_id"`
+	SinkURI               string   `json:"sink_uri"`
+	StartTS               uint64   `json:"start_ts,omitempty"`
+	TargetTS              uint64   `json:"target_ts,omitempty"`
+	ForceReplicate        bool     `json:"force_replicate,omitempty"`
+	IgnoreIneligibleTable bool     `json:"ignore_ineligible_table,omitempty"`
+	FilterRules           []string `json:"filter_rules,omitempty"`
+}
+
+// cdcAPIError is the error responded by the TiCDC open API
+type cdcAPIError struct {
+	Message string `json:"error_msg"`
+	Code    string `json:"error_code"`
+}
+
+// apiError extracts the error message from the body of a failed request, nil
+// is returned if the body is not an error of the API (e.g., the server is down)
+func apiError(body []byte) error {
+	var e cdcAPIError
+	if len(body) == 0 || json.Unmarshal(body, &e) != nil || e.Message == "" {
+		return nil
+	}
+	if e.Code != "" {
+		return errors.Errorf("%s (%s)", e.Message, e.Code)
+	}
+	return errors.New(e.Message)
+}
+
+// GetCaptures queries the captures of the TiCDC cluster
+func (c *CDCOpenAPIClient) GetCaptures() ([]CDCCapture, error) {
+	var endpoints []string
+	for _, addr := range c.addrs {
+		endpoints = append(endpoints, fmt.Sprintf("%s/%s", c.GetURL(addr), cdcCapturesURI))
+	}
+
+	captures := []CDCCapture{}
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		body, err := c.httpClient.Get(endpoint)
+		if err != nil {
+			return body, err
+		}
+
+		return body, json.Unmarshal(body, &captures)
+	})
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	return captures, nil
+}
+
+// getEndpoints returns the endpoints of all the captures with the owner first
+func (c *CDCOpenAPIClient) getEndpoints(cmd string) (endpoints []string) {
+	addrs := c.addrs
+	if captures, err := c.GetCaptures(); err == nil {
+		for _, capture := range captures {
+			if capture.IsOwner {
+				addrs = append([]string{capture.Address}, addrs...)
+				break
+			}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	for _, addr := range addrs {
+		if _, found := seen[addr]; found {
+			continue
+		}
+		seen[addr] = struct{}{}
+		endpoints = append(endpoints, fmt.Sprintf("%s/%s", c.GetURL(addr), cmd))
+	}
+	return
+}
+
+// request sends the request to the captures until one of them responds, the
+// error responded by the API is returned immediately without retrying others
+func (c *CDCOpenAPIClient) request(cmd string, f func(endpoint string) ([]byte, error)) ([]byte, error) {
+	var (
+		body []byte
+		err  error
+	)
+	for _, endpoint := range c.getEndpoints(cmd) {
+		body, err = f(endpoint)
+		if err == nil {
+			return body, nil
+		}
+		if apiErr := apiError(body); apiErr != nil {
+			return body, apiErr
+		}
+	}
+	if len(c.addrs) > 1 && err != nil {
+		err = errors.Errorf("no cdc endpoint available, the last err is: %s", err)
+	}
+	return body, err
+}
+
+// ListChangefeeds queries the changefeeds in the state, the state is one of
+// normal, stopped, error, failed, finished and all, empty means normal
+func (c *CDCOpenAPIClient) ListChangefeeds(state string) ([]CDCChangefeed, error) {
+	cmd := cdcChangefeedsURI
+	if state != "" {
+		cmd = fmt.Sprintf("%s?state=%s", cmd, url.QueryEscape(state))
+	}
+
+	changefeeds := []CDCChangefeed{}
+	_, err := c.request(cmd, func(endpoint string) ([]byte, error) {
+		body, err := c.httpClient.Get(endpoint)
+		if err != nil {
+			return body, err
+		}
+
+		return body, json.Unmarshal(body, &changefeeds)
+	})
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	return changefeeds, nil
+}
+
+// CreateChangefeed creates a changefeed
+func (c *CDCOpenAPIClient) CreateChangefeed(cfg *CDCChangefeedConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	_, err = c.request(cdcChangefeedsURI, func(endpoint string) ([]byte, error) {
+		return c.httpClient.Post(endpoint, bytes.NewReader(data))
+	})
+	return errors.AddStack(err)
+}
+
+// PauseChangefeed pauses a changefeed
+func (c *CDCOpenAPIClient) PauseChangefeed(id string) error {
+	return c.changefeedAction(id, "pause")
+}
+
+// ResumeChangefeed resumes a paused changefeed
+func (c *CDCOpenAPIClient) ResumeChangefeed(id string) error {
+	return c.changefeedAction(id, "resume")
+}
+
+func (c *CDCOpenAPIClient) changefeedAction(id, action string) error {
+	cmd := fmt.Sprintf("%s/%s/%s", cdcChangefeedsURI, url.PathEscape(id), action)
+	_, err := c.request(cmd, func(endpoint string) ([]byte, error) {
+		return c.httpClient.Post(endpoint, nil)
+	})
+	return errors.AddStack(err)
+}
+
+// RemoveChangefeed removes a changefeed
+func (c *CDCOpenAPIClient) RemoveChangefeed(id string) error {
+	cmd := fmt.Sprintf("%s/%s", cdcChangefeedsURI, url.PathEscape(id))
+	_, err := c.request(cmd, func(endpoint string) ([]byte, error) {
+		body, _, err := c.httpClient.Delete(endpoint, nil)
+		return body, err
+	})
+	return errors.AddStack(err)
+}
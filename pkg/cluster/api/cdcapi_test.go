@@ -0,0 +1,164 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+func TestAPI(t *testing.T) {
+	check.TestingT(t)
+}
+
+type cdcAPISuite struct{}
+
+var _ = check.Suite(&cdcAPISuite{})
+
+// fakeCDC is a capture serving the open API, only the owner handles the
+// changefeed requests so that the client is verified to find the owner
+type fakeCDC struct {
+	server      *httptest.Server
+	owner       bool
+	ownerAddr   string
+	changefeeds map[string]string // id -> state
+	requests    []string
+}
+
+func newFakeCDC(owner bool) *fakeCDC {
+	f := &fakeCDC{owner: owner, changefeeds: map[string]string{"cf-1": "normal"}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+func (f *fakeCDC) addr() string {
+	return strings.TrimPrefix(f.server.URL, "http://")
+}
+
+func (f *fakeCDC) serve(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if r.URL.Path == "/"+cdcCapturesURI {
+		captures := []CDCCapture{{ID: "owner", IsOwner: true, Address: f.ownerAddr}}
+		_ = json.NewEncoder(w).Encode(captures)
+		return
+	}
+	if !f.owner {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	fail := func(msg string) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error_msg":%q,"error_code":"CDC:ErrAPIInvalidParam"}`, msg)
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/"+cdcChangefeedsURI)
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		changefeeds := []CDCChangefeed{}
+		for id, state := range f.changefeeds {
+			if s := r.URL.Query().Get("state"); s == "all" || s == state {
+				changefeeds = append(changefeeds, CDCChangefeed{ID: id, State: state})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(changefeeds)
+	case path == "" && r.Method == http.MethodPost:
+		data, _ := ioutil.ReadAll(r.Body)
+		var cfg CDCChangefeedConfig
+		if err := json.Unmarshal(data, &cfg); err != nil || cfg.SinkURI == "" {
+			fail("sink uri is required")
+			return
+		}
+		if _, found := f.changefeeds[cfg.ID]; found {
+			fail("changefeed already exists")
+			return
+		}
+		f.changefeeds[cfg.ID] = "normal"
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		if _, found := f.changefeeds[parts[0]]; !found {
+			fail("changefeed not exists")
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(f.changefeeds, parts[0])
+		case len(parts) == 2 && parts[1] == "pause":
+			f.changefeeds[parts[0]] = "stopped"
+		case len(parts) == 2 && parts[1] == "resume":
+			f.changefeeds[parts[0]] = "normal"
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *cdcAPISuite) TestChangefeed(c *check.C) {
+	follower := newFakeCDC(false)
+	defer follower.server.Close()
+	owner := newFakeCDC(true)
+	defer owner.server.Close()
+	follower.ownerAddr = owner.addr()
+	owner.ownerAddr = owner.addr()
+
+	client := NewCDCOpenAPIClient([]string{follower.addr()}, 5*time.Second, nil)
+
+	captures, err := client.GetCaptures()
+	c.Assert(err, check.IsNil)
+	c.Assert(captures, check.HasLen, 1)
+	c.Assert(captures[0].IsOwner, check.IsTrue)
+
+	// the requests of changefeeds are sent to the owner
+	c.Assert(client.CreateChangefeed(&CDCChangefeedConfig{ID: "cf-2", SinkURI: "blackhole://"}), check.IsNil)
+	c.Assert(owner.changefeeds["cf-2"], check.Equals, "normal")
+	for _, req := range follower.requests {
+		c.Assert(req, check.Equals, "GET /"+cdcCapturesURI)
+	}
+
+	c.Assert(client.PauseChangefeed("cf-2"), check.IsNil)
+	stopped, err := client.ListChangefeeds("stopped")
+	c.Assert(err, check.IsNil)
+	c.Assert(stopped, check.DeepEquals, []CDCChangefeed{{ID: "cf-2", State: "stopped"}})
+
+	c.Assert(client.ResumeChangefeed("cf-2"), check.IsNil)
+	c.Assert(client.RemoveChangefeed("cf-1"), check.IsNil)
+	all, err := client.ListChangefeeds("all")
+	c.Assert(err, check.IsNil)
+	c.Assert(all, check.DeepEquals, []CDCChangefeed{{ID: "cf-2", State: "normal"}})
+
+	// the errors of the API are returned as is
+	err = client.CreateChangefeed(&CDCChangefeedConfig{ID: "cf-2", SinkURI: "blackhole://"})
+	c.Assert(err, check.ErrorMatches, `changefeed already exists \(CDC:ErrAPIInvalidParam\)`)
+	err = client.PauseChangefeed("cf-3")
+	c.Assert(err, check.ErrorMatches, "changefeed not exists.*")
+}
+
+func (s *cdcAPISuite) TestUnavailable(c *check.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	addr := strings.TrimPrefix(server.URL, "http://")
+	server.Close()
+
+	client := NewCDCOpenAPIClient([]string{addr, addr}, time.Second, nil)
+	_, err := client.ListChangefeeds("")
+	c.Assert(err, check.ErrorMatches, "no cdc endpoint available.*")
+}
@@ -0,0 +1,266 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// CDCCliRunner runs `cdc cli` with the args and returns its stdout, the PD
+// addresses and the TLS options are added by the runner. The config is the
+// content of the changefeed config file to pass by --config if not empty.
+type CDCCliRunner func(args []string, config []byte) ([]byte, error)
+
+// CDCOwnerAPIClient manages the changefeeds of the TiCDC earlier than v5.3.0,
+// which has no open API. The changefeeds are queried and administered through
+// the API of the owner capture, and they are listed and created by `cdc cli`.
+type CDCOwnerAPIClient struct {
+	addrs      []string
+	tlsEnabled bool
+	httpClient *utils.HTTPClient
+	cli        CDCCliRunner
+}
+
+// NewCDCOwnerAPIClient returns a new CDCOwnerAPIClient
+func NewCDCOwnerAPIClient(addrs []string, timeout time.Duration, tlsConfig *tls.Config, cli CDCCliRunner) *CDCOwnerAPIClient {
+	return &CDCOwnerAPIClient{
+		addrs:      addrs,
+		tlsEnabled: tlsConfig != nil,
+		httpClient: utils.NewHTTPClient(timeout, tlsConfig),
+		cli:        cli,
+	}
+}
+
+// GetURL builds the the client URL of CDCOwnerAPIClient
+func (c *CDCOwnerAPIClient) GetURL(addr string) string {
+	httpPrefix := "http"
+	if c.tlsEnabled {
+		httpPrefix = "https"
+	}
+	return fmt.Sprintf("%s://%s", httpPrefix, addr)
+}
+
+var (
+	cdcStatusURI     = "status"
+	cdcOwnerQueryURI = "capture/owner/changefeed/query"
+	cdcOwnerAdminURI = "capture/owner/admin"
+)
+
+// the types of the admin jobs of the owner API
+const (
+	cdcAdminStop   = 1
+	cdcAdminResume = 2
+	cdcAdminRemove = 3
+)
+
+// cdcStatus is the status of a capture
+type cdcStatus struct {
+	IsOwner bool `json:"is_owner"`
+}
+
+// cdcOwnerChangefeed is a changefeed queried from the owner API
+type cdcOwnerChangefeed struct {
+	State      string           `json:"state"`
+	TSO        uint64           `json:"tso"`
+	Checkpoint string           `json:"checkpoint"`
+	Error      *CDCRunningError `json:"error"`
+}
+
+// cdcCliChangefeed is a changefeed listed by `cdc cli changefeed list`
+type cdcCliChangefeed struct {
+	ID string `json:"id"`
+}
+
+// cdcReplicaConfig is the changefeed config file of `cdc cli`
+type cdcReplicaConfig struct {
+	ForceReplicate bool `toml:"force-replicate,omitempty"`
+	Filter         struct {
+		Rules []string `toml:"rules,omitempty"`
+	} `toml:"filter"`
+}
+
+// getOwner returns the address of the owner capture
+func (c *CDCOwnerAPIClient) getOwner() (string, error) {
+	var err error
+	for _, addr := range c.addrs {
+		var body []byte
+		body, err = c.httpClient.Get(fmt.Sprintf("%s/%s", c.GetURL(addr), cdcStatusURI))
+		if err != nil {
+			continue
+		}
+		var status cdcStatus
+		if err = json.Unmarshal(body, &status); err == nil && status.IsOwner {
+			return addr, nil
+		}
+	}
+	if err != nil {
+		return "", errors.Errorf("no cdc endpoint available, the last err is: %s", err)
+	}
+	return "", errors.New("the owner of cdc is not found")
+}
+
+// ownerRequest posts the form to the owner capture, the error responded by the
+// API is in plain text
+func (c *CDCOwnerAPIClient) ownerRequest(cmd string, form url.Values) ([]byte, error) {
+	owner, err := c.getOwner()
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.httpClient.PostForm(fmt.Sprintf("%s/%s", c.GetURL(owner), cmd), form)
+	if err != nil {
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return body, errors.New(msg)
+		}
+		return body, errors.AddStack(err)
+	}
+	return body, nil
+}
+
+// QueryChangefeed queries the status of the changefeed, an error is returned if
+// it doesn't exist
+func (c *CDCOwnerAPIClient) QueryChangefeed(id string) (*CDCChangefeed, error) {
+	body, err := c.ownerRequest(cdcOwnerQueryURI, url.Values{"cf-id": {id}})
+	if err != nil {
+		return nil, err
+	}
+	var cf cdcOwnerChangefeed
+	if err := json.Unmarshal(body, &cf); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	// the owner responds an empty status for the changefeed not exists
+	if cf.State == "" {
+		return nil, errors.Errorf("changefeed %s not exists", id)
+	}
+	return &CDCChangefeed{
+		ID:             id,
+		State:          cf.State,
+		CheckpointTSO:  cf.TSO,
+		CheckpointTime: cf.Checkpoint,
+		Error:          cf.Error,
+	}, nil
+}
+
+// ListChangefeeds implements CDCChangefeedClient, the changefeeds are listed by
+// `cdc cli` and their status is queried from the owner
+func (c *CDCOwnerAPIClient) ListChangefeeds(state string) ([]CDCChangefeed, error) {
+	if state == "" {
+		state = "normal"
+	}
+
+	stdout, err := c.cli([]string{"changefeed", "list"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var listed []cdcCliChangefeed
+	if err := json.Unmarshal(cliJSON(stdout), &listed); err != nil {
+		return nil, errors.Annotatef(err, "parse the changefeeds: %s", stdout)
+	}
+
+	changefeeds := []CDCChangefeed{}
+	for _, l := range listed {
+		cf, err := c.QueryChangefeed(l.ID)
+		if err != nil {
+			return nil, err
+		}
+		if state == "all" || cf.State == state {
+			changefeeds = append(changefeeds, *cf)
+		}
+	}
+	return changefeeds, nil
+}
+
+// cliJSON returns the JSON array printed by `cdc cli`, the logs may be printed
+// before it
+func cliJSON(stdout []byte) []byte {
+	offset := 0
+	for _, line := range bytes.SplitAfter(stdout, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.Equal(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("[]")) || bytes.HasPrefix(trimmed, []byte("[{")) {
+			return stdout[offset:]
+		}
+		offset += len(line)
+	}
+	return stdout
+}
+
+// CreateChangefeed implements CDCChangefeedClient, the changefeed is created by
+// `cdc cli`
+func (c *CDCOwnerAPIClient) CreateChangefeed(cfg *CDCChangefeedConfig) error {
+	args := []string{
+		"changefeed", "create",
+		"--changefeed-id=" + cfg.ID,
+		"--sink-uri=" + cfg.SinkURI,
+	}
+	if cfg.StartTS > 0 {
+		args = append(args, "--start-ts="+strconv.FormatUint(cfg.StartTS, 10))
+	}
+	if cfg.TargetTS > 0 {
+		args = append(args, "--target-ts="+strconv.FormatUint(cfg.TargetTS, 10))
+	}
+	if cfg.IgnoreIneligibleTable {
+		args = append(args, "--no-confirm")
+	}
+
+	var config []byte
+	if cfg.ForceReplicate || len(cfg.FilterRules) > 0 {
+		rc := cdcReplicaConfig{ForceReplicate: cfg.ForceReplicate}
+		rc.Filter.Rules = cfg.FilterRules
+		buf := &bytes.Buffer{}
+		if err := toml.NewEncoder(buf).Encode(rc); err != nil {
+			return errors.AddStack(err)
+		}
+		config = buf.Bytes()
+	}
+
+	_, err := c.cli(args, config)
+	return err
+}
+
+// PauseChangefeed implements CDCChangefeedClient
+func (c *CDCOwnerAPIClient) PauseChangefeed(id string) error {
+	return c.adminChangefeed(id, cdcAdminStop)
+}
+
+// ResumeChangefeed implements CDCChangefeedClient
+func (c *CDCOwnerAPIClient) ResumeChangefeed(id string) error {
+	return c.adminChangefeed(id, cdcAdminResume)
+}
+
+// RemoveChangefeed implements CDCChangefeedClient
+func (c *CDCOwnerAPIClient) RemoveChangefeed(id string) error {
+	return c.adminChangefeed(id, cdcAdminRemove)
+}
+
+func (c *CDCOwnerAPIClient) adminChangefeed(id string, job int) error {
+	// the owner accepts the jobs of the changefeeds not exist
+	if _, err := c.QueryChangefeed(id); err != nil {
+		return err
+	}
+	_, err := c.ownerRequest(cdcOwnerAdminURI, url.Values{
+		"cf-id":     {id},
+		"admin-job": {strconv.Itoa(job)},
+	})
+	return err
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+type cdcOwnerAPISuite struct{}
+
+var _ = check.Suite(&cdcOwnerAPISuite{})
+
+// fakeCDCv4 is a capture of TiCDC v4.0 serving the owner API, which is only
+// handled by the owner
+type fakeCDCv4 struct {
+	server      *httptest.Server
+	owner       bool
+	changefeeds map[string]string // id -> state
+	requests    []string
+}
+
+func newFakeCDCv4(owner bool) *fakeCDCv4 {
+	f := &fakeCDCv4{owner: owner, changefeeds: map[string]string{"cf-1": "normal"}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+func (f *fakeCDCv4) addr() string {
+	return strings.TrimPrefix(f.server.URL, "http://")
+}
+
+func (f *fakeCDCv4) serve(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if r.URL.Path == "/"+cdcStatusURI {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"version": "v4.0.9", "is_owner": f.owner})
+		return
+	}
+	if !f.owner {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("election: not leader"))
+		return
+	}
+
+	id := r.FormValue("cf-id")
+	switch r.URL.Path {
+	case "/" + cdcOwnerQueryURI:
+		resp := map[string]interface{}{"state": f.changefeeds[id], "tso": 0, "checkpoint": "", "error": nil}
+		_ = json.NewEncoder(w).Encode(resp)
+	case "/" + cdcOwnerAdminURI:
+		switch r.FormValue("admin-job") {
+		case "1":
+			f.changefeeds[id] = "stopped"
+		case "2":
+			f.changefeeds[id] = "normal"
+		case "3":
+			delete(f.changefeeds, id)
+		}
+		_, _ = w.Write([]byte(`{"status":true,"message":""}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// cli simulates `cdc cli` on the captures
+func (f *fakeCDCv4) cli(calls *[][]string) CDCCliRunner {
+	return func(args []string, config []byte) ([]byte, error) {
+		*calls = append(*calls, append(args, string(config)))
+		switch strings.Join(args[:2], " ") {
+		case "changefeed list":
+			listed := []map[string]string{}
+			for id := range f.changefeeds {
+				listed = append(listed, map[string]string{"id": id})
+			}
+			data, _ := json.MarshalIndent(listed, "", "  ")
+			return append([]byte("[2020/09/01 10:00:00.000 +08:00] [INFO] [log.go:1] [\"a log\"]\n"), data...), nil
+		case "changefeed create":
+			f.changefeeds[strings.TrimPrefix(args[2], "--changefeed-id=")] = "normal"
+		}
+		return nil, nil
+	}
+}
+
+func (s *cdcOwnerAPISuite) TestChangefeed(c *check.C) {
+	follower := newFakeCDCv4(false)
+	defer follower.server.Close()
+	owner := newFakeCDCv4(true)
+	defer owner.server.Close()
+
+	var calls [][]string
+	client := NewCDCChangefeedClient("v4.0.9", []string{follower.addr(), owner.addr()}, 5*time.Second, nil, owner.cli(&calls))
+	c.Assert(client, check.FitsTypeOf, &CDCOwnerAPIClient{})
+
+	cfg := &CDCChangefeedConfig{ID: "cf-2", SinkURI: "blackhole://", StartTS: 100, FilterRules: []string{"db1.*"}}
+	c.Assert(client.CreateChangefeed(cfg), check.IsNil)
+	c.Assert(calls[0], check.DeepEquals, []string{
+		"changefeed", "create", "--changefeed-id=cf-2", "--sink-uri=blackhole://", "--start-ts=100",
+		"[filter]\n  rules = [\"db1.*\"]\n",
+	})
+
+	// the admin jobs are sent to the owner
+	c.Assert(client.PauseChangefeed("cf-2"), check.IsNil)
+	for _, req := range follower.requests {
+		c.Assert(req, check.Equals, "GET /"+cdcStatusURI)
+	}
+	stopped, err := client.ListChangefeeds("stopped")
+	c.Assert(err, check.IsNil)
+	c.Assert(stopped, check.DeepEquals, []CDCChangefeed{{ID: "cf-2", State: "stopped"}})
+
+	c.Assert(client.ResumeChangefeed("cf-2"), check.IsNil)
+	c.Assert(client.RemoveChangefeed("cf-1"), check.IsNil)
+	all, err := client.ListChangefeeds("all")
+	c.Assert(err, check.IsNil)
+	c.Assert(all, check.DeepEquals, []CDCChangefeed{{ID: "cf-2", State: "normal"}})
+
+	err = client.PauseChangefeed("cf-3")
+	c.Assert(err, check.ErrorMatches, "changefeed cf-3 not exists")
+
+	// the open API is used since v5.3.0
+	c.Assert(NewCDCChangefeedClient("v5.3.0", nil, time.Second, nil, nil), check.FitsTypeOf, &CDCOpenAPIClient{})
+	c.Assert(NewCDCChangefeedClient("nightly", nil, time.Second, nil, nil), check.FitsTypeOf, &CDCOpenAPIClient{})
+}
@@ -73,6 +73,7 @@ var (
 	pdSchedulersURI     = "pd/api/v1/schedulers"
 	pdLeaderURI         = "pd/api/v1/leader"
 	pdLeaderTransferURI = "pd/api/v1/leader/transfer"
+	pdRegionsCheckURI   = "pd/api/v1/regions/check"
 	pdConfigReplicate   = "pd/api/v1/config/replicate"
 	pdConfigSchedule    = "pd/api/v1/config/schedule"
 )
@@ -162,6 +163,31 @@ func (pc *PDClient) GetStores() (*pdserverapi.StoresInfo, error) {
 	return &storesInfo, nil
 }
 
+// GetRegionsCheckCount queries the number of regions in the abnormal state
+// from PD server, the state is one of miss-peer, extra-peer, down-peer,
+// pending-peer, offline-peer, empty-region, etc.
+func (pc *PDClient) GetRegionsCheckCount(state string) (int, error) {
+	endpoints := pc.getEndpoints(fmt.Sprintf("%s/%s", pdRegionsCheckURI, state))
+
+	regions := struct {
+		Count int `json:"count"`
+	}{}
+
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		body, err := pc.httpClient.Get(endpoint)
+		if err != nil {
+			return body, err
+		}
+
+		return body, json.Unmarshal(body, &regions)
+	})
+	if err != nil {
+		return 0, errors.AddStack(err)
+	}
+
+	return regions.Count, nil
+}
+
 // WaitLeader wait until there's a leader or timeout.
 func (pc *PDClient) WaitLeader(retryOpt *clusterutil.RetryOption) error {
 	if retryOpt == nil {
@@ -0,0 +1,230 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/base52"
+)
+
+// recordPrefix is the prefix of the line of the structured record, which is
+// the second line of the audit file following the command line, so that the
+// first line is still the command for the older versions
+const recordPrefix = "# tiup-audit: "
+
+// Record is the structured audit record of a command
+type Record struct {
+	ID        string    `json:"id" yaml:"id"`
+	Cluster   string    `json:"cluster,omitempty" yaml:"cluster,omitempty"`     // the cluster operated
+	User      string    `json:"user,omitempty" yaml:"user,omitempty"`           // the user running the command
+	Command   string    `json:"command" yaml:"command"`                         // the command line
+	Args      []string  `json:"args,omitempty" yaml:"args,omitempty"`           // the args of the command, without the program
+	Dir       string    `json:"dir,omitempty" yaml:"dir,omitempty"`             // the working directory of the command
+	StartTime time.Time `json:"start_time" yaml:"start_time"`                   // the time the command starts
+	EndTime   time.Time `json:"end_time" yaml:"end_time"`                       // the time the command finishes, zero if unknown
+	ExitCode  int       `json:"exit_code" yaml:"exit_code"`                     // the exit code of the command
+	Hosts     []string  `json:"hosts,omitempty" yaml:"hosts,omitempty"`         // the hosts touched by the command
+	DebugLog  string    `json:"debug_log,omitempty" yaml:"debug_log,omitempty"` // the path of the debug log written on failure
+}
+
+// Structured returns true if the record is written with the structured
+// fields, the records of the older versions only have the command line
+func (r *Record) Structured() bool {
+	return !r.EndTime.IsZero()
+}
+
+// Encode returns the content of the audit file of the record and the log
+func Encode(r *Record, log []byte) ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(r.Command)
+	buf.WriteString("\n")
+	buf.WriteString(recordPrefix)
+	buf.Write(data)
+	buf.WriteString("\n")
+	buf.Write(log)
+	return buf.Bytes(), nil
+}
+
+// Decode parses the audit file of the id, the record and the log are returned
+func Decode(id string, data []byte) (*Record, []byte, error) {
+	ts, err := base52.Decode(id)
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "unrecognized audit id '%s'", id)
+	}
+
+	lines := bytes.SplitN(data, []byte("\n"), 3)
+	if len(lines) < 2 {
+		return nil, nil, errors.Errorf("unknown audit log format of %s", id)
+	}
+
+	r := &Record{}
+	log := bytes.Join(lines[1:], []byte("\n"))
+	if line := string(lines[1]); strings.HasPrefix(line, recordPrefix) {
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, recordPrefix)), r); err != nil {
+			return nil, nil, errors.Annotatef(err, "failed to parse the audit record of %s", id)
+		}
+		log = nil
+		if len(lines) > 2 {
+			log = lines[2]
+		}
+	} else {
+		r.StartTime = time.Unix(ts, 0)
+	}
+	r.ID = id
+	r.Command = string(lines[0])
+	return r, log, nil
+}
+
+// Read reads the record and the log of the id in the audit directory
+func Read(dir, id string) (*Record, []byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, errors.Errorf("cannot find the audit log '%s'", id)
+		}
+		return nil, nil, errors.AddStack(err)
+	}
+	return Decode(id, data)
+}
+
+// Write writes the record and the log to the audit directory, the ID of the
+// record is generated from the start time
+func Write(dir string, r *Record, log []byte) error {
+	ts := r.StartTime.Unix()
+	// the ID is in seconds, the commands started in the same second are
+	// recorded with the following IDs
+	for ; ; ts++ {
+		r.ID = base52.Encode(ts)
+		if _, err := os.Stat(filepath.Join(dir, r.ID)); os.IsNotExist(err) {
+			break
+		}
+	}
+
+	data, err := Encode(r, log)
+	if err != nil {
+		return err
+	}
+	return errors.AddStack(ioutil.WriteFile(filepath.Join(dir, r.ID), data, 0644))
+}
+
+// List returns the records in the audit directory, the newest first
+func List(dir string) ([]*Record, error) {
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.AddStack(err)
+	}
+
+	records := []*Record{}
+	for _, fi := range fileInfos {
+		if fi.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, errors.AddStack(err)
+		}
+		r, _, err := Decode(fi.Name(), data)
+		if err != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartTime.After(records[j].StartTime)
+	})
+	return records, nil
+}
+
+// Filter selects the records
+type Filter struct {
+	Cluster string    // the name of the cluster operated
+	Since   time.Time // the records started since the time
+	Until   time.Time // the records started before the time
+	Failed  bool      // the records of the failed commands
+}
+
+// Match returns true if the record is selected by the filter, the records
+// of the older versions are never matched by the cluster or failure
+func (f Filter) Match(r *Record) bool {
+	if f.Cluster != "" && r.Cluster != f.Cluster {
+		return false
+	}
+	if !f.Since.IsZero() && r.StartTime.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.StartTime.Before(f.Until) {
+		return false
+	}
+	if f.Failed && (!r.Structured() || r.ExitCode == 0) {
+		return false
+	}
+	return true
+}
+
+// Cleanup removes the records started before the time, the IDs of the
+// removed records are returned
+func Cleanup(dir string, before time.Time) ([]string, error) {
+	records, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, r := range records {
+		if !r.StartTime.Before(before) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, r.ID)); err != nil {
+			return removed, errors.AddStack(err)
+		}
+		removed = append(removed, r.ID)
+	}
+	return removed, nil
+}
+
+// ParseTime parses the time relative to now, in the form of a duration like
+// 12h or 7d, or the absolute time in the form of 2006-01-02 or RFC3339
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(s, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.Errorf("invalid time %s, it should be like 12h, 7d, 2006-01-02 or 2006-01-02T15:04:05+08:00", s)
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/base52"
+)
+
+func TestAudit(t *testing.T) {
+	check.TestingT(t)
+}
+
+type auditSuite struct {
+	dir string
+}
+
+var _ = check.Suite(&auditSuite{})
+
+func (s *auditSuite) SetUpTest(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-audit")
+	c.Assert(err, check.IsNil)
+	s.dir = dir
+}
+
+func (s *auditSuite) TearDownTest(c *check.C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *auditSuite) TestWriteRead(c *check.C) {
+	start := time.Date(2020, 7, 1, 10, 0, 0, 0, time.Local)
+	r := &Record{
+		Cluster:   "test",
+		User:      "tidb",
+		Command:   "tiup-cluster stop test",
+		Args:      []string{"stop", "test"},
+		StartTime: start,
+		EndTime:   start.Add(time.Minute),
+		ExitCode:  1,
+		Hosts:     []string{"172.16.5.1", "172.16.5.2"},
+	}
+	c.Assert(Write(s.dir, r, []byte("log line 1\nlog line 2\n")), check.IsNil)
+	c.Assert(r.ID, check.Equals, base52.Encode(start.Unix()))
+
+	// the record started in the same second is not overwritten
+	second := *r
+	c.Assert(Write(s.dir, &second, nil), check.IsNil)
+	c.Assert(second.ID, check.Equals, base52.Encode(start.Unix()+1))
+
+	got, log, err := Read(s.dir, r.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(got.Structured(), check.IsTrue)
+	c.Assert(got.Cluster, check.Equals, "test")
+	c.Assert(got.Command, check.Equals, "tiup-cluster stop test")
+	c.Assert(got.Args, check.DeepEquals, []string{"stop", "test"})
+	c.Assert(got.StartTime.Equal(start), check.IsTrue)
+	c.Assert(got.ExitCode, check.Equals, 1)
+	c.Assert(got.Hosts, check.DeepEquals, r.Hosts)
+	c.Assert(string(log), check.Equals, "log line 1\nlog line 2\n")
+
+	_, _, err = Read(s.dir, "notexist")
+	c.Assert(err, check.ErrorMatches, "cannot find the audit log 'notexist'")
+}
+
+func (s *auditSuite) TestLegacy(c *check.C) {
+	ts := time.Date(2020, 6, 1, 10, 0, 0, 0, time.Local).Unix()
+	id := base52.Encode(ts)
+	data := []byte("tiup-cluster deploy test v4.0.0 topo.yaml\nlog line 1\n")
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, id), data, 0644), check.IsNil)
+
+	r, log, err := Read(s.dir, id)
+	c.Assert(err, check.IsNil)
+	c.Assert(r.Structured(), check.IsFalse)
+	c.Assert(r.ID, check.Equals, id)
+	c.Assert(r.Command, check.Equals, "tiup-cluster deploy test v4.0.0 topo.yaml")
+	c.Assert(r.StartTime.Unix(), check.Equals, ts)
+	c.Assert(string(log), check.Equals, "log line 1\n")
+
+	// the legacy records are never matched by the cluster or failure
+	c.Assert(Filter{}.Match(r), check.IsTrue)
+	c.Assert(Filter{Cluster: "test"}.Match(r), check.IsFalse)
+	c.Assert(Filter{Failed: true}.Match(r), check.IsFalse)
+}
+
+func (s *auditSuite) TestListFilterCleanup(c *check.C) {
+	now := time.Now()
+	for i, cluster := range []string{"a", "b", "a"} {
+		start := now.AddDate(0, 0, -i*10)
+		r := &Record{Cluster: cluster, Command: "cmd", StartTime: start, EndTime: start, ExitCode: i % 2}
+		c.Assert(Write(s.dir, r, nil), check.IsNil)
+	}
+
+	records, err := List(s.dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 3)
+	c.Assert(records[0].StartTime.After(records[1].StartTime), check.IsTrue)
+
+	count := func(f Filter) int {
+		n := 0
+		for _, r := range records {
+			if f.Match(r) {
+				n++
+			}
+		}
+		return n
+	}
+	c.Assert(count(Filter{Cluster: "a"}), check.Equals, 2)
+	c.Assert(count(Filter{Failed: true}), check.Equals, 1)
+	c.Assert(count(Filter{Since: now.AddDate(0, 0, -15)}), check.Equals, 2)
+	c.Assert(count(Filter{Cluster: "a", Until: now.AddDate(0, 0, -15)}), check.Equals, 1)
+
+	removed, err := Cleanup(s.dir, now.AddDate(0, 0, -5))
+	c.Assert(err, check.IsNil)
+	c.Assert(removed, check.HasLen, 2)
+	records, err = List(s.dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 1)
+}
+
+func (s *auditSuite) TestParseTime(c *check.C) {
+	now := time.Date(2020, 7, 8, 10, 0, 0, 0, time.Local)
+	t, err := ParseTime("7d", now)
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Equal(time.Date(2020, 7, 1, 10, 0, 0, 0, time.Local)), check.IsTrue)
+
+	t, err = ParseTime("2h", now)
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Equal(now.Add(-2*time.Hour)), check.IsTrue)
+
+	t, err = ParseTime("2020-07-07", now)
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Equal(time.Date(2020, 7, 7, 0, 0, 0, 0, time.Local)), check.IsTrue)
+
+	_, err = ParseTime("last tuesday", now)
+	c.Assert(err, check.NotNil)
+}
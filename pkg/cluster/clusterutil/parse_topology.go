@@ -47,7 +47,7 @@ func ParseTopologyYaml(file string, out interface{}) error {
 Please check whether your topology file {{ColorKeyword}}{{.File}}{{ColorReset}} exists and try again.
 
 To generate a sample topology file:
-  {{ColorCommand}}{{OsArgs0}} template topology > topo.yaml{{ColorReset}}
+  {{ColorCommand}}{{OsArgs0}} template > topo.yaml{{ColorReset}}
 `, suggestionProps))
 	}
 
@@ -14,9 +14,15 @@
 package executor
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
 )
 
 var (
@@ -36,3 +42,119 @@ type TiOpsExecutor interface {
 	// Transfer copies files from or to a target
 	Transfer(src string, dst string, download bool) error
 }
+
+// StreamExecutor is implemented by the executors which can stream the output
+// of a long running command, e.g., following the logs of instances
+type StreamExecutor interface {
+	// ExecuteStream runs the command and writes its output to stdout and
+	// stderr as soon as it is produced. The stdin of the command is closed
+	// when ctx is done, the command is expected to exit then.
+	ExecuteStream(ctx context.Context, cmd string, sudo bool, stdout, stderr io.Writer) error
+}
+
+// streamStopTimeout is the time to wait for a streaming command to exit after
+// its stdin is closed, it is killed then
+const streamStopTimeout = 3 * time.Second
+
+// sshCommand wraps the command run through SSH with sudo, the locale and a
+// basic PATH in case it's empty on login
+func sshCommand(cmd string, sudo bool, locale string) string {
+	if sudo {
+		cmd = fmt.Sprintf("sudo -H -u root bash -c \"%s\"", cmd)
+	}
+	if locale != "" {
+		cmd = fmt.Sprintf("export LANG=%s; %s", locale, cmd)
+	}
+	return fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
+}
+
+// SSHType is the implementation of the executor used to connect hosts
+type SSHType string
+
+const (
+	// SSHTypeEasySSH connects hosts with easyssh, a new connection is opened
+	// for every command and files are copied with scp
+	SSHTypeEasySSH SSHType = "easyssh"
+	// SSHTypeNative connects hosts with golang.org/x/crypto/ssh, a connection
+	// is shared by all commands on a host and files are copied with SFTP
+	SSHTypeNative SSHType = "native"
+	// SSHTypeNone runs commands on the local host without SSH, it requires all
+	// the hosts to be the local host
+	SSHTypeNone SSHType = "none"
+)
+
+// SSHOptions are the global options of the SSH connections to hosts, they are
+// applied to all executors created with New
+type SSHOptions struct {
+	Type           SSHType // the implementation of the executor
+	ProxyJump      string  // jump hosts in the form of [user@]host[:port][,...]
+	ProxyKeyFile   string  // path to the private key file of the jump hosts
+	KnownHostsFile string  // verify host keys against the known_hosts file if set
+	UseAgent       bool    // authenticate with the keys of ssh-agent
+}
+
+var defaultSSHOptions = SSHOptions{Type: SSHTypeEasySSH}
+
+// SetDefaultSSHOptions sets the SSH options used by executors created with New
+func SetDefaultSSHOptions(opt SSHOptions) error {
+	switch opt.Type {
+	case "":
+		opt.Type = SSHTypeEasySSH
+	case SSHTypeEasySSH, SSHTypeNative, SSHTypeNone:
+	default:
+		return errors.Errorf("unknown SSH type '%s', supported types are %s, %s and %s",
+			opt.Type, SSHTypeEasySSH, SSHTypeNative, SSHTypeNone)
+	}
+
+	if opt.Type != SSHTypeNative &&
+		(opt.ProxyJump != "" || opt.KnownHostsFile != "" || opt.UseAgent) {
+		return errors.Errorf("the proxy jump, known hosts and ssh-agent options require the %s SSH type", SSHTypeNative)
+	}
+	if opt.ProxyJump != "" {
+		if _, err := parseProxyJump(opt.ProxyJump); err != nil {
+			return err
+		}
+	}
+
+	defaultSSHOptions = opt
+	return nil
+}
+
+// touchedHosts are the hosts which the executors are created for
+var touchedHosts = struct {
+	sync.Mutex
+	hosts set.StringSet
+}{hosts: set.NewStringSet()}
+
+// TouchedHosts returns the hosts which the executors are created for by New
+// in the process, in the sorted order
+func TouchedHosts() []string {
+	touchedHosts.Lock()
+	defer touchedHosts.Unlock()
+	return touchedHosts.hosts.Slice()
+}
+
+// New returns an executor connecting to the host of c with the default SSH
+// options, commands to the local host are run without SSH
+func New(c SSHConfig, sudo bool) (TiOpsExecutor, error) {
+	touchedHosts.Lock()
+	touchedHosts.hosts.Insert(c.Host)
+	touchedHosts.Unlock()
+
+	if IsLocalHost(c.Host) {
+		return NewLocalExecutor(c, sudo), nil
+	}
+
+	switch defaultSSHOptions.Type {
+	case SSHTypeNone:
+		return nil, errors.Errorf("host %s is not the local host, it can't be connected without SSH", c.Host)
+	case SSHTypeNative:
+		c.ProxyJump = defaultSSHOptions.ProxyJump
+		c.ProxyKeyFile = defaultSSHOptions.ProxyKeyFile
+		c.KnownHostsFile = defaultSSHOptions.KnownHostsFile
+		c.UseAgent = defaultSSHOptions.UseAgent
+		return NewNativeSSHExecutor(c, sudo), nil
+	default:
+		return NewSSHExecutor(c, sudo), nil
+	}
+}
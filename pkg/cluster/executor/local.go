@@ -0,0 +1,251 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	utils2 "github.com/pingcap/tiup/pkg/utils"
+	"go.uber.org/zap"
+)
+
+var (
+	errNSLocal = errNS.NewSubNamespace("local")
+
+	// ErrLocalExecuteFailed is ErrLocalExecuteFailed
+	ErrLocalExecuteFailed = errNSLocal.NewType("execute_failed")
+	// ErrLocalExecuteTimedout is ErrLocalExecuteTimedout
+	ErrLocalExecuteTimedout = errNSLocal.NewType("execute_timedout")
+)
+
+// LocalExecutor implements TiOpsExecutor by running commands on the local
+// host, the commands are run as the user of the SSHConfig with sudo if it is
+// not the current user.
+type LocalExecutor struct {
+	Config SSHConfig
+	Locale string // the locale used when executing the command
+	Sudo   bool   // all commands run with this executor will be using sudo
+}
+
+var (
+	_ TiOpsExecutor  = &LocalExecutor{}
+	_ StreamExecutor = &LocalExecutor{}
+)
+
+// NewLocalExecutor create a local executor.
+func NewLocalExecutor(c SSHConfig, sudo bool) *LocalExecutor {
+	return &LocalExecutor{
+		Config: c,
+		Locale: "C", // default locale, hard coded for now
+		Sudo:   sudo,
+	}
+}
+
+// IsLocalHost returns true if the host is the local host, the commands to it
+// are run without SSH
+func IsLocalHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// wrap runs the command in bash as the user of the executor
+func (e *LocalExecutor) wrap(cmd string, sudo bool) []string {
+	current := utils2.CurrentUser()
+	switch {
+	case e.Sudo || sudo:
+		if current != "root" {
+			return []string{"sudo", "-H", "-u", "root", "bash", "-c", cmd}
+		}
+	case e.Config.User != "" && e.Config.User != current:
+		return []string{"sudo", "-H", "-u", e.Config.User, "bash", "-c", cmd}
+	}
+	return []string{"bash", "-c", cmd}
+}
+
+// run runs the command with stdin, the output is written to stdout and stderr
+func (e *LocalExecutor) run(cmd string, sudo bool, stdin io.Reader, stdout, stderr io.Writer, timeout time.Duration) error {
+	args := e.wrap(cmd, sudo)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, args[0], args[1:]...)
+	c.Env = os.Environ()
+	if e.Locale != "" {
+		c.Env = append(c.Env, "LANG="+e.Locale)
+	}
+	// set a basic PATH in case it's empty
+	c.Env = append(c.Env, fmt.Sprintf("PATH=%s:/usr/bin:/usr/sbin", os.Getenv("PATH")))
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	err := c.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrLocalExecuteTimedout.
+			New("Execute command locally timedout for '%s'", e.Config.Host).
+			WithProperty(ErrPropSSHCommand, strings.Join(args, " "))
+	}
+	return err
+}
+
+// Execute run the command on the local host
+func (e *LocalExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if len(timeout) == 0 {
+		timeout = append(timeout, executeDefaultTimeout)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := e.run(cmd, sudo, nil, &stdout, &stderr, timeout[0])
+
+	zap.L().Info("LocalCommand",
+		zap.String("host", e.Config.Host),
+		zap.String("user", e.Config.User),
+		zap.String("cmd", cmd),
+		zap.Error(err),
+		zap.String("stdout", stdout.String()),
+		zap.String("stderr", stderr.String()))
+
+	if errorx.IsOfType(err, ErrLocalExecuteTimedout) {
+		return stdout.Bytes(), stderr.Bytes(), err
+	}
+	if err != nil {
+		baseErr := ErrLocalExecuteFailed.
+			Wrap(err, "Failed to execute command locally for '%s@%s'", e.Config.User, e.Config.Host).
+			WithProperty(ErrPropSSHCommand, cmd).
+			WithProperty(ErrPropSSHStdout, stdout.String()).
+			WithProperty(ErrPropSSHStderr, stderr.String())
+		if stdout.Len() > 0 || stderr.Len() > 0 {
+			output := strings.TrimSpace(strings.Join([]string{stdout.String(), stderr.String()}, "\n"))
+			baseErr = baseErr.
+				WithProperty(cliutil.SuggestionFromFormat("Command output on local host %s:\n%s\n",
+					e.Config.Host,
+					color.YellowString(output)))
+		}
+		return stdout.Bytes(), stderr.Bytes(), baseErr
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
+
+// ExecuteStream implements the StreamExecutor interface
+func (e *LocalExecutor) ExecuteStream(ctx context.Context, cmd string, sudo bool, stdout, stderr io.Writer) error {
+	args := e.wrap(cmd, sudo)
+	c := exec.Command(args[0], args[1:]...)
+	c.Env = os.Environ()
+	if e.Locale != "" {
+		c.Env = append(c.Env, "LANG="+e.Locale)
+	}
+	c.Env = append(c.Env, fmt.Sprintf("PATH=%s:/usr/bin:/usr/sbin", os.Getenv("PATH")))
+	c.Stdout = stdout
+	c.Stderr = stderr
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	zap.L().Info("LocalCommand",
+		zap.String("host", e.Config.Host),
+		zap.String("user", e.Config.User),
+		zap.String("cmd", cmd),
+		zap.Bool("stream", true))
+	if err := c.Start(); err != nil {
+		return ErrLocalExecuteFailed.
+			Wrap(err, "Failed to execute command locally for '%s@%s'", e.Config.User, e.Config.Host).
+			WithProperty(ErrPropSSHCommand, cmd)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Wait()
+	}()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			return ErrLocalExecuteFailed.
+				Wrap(err, "Failed to execute command locally for '%s@%s'", e.Config.User, e.Config.Host).
+				WithProperty(ErrPropSSHCommand, cmd)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	_ = stdin.Close()
+	select {
+	case <-done:
+	case <-time.After(streamStopTimeout):
+		_ = c.Process.Kill()
+		<-done
+	}
+	return nil
+}
+
+// Transfer copies files on the local host, the file is written or read as
+// the user of the executor
+func (e *LocalExecutor) Transfer(src string, dst string, download bool) error {
+	if !download {
+		f, err := os.Open(src)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		defer f.Close()
+
+		var stderr bytes.Buffer
+		cmd := fmt.Sprintf("cat > %s", utils2.ShellQuote(dst))
+		if err := e.run(cmd, false, f, ioutil.Discard, &stderr, executeDefaultTimeout); err != nil {
+			return errors.Annotatef(err, "copy %s to %s: %s", src, dst, stderr.String())
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		cmd = fmt.Sprintf("chmod %o %s", stat.Mode().Perm(), utils2.ShellQuote(dst))
+		if err := e.run(cmd, false, nil, ioutil.Discard, &stderr, executeDefaultTimeout); err != nil {
+			return errors.Annotatef(err, "chmod %s: %s", dst, stderr.String())
+		}
+		return nil
+	}
+
+	if err := utils2.CreateDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer f.Close()
+
+	var stderr bytes.Buffer
+	if err := e.run(fmt.Sprintf("cat %s", utils2.ShellQuote(src)), false, nil, f, &stderr, executeDefaultTimeout); err != nil {
+		return errors.Annotatef(err, "copy %s to %s: %s", src, dst, stderr.String())
+	}
+	return nil
+}
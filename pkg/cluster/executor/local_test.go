@@ -0,0 +1,125 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+type localSuite struct{}
+
+var _ = check.Suite(&localSuite{})
+
+func (s *localSuite) TestIsLocalHost(c *check.C) {
+	c.Assert(IsLocalHost("localhost"), check.IsTrue)
+	c.Assert(IsLocalHost("127.0.0.1"), check.IsTrue)
+	c.Assert(IsLocalHost("127.0.1.1"), check.IsTrue)
+	c.Assert(IsLocalHost("::1"), check.IsTrue)
+	c.Assert(IsLocalHost("172.16.5.1"), check.IsFalse)
+	c.Assert(IsLocalHost("tidb-host"), check.IsFalse)
+}
+
+func (s *localSuite) TestExecute(c *check.C) {
+	e := NewLocalExecutor(SSHConfig{Host: "127.0.0.1", User: utils.CurrentUser()}, false)
+
+	stdout, _, err := e.Execute("echo $LANG; echo error >&2", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(stdout), check.Equals, "C\n")
+
+	stdout, stderr, err := e.Execute("echo output; echo error >&2; exit 1", false)
+	c.Assert(errorx.IsOfType(err, ErrLocalExecuteFailed), check.IsTrue)
+	c.Assert(string(stdout), check.Equals, "output\n")
+	c.Assert(string(stderr), check.Equals, "error\n")
+
+	_, _, err = e.Execute("sleep 5", false, time.Millisecond*100)
+	c.Assert(errorx.IsOfType(err, ErrLocalExecuteTimedout), check.IsTrue)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (s *localSuite) TestExecuteStream(c *check.C) {
+	e := NewLocalExecutor(SSHConfig{Host: "127.0.0.1", User: utils.CurrentUser()}, false)
+
+	// the command exits by itself
+	stdout := &syncBuffer{}
+	err := e.ExecuteStream(context.Background(), "echo a; echo b", false, stdout, ioutil.Discard)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "a\nb\n")
+	err = e.ExecuteStream(context.Background(), "exit 2", false, stdout, ioutil.Discard)
+	c.Assert(errorx.IsOfType(err, ErrLocalExecuteFailed), check.IsTrue)
+
+	// the command is stopped by closing its stdin
+	ctx, cancel := context.WithCancel(context.Background())
+	stdout = &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- e.ExecuteStream(ctx, "echo start; cat >/dev/null; echo stop", false, stdout, ioutil.Discard)
+	}()
+	for stdout.String() == "" {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	c.Assert(<-done, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "start\nstop\n")
+}
+
+func (s *localSuite) TestTransfer(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-local")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	e := NewLocalExecutor(SSHConfig{Host: "localhost", User: utils.CurrentUser()}, false)
+
+	src := filepath.Join(dir, "src")
+	c.Assert(ioutil.WriteFile(src, []byte("content"), 0750), check.IsNil)
+	dst := filepath.Join(dir, "dst with space")
+	c.Assert(e.Transfer(src, dst, false), check.IsNil)
+	data, err := ioutil.ReadFile(dst)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "content")
+	stat, err := os.Stat(dst)
+	c.Assert(err, check.IsNil)
+	c.Assert(stat.Mode().Perm(), check.Equals, os.FileMode(0750))
+
+	local := filepath.Join(dir, "download", "local")
+	c.Assert(e.Transfer(dst, local, true), check.IsNil)
+	data, err = ioutil.ReadFile(local)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "content")
+}
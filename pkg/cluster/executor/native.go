@@ -0,0 +1,580 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	utils2 "github.com/pingcap/tiup/pkg/utils"
+	"github.com/pkg/sftp"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var (
+	// ErrSSHConnectFailed is ErrSSHConnectFailed
+	ErrSSHConnectFailed = errNSSSH.NewType("connect_failed")
+)
+
+// maxSessionsPerConn is the max number of concurrent sessions on a connection,
+// it is kept below the default MaxSessions (10) of sshd to leave room for SFTP
+const maxSessionsPerConn = 8
+
+// NativeSSHExecutor implements TiOpsExecutor with golang.org/x/crypto/ssh, the
+// connection to a host is shared by all executors with the same SSHConfig and
+// files are transferred with SFTP.
+type NativeSSHExecutor struct {
+	Config SSHConfig
+	Locale string // the locale used when executing the command
+	Sudo   bool   // all commands run with this executor will be using sudo
+}
+
+var (
+	_ TiOpsExecutor  = &NativeSSHExecutor{}
+	_ StreamExecutor = &NativeSSHExecutor{}
+)
+
+// NewNativeSSHExecutor create a native ssh executor.
+func NewNativeSSHExecutor(c SSHConfig, sudo bool) *NativeSSHExecutor {
+	// set default values
+	if c.Port <= 0 {
+		c.Port = 22
+	}
+	if c.Timeout == 0 {
+		c.Timeout = time.Second * 5 // default timeout is 5 sec
+	}
+
+	return &NativeSSHExecutor{
+		Config: c,
+		Locale: "C", // default locale, hard coded for now
+		Sudo:   sudo,
+	}
+}
+
+// Execute run the command via SSH, it's not invoking any specific shell by default.
+func (e *NativeSSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	cmd = sshCommand(cmd, e.Sudo || sudo, e.Locale)
+
+	if len(timeout) == 0 {
+		timeout = append(timeout, executeDefaultTimeout)
+	}
+
+	session, release, err := pooledConn(e.Config).newSession(e.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(cmd)
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(timeout[0]):
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+		zap.L().Info("SSHCommand",
+			zap.String("host", e.Config.Host),
+			zap.Int("port", e.Config.Port),
+			zap.String("cmd", cmd),
+			zap.Duration("timeout", timeout[0]))
+		// the output may still be written by the session, so it is dropped
+		return nil, nil, ErrSSHExecuteTimedout.
+			New("Execute command over SSH timedout for '%s@%s:%d'", e.Config.User, e.Config.Host, e.Config.Port).
+			WithProperty(ErrPropSSHCommand, cmd)
+	}
+
+	zap.L().Info("SSHCommand",
+		zap.String("host", e.Config.Host),
+		zap.Int("port", e.Config.Port),
+		zap.String("cmd", cmd),
+		zap.Error(err),
+		zap.String("stdout", stdout.String()),
+		zap.String("stderr", stderr.String()))
+
+	if err != nil {
+		baseErr := ErrSSHExecuteFailed.
+			Wrap(err, "Failed to execute command over SSH for '%s@%s:%d'", e.Config.User, e.Config.Host, e.Config.Port).
+			WithProperty(ErrPropSSHCommand, cmd).
+			WithProperty(ErrPropSSHStdout, stdout.String()).
+			WithProperty(ErrPropSSHStderr, stderr.String())
+		if stdout.Len() > 0 || stderr.Len() > 0 {
+			output := strings.TrimSpace(strings.Join([]string{stdout.String(), stderr.String()}, "\n"))
+			baseErr = baseErr.
+				WithProperty(cliutil.SuggestionFromFormat("Command output on remote host %s:\n%s\n",
+					e.Config.Host,
+					color.YellowString(output)))
+		}
+		return stdout.Bytes(), stderr.Bytes(), baseErr
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
+
+// ExecuteStream implements the StreamExecutor interface
+func (e *NativeSSHExecutor) ExecuteStream(ctx context.Context, cmd string, sudo bool, stdout, stderr io.Writer) error {
+	cmd = sshCommand(cmd, e.Sudo || sudo, e.Locale)
+
+	session, release, err := pooledConn(e.Config).newSession(e.Config)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	zap.L().Info("SSHCommand",
+		zap.String("host", e.Config.Host),
+		zap.Int("port", e.Config.Port),
+		zap.String("cmd", cmd),
+		zap.Bool("stream", true))
+	if err := streamSession(ctx, session, cmd, stdout, stderr); err != nil {
+		return ErrSSHExecuteFailed.
+			Wrap(err, "Failed to execute command over SSH for '%s@%s:%d'", e.Config.User, e.Config.Host, e.Config.Port).
+			WithProperty(ErrPropSSHCommand, cmd)
+	}
+	return nil
+}
+
+// streamSession runs the command in the session until it exits or ctx is
+// done, the stdin of the command is closed when ctx is done
+func streamSession(ctx context.Context, session *ssh.Session, cmd string, stdout, stderr io.Writer) error {
+	session.Stdout = stdout
+	session.Stderr = stderr
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := session.Start(cmd); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	_ = stdin.Close()
+	select {
+	case <-done:
+	case <-time.After(streamStopTimeout):
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+	}
+	return nil
+}
+
+// Transfer copies files via SFTP
+func (e *NativeSSHExecutor) Transfer(src string, dst string, download bool) error {
+	client, err := pooledConn(e.Config).sftpClient(e.Config)
+	if err != nil {
+		return err
+	}
+
+	if !download {
+		return upload(client, src, dst)
+	}
+	return fetch(client, src, dst)
+}
+
+func upload(client *sftp.Client, src, dst string) error {
+	local, err := os.Open(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer local.Close()
+	stat, err := local.Stat()
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	remote, err := client.Create(dst)
+	if err != nil {
+		return errors.Annotatef(err, "create remote file %s", dst)
+	}
+	defer remote.Close()
+	if _, err := io.Copy(remote, local); err != nil {
+		return errors.Annotatef(err, "upload %s to %s", src, dst)
+	}
+	return errors.AddStack(remote.Chmod(stat.Mode().Perm()))
+}
+
+func fetch(client *sftp.Client, src, dst string) error {
+	remote, err := client.Open(src)
+	if err != nil {
+		return errors.Annotatef(err, "open remote file %s", src)
+	}
+	defer remote.Close()
+
+	if err := utils2.CreateDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	local, err := os.Create(dst)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer local.Close()
+	if _, err := io.Copy(local, remote); err != nil {
+		return errors.Annotatef(err, "download %s to %s", src, dst)
+	}
+	return nil
+}
+
+// sshConn is a connection to a host shared by the executors, it is dialed on
+// the first use and dialed again if it is broken
+type sshConn struct {
+	mu     sync.Mutex
+	client *ssh.Client
+	jumps  []*ssh.Client // the connections to the jump hosts
+	sftp   *sftp.Client
+	slots  chan struct{} // limits the concurrent sessions
+}
+
+var connPool = struct {
+	sync.Mutex
+	conns map[string]*sshConn
+}{conns: make(map[string]*sshConn)}
+
+// pooledConn returns the shared connection of c
+func pooledConn(c SSHConfig) *sshConn {
+	key := fmt.Sprintf("%s@%s:%d/%s", c.User, c.Host, c.Port, c.KeyFile)
+
+	connPool.Lock()
+	defer connPool.Unlock()
+	conn, ok := connPool.conns[key]
+	if !ok {
+		conn = &sshConn{slots: make(chan struct{}, maxSessionsPerConn)}
+		connPool.conns[key] = conn
+	}
+	return conn
+}
+
+// CloseSSHConnections closes all the connections of the native executors
+func CloseSSHConnections() {
+	connPool.Lock()
+	defer connPool.Unlock()
+	for key, conn := range connPool.conns {
+		conn.mu.Lock()
+		conn.closeLocked()
+		conn.mu.Unlock()
+		delete(connPool.conns, key)
+	}
+}
+
+func (conn *sshConn) connectLocked(c SSHConfig) (*ssh.Client, error) {
+	if conn.client != nil {
+		return conn.client, nil
+	}
+
+	client, jumps, err := dial(c)
+	if err != nil {
+		return nil, ErrSSHConnectFailed.
+			Wrap(err, "Failed to connect to '%s@%s:%d' over SSH", c.User, c.Host, c.Port)
+	}
+	conn.client = client
+	conn.jumps = jumps
+	return client, nil
+}
+
+func (conn *sshConn) closeLocked() {
+	if conn.sftp != nil {
+		_ = conn.sftp.Close()
+		conn.sftp = nil
+	}
+	if conn.client != nil {
+		_ = conn.client.Close()
+		conn.client = nil
+	}
+	// close from the nearest jump host
+	for i := len(conn.jumps) - 1; i >= 0; i-- {
+		_ = conn.jumps[i].Close()
+	}
+	conn.jumps = nil
+}
+
+// newSession opens a session on the connection, the returned function must
+// be called to close the session
+func (conn *sshConn) newSession(c SSHConfig) (*ssh.Session, func(), error) {
+	conn.slots <- struct{}{}
+
+	var session *ssh.Session
+	var err error
+	// the connection may be broken after the last use, dial it again once
+	for retry := 0; retry < 2; retry++ {
+		conn.mu.Lock()
+		var client *ssh.Client
+		if client, err = conn.connectLocked(c); err != nil {
+			conn.mu.Unlock()
+			break
+		}
+		if session, err = client.NewSession(); err != nil && conn.client == client {
+			conn.closeLocked()
+		}
+		conn.mu.Unlock()
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		<-conn.slots
+		return nil, nil, ErrSSHConnectFailed.
+			Wrap(err, "Failed to open SSH session to '%s@%s:%d'", c.User, c.Host, c.Port)
+	}
+
+	return session, func() {
+		_ = session.Close()
+		<-conn.slots
+	}, nil
+}
+
+func (conn *sshConn) sftpClient(c SSHConfig) (*sftp.Client, error) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.sftp != nil {
+		return conn.sftp, nil
+	}
+
+	client, err := conn.connectLocked(c)
+	if err != nil {
+		return nil, err
+	}
+	if conn.sftp, err = sftp.NewClient(client); err != nil {
+		conn.closeLocked()
+		return nil, ErrSSHConnectFailed.
+			Wrap(err, "Failed to start SFTP to '%s@%s:%d'", c.User, c.Host, c.Port)
+	}
+	return conn.sftp, nil
+}
+
+// jumpHost is a hop of ProxyJump
+type jumpHost struct {
+	user string
+	host string
+	port int
+}
+
+func (h jumpHost) addr() string {
+	return net.JoinHostPort(h.host, strconv.Itoa(h.port))
+}
+
+// parseProxyJump parses jump hosts in the same form as the ProxyJump option of
+// OpenSSH: [user@]host[:port][,[user@]host[:port]...]
+func parseProxyJump(s string) ([]jumpHost, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var hops []jumpHost
+	for _, hop := range strings.Split(s, ",") {
+		h := jumpHost{user: utils2.CurrentUser(), port: 22}
+		hop = strings.TrimSpace(hop)
+		if i := strings.LastIndex(hop, "@"); i >= 0 {
+			h.user = hop[:i]
+			hop = hop[i+1:]
+		}
+		h.host = hop
+		if host, port, err := net.SplitHostPort(hop); err == nil {
+			h.host = host
+			if h.port, err = strconv.Atoi(port); err != nil || h.port <= 0 {
+				return nil, errors.Errorf("invalid port of jump host '%s'", hop)
+			}
+		}
+		if h.host == "" || h.user == "" {
+			return nil, errors.Errorf("invalid jump host '%s'", hop)
+		}
+		hops = append(hops, h)
+	}
+	return hops, nil
+}
+
+// dial connects to the host of c through the jump hosts
+func dial(c SSHConfig) (*ssh.Client, []*ssh.Client, error) {
+	hostKey, err := hostKeyCallback(c.KnownHostsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	hops, err := parseProxyJump(c.ProxyJump)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var jumps []*ssh.Client
+	closeJumps := func() {
+		for i := len(jumps) - 1; i >= 0; i-- {
+			_ = jumps[i].Close()
+		}
+	}
+
+	var via *ssh.Client
+	for _, hop := range hops {
+		keyFile, passphrase := c.ProxyKeyFile, ""
+		if keyFile == "" {
+			keyFile, passphrase = c.KeyFile, c.Passphrase
+		}
+		auth, err := authMethods(keyFile, passphrase, "", c.UseAgent)
+		if err != nil {
+			closeJumps()
+			return nil, nil, err
+		}
+		client, err := dialVia(via, hop.addr(), &ssh.ClientConfig{
+			User:            hop.user,
+			Auth:            auth,
+			HostKeyCallback: hostKey,
+			Timeout:         c.Timeout,
+		})
+		if err != nil {
+			closeJumps()
+			return nil, nil, errors.Annotatef(err, "connect to jump host %s", hop.addr())
+		}
+		jumps = append(jumps, client)
+		via = client
+	}
+
+	auth, err := authMethods(c.KeyFile, c.Passphrase, c.Password, c.UseAgent)
+	if err != nil {
+		closeJumps()
+		return nil, nil, err
+	}
+	client, err := dialVia(via, net.JoinHostPort(c.Host, strconv.Itoa(c.Port)), &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         c.Timeout,
+	})
+	if err != nil {
+		closeJumps()
+		return nil, nil, err
+	}
+	return client, jumps, nil
+}
+
+// dialVia connects to addr directly if via is nil, or through the connection via
+func dialVia(via *ssh.Client, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, cfg)
+	}
+
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func authMethods(keyFile, passphrase, password string, useAgent bool) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+
+	// prefer private key authentication
+	if keyFile != "" {
+		data, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, errors.Annotatef(err, "read private key %s", keyFile)
+		}
+		var signer ssh.Signer
+		if passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(data)
+		}
+		if err != nil {
+			return nil, errors.Annotatef(err, "parse private key %s", keyFile)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	if useAgent {
+		signers, err := agentSigners()
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, ssh.PublicKeysCallback(signers))
+	}
+
+	if password != "" {
+		methods = append(methods, ssh.Password(password))
+	}
+	return methods, nil
+}
+
+var sshAgent struct {
+	once   sync.Once
+	client agent.Agent
+	err    error
+}
+
+// agentSigners returns the signers of the ssh-agent at SSH_AUTH_SOCK, the
+// connection to the agent is shared by all connections
+func agentSigners() (func() ([]ssh.Signer, error), error) {
+	sshAgent.once.Do(func() {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			sshAgent.err = errors.New("SSH_AUTH_SOCK is not set, is the ssh-agent running?")
+			return
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			sshAgent.err = errors.Annotatef(err, "connect to ssh-agent %s", sock)
+			return
+		}
+		sshAgent.client = agent.NewClient(conn)
+	})
+	if sshAgent.err != nil {
+		return nil, sshAgent.err
+	}
+	return sshAgent.client.Signers, nil
+}
+
+// hostKeyCallback verifies host keys against the known_hosts file, the host
+// keys are not verified if the file is not set
+func hostKeyCallback(knownHostsFile string) (ssh.HostKeyCallback, error) {
+	if knownHostsFile == "" {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	cb, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, errors.Annotatef(err, "load known hosts %s", knownHostsFile)
+	}
+	return cb, nil
+}
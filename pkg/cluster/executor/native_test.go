@@ -0,0 +1,343 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestExecutor(t *testing.T) {
+	check.TestingT(t)
+}
+
+type nativeSuite struct {
+	dir     string
+	keyFile string
+	target  *testSSHServer
+	jump    *testSSHServer
+}
+
+var _ = check.Suite(&nativeSuite{})
+
+// testSSHServer is a minimal SSH server, commands are echoed back instead of
+// being executed, and it serves SFTP and direct-tcpip (for ProxyJump)
+type testSSHServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	conns    int32 // the number of accepted connections
+}
+
+func newTestSSHServer(c *check.C, authorized ssh.PublicKey) *testSSHServer {
+	hostKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, check.IsNil)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	c.Assert(err, check.IsNil)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	s := &testSSHServer{listener: l, config: config}
+	go s.serve()
+	return s
+}
+
+func (s *testSSHServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		atomic.AddInt32(&s.conns, 1)
+		go func() {
+			_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			for ch := range chans {
+				go s.handleChannel(ch)
+			}
+		}()
+	}
+}
+
+func (s *testSSHServer) handleChannel(newCh ssh.NewChannel) {
+	switch newCh.ChannelType() {
+	case "session":
+	case "direct-tcpip":
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newCh.ExtraData(), &target); err != nil {
+			_ = newCh.Reject(ssh.ConnectionFailed, err.Error())
+			return
+		}
+		conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			_ = newCh.Reject(ssh.ConnectionFailed, err.Error())
+			return
+		}
+		ch, reqs, err := newCh.Accept()
+		if err != nil {
+			conn.Close()
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			_, _ = io.Copy(ch, conn)
+			ch.Close()
+		}()
+		_, _ = io.Copy(conn, ch)
+		conn.Close()
+		return
+	default:
+		_ = newCh.Reject(ssh.UnknownChannelType, newCh.ChannelType())
+		return
+	}
+
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+	for req := range reqs {
+		var payload struct{ Value string }
+		_ = ssh.Unmarshal(req.Payload, &payload)
+		switch req.Type {
+		case "exec":
+			_ = req.Reply(true, nil)
+			_, _ = ch.Write([]byte(payload.Value))
+			if strings.HasSuffix(payload.Value, "; cat") {
+				// the command streams until the stdin is closed
+				_, _ = io.Copy(ioutil.Discard, ch)
+				_, _ = ch.Write([]byte("\nEOF"))
+			}
+			_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			return
+		case "subsystem":
+			if payload.Value != "sftp" {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			server, err := sftp.NewServer(ch)
+			if err != nil {
+				return
+			}
+			_ = server.Serve()
+			return
+		default:
+			_ = req.Reply(false, nil)
+		}
+	}
+}
+
+func (s *nativeSuite) SetUpSuite(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-executor")
+	c.Assert(err, check.IsNil)
+	s.dir = dir
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, check.IsNil)
+	s.keyFile = filepath.Join(dir, "id_rsa")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	c.Assert(ioutil.WriteFile(s.keyFile, data, 0600), check.IsNil)
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	c.Assert(err, check.IsNil)
+
+	s.target = newTestSSHServer(c, pub)
+	s.jump = newTestSSHServer(c, pub)
+}
+
+func (s *nativeSuite) TearDownSuite(c *check.C) {
+	CloseSSHConnections()
+	s.target.listener.Close()
+	s.jump.listener.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *nativeSuite) config() SSHConfig {
+	return SSHConfig{
+		Host:    "127.0.0.1",
+		Port:    s.target.port(),
+		User:    "tidb",
+		KeyFile: s.keyFile,
+	}
+}
+
+func (s *nativeSuite) TestExecuteReusesConnection(c *check.C) {
+	CloseSSHConnections()
+	before := atomic.LoadInt32(&s.target.conns)
+
+	for i := 0; i < 3; i++ {
+		e := NewNativeSSHExecutor(s.config(), false)
+		stdout, _, err := e.Execute("echo hello", false)
+		c.Assert(err, check.IsNil)
+		c.Assert(string(stdout), check.Equals, "PATH=$PATH:/usr/bin:/usr/sbin export LANG=C; echo hello")
+	}
+	c.Assert(atomic.LoadInt32(&s.target.conns)-before, check.Equals, int32(1))
+
+	// a broken connection is dialed again
+	CloseSSHConnections()
+	_, _, err := NewNativeSSHExecutor(s.config(), false).Execute("true", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(atomic.LoadInt32(&s.target.conns)-before, check.Equals, int32(2))
+}
+
+func (s *nativeSuite) TestExecuteStream(c *check.C) {
+	e := NewNativeSSHExecutor(s.config(), false)
+	ctx, cancel := context.WithCancel(context.Background())
+	stdout := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- e.ExecuteStream(ctx, "echo start; cat", false, stdout, ioutil.Discard)
+	}()
+
+	for !strings.Contains(stdout.String(), "start") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	c.Assert(<-done, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "PATH=$PATH:/usr/bin:/usr/sbin export LANG=C; echo start; cat\nEOF")
+}
+
+func (s *nativeSuite) TestTransfer(c *check.C) {
+	e := NewNativeSSHExecutor(s.config(), false)
+
+	src := filepath.Join(s.dir, "src")
+	c.Assert(ioutil.WriteFile(src, []byte("content"), 0755), check.IsNil)
+	remote := filepath.Join(s.dir, "remote")
+	c.Assert(e.Transfer(src, remote, false), check.IsNil)
+	data, err := ioutil.ReadFile(remote)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "content")
+	stat, err := os.Stat(remote)
+	c.Assert(err, check.IsNil)
+	c.Assert(stat.Mode().Perm(), check.Equals, os.FileMode(0755))
+
+	local := filepath.Join(s.dir, "download", "local")
+	c.Assert(e.Transfer(remote, local, true), check.IsNil)
+	data, err = ioutil.ReadFile(local)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "content")
+}
+
+func (s *nativeSuite) TestProxyJump(c *check.C) {
+	cfg := s.config()
+	cfg.User = "proxied"
+	cfg.ProxyJump = "jumper@127.0.0.1:" + strconv.Itoa(s.jump.port())
+	before := atomic.LoadInt32(&s.jump.conns)
+
+	stdout, _, err := NewNativeSSHExecutor(cfg, false).Execute("hostname", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(stdout), check.Matches, ".*hostname")
+	c.Assert(atomic.LoadInt32(&s.jump.conns)-before, check.Equals, int32(1))
+}
+
+func (s *nativeSuite) TestKnownHosts(c *check.C) {
+	cfg := s.config()
+	cfg.User = "verified"
+	cfg.KnownHostsFile = filepath.Join(s.dir, "known_hosts")
+	c.Assert(ioutil.WriteFile(cfg.KnownHostsFile, nil, 0644), check.IsNil)
+
+	_, _, err := NewNativeSSHExecutor(cfg, false).Execute("true", false)
+	c.Assert(err, check.NotNil)
+	c.Assert(errorx.IsOfType(err, ErrSSHConnectFailed), check.IsTrue)
+}
+
+func (s *nativeSuite) TestParseProxyJump(c *check.C) {
+	hops, err := parseProxyJump("admin@bastion:2222, 10.0.0.1")
+	c.Assert(err, check.IsNil)
+	c.Assert(hops, check.HasLen, 2)
+	c.Assert(hops[0], check.Equals, jumpHost{user: "admin", host: "bastion", port: 2222})
+	c.Assert(hops[1].host, check.Equals, "10.0.0.1")
+	c.Assert(hops[1].port, check.Equals, 22)
+	c.Assert(hops[1].addr(), check.Equals, "10.0.0.1:22")
+
+	hops, err = parseProxyJump("root@[::1]:22")
+	c.Assert(err, check.IsNil)
+	c.Assert(hops[0].addr(), check.Equals, "[::1]:22")
+
+	_, err = parseProxyJump("admin@bastion:port")
+	c.Assert(err, check.NotNil)
+	_, err = parseProxyJump("admin@")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *nativeSuite) TestSetDefaultSSHOptions(c *check.C) {
+	defer func() { defaultSSHOptions = SSHOptions{Type: SSHTypeEasySSH} }()
+	remote := SSHConfig{Host: "172.16.5.1", User: "tidb"}
+
+	c.Assert(SetDefaultSSHOptions(SSHOptions{Type: "telnet"}), check.NotNil)
+	c.Assert(SetDefaultSSHOptions(SSHOptions{Type: SSHTypeEasySSH, UseAgent: true}), check.NotNil)
+	c.Assert(SetDefaultSSHOptions(SSHOptions{Type: SSHTypeNative, ProxyJump: "a@"}), check.NotNil)
+
+	c.Assert(SetDefaultSSHOptions(SSHOptions{}), check.IsNil)
+	e, err := New(remote, false)
+	c.Assert(err, check.IsNil)
+	_, ok := e.(*SSHExecutor)
+	c.Assert(ok, check.IsTrue)
+
+	c.Assert(SetDefaultSSHOptions(SSHOptions{Type: SSHTypeNative, ProxyJump: "bastion"}), check.IsNil)
+	e, err = New(remote, false)
+	c.Assert(err, check.IsNil)
+	native, ok := e.(*NativeSSHExecutor)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(native.Config.ProxyJump, check.Equals, "bastion")
+
+	// the local host is always run without SSH, and other hosts can't be
+	// connected without SSH
+	e, err = New(s.config(), false)
+	c.Assert(err, check.IsNil)
+	_, ok = e.(*LocalExecutor)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(SetDefaultSSHOptions(SSHOptions{Type: SSHTypeNone}), check.IsNil)
+	_, err = New(remote, false)
+	c.Assert(err, check.NotNil)
+}
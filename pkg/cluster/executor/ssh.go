@@ -14,7 +14,9 @@
 package executor
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -79,10 +81,19 @@ type (
 		Passphrase string // passphrase of the private key file
 		// Timeout is the maximum amount of time for the TCP connection to establish.
 		Timeout time.Duration
+
+		// the options below are only supported by the native executor
+		ProxyJump      string // jump hosts in the form of [user@]host[:port][,...]
+		ProxyKeyFile   string // path to the private key file of the jump hosts
+		KnownHostsFile string // verify host keys against the known_hosts file if set
+		UseAgent       bool   // authenticate with the keys of ssh-agent
 	}
 )
 
-var _ TiOpsExecutor = &SSHExecutor{}
+var (
+	_ TiOpsExecutor  = &SSHExecutor{}
+	_ StreamExecutor = &SSHExecutor{}
+)
 
 // NewSSHExecutor create a ssh executor.
 func NewSSHExecutor(c SSHConfig, sudo bool) *SSHExecutor {
@@ -123,17 +134,7 @@ func (e *SSHExecutor) Initialize(config SSHConfig) {
 
 // Execute run the command via SSH, it's not invoking any specific shell by default.
 func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
-	// try to acquire root permission
-	if e.Sudo || sudo {
-		cmd = fmt.Sprintf("sudo -H -u root bash -c \"%s\"", cmd)
-	}
-
-	if e.Locale != "" {
-		cmd = fmt.Sprintf("export LANG=%s; %s", e.Locale, cmd)
-	}
-
-	// set a basic PATH in case it's empty on login
-	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
+	cmd = sshCommand(cmd, e.Sudo || sudo, e.Locale)
 
 	// run command on remote host
 	// default timeout is 60s in easyssh-proxy
@@ -178,6 +179,32 @@ func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) (
 	return []byte(stdout), []byte(stderr), nil
 }
 
+// ExecuteStream implements the StreamExecutor interface, a new connection is
+// opened for the command
+func (e *SSHExecutor) ExecuteStream(ctx context.Context, cmd string, sudo bool, stdout, stderr io.Writer) error {
+	cmd = sshCommand(cmd, e.Sudo || sudo, e.Locale)
+
+	session, client, err := e.Config.Connect()
+	if err != nil {
+		return ErrSSHExecuteFailed.
+			Wrap(err, "Failed to connect '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port)
+	}
+	defer client.Close()
+	defer session.Close()
+
+	zap.L().Info("SSHCommand",
+		zap.String("host", e.Config.Server),
+		zap.String("port", e.Config.Port),
+		zap.String("cmd", cmd),
+		zap.Bool("stream", true))
+	if err := streamSession(ctx, session, cmd, stdout, stderr); err != nil {
+		return ErrSSHExecuteFailed.
+			Wrap(err, "Failed to execute command over SSH for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port).
+			WithProperty(ErrPropSSHCommand, cmd)
+	}
+	return nil
+}
+
 // Transfer copies files via SCP
 // This function depends on `scp` (a tool from OpenSSH or other SSH implementation)
 // This function is based on easyssh.MakeConfig.Scp() but with support of copying
@@ -0,0 +1,192 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/crypto"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// ArchiveCluster packs everything in the directory of a cluster, including the
+// meta, SSH keys, TLS certificates, patches and backups, into a gzipped tarball
+// encrypted with the passphrase. The operation lock is not archived.
+func ArchiveCluster(clusterName string, passphrase []byte) ([]byte, error) {
+	root := ClusterPath(clusterName)
+	if utils.IsNotExist(filepath.Join(root, MetaFileName)) {
+		return nil, errors.Errorf("cluster %s does not exist", clusterName)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." || rel == LockFileName {
+			return nil
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "archive cluster %s", clusterName)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return crypto.EncryptWithPassphrase(buf.Bytes(), passphrase)
+}
+
+// RestoreCluster imports the archive created by ArchiveCluster as the cluster,
+// the files are extracted to a temporary directory first so the existing
+// cluster is only replaced when the archive is fully restored.
+func RestoreCluster(clusterName string, archive, passphrase []byte, overwrite bool) error {
+	root := ClusterPath(clusterName)
+	if !overwrite && utils.IsExist(filepath.Join(root, MetaFileName)) {
+		return errors.Errorf("cluster %s already exists", clusterName)
+	}
+
+	data, err := crypto.DecryptWithPassphrase(archive, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.CreateDir(ProfilePath(TiOpsClusterDir)); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(ProfilePath(TiOpsClusterDir), "."+clusterName+".restore")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := extractArchive(data, tmp); err != nil {
+		return errors.Annotate(err, "invalid archive")
+	}
+	if utils.IsNotExist(filepath.Join(tmp, MetaFileName)) {
+		return errors.Errorf("invalid archive, %s not found", MetaFileName)
+	}
+
+	if utils.IsNotExist(root) {
+		return errors.Trace(os.Rename(tmp, root))
+	}
+
+	// the current dir is moved aside until the restored one is in place, so
+	// it's left intact if anything fails
+	old := tmp + ".old"
+	if err := os.Rename(root, old); err != nil {
+		return errors.Trace(err)
+	}
+	// keep the lock held by the running command
+	lockFile := filepath.Join(old, LockFileName)
+	hasLock := utils.IsExist(lockFile)
+	if hasLock {
+		if err := os.Rename(lockFile, filepath.Join(tmp, LockFileName)); err != nil {
+			_ = os.Rename(old, root)
+			return errors.Trace(err)
+		}
+	}
+	if err := os.Rename(tmp, root); err != nil {
+		if hasLock {
+			_ = os.Rename(filepath.Join(tmp, LockFileName), lockFile)
+		}
+		_ = os.Rename(old, root)
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.RemoveAll(old))
+}
+
+func extractArchive(data []byte, dir string) error {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return errors.Errorf("illegal file path %s", hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.FileMode(hdr.Mode)|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/crypto"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+type archiveSuite struct {
+	profile string
+}
+
+var _ = check.Suite(&archiveSuite{})
+
+func (s *archiveSuite) SetUpTest(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-archive")
+	c.Assert(err, check.IsNil)
+	s.profile = dir
+	profileDir = dir
+}
+
+func (s *archiveSuite) TearDownTest(c *check.C) {
+	os.RemoveAll(s.profile)
+}
+
+func (s *archiveSuite) TestArchiveRestore(c *check.C) {
+	_, err := ArchiveCluster("test-archive", []byte("secret"))
+	c.Assert(err, check.NotNil)
+
+	c.Assert(utils.CreateDir(ClusterPath("test-archive", "ssh")), check.IsNil)
+	c.Assert(ioutil.WriteFile(ClusterPath("test-archive", MetaFileName), []byte("user: tidb\n"), 0644), check.IsNil)
+	c.Assert(ioutil.WriteFile(ClusterPath("test-archive", "ssh", "id_rsa"), []byte("private key"), 0600), check.IsNil)
+	c.Assert(ioutil.WriteFile(ClusterPath("test-archive", LockFileName), nil, 0644), check.IsNil)
+
+	archive, err := ArchiveCluster("test-archive", []byte("secret"))
+	c.Assert(err, check.IsNil)
+
+	// the cluster exists
+	c.Assert(RestoreCluster("test-archive", archive, []byte("secret"), false), check.NotNil)
+	c.Assert(RestoreCluster("test-restore", archive, []byte("wrong"), false), check.Equals, crypto.ErrorWrongPassphrase)
+
+	c.Assert(RestoreCluster("test-restore", archive, []byte("secret"), false), check.IsNil)
+	data, err := ioutil.ReadFile(ClusterPath("test-restore", "ssh", "id_rsa"))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "private key")
+	info, err := os.Stat(ClusterPath("test-restore", "ssh", "id_rsa"))
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+	c.Assert(utils.IsNotExist(ClusterPath("test-restore", LockFileName)), check.IsTrue)
+
+	// overwrite the existing cluster, the files not in the archive are removed
+	c.Assert(ioutil.WriteFile(ClusterPath("test-archive", "stale"), nil, 0644), check.IsNil)
+	c.Assert(RestoreCluster("test-archive", archive, []byte("secret"), true), check.IsNil)
+	c.Assert(utils.IsNotExist(ClusterPath("test-archive", "stale")), check.IsTrue)
+	c.Assert(utils.IsExist(ClusterPath("test-archive", LockFileName)), check.IsTrue)
+	c.Assert(utils.IsExist(ClusterPath("test-archive", MetaFileName)), check.IsTrue)
+
+	// neither the restored nor the replaced dir is left behind
+	files, err := ioutil.ReadDir(ProfilePath(TiOpsClusterDir))
+	c.Assert(err, check.IsNil)
+	for _, f := range files {
+		c.Assert(strings.HasPrefix(f.Name(), "."), check.IsFalse, check.Commentf("left %s", f.Name()))
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// BRJobDir is the directory to store the records of the BR jobs of a cluster
+const BRJobDir = "br_jobs"
+
+// The types of BR jobs
+const (
+	BRJobBackup  = "backup"
+	BRJobRestore = "restore"
+)
+
+// The status of BR jobs
+const (
+	BRJobRunning = "running"
+	BRJobSuccess = "success"
+	BRJobFailed  = "failed"
+)
+
+// BRJob is the record of a backup or restore job run by BR
+type BRJob struct {
+	ID        string    `json:"id" yaml:"id"`
+	Type      string    `json:"type" yaml:"type"`
+	Storage   string    `json:"storage" yaml:"storage"`
+	DB        string    `json:"db,omitempty" yaml:"db,omitempty"`
+	Table     string    `json:"table,omitempty" yaml:"table,omitempty"`
+	Host      string    `json:"host" yaml:"host"`
+	Version   string    `json:"version" yaml:"version"`
+	Status    string    `json:"status" yaml:"status"`
+	StartTime time.Time `json:"start_time" yaml:"start_time"`
+	EndTime   time.Time `json:"end_time,omitempty" yaml:"end_time,omitempty"`
+	// Source is the ID of the backup job restored from, if any
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	// LogFile is the path of the log of BR on the host
+	LogFile string `json:"log_file" yaml:"log_file"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// NewBRJob returns a running job of the type, the ID is generated from the
+// start time
+func NewBRJob(typ, storage string) *BRJob {
+	now := time.Now()
+	return &BRJob{
+		ID:        fmt.Sprintf("%s-%s", typ, now.Format("20060102150405")),
+		Type:      typ,
+		Storage:   storage,
+		Status:    BRJobRunning,
+		StartTime: now,
+	}
+}
+
+// Scope returns the scope of the job, it is one of full, db and table
+func (j *BRJob) Scope() string {
+	switch {
+	case j.Table != "":
+		return "table"
+	case j.DB != "":
+		return "db"
+	default:
+		return "full"
+	}
+}
+
+// Finish marks the job as finished with the error
+func (j *BRJob) Finish(err error) {
+	j.EndTime = time.Now()
+	if err != nil {
+		j.Status = BRJobFailed
+		j.Error = err.Error()
+		return
+	}
+	j.Status = BRJobSuccess
+}
+
+// SaveBRJob saves the record of the job of the cluster
+func SaveBRJob(clusterName string, job *BRJob) error {
+	dir := ClusterPath(clusterName, BRJobDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.AddStack(err)
+	}
+
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(ioutil.WriteFile(filepath.Join(dir, job.ID+".json"), data, 0644))
+}
+
+// GetBRJob returns the record of the job of the cluster
+func GetBRJob(clusterName, id string) (*BRJob, error) {
+	data, err := ioutil.ReadFile(ClusterPath(clusterName, BRJobDir, id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Errorf("job %s of cluster %s does not exist", id, clusterName)
+		}
+		return nil, errors.AddStack(err)
+	}
+
+	job := &BRJob{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, errors.Annotatef(err, "parse job %s", id)
+	}
+	return job, nil
+}
+
+// ListBRJobs returns the records of the jobs of the cluster ordered by the
+// start time
+func ListBRJobs(clusterName string) ([]*BRJob, error) {
+	files, err := ioutil.ReadDir(ClusterPath(clusterName, BRJobDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.AddStack(err)
+	}
+
+	var jobs []*BRJob
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		job, err := GetBRJob(clusterName, strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartTime.Before(jobs[j].StartTime)
+	})
+	return jobs, nil
+}
+
+// ParseBRStorage validates the storage URL of BR, a local path or a URL of
+// the local, s3 or gcs storage, and returns it in the form accepted by BR
+func ParseBRStorage(storage string) (string, error) {
+	if storage == "" {
+		return "", errors.New("the storage is required")
+	}
+	if strings.HasPrefix(storage, "/") {
+		storage = "local://" + storage
+	}
+
+	u, err := url.Parse(storage)
+	if err != nil {
+		return "", errors.Annotatef(err, "invalid storage %s", storage)
+	}
+	switch u.Scheme {
+	case "local", "file":
+		if u.Host != "" || !filepath.IsAbs(u.Path) {
+			return "", errors.Errorf("the path of the local storage %s must be absolute", storage)
+		}
+	case "s3", "gcs":
+		if u.Host == "" {
+			return "", errors.Errorf("the bucket of the storage %s is missing", storage)
+		}
+	default:
+		return "", errors.Errorf("unsupported storage %s, it must be a local path or a URL of local://, s3:// or gcs://", storage)
+	}
+	return storage, nil
+}
+
+// brStorageCredentials are the query parameters of the storage URL which carry
+// the credentials of the storage
+var brStorageCredentials = []string{"access-key", "secret-access-key", "session-token"}
+
+// ScrubBRStorage removes the credentials from the storage URL so that it can
+// be recorded or shown
+func ScrubBRStorage(storage string) string {
+	u, err := url.Parse(storage)
+	if err != nil {
+		return storage
+	}
+	scrubbed := u.User != nil
+	u.User = nil
+	query := u.Query()
+	for key := range query {
+		// BR accepts both the dashed and the underscored names
+		name := strings.ReplaceAll(strings.ToLower(key), "_", "-")
+		for _, c := range brStorageCredentials {
+			if name == c {
+				query.Del(key)
+				scrubbed = true
+			}
+		}
+	}
+	if !scrubbed {
+		return storage
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+)
+
+type brJobSuite struct {
+	profile string
+}
+
+var _ = check.Suite(&brJobSuite{})
+
+func (s *brJobSuite) SetUpSuite(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-br-job")
+	c.Assert(err, check.IsNil)
+	s.profile = dir
+	profileDir = dir
+}
+
+func (s *brJobSuite) TearDownSuite(c *check.C) {
+	os.RemoveAll(s.profile)
+}
+
+func (s *brJobSuite) TestJobRecords(c *check.C) {
+	jobs, err := ListBRJobs("test-br")
+	c.Assert(err, check.IsNil)
+	c.Assert(jobs, check.HasLen, 0)
+
+	backup := NewBRJob(BRJobBackup, "s3://bucket/full")
+	backup.StartTime = backup.StartTime.Add(-time.Hour)
+	backup.ID = "backup-1"
+	c.Assert(backup.Scope(), check.Equals, "full")
+	c.Assert(SaveBRJob("test-br", backup), check.IsNil)
+	backup.Finish(nil)
+	c.Assert(SaveBRJob("test-br", backup), check.IsNil)
+
+	restore := NewBRJob(BRJobRestore, "local:///data/db1")
+	restore.ID = "restore-1"
+	restore.DB = "db1"
+	c.Assert(restore.Scope(), check.Equals, "db")
+	restore.Finish(errors.New("connection refused"))
+	c.Assert(SaveBRJob("test-br", restore), check.IsNil)
+
+	jobs, err = ListBRJobs("test-br")
+	c.Assert(err, check.IsNil)
+	c.Assert(jobs, check.HasLen, 2)
+	c.Assert(jobs[0].ID, check.Equals, "backup-1")
+	c.Assert(jobs[0].Status, check.Equals, BRJobSuccess)
+	c.Assert(jobs[1].ID, check.Equals, "restore-1")
+	c.Assert(jobs[1].Status, check.Equals, BRJobFailed)
+	c.Assert(jobs[1].Error, check.Equals, "connection refused")
+
+	job, err := GetBRJob("test-br", "restore-1")
+	c.Assert(err, check.IsNil)
+	c.Assert(job.DB, check.Equals, "db1")
+	_, err = GetBRJob("test-br", "backup-2")
+	c.Assert(err, check.ErrorMatches, "job backup-2 of cluster test-br does not exist")
+}
+
+func (s *brJobSuite) TestParseStorage(c *check.C) {
+	for _, t := range []struct {
+		storage string
+		expect  string
+		err     string
+	}{
+		{"/data/backup", "local:///data/backup", ""},
+		{"local:///data/backup", "local:///data/backup", ""},
+		{"s3://bucket/prefix?endpoint=http://minio:9000", "s3://bucket/prefix?endpoint=http://minio:9000", ""},
+		{"", "", "the storage is required"},
+		{"data/backup", "", "unsupported storage data/backup.*"},
+		{"local://data/backup", "", "the path of the local storage .* must be absolute"},
+		{"s3:///prefix", "", "the bucket of the storage .* is missing"},
+		{"hdfs://nn/backup", "", "unsupported storage .*"},
+	} {
+		storage, err := ParseBRStorage(t.storage)
+		if t.err != "" {
+			c.Assert(err, check.ErrorMatches, t.err, check.Commentf(t.storage))
+			continue
+		}
+		c.Assert(err, check.IsNil, check.Commentf(t.storage))
+		c.Assert(storage, check.Equals, t.expect)
+	}
+}
+
+func (s *brJobSuite) TestScrubStorage(c *check.C) {
+	for storage, expect := range map[string]string{
+		"local:///data/backup":                                                   "local:///data/backup",
+		"s3://bucket/prefix?endpoint=http://minio:9000":                          "s3://bucket/prefix?endpoint=http://minio:9000",
+		"s3://bucket/prefix?access-key=ak&secret-access-key=sk&region=us-west-2": "s3://bucket/prefix?region=us-west-2",
+		"s3://bucket/prefix?Access_Key=ak&secret_access_key=sk&session-token=t":  "s3://bucket/prefix",
+		"gcs://bucket/prefix?credentials-file=/home/tidb/gcs.json":               "gcs://bucket/prefix?credentials-file=/home/tidb/gcs.json",
+		"s3://user:password@bucket/prefix":                                       "s3://bucket/prefix",
+	} {
+		c.Assert(ScrubBRStorage(storage), check.Equals, expect, check.Commentf(storage))
+	}
+}
@@ -14,6 +14,7 @@
 package meta
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 
@@ -33,6 +34,9 @@ const (
 	PatchDirName = "patch"
 	// BackupDirName is the directory to save backup files.
 	BackupDirName = "backup"
+	// checkPointFilePrefix is the prefix of the logs of finished tasks, they
+	// are used to resume an interrupted deploy, scale-out or upgrade
+	checkPointFilePrefix = "checkpoint"
 )
 
 var (
@@ -45,15 +49,21 @@ var (
 
 // ClusterMeta is the specification of generic cluster metadata
 type ClusterMeta struct {
-	User    string `yaml:"user"`         // the user to run and manage cluster on remote
-	Version string `yaml:"tidb_version"` // the version of TiDB cluster
-	//EnableTLS      bool   `yaml:"enable_tls"`
-	//EnableFirewall bool   `yaml:"firewall"`
-	OpsVer string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	User    string `yaml:"user"`                   // the user to run and manage cluster on remote
+	Version string `yaml:"tidb_version"`           // the version of TiDB cluster
+	OpsVer  string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+
+	Labels map[string]string `yaml:"labels,omitempty"` // the labels to select the cluster in batch operations
 
 	Topology *TopologySpecification `yaml:"topology"`
 }
 
+// CheckPointFileName returns the file name of the log of finished tasks of the
+// operation, each operation has its own log to not resume the tasks of another one.
+func CheckPointFileName(operation string) string {
+	return fmt.Sprintf("%s-%s.log", checkPointFilePrefix, operation)
+}
+
 // EnsureClusterDir ensures that the cluster directory exists.
 func EnsureClusterDir(clusterName string) error {
 	if err := utils.CreateDir(ClusterPath(clusterName)); err != nil {
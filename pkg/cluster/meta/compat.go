@@ -0,0 +1,124 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	errNSCompat = errNS.NewSubNamespace("compat")
+	// ErrDowngradeIncompatible means the data written by the current version
+	// can't be read by the version to roll back to
+	ErrDowngradeIncompatible = errNSCompat.NewType("downgrade_incompatible", errutil.ErrTraitPreCheck)
+)
+
+// dataFormatChanges are the versions since which the data on disk can't be
+// read by the older versions, the cluster can't be rolled back across them
+var dataFormatChanges = []struct {
+	version string
+	reason  string
+}{
+	{"v3.0.0", "TiDB v3.0 upgrades the schema of the system tables and TiKV v3.0 changes the format of the raft logs"},
+	{"v3.1.0", "TiDB v3.1 upgrades the schema of the system tables for the backup and restore"},
+	{"v4.0.0", "PD v4.0 keeps its config in etcd and TiDB v4.0 upgrades the schema of the system tables"},
+}
+
+// CheckDowngrade returns an error if the cluster of version from can't be
+// rolled back to the version to, which is the case if the data format has
+// moved forward between them
+func CheckDowngrade(from, to string) error {
+	if !semver.IsValid(from) || !semver.IsValid(to) {
+		return ErrDowngradeIncompatible.New("can't roll back from %s to %s, only the released versions are supported", from, to)
+	}
+	if semver.Compare(from, to) < 0 {
+		return ErrDowngradeIncompatible.New("can't roll back from %s to the newer version %s, please upgrade the cluster instead", from, to)
+	}
+	// the pre-releases have the changes of the release
+	release := strings.TrimSuffix(from, semver.Prerelease(from))
+	for _, c := range dataFormatChanges {
+		if semver.Compare(to, c.version) < 0 && semver.Compare(release, c.version) >= 0 {
+			return ErrDowngradeIncompatible.New("can't roll back from %s to %s as the data format is changed since %s: %s", from, to, c.version, c.reason)
+		}
+	}
+	return nil
+}
+
+// PreviousClusterVersion returns the version of the cluster before the last
+// upgrade, which is found in the backups of the meta. The current version is
+// returned if the cluster is never upgraded.
+func PreviousClusterVersion(clusterName string) (string, error) {
+	metadata, err := ClusterMetadata(clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	backup, err := findClusterMetaBackup(clusterName, func(backup *ClusterMeta) bool {
+		return backup.Version != "" && backup.Version != metadata.Version
+	})
+	if err != nil || backup == nil {
+		return metadata.Version, err
+	}
+	return backup.Version, nil
+}
+
+// ClusterMetaBackup returns the newest backup of the meta of the cluster at
+// the version, which is the meta before it's upgraded from the version. It's
+// nil if no backup is found.
+func ClusterMetaBackup(clusterName, version string) (*ClusterMeta, error) {
+	return findClusterMetaBackup(clusterName, func(backup *ClusterMeta) bool {
+		return backup.Version == version
+	})
+}
+
+// findClusterMetaBackup returns the newest backup of the meta matching fn
+func findClusterMetaBackup(clusterName string, fn func(*ClusterMeta) bool) (*ClusterMeta, error) {
+	backupDir := ClusterPath(clusterName, BackupDirName)
+	files, err := ioutil.ReadDir(backupDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.AddStack(err)
+	}
+
+	// the backups are named after the time they are saved, the newest first
+	prefix := strings.TrimSuffix(MetaFileName, filepath.Ext(MetaFileName)) + "-"
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() > files[j].Name()
+	})
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), prefix) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(backupDir, f.Name()))
+		if err != nil {
+			return nil, errors.AddStack(err)
+		}
+		var backup ClusterMeta
+		if err := yaml.Unmarshal(data, &backup); err != nil {
+			return nil, errors.Annotatef(err, "failed to parse the meta backup %s", f.Name())
+		}
+		if fn(&backup) {
+			return &backup, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+type compatSuite struct {
+	profile string
+}
+
+var _ = check.Suite(&compatSuite{})
+
+func (s *compatSuite) SetUpTest(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-compat")
+	c.Assert(err, check.IsNil)
+	s.profile = dir
+	profileDir = dir
+}
+
+func (s *compatSuite) TearDownTest(c *check.C) {
+	os.RemoveAll(s.profile)
+}
+
+func (s *compatSuite) TestCheckDowngrade(c *check.C) {
+	c.Assert(CheckDowngrade("v4.0.2", "v4.0.0"), check.IsNil)
+	c.Assert(CheckDowngrade("v4.0.2", "v4.0.2"), check.IsNil)
+	c.Assert(CheckDowngrade("v3.0.15", "v3.0.12"), check.IsNil)
+
+	for _, versions := range [][2]string{
+		{"v4.0.0", "v3.0.15"},
+		{"v4.0.0-rc.1", "v3.1.2"},
+		{"v3.0.1", "v2.1.19"},
+		{"v4.0.0", "v4.0.2"},
+		{"nightly", "v4.0.0"},
+	} {
+		err := CheckDowngrade(versions[0], versions[1])
+		c.Assert(errorx.IsOfType(err, ErrDowngradeIncompatible), check.IsTrue, check.Commentf("%v", versions))
+	}
+}
+
+func (s *compatSuite) TestPreviousClusterVersion(c *check.C) {
+	c.Assert(utils.CreateDir(ClusterPath("test-compat")), check.IsNil)
+	c.Assert(SaveClusterMeta("test-compat", &ClusterMeta{User: "tidb", Version: "v4.0.0"}), check.IsNil)
+	version, err := PreviousClusterVersion("test-compat")
+	c.Assert(err, check.IsNil)
+	c.Assert(version, check.Equals, "v4.0.0")
+
+	c.Assert(SaveClusterMeta("test-compat", &ClusterMeta{User: "tidb", Version: "v4.0.1"}), check.IsNil)
+	c.Assert(SaveClusterMeta("test-compat", &ClusterMeta{User: "tidb", Version: "v4.0.2"}), check.IsNil)
+	c.Assert(SaveClusterMeta("test-compat", &ClusterMeta{User: "tidb", Version: "v4.0.2"}), check.IsNil)
+	version, err = PreviousClusterVersion("test-compat")
+	c.Assert(err, check.IsNil)
+	c.Assert(version, check.Equals, "v4.0.1")
+
+	backup, err := ClusterMetaBackup("test-compat", "v4.0.0")
+	c.Assert(err, check.IsNil)
+	c.Assert(backup.Version, check.Equals, "v4.0.0")
+	backup, err = ClusterMetaBackup("test-compat", "v3.0.0")
+	c.Assert(err, check.IsNil)
+	c.Assert(backup, check.IsNil)
+}
@@ -0,0 +1,228 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pingcap/errors"
+)
+
+// the fields of an instance specification which can be changed without
+// rebuilding the instance
+var reconfigurableFields = []string{"Config", "ResourceControl"}
+
+// the fields of an instance specification which are maintained by tiup and
+// not compared with the desired topology
+var internalFields = []string{"Imported", "Offline"}
+
+// the fields which are only used by tiup, they are saved in the meta without
+// touching the instances
+var metaOnlyFields = []string{"Hooks"}
+
+// the roles which are configured by each field of ServerConfigs
+var serverConfigsRoles = map[string]string{
+	"TiDB":           ComponentTiDB,
+	"TiKV":           ComponentTiKV,
+	"PD":             ComponentPD,
+	"TiFlash":        ComponentTiFlash,
+	"TiFlashLearner": ComponentTiFlash,
+	"Pump":           ComponentPump,
+	"Drainer":        ComponentDrainer,
+	"CDC":            ComponentCDC,
+}
+
+// TopologyDiff represents the changes to reconcile a cluster to the desired topology
+type TopologyDiff struct {
+	// Added contains the instances only in the desired topology, the options
+	// and server configs are inherited from it so it can be used to scale out
+	Added *TopologySpecification
+	// Removed contains the IDs of the instances only in the current topology
+	Removed []string
+	// Reconfigured contains the IDs of the instances whose configuration is
+	// changed, either by the instance itself or by the server_configs
+	Reconfigured []string
+	// HooksChanged is true if the hooks of the cluster or any instance are
+	// changed, which only need the meta to be saved
+	HooksChanged bool
+}
+
+// Empty returns true if the current topology is the same as the desired one
+func (d *TopologyDiff) Empty() bool {
+	empty := true
+	d.Added.IterInstance(func(Instance) {
+		empty = false
+	})
+	return empty && len(d.Removed) == 0 && len(d.Reconfigured) == 0 && !d.HooksChanged
+}
+
+// specID returns the ID of the instance created by the specification, which
+// is the same as Instance.ID()
+func specID(spec reflect.Value) string {
+	return fmt.Sprintf("%s:%d", spec.FieldByName("Host").String(), spec.Interface().(InstanceSpec).GetMainPort())
+}
+
+// stripFields returns a copy of the specification with the fields set to zero
+// values, the empty maps are set to nil as they are the same in the topology
+func stripFields(spec reflect.Value, fields ...string) interface{} {
+	v := reflect.New(spec.Type()).Elem()
+	v.Set(spec)
+	for _, name := range fields {
+		if i, found := findField(v, name); found {
+			v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Kind() == reflect.Map && v.Field(i).Len() == 0 {
+			v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+		}
+	}
+	return v.Interface()
+}
+
+// DiffTopology compares the current topology of a cluster with the desired
+// one. Instances are matched by their IDs, and only the configuration and the
+// hooks of an existing instance can be changed, other changes (the directories,
+// ports of other than the main one, etc.) and the changes of the global options
+// other than the hooks and the monitored options are rejected as they need the
+// instance to be rebuilt.
+func DiffTopology(current, desired *TopologySpecification) (*TopologyDiff, error) {
+	currentGlobal := reflect.ValueOf(current.GlobalOptions)
+	desiredGlobal := reflect.ValueOf(desired.GlobalOptions)
+	if !reflect.DeepEqual(stripFields(currentGlobal, metaOnlyFields...), stripFields(desiredGlobal, metaOnlyFields...)) {
+		return nil, errors.New("the global options other than the hooks can't be changed")
+	}
+	if !reflect.DeepEqual(current.MonitoredOptions, desired.MonitoredOptions) {
+		return nil, errors.New("the monitored options can't be changed")
+	}
+
+	diff := &TopologyDiff{
+		Added: &TopologySpecification{
+			GlobalOptions:    current.GlobalOptions,
+			MonitoredOptions: current.MonitoredOptions,
+			ServerConfigs:    desired.ServerConfigs,
+		},
+		HooksChanged: !reflect.DeepEqual(stripFields(currentGlobal), stripFields(desiredGlobal)),
+	}
+	diff.Added.GlobalOptions.Hooks = desired.GlobalOptions.Hooks
+
+	// roles of which all instances are reconfigured
+	changedRoles := map[string]struct{}{}
+	currentConfigs := reflect.ValueOf(current.ServerConfigs)
+	desiredConfigs := reflect.ValueOf(desired.ServerConfigs)
+	for i := 0; i < currentConfigs.NumField(); i++ {
+		name := currentConfigs.Type().Field(i).Name
+		if currentConfigs.Field(i).Len() == 0 && desiredConfigs.Field(i).Len() == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(currentConfigs.Field(i).Interface(), desiredConfigs.Field(i).Interface()) {
+			changedRoles[serverConfigsRoles[name]] = struct{}{}
+		}
+	}
+
+	currentSpec := reflect.ValueOf(current).Elem()
+	desiredSpec := reflect.ValueOf(desired).Elem()
+	addedSpec := reflect.ValueOf(diff.Added).Elem()
+	for i := 0; i < currentSpec.NumField(); i++ {
+		if isSkipField(currentSpec.Field(i)) {
+			continue
+		}
+
+		existing := map[string]reflect.Value{}
+		for index := 0; index < currentSpec.Field(i).Len(); index++ {
+			spec := currentSpec.Field(i).Index(index)
+			existing[specID(spec)] = spec
+		}
+
+		kept := map[string]struct{}{}
+		for index := 0; index < desiredSpec.Field(i).Len(); index++ {
+			spec := desiredSpec.Field(i).Index(index)
+			id := specID(spec)
+			prev, found := existing[id]
+			if !found {
+				addedSpec.Field(i).Set(reflect.Append(addedSpec.Field(i), spec))
+				continue
+			}
+			kept[id] = struct{}{}
+
+			unchanged := append(append([]string{}, internalFields...), reconfigurableFields...)
+			ignored := append(append([]string{}, unchanged...), metaOnlyFields...)
+			if !reflect.DeepEqual(stripFields(prev, ignored...), stripFields(spec, ignored...)) {
+				return nil, errors.Errorf("only the config, resource_control and hooks of the existing instance %s can be changed, scale it in and out instead", id)
+			}
+			if !reflect.DeepEqual(stripFields(prev, unchanged...), stripFields(spec, unchanged...)) {
+				diff.HooksChanged = true
+			}
+
+			internal := append(append([]string{}, internalFields...), metaOnlyFields...)
+			_, roleChanged := changedRoles[spec.Interface().(InstanceSpec).Role()]
+			if roleChanged || !reflect.DeepEqual(stripFields(prev, internal...), stripFields(spec, internal...)) {
+				diff.Reconfigured = append(diff.Reconfigured, id)
+			}
+		}
+
+		for id, spec := range existing {
+			if _, found := kept[id]; found {
+				continue
+			}
+			// the instance is already being removed
+			if j, found := findField(spec, "Offline"); found && spec.Field(j).Bool() {
+				continue
+			}
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Reconfigured)
+
+	return diff, nil
+}
+
+// PatchConfig copies the server configs, the global hooks, and the reconfigurable
+// and meta only fields of the instances existing in both topologies, from the
+// desired topology
+func (topo *TopologySpecification) PatchConfig(desired *TopologySpecification) {
+	topo.ServerConfigs = desired.ServerConfigs
+	topo.GlobalOptions.Hooks = desired.GlobalOptions.Hooks
+
+	topoSpec := reflect.ValueOf(topo).Elem()
+	desiredSpec := reflect.ValueOf(desired).Elem()
+	for i := 0; i < topoSpec.NumField(); i++ {
+		if isSkipField(topoSpec.Field(i)) {
+			continue
+		}
+
+		wanted := map[string]reflect.Value{}
+		for index := 0; index < desiredSpec.Field(i).Len(); index++ {
+			spec := desiredSpec.Field(i).Index(index)
+			wanted[specID(spec)] = spec
+		}
+		for index := 0; index < topoSpec.Field(i).Len(); index++ {
+			spec := topoSpec.Field(i).Index(index)
+			want, found := wanted[specID(spec)]
+			if !found {
+				continue
+			}
+			for _, name := range append(append([]string{}, reconfigurableFields...), metaOnlyFields...) {
+				j, found := findField(spec, name)
+				if !found {
+					continue
+				}
+				spec.Field(j).Set(want.Field(j))
+			}
+		}
+	}
+}
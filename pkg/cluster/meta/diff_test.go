@@ -0,0 +1,160 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+type diffSuite struct{}
+
+var _ = Suite(&diffSuite{})
+
+const diffBaseTopology = `
+global:
+  user: tidb
+server_configs:
+  tikv:
+    storage.reserve-space: 1GB
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+  - host: 172.16.5.3
+tidb_servers:
+  - host: 172.16.5.1
+    config:
+      log.level: info
+`
+
+func parseDiffTopology(c *C, data string) *TopologySpecification {
+	topo := &TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(data), topo), IsNil)
+	return topo
+}
+
+// reloadTopology simulates the topology being saved to and loaded from meta.yaml
+func reloadTopology(c *C, topo *TopologySpecification) *TopologySpecification {
+	data, err := yaml.Marshal(topo)
+	c.Assert(err, IsNil)
+	return parseDiffTopology(c, string(data))
+}
+
+func (s *diffSuite) TestNoChange(c *C) {
+	current := reloadTopology(c, parseDiffTopology(c, diffBaseTopology))
+	diff, err := DiffTopology(current, parseDiffTopology(c, diffBaseTopology))
+	c.Assert(err, IsNil)
+	c.Assert(diff.Empty(), IsTrue)
+}
+
+func (s *diffSuite) TestAddRemoveReconfigure(c *C) {
+	current := reloadTopology(c, parseDiffTopology(c, diffBaseTopology))
+	desired := parseDiffTopology(c, `
+global:
+  user: tidb
+server_configs:
+  tikv:
+    storage.reserve-space: 2GB
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+  - host: 172.16.5.4
+tidb_servers:
+  - host: 172.16.5.1
+    config:
+      log.level: info
+  - host: 172.16.5.2
+`)
+	diff, err := DiffTopology(current, desired)
+	c.Assert(err, IsNil)
+	c.Assert(diff.Empty(), IsFalse)
+	c.Assert(diff.Removed, DeepEquals, []string{"172.16.5.3:20160"})
+	c.Assert(diff.Reconfigured, DeepEquals, []string{"172.16.5.1:20160", "172.16.5.2:20160"})
+	c.Assert(diff.Added.TiKVServers, HasLen, 1)
+	c.Assert(diff.Added.TiKVServers[0].Host, Equals, "172.16.5.4")
+	c.Assert(diff.Added.TiDBServers, HasLen, 1)
+	c.Assert(diff.Added.TiDBServers[0].Host, Equals, "172.16.5.2")
+	c.Assert(diff.Added.PDServers, HasLen, 0)
+	c.Assert(diff.Added.GlobalOptions, DeepEquals, current.GlobalOptions)
+
+	// the instance configuration
+	desired = parseDiffTopology(c, diffBaseTopology)
+	desired.TiDBServers[0].Config["log.level"] = "warn"
+	desired.TiKVServers[1].ResourceControl.MemoryLimit = "4G"
+	diff, err = DiffTopology(current, desired)
+	c.Assert(err, IsNil)
+	c.Assert(diff.Removed, HasLen, 0)
+	c.Assert(diff.Reconfigured, DeepEquals, []string{"172.16.5.1:4000", "172.16.5.2:20160"})
+
+	current.PatchConfig(desired)
+	c.Assert(current.TiDBServers[0].Config["log.level"], Equals, "warn")
+	c.Assert(current.TiKVServers[1].ResourceControl.MemoryLimit, Equals, "4G")
+	diff, err = DiffTopology(current, desired)
+	c.Assert(err, IsNil)
+	c.Assert(diff.Empty(), IsTrue)
+}
+
+func (s *diffSuite) TestOfflineInstance(c *C) {
+	current := reloadTopology(c, parseDiffTopology(c, diffBaseTopology))
+	current.TiKVServers[2].Offline = true
+	desired := parseDiffTopology(c, diffBaseTopology)
+	desired.TiKVServers = desired.TiKVServers[:2]
+
+	diff, err := DiffTopology(current, desired)
+	c.Assert(err, IsNil)
+	c.Assert(diff.Empty(), IsTrue)
+}
+
+func (s *diffSuite) TestHooksChange(c *C) {
+	current := reloadTopology(c, parseDiffTopology(c, diffBaseTopology))
+	desired := parseDiffTopology(c, diffBaseTopology)
+	desired.GlobalOptions.Hooks = Hooks{"post_deploy": {{Command: "echo deployed"}}}
+	desired.TiKVServers[0].Hooks = Hooks{"pre_start": {{Command: "echo starting"}}}
+
+	diff, err := DiffTopology(current, desired)
+	c.Assert(err, IsNil)
+	c.Assert(diff.HooksChanged, IsTrue)
+	c.Assert(diff.Reconfigured, HasLen, 0)
+	c.Assert(diff.Empty(), IsFalse)
+
+	current.PatchConfig(desired)
+	c.Assert(current.GlobalOptions.Hooks, DeepEquals, desired.GlobalOptions.Hooks)
+	c.Assert(current.TiKVServers[0].Hooks, DeepEquals, desired.TiKVServers[0].Hooks)
+	diff, err = DiffTopology(current, desired)
+	c.Assert(err, IsNil)
+	c.Assert(diff.Empty(), IsTrue)
+}
+
+func (s *diffSuite) TestUnsupportedChange(c *C) {
+	current := reloadTopology(c, parseDiffTopology(c, diffBaseTopology))
+
+	desired := parseDiffTopology(c, diffBaseTopology)
+	desired.TiKVServers[0].DataDir = "/data1"
+	_, err := DiffTopology(current, desired)
+	c.Assert(err, ErrorMatches, ".*172.16.5.1:20160.*")
+
+	desired = parseDiffTopology(c, diffBaseTopology)
+	desired.GlobalOptions.DeployDir = "/deploy"
+	_, err = DiffTopology(current, desired)
+	c.Assert(err, ErrorMatches, "the global options other than the hooks can't be changed")
+
+	desired = parseDiffTopology(c, diffBaseTopology)
+	desired.MonitoredOptions.NodeExporterPort = 9101
+	_, err = DiffTopology(current, desired)
+	c.Assert(err, ErrorMatches, "the monitored options can't be changed")
+}
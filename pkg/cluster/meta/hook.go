@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// Targets of the hooks
+const (
+	HookTargetControl = "control" // the hook runs on the control machine
+	HookTargetHost    = "host"    // the hook runs on the host of the instances
+)
+
+// Operations the hooks run around
+const (
+	HookOperationDeploy   = "deploy"
+	HookOperationStart    = "start"
+	HookOperationStop     = "stop"
+	HookOperationScaleIn  = "scale_in"
+	HookOperationScaleOut = "scale_out"
+	HookOperationUpgrade  = "upgrade"
+	HookOperationDestroy  = "destroy"
+)
+
+// HookOperations are all the operations the hooks can be declared for
+var HookOperations = []string{
+	HookOperationDeploy,
+	HookOperationStart,
+	HookOperationStop,
+	HookOperationScaleIn,
+	HookOperationScaleOut,
+	HookOperationUpgrade,
+	HookOperationDestroy,
+}
+
+// HookSpec represents a command run before or after an operation
+type HookSpec struct {
+	Command string `yaml:"command"`
+	Target  string `yaml:"target,omitempty"` // control or host, control by default
+}
+
+// RunOnHost returns true if the hook runs on the host of the instances
+func (h HookSpec) RunOnHost() bool {
+	return h.Target == HookTargetHost
+}
+
+// Hooks are the hooks declared in the topology, keyed by the stage which is
+// the operation prefixed with pre_ or post_, e.g: pre_start
+type Hooks map[string][]HookSpec
+
+// HookStage returns the stage of the hooks run before or after the operation
+func HookStage(operation string, pre bool) string {
+	if pre {
+		return "pre_" + operation
+	}
+	return "post_" + operation
+}
+
+// Get returns the hooks of the stage
+func (h Hooks) Get(operation string, pre bool) []HookSpec {
+	return h[HookStage(operation, pre)]
+}
+
+// Validate returns an error if any stage or target of the hooks is unknown
+func (h Hooks) Validate() error {
+	stages := make(map[string]struct{})
+	for _, op := range HookOperations {
+		stages[HookStage(op, true)] = struct{}{}
+		stages[HookStage(op, false)] = struct{}{}
+	}
+
+	for stage, hooks := range h {
+		if _, found := stages[stage]; !found {
+			supported := make([]string, 0, len(stages))
+			for s := range stages {
+				supported = append(supported, s)
+			}
+			sort.Strings(supported)
+			return errors.Errorf("unknown hook stage '%s', supported stages are %s", stage, strings.Join(supported, ", "))
+		}
+		for _, hook := range hooks {
+			if strings.TrimSpace(hook.Command) == "" {
+				return errors.Errorf("the command of the hook of '%s' is empty", stage)
+			}
+			switch hook.Target {
+			case "", HookTargetControl, HookTargetHost:
+			default:
+				return errors.Errorf("unknown target '%s' of the hook of '%s', it should be %s or %s",
+					hook.Target, stage, HookTargetControl, HookTargetHost)
+			}
+			// nothing is set up on the hosts before the deployment
+			if stage == HookStage(HookOperationDeploy, true) && hook.RunOnHost() {
+				return errors.Errorf("the hooks of '%s' can only run on the %s machine", stage, HookTargetControl)
+			}
+		}
+	}
+	return nil
+}
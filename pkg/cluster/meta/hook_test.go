@@ -0,0 +1,109 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+type hookSuite struct{}
+
+var _ = Suite(&hookSuite{})
+
+func (s *hookSuite) TestParseHooks(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  hooks:
+    pre_stop:
+      - command: "echo stop"
+    post_start:
+      - command: "echo started"
+        target: host
+tidb_servers:
+  - host: 172.16.5.138
+    hooks:
+      pre_upgrade:
+        - command: "deregister $TIUP_INSTANCE_ID"
+  - host: 172.16.5.139
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.GlobalOptions.Hooks.Get(HookOperationStop, true), DeepEquals, []HookSpec{{Command: "echo stop"}})
+	c.Assert(topo.GlobalOptions.Hooks.Get(HookOperationStart, false)[0].RunOnHost(), IsTrue)
+	c.Assert(topo.GlobalOptions.Hooks.Get(HookOperationStart, true), HasLen, 0)
+
+	var hooks []Hooks
+	topo.IterInstance(func(inst Instance) {
+		hooks = append(hooks, inst.Hooks())
+	})
+	c.Assert(hooks, HasLen, 2)
+	c.Assert(hooks[0].Get(HookOperationUpgrade, true), DeepEquals, []HookSpec{{Command: "deregister $TIUP_INSTANCE_ID"}})
+	c.Assert(hooks[1], IsNil)
+}
+
+func (s *hookSuite) TestValidateHooks(c *C) {
+	cases := []struct {
+		topo string
+		err  string
+	}{
+		{`
+global:
+  hooks:
+    pre_restart:
+      - command: "echo"
+`, "invalid hooks of global: unknown hook stage 'pre_restart'.*"},
+		{`
+global:
+  hooks:
+    post_stop:
+      - command: " "
+`, "invalid hooks of global: the command of the hook of 'post_stop' is empty"},
+		{`
+tidb_servers:
+  - host: 172.16.5.138
+    hooks:
+      post_start:
+        - command: "echo"
+          target: remote
+`, "invalid hooks of 172.16.5.138:4000: unknown target 'remote' .*"},
+		{`
+global:
+  hooks:
+    pre_deploy:
+      - command: "echo"
+        target: host
+`, ".*the hooks of 'pre_deploy' can only run on the control machine"},
+		{`
+global:
+  hooks:
+    pre_deploy:
+      - command: "echo"
+        target: control
+    post_destroy:
+      - command: "echo"
+        target: host
+`, ""},
+	}
+
+	for _, t := range cases {
+		topo := TopologySpecification{}
+		err := yaml.Unmarshal([]byte(t.topo), &topo)
+		if t.err == "" {
+			c.Assert(err, IsNil)
+			continue
+		}
+		c.Assert(err, ErrorMatches, t.err)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/utils"
+	"go.etcd.io/etcd/clientv3"
+)
+
+const (
+	// LockFileName is the file name of the local operation lock of a cluster
+	LockFileName = "cluster.lock"
+	// EtcdLockKey is the key of the operation lock in the etcd of PD
+	EtcdLockKey = "/tiup/cluster/lock"
+	// etcdLockTTL is the TTL in seconds of the lease of the etcd lock, the
+	// lock is released automatically if the holder dies
+	etcdLockTTL = 30
+	// etcdTimeout is the timeout of requests to etcd
+	etcdTimeout = 5 * time.Second
+)
+
+var (
+	errNSLock = errNS.NewSubNamespace("lock")
+	// ErrClusterLocked means another operation is running on the cluster
+	ErrClusterLocked = errNSLock.NewType("locked", errutil.ErrTraitPreCheck)
+)
+
+// LockInfo describes the holder of a cluster lock
+type LockInfo struct {
+	User      string    `json:"user"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Command   string    `json:"command"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// NewLockInfo returns the LockInfo of the current process running command
+func NewLockInfo(command string) *LockInfo {
+	host, _ := os.Hostname()
+	return &LockInfo{
+		User:      utils.CurrentUser(),
+		Host:      host,
+		PID:       os.Getpid(),
+		Command:   command,
+		StartTime: time.Now(),
+	}
+}
+
+// String implements the fmt.Stringer interface
+func (l *LockInfo) String() string {
+	return fmt.Sprintf("%s@%s (pid %d) is running `%s` since %s",
+		l.User, l.Host, l.PID, l.Command, l.StartTime.Format(time.RFC3339))
+}
+
+// ClusterLock is an advisory lock of a cluster held by the current process,
+// the local lock is a flock on the lock file in the cluster directory, and
+// the optional etcd lock is a key in the etcd of PD bound to a lease
+type ClusterLock struct {
+	name  string
+	file  *os.File
+	etcd  *clientv3.Client
+	lease clientv3.LeaseID
+	// stopKeepAlive stops keeping the lease alive
+	stopKeepAlive context.CancelFunc
+}
+
+// LockCluster acquires the local lock of the cluster, ErrClusterLocked is
+// returned if it is held by another process
+func LockCluster(clusterName string, info *LockInfo) (*ClusterLock, error) {
+	if err := os.MkdirAll(ClusterPath(clusterName), 0755); err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	path := ClusterPath(clusterName, LockFileName)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, errors.Annotatef(err, "open lock file %s", path)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer f.Close()
+		if err != syscall.EWOULDBLOCK {
+			return nil, errors.Annotatef(err, "lock file %s", path)
+		}
+		holder, _ := readLockInfo(f)
+		return nil, lockedError(clusterName, holder)
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		f.Close()
+		return nil, errors.AddStack(err)
+	}
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, errors.AddStack(err)
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		f.Close()
+		return nil, errors.AddStack(err)
+	}
+
+	return &ClusterLock{name: clusterName, file: f}, nil
+}
+
+// LockEtcd acquires the etcd lock of the cluster in addition to the local
+// lock, it is kept alive until Unlock is called or the process exits
+func (l *ClusterLock) LockEtcd(cli *clientv3.Client, info *LockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	lease, err := cli.Grant(ctx, etcdLockTTL)
+	if err != nil {
+		return errors.Annotate(err, "grant etcd lease")
+	}
+
+	resp, err := cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(EtcdLockKey), "=", 0)).
+		Then(clientv3.OpPut(EtcdLockKey, string(data), clientv3.WithLease(lease.ID))).
+		Else(clientv3.OpGet(EtcdLockKey)).
+		Commit()
+	if err != nil {
+		_, _ = cli.Revoke(context.Background(), lease.ID)
+		return errors.Annotate(err, "put etcd lock")
+	}
+	if !resp.Succeeded {
+		_, _ = cli.Revoke(context.Background(), lease.ID)
+		var holder *LockInfo
+		if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			holder = &LockInfo{}
+			if err := json.Unmarshal(kvs[0].Value, holder); err != nil {
+				holder = nil
+			}
+		}
+		return lockedError(l.name, holder)
+	}
+
+	keepCtx, stopKeepAlive := context.WithCancel(context.Background())
+	ch, err := cli.KeepAlive(keepCtx, lease.ID)
+	if err != nil {
+		stopKeepAlive()
+		_, _ = cli.Revoke(context.Background(), lease.ID)
+		return errors.Annotate(err, "keep etcd lease alive")
+	}
+	// the responses must be consumed, or the channel fills up and the client
+	// logs a warning for each of the dropped ones
+	go func() {
+		for range ch {
+		}
+	}()
+	l.etcd = cli
+	l.lease = lease.ID
+	l.stopKeepAlive = stopKeepAlive
+	return nil
+}
+
+// Unlock releases the lock
+func (l *ClusterLock) Unlock() error {
+	if l.etcd != nil {
+		l.stopKeepAlive()
+		ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+		_, _ = l.etcd.Revoke(ctx, l.lease)
+		cancel()
+		l.etcd.Close()
+		l.etcd = nil
+	}
+
+	// truncate before unlocking, so the lock file never shows a finished holder
+	_ = l.file.Truncate(0)
+	if err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN); err != nil {
+		l.file.Close()
+		return errors.AddStack(err)
+	}
+	return l.file.Close()
+}
+
+// ClusterLockStatus returns the holder of the local lock of the cluster, it
+// is nil if the lock is not held
+func ClusterLockStatus(clusterName string) (*LockInfo, error) {
+	path := ClusterPath(clusterName, LockFileName)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err == nil {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		return nil, nil
+	} else if err != syscall.EWOULDBLOCK {
+		return nil, errors.Annotatef(err, "lock file %s", path)
+	}
+	return readLockInfo(f)
+}
+
+// EtcdLockStatus returns the holder of the etcd lock, it is nil if the lock is not held
+func EtcdLockStatus(cli *clientv3.Client) (*LockInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	resp, err := cli.Get(ctx, EtcdLockKey)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	info := &LockInfo{}
+	if err := json.Unmarshal(resp.Kvs[0].Value, info); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return info, nil
+}
+
+// BreakClusterLock removes the local lock file of the cluster, the process
+// holding it is not stopped, but others are able to acquire the lock again
+func BreakClusterLock(clusterName string) error {
+	err := os.Remove(ClusterPath(clusterName, LockFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.AddStack(err)
+	}
+	return nil
+}
+
+// BreakEtcdLock deletes the etcd lock of the cluster
+func BreakEtcdLock(cli *clientv3.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	_, err := cli.Delete(ctx, EtcdLockKey)
+	return errors.AddStack(err)
+}
+
+func readLockInfo(f *os.File) (*LockInfo, error) {
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	info := &LockInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, errors.Annotate(err, "invalid lock file")
+	}
+	return info, nil
+}
+
+func lockedError(clusterName string, holder *LockInfo) error {
+	who := "another operation is running"
+	if holder != nil {
+		who = holder.String()
+	}
+	return ErrClusterLocked.
+		New("Cluster `%s` is locked: %s", clusterName, who).
+		WithProperty(errutil.ErrPropSuggestion,
+			fmt.Sprintf("Please wait for the operation to finish, or run `tiup cluster lock break %s` if it is stale.", clusterName))
+}
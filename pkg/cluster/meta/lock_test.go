@@ -0,0 +1,60 @@
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+)
+
+type lockSuite struct {
+	profile string
+}
+
+var _ = check.Suite(&lockSuite{})
+
+func (s *lockSuite) SetUpSuite(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-lock")
+	c.Assert(err, check.IsNil)
+	s.profile = dir
+	profileDir = dir
+}
+
+func (s *lockSuite) TearDownSuite(c *check.C) {
+	os.RemoveAll(s.profile)
+}
+
+func (s *lockSuite) TestClusterLock(c *check.C) {
+	holder, err := ClusterLockStatus("test-lock")
+	c.Assert(err, check.IsNil)
+	c.Assert(holder, check.IsNil)
+
+	info := NewLockInfo("tiup cluster upgrade test-lock v4.0.0")
+	lock, err := LockCluster("test-lock", info)
+	c.Assert(err, check.IsNil)
+
+	holder, err = ClusterLockStatus("test-lock")
+	c.Assert(err, check.IsNil)
+	c.Assert(holder, check.NotNil)
+	c.Assert(holder.Command, check.Equals, info.Command)
+	c.Assert(holder.PID, check.Equals, os.Getpid())
+
+	// the lock is exclusive even in the same process as the files are opened separately
+	_, err = LockCluster("test-lock", NewLockInfo("tiup cluster scale-in test-lock"))
+	c.Assert(errorx.IsOfType(err, ErrClusterLocked), check.IsTrue)
+
+	c.Assert(lock.Unlock(), check.IsNil)
+	holder, err = ClusterLockStatus("test-lock")
+	c.Assert(err, check.IsNil)
+	c.Assert(holder, check.IsNil)
+
+	// a broken lock can be acquired again
+	lock, err = LockCluster("test-lock", info)
+	c.Assert(err, check.IsNil)
+	c.Assert(BreakClusterLock("test-lock"), check.IsNil)
+	again, err := LockCluster("test-lock", info)
+	c.Assert(err, check.IsNil)
+	c.Assert(again.Unlock(), check.IsNil)
+	c.Assert(lock.Unlock(), check.IsNil)
+}
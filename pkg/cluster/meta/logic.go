@@ -15,6 +15,7 @@ package meta
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -58,6 +59,7 @@ const (
 	ComponentBlackboxExporter = "blackbox_exporter"
 	ComponentNodeExporter     = "node_exporter"
 	ComponentCheckCollector   = "insight"
+	ComponentBR               = "br"
 )
 
 // Component represents a component of the cluster.
@@ -74,7 +76,7 @@ type Instance interface {
 	WaitForDown(executor.TiOpsExecutor, int64) error
 	InitConfig(e executor.TiOpsExecutor, clusterName string, clusterVersion string, deployUser string, paths DirPaths) error
 	ScaleConfig(e executor.TiOpsExecutor, topo Specification, clusterName string, clusterVersion string, deployUser string, paths DirPaths) error
-	PrepareStart() error
+	PrepareStart(tlsCfg *tls.Config) error
 	ComponentName() string
 	InstanceName() string
 	ServiceName() string
@@ -84,11 +86,12 @@ type Instance interface {
 	DeployDir() string
 	UsedPorts() []int
 	UsedDirs() []string
-	Status(pdList ...string) string
+	Status(tlsCfg *tls.Config, pdList ...string) string
 	DataDir() string
 	LogDir() string
 	OS() string // only linux supported now
 	Arch() string
+	Hooks() Hooks
 }
 
 // Specification represents the topology of cluster/dm
@@ -138,7 +141,7 @@ type instance struct {
 
 	usedPorts []int
 	usedDirs  []string
-	statusFn  func(pdHosts ...string) string
+	statusFn  func(tlsCfg *tls.Config, pdHosts ...string) string
 }
 
 // Ready implements Instance interface
@@ -188,6 +191,10 @@ func (i *instance) InitConfig(e executor.TiOpsExecutor, _, _, user string, paths
 // mergeServerConfig merges the server configuration and overwrite the global configuration
 func (i *instance) mergeServerConfig(e executor.TiOpsExecutor, globalConf, instanceConf map[string]interface{}, paths DirPaths) error {
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("%s-%s-%d.toml", i.ComponentName(), i.GetHost(), i.GetPort()))
+	globalConf, err := i.withTLSConfig(i.ComponentName(), globalConf, paths)
+	if err != nil {
+		return err
+	}
 	conf, err := merge2Toml(i.ComponentName(), globalConf, instanceConf)
 	if err != nil {
 		return err
@@ -204,6 +211,10 @@ func (i *instance) mergeServerConfig(e executor.TiOpsExecutor, globalConf, insta
 // mergeTiFlashLearnerServerConfig merges the server configuration and overwrite the global configuration
 func (i *instance) mergeTiFlashLearnerServerConfig(e executor.TiOpsExecutor, globalConf, instanceConf map[string]interface{}, paths DirPaths) error {
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("%s-learner-%s-%d.toml", i.ComponentName(), i.GetHost(), i.GetPort()))
+	globalConf, err := i.withTLSConfig(i.ComponentName()+"-learner", globalConf, paths)
+	if err != nil {
+		return err
+	}
 	conf, err := merge2Toml(i.ComponentName()+"-learner", globalConf, instanceConf)
 	if err != nil {
 		return err
@@ -217,6 +228,15 @@ func (i *instance) mergeTiFlashLearnerServerConfig(e executor.TiOpsExecutor, glo
 	return e.Transfer(fp, dst, false)
 }
 
+// withTLSConfig merges the security section into the global configuration
+// of the component if TLS is enabled for the cluster
+func (i *instance) withTLSConfig(comp string, globalConf map[string]interface{}, paths DirPaths) (map[string]interface{}, error) {
+	if i.topo == nil || !i.topo.GlobalOptions.TLSEnabled {
+		return globalConf, nil
+	}
+	return merge(globalConf, tlsServerConfig(comp, paths.Deploy))
+}
+
 // ID returns the identifier of this instance, the ID is constructed by host:port
 func (i *instance) ID() string {
 	return fmt.Sprintf("%s:%d", i.host, i.port)
@@ -279,8 +299,17 @@ func (i *instance) Arch() string {
 	return reflect.ValueOf(i.InstanceSpec).FieldByName("Arch").Interface().(string)
 }
 
+// Hooks returns the hooks declared for the instance
+func (i *instance) Hooks() Hooks {
+	v := reflect.ValueOf(i.InstanceSpec).FieldByName("Hooks")
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface().(Hooks)
+}
+
 // PrepareStart checks instance requirements before starting
-func (i *instance) PrepareStart() error {
+func (i *instance) PrepareStart(tlsCfg *tls.Config) error {
 	return nil
 }
 
@@ -336,8 +365,8 @@ func (i *instance) UsedDirs() []string {
 	return i.usedDirs
 }
 
-func (i *instance) Status(pdList ...string) string {
-	return i.statusFn(pdList...)
+func (i *instance) Status(tlsCfg *tls.Config, pdList ...string) string {
+	return i.statusFn(tlsCfg, pdList...)
 }
 
 // ClusterSpecification of cluster
@@ -613,7 +642,7 @@ func (i *PDInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clusterVe
 		paths.Deploy,
 		paths.Data[0],
 		paths.Log,
-	).WithClientPort(spec.ClientPort).WithPeerPort(spec.PeerPort).AppendEndpoints(i.instance.topo.Endpoints(deployUser)...)
+	).WithScheme(i.instance.topo.scheme()).WithClientPort(spec.ClientPort).WithPeerPort(spec.PeerPort).AppendEndpoints(i.instance.topo.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pd_%s_%d.sh", i.GetHost(), i.GetPort()))
 	if err := cfg.ConfigToFile(fp); err != nil {
@@ -681,7 +710,7 @@ func (i *PDInstance) ScaleConfig(e executor.TiOpsExecutor, b Specification, clus
 		paths.Deploy,
 		paths.Data[0],
 		paths.Log,
-	).WithPeerPort(spec.PeerPort).WithNumaNode(spec.NumaNode).WithClientPort(spec.ClientPort).AppendEndpoints(c.Endpoints(deployUser)...)
+	).WithScheme(c.scheme()).WithPeerPort(spec.PeerPort).WithNumaNode(spec.NumaNode).WithClientPort(spec.ClientPort).AppendEndpoints(c.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pd_%s_%d.sh", i.GetHost(), i.GetPort()))
 	log.Infof("script path: %s", fp)
@@ -1025,10 +1054,10 @@ func (i *TiFlashInstance) getEndpoints() []string {
 }
 
 // PrepareStart checks TiFlash requirements before starting
-func (i *TiFlashInstance) PrepareStart() error {
+func (i *TiFlashInstance) PrepareStart(tlsCfg *tls.Config) error {
 	endPoints := i.getEndpoints()
 	// set enable-placement-rules to true via PDClient
-	pdClient := api.NewPDClient(endPoints, 10*time.Second, nil)
+	pdClient := api.NewPDClient(endPoints, 10*time.Second, tlsCfg)
 	enablePlacementRules, err := json.Marshal(replicateConfig{
 		EnablePlacementRules: "true",
 	})
@@ -1066,7 +1095,7 @@ func (c *MonitorComponent) Instances() []Instance {
 				s.DeployDir,
 				s.DataDir,
 			},
-			statusFn: func(_ ...string) string {
+			statusFn: func(_ *tls.Config, _ ...string) string {
 				return "-"
 			},
 		}})
@@ -1110,7 +1139,8 @@ func (i *MonitorInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 
 	// transfer config
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("tikv_%s.yml", i.GetHost()))
-	cfig := config.NewPrometheusConfig(clusterName)
+	cfig := config.NewPrometheusConfig(clusterName).
+		WithTLSEnabled(i.instance.topo.GlobalOptions.TLSEnabled, paths.Deploy)
 	cfig.AddBlackbox(i.GetHost(), uint64(i.instance.topo.MonitoredOptions.BlackboxExporterPort))
 	uniqueHosts := set.NewStringSet()
 	for _, pd := range i.instance.topo.PDServers {
@@ -1199,7 +1229,7 @@ func (c *GrafanaComponent) Instances() []Instance {
 				usedDirs: []string{
 					s.DeployDir,
 				},
-				statusFn: func(_ ...string) string {
+				statusFn: func(_ *tls.Config, _ ...string) string {
 					return "-"
 				},
 			},
@@ -1307,7 +1337,7 @@ func (c *AlertManagerComponent) Instances() []Instance {
 					s.DeployDir,
 					s.DataDir,
 				},
-				statusFn: func(_ ...string) string {
+				statusFn: func(_ *tls.Config, _ ...string) string {
 					return "-"
 				},
 			},
@@ -1477,6 +1507,7 @@ func (topo *ClusterSpecification) Endpoints(user string) []*scripts.PDScript {
 			deployDir,
 			dataDir,
 			logDir).
+			WithScheme(topo.scheme()).
 			WithClientPort(spec.ClientPort).
 			WithPeerPort(spec.PeerPort)
 		ends = append(ends, script)
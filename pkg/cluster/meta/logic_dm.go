@@ -14,6 +14,7 @@
 package meta
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -41,7 +42,7 @@ type dmInstance struct {
 
 	usedPorts []int
 	usedDirs  []string
-	statusFn  func(masterHosts ...string) string
+	statusFn  func(tlsCfg *tls.Config, masterHosts ...string) string
 }
 
 // Ready implements Instance interface
@@ -171,7 +172,12 @@ func (i *dmInstance) Arch() string {
 	return reflect.ValueOf(i.InstanceSpec).FieldByName("Arch").Interface().(string)
 }
 
-func (i *dmInstance) PrepareStart() error {
+// Hooks returns nil as the hooks are not supported by DM
+func (i *dmInstance) Hooks() Hooks {
+	return nil
+}
+
+func (i *dmInstance) PrepareStart(tlsCfg *tls.Config) error {
 	return nil
 }
 
@@ -204,8 +210,8 @@ func (i *dmInstance) UsedDirs() []string {
 	return i.usedDirs
 }
 
-func (i *dmInstance) Status(masterList ...string) string {
-	return i.statusFn(masterList...)
+func (i *dmInstance) Status(tlsCfg *tls.Config, masterList ...string) string {
+	return i.statusFn(tlsCfg, masterList...)
 }
 
 // DMSpecification of cluster
@@ -485,9 +491,9 @@ func (c *DMPortalComponent) Instances() []Instance {
 					s.DeployDir,
 					s.DataDir,
 				},
-				statusFn: func(_ ...string) string {
-					url := fmt.Sprintf("http://%s:%d", s.Host, s.Port)
-					return statusByURL(url)
+				statusFn: func(tlsCfg *tls.Config, _ ...string) string {
+					url := fmt.Sprintf("%s:%d", s.Host, s.Port)
+					return statusByURL(url, tlsCfg)
 				},
 			}})
 	}
@@ -0,0 +1,129 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+var (
+	labelKeyRegexp   = regexp.MustCompile(`^[a-zA-Z0-9]([-_./a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+	labelValueRegexp = regexp.MustCompile(`^[-_./a-zA-Z0-9]*$`)
+)
+
+// ValidateLabel returns an error if the key or the value of a cluster label is invalid
+func ValidateLabel(key, value string) error {
+	if !labelKeyRegexp.MatchString(key) {
+		return errors.Errorf("invalid label key '%s', it should only contain alphabets, numbers, '-', '_', '.' and '/', and begin and end with an alphabet or a number", key)
+	}
+	if !labelValueRegexp.MatchString(value) {
+		return errors.Errorf("invalid value '%s' of label '%s', it should only contain alphabets, numbers, '-', '_', '.' and '/'", value, key)
+	}
+	return nil
+}
+
+// ParseLabels parses the labels in the form of key=value, the value is empty
+// if only the key is specified
+func ParseLabels(labels []string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, l := range labels {
+		kv := strings.SplitN(l, "=", 2)
+		key, value := kv[0], ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+		if err := ValidateLabel(key, value); err != nil {
+			return nil, err
+		}
+		parsed[key] = value
+	}
+	return parsed, nil
+}
+
+// HasGlob returns true if the string is a glob pattern instead of a name
+func HasGlob(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
+
+// ClusterSelector selects the clusters by the name and the labels
+type ClusterSelector struct {
+	Pattern string            // the glob pattern of the name, all names are matched if empty
+	Labels  map[string]string // the labels the cluster must have, any value is matched if it is empty
+}
+
+// Match returns true if the cluster of the name and the labels is selected
+func (s ClusterSelector) Match(name string, labels map[string]string) bool {
+	if s.Pattern != "" {
+		if matched, _ := path.Match(s.Pattern, name); !matched {
+			return false
+		}
+	}
+	for key, value := range s.Labels {
+		v, found := labels[key]
+		if !found || (value != "" && v != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ListClusterNames returns the names of all the clusters in the profile directory
+func ListClusterNames() ([]string, error) {
+	fileInfos, err := ioutil.ReadDir(ProfilePath(TiOpsClusterDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.AddStack(err)
+	}
+
+	var names []string
+	for _, fi := range fileInfos {
+		if utils.IsNotExist(ClusterPath(fi.Name(), MetaFileName)) {
+			continue
+		}
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SelectClusters returns the names of the clusters selected
+func SelectClusters(s ClusterSelector) ([]string, error) {
+	if _, err := path.Match(s.Pattern, ""); err != nil {
+		return nil, errors.Errorf("invalid cluster name pattern '%s'", s.Pattern)
+	}
+
+	names, err := ListClusterNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []string
+	for _, name := range names {
+		metadata, err := ClusterMetadata(name)
+		if err != nil {
+			return nil, errors.Annotatef(err, "failed to read the meta of cluster %s", name)
+		}
+		if s.Match(name, metadata.Labels) {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
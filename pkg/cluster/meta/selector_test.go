@@ -0,0 +1,99 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+type selectorSuite struct {
+	profile string
+}
+
+var _ = check.Suite(&selectorSuite{})
+
+func (s *selectorSuite) SetUpTest(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-selector")
+	c.Assert(err, check.IsNil)
+	s.profile = dir
+	profileDir = dir
+}
+
+func (s *selectorSuite) TearDownTest(c *check.C) {
+	os.RemoveAll(s.profile)
+}
+
+func (s *selectorSuite) TestParseLabels(c *check.C) {
+	labels, err := ParseLabels([]string{"env=prod", "region", "team=db/core", "empty="})
+	c.Assert(err, check.IsNil)
+	c.Assert(labels, check.DeepEquals, map[string]string{"env": "prod", "region": "", "team": "db/core", "empty": ""})
+
+	for _, l := range []string{"", "-env=prod", "env=a,b", "en v=prod", "env=a=b"} {
+		_, err := ParseLabels([]string{l})
+		c.Assert(err, check.NotNil, check.Commentf("%s", l))
+	}
+}
+
+func (s *selectorSuite) TestMatch(c *check.C) {
+	labels := map[string]string{"env": "prod", "region": "us"}
+	c.Assert(ClusterSelector{}.Match("prod-1", nil), check.IsTrue)
+	c.Assert(ClusterSelector{Pattern: "prod-*"}.Match("prod-1", nil), check.IsTrue)
+	c.Assert(ClusterSelector{Pattern: "prod-?"}.Match("prod-12", nil), check.IsFalse)
+	c.Assert(ClusterSelector{Labels: map[string]string{"env": "prod"}}.Match("a", labels), check.IsTrue)
+	c.Assert(ClusterSelector{Labels: map[string]string{"region": ""}}.Match("a", labels), check.IsTrue)
+	c.Assert(ClusterSelector{Labels: map[string]string{"env": "test"}}.Match("a", labels), check.IsFalse)
+	c.Assert(ClusterSelector{Labels: map[string]string{"zone": ""}}.Match("a", labels), check.IsFalse)
+	c.Assert(ClusterSelector{Pattern: "b*", Labels: map[string]string{"env": "prod"}}.Match("a", labels), check.IsFalse)
+
+	c.Assert(HasGlob("prod-*"), check.IsTrue)
+	c.Assert(HasGlob("prod-[12]"), check.IsTrue)
+	c.Assert(HasGlob("prod-1"), check.IsFalse)
+}
+
+func (s *selectorSuite) TestSelectClusters(c *check.C) {
+	for name, labels := range map[string]map[string]string{
+		"prod-1": {"env": "prod"},
+		"prod-2": {"env": "prod", "region": "us"},
+		"test-1": {"env": "test"},
+		"test-2": nil,
+	} {
+		c.Assert(utils.CreateDir(ClusterPath(name)), check.IsNil)
+		c.Assert(SaveClusterMeta(name, &ClusterMeta{User: "tidb", Version: "v4.0.0", Labels: labels}), check.IsNil)
+	}
+	// not a cluster
+	c.Assert(utils.CreateDir(ClusterPath("prod-3")), check.IsNil)
+
+	names, err := ListClusterNames()
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"prod-1", "prod-2", "test-1", "test-2"})
+
+	names, err = SelectClusters(ClusterSelector{Pattern: "prod-*"})
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"prod-1", "prod-2"})
+
+	names, err = SelectClusters(ClusterSelector{Labels: map[string]string{"env": ""}})
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"prod-1", "prod-2", "test-1"})
+
+	names, err = SelectClusters(ClusterSelector{Pattern: "*-2", Labels: map[string]string{"region": "us"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"prod-2"})
+
+	_, err = SelectClusters(ClusterSelector{Pattern: "prod-["})
+	c.Assert(err, check.NotNil)
+}
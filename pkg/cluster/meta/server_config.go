@@ -155,7 +155,19 @@ func mergeImported(importConfig []byte, specConfig map[string]interface{}) (map[
 	return lhs, nil
 }
 
+// RenderOnlyExecutor is implemented by the executors only recording the files
+// transferred by InitConfig, the configs are not checked with them as the
+// binaries are not run
+type RenderOnlyExecutor interface {
+	executor.TiOpsExecutor
+	RenderOnly()
+}
+
 func checkConfig(e executor.TiOpsExecutor, componentName, clusterVersion, nodeOS, arch, config string, paths DirPaths) error {
+	if _, ok := e.(RenderOnlyExecutor); ok {
+		return nil
+	}
+
 	repo, err := clusterutil.NewRepository(nodeOS, arch)
 	if err != nil {
 		return err
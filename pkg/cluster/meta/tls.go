@@ -0,0 +1,188 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/crypto"
+)
+
+// TLS related file and directory names
+const (
+	// TLSCertKeyDir is the directory to store certificates and keys, it is
+	// used both in the cluster profile directory and the deploy dir of instances
+	TLSCertKeyDir = "tls"
+	// TLSCACert is the file name of the CA certificate
+	TLSCACert = "ca.crt"
+	// TLSCAKey is the file name of the private key of the CA
+	TLSCAKey = "ca.pem"
+	// TLSClientCert is the file name of the client certificate used by tiup
+	TLSClientCert = "client.crt"
+	// TLSClientKey is the file name of the private key of the client certificate
+	TLSClientKey = "client.pem"
+)
+
+// TLSConfig returns the tls.Config used to connect to the cluster, it is nil
+// if TLS is not enabled for the cluster, dir is the directory storing the CA
+// and client certificate, normally ClusterPath(name, TLSCertKeyDir)
+func (topo *TopologySpecification) TLSConfig(dir string) (*tls.Config, error) {
+	if !topo.GlobalOptions.TLSEnabled {
+		return nil, nil
+	}
+	return LoadClientCert(dir)
+}
+
+// LoadClientCert loads the CA and client certificate in dir to a tls.Config
+func LoadClientCert(dir string) (*tls.Config, error) {
+	caData, err := ioutil.ReadFile(filepath.Join(dir, TLSCACert))
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to read CA certificate")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, errors.Errorf("invalid CA certificate in %s", dir)
+	}
+
+	cert, err := tls.LoadX509KeyPair(
+		filepath.Join(dir, TLSClientCert),
+		filepath.Join(dir, TLSClientKey),
+	)
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to load client certificate")
+	}
+
+	return &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{cert},
+	}, nil
+}
+
+// GenClusterCA generates the CA and the client certificate of the cluster
+// and saves them to dir, an existing CA in dir is reused. They are generated
+// only if neither the certificate nor the key of the CA exists
+func GenClusterCA(clusterName, dir string) (*crypto.CertificateAuthority, error) {
+	caCert := filepath.Join(dir, TLSCACert)
+	caKey := filepath.Join(dir, TLSCAKey)
+
+	missing := 0
+	for _, path := range []string{caCert, caKey} {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			missing++
+		} else if err != nil {
+			return nil, errors.AddStack(err)
+		}
+	}
+	switch missing {
+	case 0:
+		ca, err := crypto.ReadCA(clusterName, caCert, caKey)
+		return ca, errors.Annotatef(err, "read the CA from %s", dir)
+	case 1:
+		return nil, errors.Errorf("only one of %s and %s exists in %s", TLSCACert, TLSCAKey, dir)
+	}
+
+	ca, err := crypto.NewCA(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(caCert, ca.CertPEM(), 0644); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if err := ioutil.WriteFile(caKey, ca.KeyPEM(), 0600); err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	// client certificate for tiup itself to access the cluster
+	key, err := crypto.NewPrivateKey()
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	cert, err := ca.Sign("tiup", nil, &key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, TLSClientCert), cert, 0644); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, TLSClientKey), crypto.EncodePrivateKey(key), 0600); err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	return ca, nil
+}
+
+// scheme returns the URL scheme of the cluster's HTTP services
+func (topo *TopologySpecification) scheme() string {
+	if topo.GlobalOptions.TLSEnabled {
+		return "https"
+	}
+	return "http"
+}
+
+// tlsServerConfig returns the security section of the config of a component
+// whose certificates are placed under the tls sub directory of deployDir
+func tlsServerConfig(comp, deployDir string) map[string]interface{} {
+	ca := filepath.Join(deployDir, TLSCertKeyDir, TLSCACert)
+	cert := filepath.Join(deployDir, TLSCertKeyDir, fmt.Sprintf("%s.crt", comp))
+	key := filepath.Join(deployDir, TLSCertKeyDir, fmt.Sprintf("%s.pem", comp))
+
+	switch comp {
+	case ComponentTiDB:
+		return map[string]interface{}{
+			"security.cluster-ssl-ca":   ca,
+			"security.cluster-ssl-cert": cert,
+			"security.cluster-ssl-key":  key,
+		}
+	case ComponentTiKV:
+		return map[string]interface{}{
+			"security.ca-path":   ca,
+			"security.cert-path": cert,
+			"security.key-path":  key,
+		}
+	case ComponentTiFlash + "-learner":
+		// the proxy of TiFlash shares the certificate with TiFlash
+		cert = filepath.Join(deployDir, TLSCertKeyDir, fmt.Sprintf("%s.crt", ComponentTiFlash))
+		key = filepath.Join(deployDir, TLSCertKeyDir, fmt.Sprintf("%s.pem", ComponentTiFlash))
+		return map[string]interface{}{
+			"security.ca-path":   ca,
+			"security.cert-path": cert,
+			"security.key-path":  key,
+		}
+	case ComponentPD:
+		return map[string]interface{}{
+			"security.cacert-path": ca,
+			"security.cert-path":   cert,
+			"security.key-path":    key,
+		}
+	case ComponentTiFlash:
+		return map[string]interface{}{
+			"security.ca_path":   ca,
+			"security.cert_path": cert,
+			"security.key_path":  key,
+		}
+	case ComponentPump, ComponentDrainer:
+		return map[string]interface{}{
+			"security.ssl-ca":   ca,
+			"security.ssl-cert": cert,
+			"security.ssl-key":  key,
+		}
+	}
+	return nil
+}
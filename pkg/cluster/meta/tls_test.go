@@ -0,0 +1,72 @@
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+type tlsSuite struct {
+}
+
+var _ = check.Suite(&tlsSuite{})
+
+func (s *tlsSuite) TestTLSConfig(c *check.C) {
+	topo := new(TopologySpecification)
+	err := yaml.Unmarshal([]byte(`
+global:
+  enable_tls: true
+pd_servers:
+  - host: 172.16.5.140
+`), topo)
+	c.Assert(err, check.IsNil)
+	c.Assert(topo.GlobalOptions.TLSEnabled, check.IsTrue)
+	c.Assert(topo.scheme(), check.Equals, "https")
+
+	dir, err := ioutil.TempDir("", "tiup-tls")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	// the certificates are not generated yet
+	_, err = topo.TLSConfig(dir)
+	c.Assert(err, check.NotNil)
+
+	ca, err := GenClusterCA("test-cluster", dir)
+	c.Assert(err, check.IsNil)
+	tlsCfg, err := topo.TLSConfig(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(tlsCfg.Certificates, check.HasLen, 1)
+
+	// the existing CA is reused
+	again, err := GenClusterCA("test-cluster", dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(again.Cert.Equal(ca.Cert), check.IsTrue)
+
+	// a broken CA is not replaced silently
+	err = ioutil.WriteFile(filepath.Join(dir, TLSCAKey), []byte("broken"), 0600)
+	c.Assert(err, check.IsNil)
+	_, err = GenClusterCA("test-cluster", dir)
+	c.Assert(err, check.NotNil)
+	err = os.Remove(filepath.Join(dir, TLSCAKey))
+	c.Assert(err, check.IsNil)
+	_, err = GenClusterCA("test-cluster", dir)
+	c.Assert(err, check.NotNil)
+
+	topo.GlobalOptions.TLSEnabled = false
+	tlsCfg, err = topo.TLSConfig(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(tlsCfg, check.IsNil)
+	c.Assert(topo.scheme(), check.Equals, "http")
+}
+
+func (s *tlsSuite) TestTLSServerConfig(c *check.C) {
+	conf := tlsServerConfig(ComponentTiKV, "/home/tidb/deploy/tikv-20160")
+	c.Assert(conf["security.ca-path"], check.Equals, "/home/tidb/deploy/tikv-20160/tls/ca.crt")
+	c.Assert(conf["security.cert-path"], check.Equals, "/home/tidb/deploy/tikv-20160/tls/tikv.crt")
+	c.Assert(conf["security.key-path"], check.Equals, "/home/tidb/deploy/tikv-20160/tls/tikv.pem")
+
+	c.Assert(tlsServerConfig(ComponentGrafana, "/home/tidb/deploy/grafana-3000"), check.IsNil)
+}
@@ -14,6 +14,7 @@
 package meta
 
 import (
+	"crypto/tls"
 	"fmt"
 	"path/filepath"
 	"reflect"
@@ -64,10 +65,12 @@ type (
 	GlobalOptions struct {
 		User            string          `yaml:"user,omitempty" default:"tidb"`
 		SSHPort         int             `yaml:"ssh_port,omitempty" default:"22"`
+		TLSEnabled      bool            `yaml:"enable_tls,omitempty"`
 		DeployDir       string          `yaml:"deploy_dir,omitempty" default:"deploy"`
 		DataDir         string          `yaml:"data_dir,omitempty" default:"data"`
 		LogDir          string          `yaml:"log_dir,omitempty"`
 		ResourceControl ResourceControl `yaml:"resource_control,omitempty"`
+		Hooks           Hooks           `yaml:"hooks,omitempty"`
 		OS              string          `yaml:"os,omitempty" default:"linux"`
 		Arch            string          `yaml:"arch,omitempty" default:"amd64"`
 	}
@@ -136,13 +139,21 @@ type TiDBSpec struct {
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
 	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
+	Hooks           Hooks                  `yaml:"hooks,omitempty"`
 	Arch            string                 `yaml:"arch,omitempty"`
 	OS              string                 `yaml:"os,omitempty"`
 }
 
 // statusByURL queries current status of the instance by http status api.
-func statusByURL(url string) string {
-	client := utils2.NewHTTPClient(statusQueryTimeout, nil)
+func statusByURL(url string, tlsCfg *tls.Config) string {
+	client := utils2.NewHTTPClient(statusQueryTimeout, tlsCfg)
+
+	// the url is built without scheme, add it according to the TLS setting
+	scheme := "http"
+	if tlsCfg != nil {
+		scheme = "https"
+	}
+	url = fmt.Sprintf("%s://%s", scheme, url)
 
 	// body doesn't have any status section needed
 	body, err := client.Get(url)
@@ -157,9 +168,9 @@ func statusByURL(url string) string {
 }
 
 // Status queries current status of the instance
-func (s TiDBSpec) Status(pdList ...string) string {
-	url := fmt.Sprintf("http://%s:%d/status", s.Host, s.StatusPort)
-	return statusByURL(url)
+func (s TiDBSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
+	url := fmt.Sprintf("%s:%d/status", s.Host, s.StatusPort)
+	return statusByURL(url, tlsCfg)
 }
 
 // Role returns the component role of the instance
@@ -196,16 +207,17 @@ type TiKVSpec struct {
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
 	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
+	Hooks           Hooks                  `yaml:"hooks,omitempty"`
 	Arch            string                 `yaml:"arch,omitempty"`
 	OS              string                 `yaml:"os,omitempty"`
 }
 
 // checkStoreStatus checks the store status in current cluster
-func checkStoreStatus(storeAddr string, pdList ...string) string {
+func checkStoreStatus(storeAddr string, tlsCfg *tls.Config, pdList ...string) string {
 	if len(pdList) < 1 {
 		return "N/A"
 	}
-	pdapi := api.NewPDClient(pdList, statusQueryTimeout, nil)
+	pdapi := api.NewPDClient(pdList, statusQueryTimeout, tlsCfg)
 	stores, err := pdapi.GetStores()
 	if err != nil {
 		return "Down"
@@ -232,9 +244,9 @@ func checkStoreStatus(storeAddr string, pdList ...string) string {
 }
 
 // Status queries current status of the instance
-func (s TiKVSpec) Status(pdList ...string) string {
+func (s TiKVSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
 	storeAddr := fmt.Sprintf("%s:%d", s.Host, s.Port)
-	state := checkStoreStatus(storeAddr, pdList...)
+	state := checkStoreStatus(storeAddr, tlsCfg, pdList...)
 	if s.Offline && strings.ToLower(state) == "offline" {
 		state = "Pending Offline" // avoid misleading
 	}
@@ -276,15 +288,16 @@ type PDSpec struct {
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
 	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
+	Hooks           Hooks                  `yaml:"hooks,omitempty"`
 	Arch            string                 `yaml:"arch,omitempty"`
 	OS              string                 `yaml:"os,omitempty"`
 }
 
 // Status queries current status of the instance
-func (s PDSpec) Status(pdList ...string) string {
+func (s PDSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
 	curAddr := fmt.Sprintf("%s:%d", s.Host, s.ClientPort)
-	curPdAPI := api.NewPDClient([]string{curAddr}, statusQueryTimeout, nil)
-	allPdAPI := api.NewPDClient(pdList, statusQueryTimeout, nil)
+	curPdAPI := api.NewPDClient([]string{curAddr}, statusQueryTimeout, tlsCfg)
+	allPdAPI := api.NewPDClient(pdList, statusQueryTimeout, tlsCfg)
 	suffix := ""
 
 	// find dashboard node
@@ -363,14 +376,15 @@ type TiFlashSpec struct {
 	Config               map[string]interface{} `yaml:"config,omitempty"`
 	LearnerConfig        map[string]interface{} `yaml:"learner_config,omitempty"`
 	ResourceControl      ResourceControl        `yaml:"resource_control,omitempty"`
+	Hooks                Hooks                  `yaml:"hooks,omitempty"`
 	Arch                 string                 `yaml:"arch,omitempty"`
 	OS                   string                 `yaml:"os,omitempty"`
 }
 
 // Status queries current status of the instance
-func (s TiFlashSpec) Status(pdList ...string) string {
+func (s TiFlashSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
 	storeAddr := fmt.Sprintf("%s:%d", s.Host, s.FlashServicePort)
-	state := checkStoreStatus(storeAddr, pdList...)
+	state := checkStoreStatus(storeAddr, tlsCfg, pdList...)
 	if s.Offline && strings.ToLower(state) == "offline" {
 		state = "Pending Offline" // avoid misleading
 	}
@@ -410,6 +424,7 @@ type PumpSpec struct {
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
 	ResourceControl ResourceControl        `yaml:"resource_control"`
+	Hooks           Hooks                  `yaml:"hooks,omitempty"`
 	Arch            string                 `yaml:"arch,omitempty"`
 	OS              string                 `yaml:"os,omitempty"`
 }
@@ -448,6 +463,7 @@ type DrainerSpec struct {
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
 	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
+	Hooks           Hooks                  `yaml:"hooks,omitempty"`
 	Arch            string                 `yaml:"arch,omitempty"`
 	OS              string                 `yaml:"os,omitempty"`
 }
@@ -484,6 +500,7 @@ type CDCSpec struct {
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
 	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
+	Hooks           Hooks                  `yaml:"hooks,omitempty"`
 	Arch            string                 `yaml:"arch,omitempty"`
 	OS              string                 `yaml:"os,omitempty"`
 }
@@ -520,6 +537,7 @@ type PrometheusSpec struct {
 	NumaNode        string          `yaml:"numa_node,omitempty"`
 	Retention       string          `yaml:"storage_retention,omitempty"`
 	ResourceControl ResourceControl `yaml:"resource_control,omitempty"`
+	Hooks           Hooks           `yaml:"hooks,omitempty"`
 	Arch            string          `yaml:"arch,omitempty"`
 	OS              string          `yaml:"os,omitempty"`
 }
@@ -552,6 +570,7 @@ type GrafanaSpec struct {
 	Port            int             `yaml:"port" default:"3000"`
 	DeployDir       string          `yaml:"deploy_dir,omitempty"`
 	ResourceControl ResourceControl `yaml:"resource_control,omitempty"`
+	Hooks           Hooks           `yaml:"hooks,omitempty"`
 	Arch            string          `yaml:"arch,omitempty"`
 	OS              string          `yaml:"os,omitempty"`
 }
@@ -588,6 +607,7 @@ type AlertManagerSpec struct {
 	LogDir          string          `yaml:"log_dir,omitempty"`
 	NumaNode        string          `yaml:"numa_node,omitempty"`
 	ResourceControl ResourceControl `yaml:"resource_control,omitempty"`
+	Hooks           Hooks           `yaml:"hooks,omitempty"`
 	Arch            string          `yaml:"arch,omitempty"`
 	OS              string          `yaml:"os,omitempty"`
 }
@@ -909,7 +929,29 @@ func (topo *TopologySpecification) Validate() error {
 		return err
 	}
 
-	return topo.dirConflictsDetect()
+	if err := topo.dirConflictsDetect(); err != nil {
+		return err
+	}
+
+	return topo.hooksValidate()
+}
+
+// hooksValidate checks the hooks declared in the global options and the instances
+func (topo *TopologySpecification) hooksValidate() error {
+	if err := topo.GlobalOptions.Hooks.Validate(); err != nil {
+		return errors.Annotate(err, "invalid hooks of global")
+	}
+
+	var err error
+	topo.IterInstance(func(inst Instance) {
+		if err != nil {
+			return
+		}
+		if e := inst.Hooks().Validate(); e != nil {
+			err = errors.Annotatef(e, "invalid hooks of %s", inst.ID())
+		}
+	})
+	return err
 }
 
 // GetPDList returns a list of PD API hosts of the current cluster
@@ -924,9 +966,10 @@ func (topo *TopologySpecification) GetPDList() []string {
 }
 
 // GetEtcdClient load EtcdClient of current cluster
-func (topo *TopologySpecification) GetEtcdClient() (*clientv3.Client, error) {
+func (topo *TopologySpecification) GetEtcdClient(tlsCfg *tls.Config) (*clientv3.Client, error) {
 	return clientv3.New(clientv3.Config{
 		Endpoints: topo.GetPDList(),
+		TLS:       tlsCfg,
 	})
 }
 
@@ -1070,19 +1113,8 @@ func setCustomDefaults(globalOptions *GlobalOptions, field reflect.Value) error
 				field.Field(j).Set(reflect.ValueOf(globalOptions.Arch))
 			}
 
-			switch strings.ToLower(field.Field(j).String()) {
-			// replace "x86_64" with amd64, they are the same in our repo
-			case "x86_64":
-				field.Field(j).Set(reflect.ValueOf("amd64"))
-			// replace "aarch64" with arm64
-			case "aarch64":
-				field.Field(j).Set(reflect.ValueOf("arm64"))
-			}
-
-			// convert to lower case
-			if field.Field(j).String() != "" {
-				field.Field(j).Set(reflect.ValueOf(strings.ToLower(field.Field(j).String())))
-			}
+			// replace "x86_64" with amd64 and "aarch64" with arm64, they are the same in our repo
+			field.Field(j).Set(reflect.ValueOf(NormalizeArch(field.Field(j).String())))
 		case "OS":
 			// default value of globalOptions.OS is already set, same as "Arch"
 			if field.Field(j).String() == "" {
@@ -1107,3 +1139,16 @@ func getPort(v reflect.Value) string {
 	}
 	return ""
 }
+
+// NormalizeArch converts the arch printed by `uname -m` to the one used in
+// the topology, e.g. x86_64 to amd64
+func NormalizeArch(arch string) string {
+	arch = strings.ToLower(strings.TrimSpace(arch))
+	switch arch {
+	case "x86_64", "amd64":
+		return "amd64"
+	case "aarch64", "arm64":
+		return "arm64"
+	}
+	return arch
+}
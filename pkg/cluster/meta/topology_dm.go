@@ -14,6 +14,7 @@
 package meta
 
 import (
+	"crypto/tls"
 	"fmt"
 	"path/filepath"
 	"reflect"
@@ -78,11 +79,11 @@ type MasterSpec struct {
 }
 
 // Status queries current status of the instance
-func (s MasterSpec) Status(masterList ...string) string {
+func (s MasterSpec) Status(tlsCfg *tls.Config, masterList ...string) string {
 	if len(masterList) < 1 {
 		return "N/A"
 	}
-	masterapi := api.NewDMMasterClient(masterList, statusQueryTimeout, nil)
+	masterapi := api.NewDMMasterClient(masterList, statusQueryTimeout, tlsCfg)
 	isFound, isActive, isLeader, err := masterapi.GetMaster(s.Name)
 	if err != nil {
 		return "Down"
@@ -139,11 +140,11 @@ type WorkerSpec struct {
 }
 
 // Status queries current status of the instance
-func (s WorkerSpec) Status(masterList ...string) string {
+func (s WorkerSpec) Status(tlsCfg *tls.Config, masterList ...string) string {
 	if len(masterList) < 1 {
 		return "N/A"
 	}
-	masterapi := api.NewDMMasterClient(masterList, statusQueryTimeout, nil)
+	masterapi := api.NewDMMasterClient(masterList, statusQueryTimeout, tlsCfg)
 	stage, err := masterapi.GetWorker(s.Name)
 	if err != nil {
 		return "Down"
@@ -613,19 +614,8 @@ func setDMCustomDefaults(globalOptions *GlobalOptions, field reflect.Value) erro
 				field.Field(j).Set(reflect.ValueOf(globalOptions.Arch))
 			}
 
-			switch strings.ToLower(field.Field(j).String()) {
-			// replace "x86_64" with amd64, they are the same in our repo
-			case "x86_64":
-				field.Field(j).Set(reflect.ValueOf("amd64"))
-			// replace "aarch64" with arm64
-			case "aarch64":
-				field.Field(j).Set(reflect.ValueOf("arm64"))
-			}
-
-			// convert to lower case
-			if field.Field(j).String() != "" {
-				field.Field(j).Set(reflect.ValueOf(strings.ToLower(field.Field(j).String())))
-			}
+			// replace "x86_64" with amd64 and "aarch64" with arm64, they are the same in our repo
+			field.Field(j).Set(reflect.ValueOf(NormalizeArch(field.Field(j).String())))
 		case "OS":
 			// default value of globalOptions.OS is already set, same as "Arch"
 			if field.Field(j).String() == "" {
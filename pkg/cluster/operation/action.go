@@ -16,6 +16,7 @@ package operator
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"strconv"
 	"time"
@@ -34,6 +35,7 @@ func Start(
 	getter ExecutorGetter,
 	spec meta.Specification,
 	options Options,
+	tlsCfg *tls.Config,
 ) error {
 	uniqueHosts := set.NewStringSet()
 	roleFilter := set.NewStringSet(options.Roles...)
@@ -43,7 +45,7 @@ func Start(
 
 	for _, com := range components {
 		insts := FilterInstance(com.Instances(), nodeFilter)
-		err := StartComponent(getter, insts, options)
+		err := StartComponent(getter, insts, options, tlsCfg)
 		if err != nil {
 			return errors.Annotatef(err, "failed to start %s", com.Name())
 		}
@@ -130,9 +132,10 @@ func DestroyTombstone(
 	spec meta.Specification,
 	returNodesOnly bool,
 	options Options,
+	tlsCfg *tls.Config,
 ) (nodes []string, err error) {
 	if clusterSpec := spec.GetClusterSpecification(); clusterSpec != nil {
-		return DestroyClusterTombstone(getter, clusterSpec, returNodesOnly, options)
+		return DestroyClusterTombstone(getter, clusterSpec, returNodesOnly, options, tlsCfg)
 	}
 	return nil, nil
 }
@@ -144,10 +147,11 @@ func DestroyClusterTombstone(
 	spec *meta.ClusterSpecification,
 	returNodesOnly bool,
 	options Options,
+	tlsCfg *tls.Config,
 ) (nodes []string, err error) {
-	var pdClient = api.NewPDClient(spec.GetPDList(), 10*time.Second, nil)
+	var pdClient = api.NewPDClient(spec.GetPDList(), 10*time.Second, tlsCfg)
 
-	binlogClient, err := api.NewBinlogClient(spec.GetPDList(), nil)
+	binlogClient, err := api.NewBinlogClient(spec.GetPDList(), tlsCfg)
 	if err != nil {
 		return nil, errors.AddStack(err)
 	}
@@ -330,13 +334,14 @@ func Restart(
 	getter ExecutorGetter,
 	spec meta.Specification,
 	options Options,
+	tlsCfg *tls.Config,
 ) error {
 	err := Stop(getter, spec, options)
 	if err != nil {
 		return errors.Annotatef(err, "failed to stop")
 	}
 
-	err = Start(getter, spec, options)
+	err = Start(getter, spec, options, tlsCfg)
 	if err != nil {
 		return errors.Annotatef(err, "failed to start")
 	}
@@ -396,40 +401,47 @@ func RestartComponent(getter ExecutorGetter, instances []meta.Instance, timeout
 	log.Infof("Restarting component %s", name)
 
 	for _, ins := range instances {
-		e := getter.Get(ins.GetHost())
-		log.Infof("\tRestarting instance %s", ins.GetHost())
-
-		// Restart by systemd.
-		c := module.SystemdModuleConfig{
-			Unit:         ins.ServiceName(),
-			ReloadDaemon: true,
-			Action:       "restart",
+		if err := restartInstance(getter, ins, timeout); err != nil {
+			return err
 		}
-		systemd := module.NewSystemdModule(c)
-		stdout, stderr, err := systemd.Execute(e)
+	}
 
-		if len(stdout) > 0 {
-			fmt.Println(string(stdout))
-		}
-		if len(stderr) > 0 {
-			log.Errorf(string(stderr))
-		}
+	return nil
+}
 
-		if err != nil {
-			return errors.Annotatef(err, "failed to restart: %s", ins.GetHost())
-		}
+func restartInstance(getter ExecutorGetter, ins meta.Instance, timeout int64) error {
+	e := getter.Get(ins.GetHost())
+	log.Infof("\tRestarting instance %s", ins.GetHost())
 
-		// Check ready.
-		err = ins.Ready(e, timeout)
-		if err != nil {
-			str := fmt.Sprintf("\t%s failed to restart: %s", ins.GetHost(), err)
-			log.Errorf(str)
-			return errors.Annotatef(err, str)
-		}
+	// Restart by systemd.
+	c := module.SystemdModuleConfig{
+		Unit:         ins.ServiceName(),
+		ReloadDaemon: true,
+		Action:       "restart",
+	}
+	systemd := module.NewSystemdModule(c)
+	stdout, stderr, err := systemd.Execute(e)
 
-		log.Infof("\tRestart %s success", ins.GetHost())
+	if len(stdout) > 0 {
+		fmt.Println(string(stdout))
+	}
+	if len(stderr) > 0 {
+		log.Errorf(string(stderr))
+	}
+
+	if err != nil {
+		return errors.Annotatef(err, "failed to restart: %s", ins.GetHost())
+	}
+
+	// Check ready.
+	err = ins.Ready(e, timeout)
+	if err != nil {
+		str := fmt.Sprintf("\t%s failed to restart: %s", ins.GetHost(), err)
+		log.Errorf(str)
+		return errors.Annotatef(err, str)
 	}
 
+	log.Infof("\tRestart %s success", ins.GetHost())
 	return nil
 }
 
@@ -484,7 +496,7 @@ func startInstance(getter ExecutorGetter, ins meta.Instance, timeout int64) erro
 }
 
 // StartComponent start the instances.
-func StartComponent(getter ExecutorGetter, instances []meta.Instance, options Options) error {
+func StartComponent(getter ExecutorGetter, instances []meta.Instance, options Options, tlsCfg *tls.Config) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -498,7 +510,7 @@ func StartComponent(getter ExecutorGetter, instances []meta.Instance, options Op
 		ins := ins
 
 		errg.Go(func() error {
-			if err := ins.PrepareStart(); err != nil {
+			if err := ins.PrepareStart(tlsCfg); err != nil {
 				return err
 			}
 			err := startInstance(getter, ins, options.OptTimeout)
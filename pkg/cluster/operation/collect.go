@@ -0,0 +1,212 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// CollectOptions are the options to collect the diagnostic files of instances
+type CollectOptions struct {
+	// DeployUser is the user the relative dirs of the instances belong to
+	DeployUser string
+	// Since limits the logs collected to the ones modified in the duration,
+	// all the logs are collected if it is zero
+	Since time.Duration
+	// DmesgLines is the number of the last lines of dmesg collected
+	DmesgLines int
+	// Timeout is the timeout to collect the files on a host
+	Timeout time.Duration
+}
+
+// CollectManifest describes the content of a diagnostic bundle
+type CollectManifest struct {
+	Cluster   string          `json:"cluster"`
+	Version   string          `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Since     string          `json:"since,omitempty"`
+	Roles     []string        `json:"roles,omitempty"`
+	Nodes     []string        `json:"nodes,omitempty"`
+	Hosts     []CollectedHost `json:"hosts"`
+}
+
+// CollectedHost is the result of collecting the files on a host
+type CollectedHost struct {
+	Host      string   `json:"host"`
+	Instances []string `json:"instances"`
+	Files     []string `json:"files,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// InstanceCollectDir returns the directory of the files of the instance in
+// the collected files of its host
+func InstanceCollectDir(inst meta.Instance) string {
+	return fmt.Sprintf("%s-%d", inst.ComponentName(), inst.GetPort())
+}
+
+// CollectScript returns the shell script copying the logs, the rendered
+// configs, the systemd status of the instances on a host and the dmesg of
+// the host to dir, and then packaging them to dir.tar.gz. The failures of
+// copying files are ignored as the files may not exist on a broken host.
+func CollectScript(instances []meta.Instance, dir string, opt CollectOptions) string {
+	findCond := ""
+	if opt.Since > 0 {
+		findCond = fmt.Sprintf(" -mmin -%d", int(math.Ceil(opt.Since.Minutes())))
+	}
+
+	lines := []string{fmt.Sprintf("rm -rf %[1]s %[1]s.tar.gz && mkdir -p %[1]s", utils.ShellQuote(dir))}
+	for _, inst := range instances {
+		instDir := filepath.Join(dir, InstanceCollectDir(inst))
+		deployDir := clusterutil.Abs(opt.DeployUser, inst.DeployDir())
+		q := func(p ...string) string { return utils.ShellQuote(filepath.Join(p...)) }
+		lines = append(lines,
+			fmt.Sprintf("mkdir -p %s %s %s", q(instDir, "log"), q(instDir, "conf"), q(instDir, "scripts")),
+			fmt.Sprintf("find %s -maxdepth 1 -type f%s -exec cp -p {} %s \\; 2>/dev/null",
+				utils.ShellQuote(clusterutil.Abs(opt.DeployUser, inst.LogDir())), findCond, q(instDir, "log")),
+			fmt.Sprintf("cp -rp %s/. %s 2>/dev/null", q(deployDir, "conf"), q(instDir, "conf")),
+			fmt.Sprintf("cp -p %s/run_*.sh %s 2>/dev/null", q(deployDir, "scripts"), q(instDir, "scripts")),
+			fmt.Sprintf("systemctl status %s --no-pager -l > %s 2>&1", utils.ShellQuote(inst.ServiceName()), q(instDir, "systemd_status.txt")),
+		)
+	}
+	if opt.DmesgLines > 0 {
+		lines = append(lines, fmt.Sprintf("(sudo -n dmesg -T 2>/dev/null || dmesg -T 2>&1) | tail -n %d > %s",
+			opt.DmesgLines, utils.ShellQuote(filepath.Join(dir, "dmesg.txt"))))
+	}
+	lines = append(lines, fmt.Sprintf("tar -czf %[1]s.tar.gz -C %[1]s . && rm -rf %[1]s", utils.ShellQuote(dir)))
+
+	// the lines are joined by ; instead of && to collect as many files as possible
+	return strings.Join(lines, "; ")
+}
+
+// CollectHost collects the files of the instances on the host to the local
+// archive file
+func CollectHost(e executor.TiOpsExecutor, instances []meta.Instance, remoteDir, localFile string, opt CollectOptions) error {
+	script := CollectScript(instances, remoteDir, opt)
+	if _, _, err := e.Execute(script, false, opt.Timeout); err != nil {
+		return errors.Trace(err)
+	}
+
+	remoteFile := remoteDir + ".tar.gz"
+	defer func() {
+		_, _, _ = e.Execute(fmt.Sprintf("rm -f %s", utils.ShellQuote(remoteFile)), false)
+	}()
+	return errors.Annotatef(e.Transfer(remoteFile, localFile, true), "download %s", remoteFile)
+}
+
+// BundleWriter writes the files to a gzipped tarball, all the files are put
+// in the root directory of the tarball
+type BundleWriter struct {
+	root string
+	file *os.File
+	gz   *gzip.Writer
+	tw   *tar.Writer
+}
+
+// NewBundleWriter creates the tarball of the path
+func NewBundleWriter(file, root string) (*BundleWriter, error) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	gz := gzip.NewWriter(f)
+	return &BundleWriter{root: root, file: f, gz: gz, tw: tar.NewWriter(gz)}, nil
+}
+
+// AddFile adds a regular file with the content to the bundle
+func (w *BundleWriter) AddFile(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:     path.Join(w.root, name),
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return errors.AddStack(err)
+	}
+	_, err := w.tw.Write(data)
+	return errors.AddStack(err)
+}
+
+// AddArchive adds the files in the gzipped tarball to the dir of the bundle,
+// the names of the regular files added are returned
+func (w *BundleWriter) AddArchive(dir, archive string) ([]string, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Annotatef(err, "read %s", archive)
+	}
+	defer gz.Close()
+
+	var files []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, errors.Annotatef(err, "read %s", archive)
+		}
+		name := path.Clean(hdr.Name)
+		if name == "." || strings.HasPrefix(name, "../") {
+			continue
+		}
+
+		hdr.Name = path.Join(w.root, dir, name)
+		if err := w.tw.WriteHeader(hdr); err != nil {
+			return files, errors.AddStack(err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if _, err := io.Copy(w.tw, tr); err != nil {
+			return files, errors.AddStack(err)
+		}
+		files = append(files, path.Join(dir, name))
+	}
+	return files, nil
+}
+
+// Close flushes the bundle and closes the file
+func (w *BundleWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		w.file.Close()
+		return errors.AddStack(err)
+	}
+	if err := w.gz.Close(); err != nil {
+		w.file.Close()
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(w.file.Close())
+}
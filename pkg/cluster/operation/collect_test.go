@@ -0,0 +1,137 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"gopkg.in/yaml.v2"
+)
+
+type collectSuite struct {
+	dir string
+}
+
+var _ = check.Suite(&collectSuite{})
+
+func (s *collectSuite) SetUpTest(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-collect")
+	c.Assert(err, check.IsNil)
+	s.dir = dir
+}
+
+func (s *collectSuite) TearDownTest(c *check.C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *collectSuite) writeFile(c *check.C, name string, mtime time.Time) {
+	path := filepath.Join(s.dir, name)
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(name), 0644), check.IsNil)
+	c.Assert(os.Chtimes(path, mtime, mtime), check.IsNil)
+}
+
+func (s *collectSuite) TestCollect(c *check.C) {
+	topo := meta.TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  deploy_dir: `+filepath.Join(s.dir, "deploy")+`
+tidb_servers:
+  - host: 172.16.5.138
+tikv_servers:
+  - host: 172.16.5.138
+    log_dir: `+filepath.Join(s.dir, "tikv-log")+`
+`), &topo)
+	c.Assert(err, check.IsNil)
+
+	var instances []meta.Instance
+	topo.IterInstance(func(inst meta.Instance) {
+		instances = append(instances, inst)
+	})
+
+	now := time.Now()
+	s.writeFile(c, "deploy/tidb-4000/log/tidb.log", now)
+	s.writeFile(c, "deploy/tidb-4000/log/tidb-2020-01-01.log", now.Add(-3*time.Hour))
+	s.writeFile(c, "deploy/tidb-4000/conf/tidb.toml", now)
+	s.writeFile(c, "deploy/tidb-4000/scripts/run_tidb.sh", now)
+	s.writeFile(c, "tikv-log/tikv.log", now)
+
+	// the script is run locally to verify the files collected
+	remoteDir := filepath.Join(s.dir, "collect")
+	script := CollectScript(instances, remoteDir, CollectOptions{Since: 2 * time.Hour})
+	output, err := exec.Command("sh", "-c", script).CombinedOutput()
+	c.Assert(err, check.IsNil, check.Commentf("%s", output))
+	_, err = os.Stat(remoteDir)
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+
+	bundle := filepath.Join(s.dir, "bundle.tar.gz")
+	w, err := NewBundleWriter(bundle, "test-diag")
+	c.Assert(err, check.IsNil)
+	files, err := w.AddArchive("172.16.5.138", remoteDir+".tar.gz")
+	c.Assert(err, check.IsNil)
+	c.Assert(w.AddFile("manifest.json", []byte("{}")), check.IsNil)
+	c.Assert(w.Close(), check.IsNil)
+
+	// the log out of the time range is not collected
+	sort.Strings(files)
+	c.Assert(files, check.DeepEquals, []string{
+		"172.16.5.138/tidb-4000/conf/tidb.toml",
+		"172.16.5.138/tidb-4000/log/tidb.log",
+		"172.16.5.138/tidb-4000/scripts/run_tidb.sh",
+		"172.16.5.138/tidb-4000/systemd_status.txt",
+		"172.16.5.138/tikv-20160/log/tikv.log",
+		"172.16.5.138/tikv-20160/systemd_status.txt",
+	})
+
+	content := readBundle(c, bundle)
+	c.Assert(content["test-diag/172.16.5.138/tidb-4000/conf/tidb.toml"], check.Equals, "deploy/tidb-4000/conf/tidb.toml")
+	c.Assert(content["test-diag/172.16.5.138/tikv-20160/log/tikv.log"], check.Equals, "tikv-log/tikv.log")
+	c.Assert(content["test-diag/manifest.json"], check.Equals, "{}")
+}
+
+// readBundle returns the content of the regular files in the bundle
+func readBundle(c *check.C, bundle string) map[string]string {
+	f, err := os.Open(bundle)
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	c.Assert(err, check.IsNil)
+
+	content := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, check.IsNil)
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		c.Assert(err, check.IsNil)
+		content[hdr.Name] = string(data)
+	}
+	return content
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// renderExecutor records the files transferred to the remote by InitConfig
+// instead of transferring them, the commands are not executed
+type renderExecutor struct {
+	files map[string][]byte // remote path -> content
+}
+
+var _ meta.RenderOnlyExecutor = &renderExecutor{}
+
+// RenderOnly implements the RenderOnlyExecutor interface
+func (e *renderExecutor) RenderOnly() {}
+
+// Execute implements the TiOpsExecutor interface, only the files moved by
+// mv are tracked
+func (e *renderExecutor) Execute(cmd string, _ bool, _ ...time.Duration) ([]byte, []byte, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 3 && fields[0] == "mv" {
+		if content, found := e.files[fields[1]]; found {
+			delete(e.files, fields[1])
+			e.files[fields[2]] = content
+		}
+	}
+	return nil, nil, nil
+}
+
+// Transfer implements the TiOpsExecutor interface
+func (e *renderExecutor) Transfer(src, dst string, download bool) error {
+	if download {
+		return errors.Errorf("download %s is not supported when rendering configs", src)
+	}
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	e.files[dst] = content
+	return nil
+}
+
+// RenderConfigs renders the config files, scripts and systemd unit of the
+// instance the same way as InitConfig, the contents are returned by the
+// remote paths. The rendered files are also written to paths.Cache.
+func RenderConfigs(inst meta.Instance, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) (map[string][]byte, error) {
+	e := &renderExecutor{files: make(map[string][]byte)}
+	if err := inst.InitConfig(e, clusterName, clusterVersion, deployUser, paths); err != nil {
+		return nil, errors.Annotatef(err, "render config of %s", inst.ID())
+	}
+	return e.files, nil
+}
+
+// ReadRemoteFiles reads the files on the host of e, the files not existing
+// are absent from the result
+func ReadRemoteFiles(e executor.TiOpsExecutor, files []string) (map[string][]byte, error) {
+	var cmds []string
+	for _, f := range files {
+		// each file is printed as one line: <path> <content in base64>
+		cmds = append(cmds, fmt.Sprintf("if [ -f %[1]s ]; then printf '%%s ' %[1]s; base64 < %[1]s | tr -d '\\n'; echo; fi", utils.ShellQuote(f)))
+	}
+	stdout, _, err := e.Execute(strings.Join(cmds, "; "), false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	contents := make(map[string][]byte)
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		content, err := base64.StdEncoding.DecodeString(line[i+1:])
+		if err != nil {
+			return nil, errors.Annotatef(err, "read %s", line[:i])
+		}
+		contents[line[:i]] = content
+	}
+	return contents, errors.AddStack(scanner.Err())
+}
+
+// ConfigDrift is a file of an instance differing from the rendered one
+type ConfigDrift struct {
+	Instance string `json:"instance" yaml:"instance"`
+	Path     string `json:"path" yaml:"path"`
+	Missing  bool   `json:"missing" yaml:"missing"`
+	Expected string `json:"expected" yaml:"expected"`
+	Actual   string `json:"actual" yaml:"actual"`
+}
+
+// DiffConfigs compares the rendered files of the instance with the files on
+// the host, the drifts are sorted by the paths
+func DiffConfigs(instance string, expected, actual map[string][]byte) []ConfigDrift {
+	var drifts []ConfigDrift
+	for path, content := range expected {
+		remote, found := actual[path]
+		if found && bytes.Equal(content, remote) {
+			continue
+		}
+		drifts = append(drifts, ConfigDrift{
+			Instance: instance,
+			Path:     path,
+			Missing:  !found,
+			Expected: string(content),
+			Actual:   string(remote),
+		})
+	}
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].Path < drifts[j].Path
+	})
+	return drifts
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+type configDiffSuite struct{}
+
+var _ = check.Suite(&configDiffSuite{})
+
+func (s *configDiffSuite) TestRenderConfigs(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-render")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	spec := &meta.ClusterSpecification{
+		Alertmanager: []meta.AlertManagerSpec{{Host: "172.16.5.1", WebPort: 9093, ClusterPort: 9094}},
+	}
+	inst := (&meta.AlertManagerComponent{ClusterSpecification: spec}).Instances()[0]
+	files, err := RenderConfigs(inst, "test", "v4.0.0", "tidb", meta.DirPaths{
+		Deploy: "/tidb/alertmanager",
+		Data:   []string{"/tidb/alertmanager/data"},
+		Log:    "/tidb/alertmanager/log",
+		Cache:  dir,
+	})
+	c.Assert(err, check.IsNil)
+
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	// the systemd unit is tracked to where it's moved
+	c.Assert(paths, check.DeepEquals, []string{
+		"/etc/systemd/system/alertmanager-9093.service",
+		"/tidb/alertmanager/conf/alertmanager.yml",
+		"/tidb/alertmanager/scripts/run_alertmanager.sh",
+	})
+	c.Assert(strings.Contains(string(files["/tidb/alertmanager/scripts/run_alertmanager.sh"]), "--web.listen-address=\"172.16.5.1:9093\""), check.IsTrue)
+
+	// the config is not checked by the binary, which needs the repository
+	spec.PDServers = []meta.PDSpec{{Host: "172.16.5.1", Name: "pd-1", ClientPort: 2379, PeerPort: 2380}}
+	inst = (&meta.PDComponent{ClusterSpecification: spec}).Instances()[0]
+	files, err = RenderConfigs(inst, "test", "v4.0.0", "tidb", meta.DirPaths{
+		Deploy: "/tidb/pd",
+		Data:   []string{"/tidb/pd/data"},
+		Log:    "/tidb/pd/log",
+		Cache:  dir,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 3)
+	c.Assert(files["/tidb/pd/conf/pd.toml"], check.NotNil)
+	c.Assert(strings.Contains(string(files["/tidb/pd/scripts/run_pd.sh"]), "--name=\"pd-1\""), check.IsTrue)
+}
+
+func (s *configDiffSuite) TestDiffConfigs(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-diff")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	same := filepath.Join(dir, "same.toml")
+	changed := filepath.Join(dir, "changed sh")
+	missing := filepath.Join(dir, "missing.toml")
+	c.Assert(ioutil.WriteFile(same, []byte("a = 1\n"), 0644), check.IsNil)
+	c.Assert(ioutil.WriteFile(changed, []byte("#!/bin/bash\nexec bin/tikv-server --addr 1\n"), 0644), check.IsNil)
+
+	e := executor.NewLocalExecutor(executor.SSHConfig{Host: "127.0.0.1", User: utils.CurrentUser()}, false)
+	actual, err := ReadRemoteFiles(e, []string{same, changed, missing})
+	c.Assert(err, check.IsNil)
+	c.Assert(actual, check.HasLen, 2)
+	c.Assert(string(actual[changed]), check.Equals, "#!/bin/bash\nexec bin/tikv-server --addr 1\n")
+
+	drifts := DiffConfigs("tikv", map[string][]byte{
+		same:    []byte("a = 1\n"),
+		changed: []byte("#!/bin/bash\nexec bin/tikv-server --addr 2\n"),
+		missing: []byte("b = 2\n"),
+	}, actual)
+	c.Assert(drifts, check.HasLen, 2)
+	c.Assert(drifts[0].Path, check.Equals, changed)
+	c.Assert(drifts[0].Missing, check.IsFalse)
+	c.Assert(drifts[0].Actual, check.Equals, "#!/bin/bash\nexec bin/tikv-server --addr 1\n")
+	c.Assert(drifts[1].Path, check.Equals, missing)
+	c.Assert(drifts[1].Missing, check.IsTrue)
+}
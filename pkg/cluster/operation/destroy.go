@@ -167,6 +167,30 @@ func DestroyMonitored(getter ExecutorGetter, inst meta.Instance, options meta.Mo
 	return nil
 }
 
+// DestroyPaths returns the data, deploy and log directories and the systemd
+// unit of the instance which are removed by destroying it
+func DestroyPaths(ins meta.Instance) []string {
+	delPaths := make([]string, 0)
+	switch ins.ComponentName() {
+	case meta.ComponentTiKV, meta.ComponentPD, meta.ComponentPump, meta.ComponentDrainer, meta.ComponentPrometheus, meta.ComponentAlertManager, meta.ComponentDMMaster, meta.ComponentDMWorker, meta.ComponentDMPortal:
+		delPaths = append(delPaths, ins.DataDir())
+	case meta.ComponentTiFlash:
+		delPaths = append(delPaths, strings.Split(ins.DataDir(), ",")...)
+	}
+
+	// In TiDB-Ansible, deploy dir are shared by all components on the same
+	// host, so not deleting it.
+	// TODO: this may leave undeleted files when destroying the cluster, fix
+	// that later.
+	if !ins.IsImported() {
+		delPaths = append(delPaths, ins.DeployDir())
+		if logDir := ins.LogDir(); !strings.HasPrefix(ins.DeployDir(), logDir) {
+			delPaths = append(delPaths, logDir)
+		}
+	}
+	return append(delPaths, fmt.Sprintf("/etc/systemd/system/%s", ins.ServiceName()))
+}
+
 // DestroyComponent destroy the instances.
 func DestroyComponent(getter ExecutorGetter, instances []meta.Instance, timeout int64) error {
 	if len(instances) <= 0 {
@@ -180,15 +204,6 @@ func DestroyComponent(getter ExecutorGetter, instances []meta.Instance, timeout
 		e := getter.Get(ins.GetHost())
 		log.Infof("Destroying instance %s", ins.GetHost())
 
-		// Stop by systemd.
-		delPaths := make([]string, 0)
-		switch name {
-		case meta.ComponentTiKV, meta.ComponentPD, meta.ComponentPump, meta.ComponentDrainer, meta.ComponentPrometheus, meta.ComponentAlertManager, meta.ComponentDMMaster, meta.ComponentDMWorker, meta.ComponentDMPortal:
-			delPaths = append(delPaths, ins.DataDir())
-		case meta.ComponentTiFlash:
-			delPaths = append(delPaths, strings.Split(ins.DataDir(), ",")...)
-		}
-
 		// check if service is down before deleting files
 		if err := ins.WaitForDown(e, timeout); err != nil {
 			str := fmt.Sprintf("%s error destroying %s: %s", ins.GetHost(), ins.ComponentName(), err)
@@ -199,20 +214,11 @@ func DestroyComponent(getter ExecutorGetter, instances []meta.Instance, timeout
 			log.Warnf("You may manually check if the process on %s:%d is still running", ins.GetHost(), ins.GetPort())
 		}
 
-		// In TiDB-Ansible, deploy dir are shared by all components on the same
-		// host, so not deleting it.
-		// TODO: this may leave undeleted files when destroying the cluster, fix
-		// that later.
-		if !ins.IsImported() {
-			delPaths = append(delPaths, ins.DeployDir())
-			if logDir := ins.LogDir(); !strings.HasPrefix(ins.DeployDir(), logDir) {
-				delPaths = append(delPaths, logDir)
-			}
-		} else {
+		if ins.IsImported() {
 			log.Warnf("Deploy dir %s not deleted for TiDB-Ansible imported instance %s.",
 				ins.DeployDir(), ins.InstanceName())
 		}
-		delPaths = append(delPaths, fmt.Sprintf("/etc/systemd/system/%s", ins.ServiceName()))
+		delPaths := DestroyPaths(ins)
 		log.Debugf("Deleting paths on %s: %s", ins.GetHost(), strings.Join(delPaths, " "))
 		c := module.ShellModuleConfig{
 			Command:  fmt.Sprintf("rm -rf %s;", strings.Join(delPaths, " ")),
@@ -0,0 +1,245 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	// for the TiDB gate
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/logger/log"
+)
+
+// Names of the health gates
+const (
+	HealthGatePD            = "pd-health"      // all PD members are healthy
+	HealthGateStoresUp      = "stores-up"      // no store is disconnected or down
+	HealthGateRegionHealth  = "region-health"  // the regions with down or pending peers are under the threshold
+	HealthGateLeaderBalance = "leader-balance" // every store holds its share of leaders
+	HealthGateTiDB          = "tidb"           // every TiDB answers SELECT 1
+)
+
+// HealthGates are all the supported health gates in the order they are checked
+var HealthGates = []string{
+	HealthGatePD,
+	HealthGateStoresUp,
+	HealthGateRegionHealth,
+	HealthGateLeaderBalance,
+	HealthGateTiDB,
+}
+
+var (
+	errNSHealth = errorx.NewNamespace("health")
+	// ErrHealthGateFailed means the cluster is not healthy after an instance is restarted
+	ErrHealthGateFailed = errNSHealth.NewType("gate_failed")
+)
+
+// HealthGateOptions are the gates checked between the instances in rolling
+// operations, the operation is halted if the gates don't pass
+type HealthGateOptions struct {
+	Gates               []string // the names of the gates to check, none is checked if empty
+	Timeout             int64    // timeout in seconds to wait for the gates to pass
+	StableTime          int64    // the gates must keep passing for the seconds after a restart
+	MaxUnhealthyRegions int      // region-health: the max number of regions with down or pending peers
+	MinLeaderRatio      float64  // leader-balance: the min ratio of the leaders of a store to the average
+	TiDBUser            string   // tidb: the user to connect TiDB
+	TiDBPassword        string   // tidb: the password of the user
+}
+
+// ValidateHealthGates returns an error if any of the gates is unknown
+func ValidateHealthGates(gates []string) error {
+	for _, gate := range gates {
+		found := false
+		for _, g := range HealthGates {
+			found = found || g == gate
+		}
+		if !found {
+			return errors.Errorf("unknown health gate %s, supported gates are %s", gate, strings.Join(HealthGates, ", "))
+		}
+	}
+	return nil
+}
+
+// CheckHealthGates checks the gates once, the error of the first failed gate is returned
+func CheckHealthGates(spec *meta.ClusterSpecification, opt HealthGateOptions, tlsCfg *tls.Config) error {
+	pdClient := api.NewPDClient(spec.GetPDList(), 5*time.Second, tlsCfg)
+	for _, gate := range opt.Gates {
+		var err error
+		switch gate {
+		case HealthGatePD:
+			err = checkPDHealth(pdClient)
+		case HealthGateStoresUp:
+			err = checkStoresUp(pdClient)
+		case HealthGateRegionHealth:
+			err = checkRegionHealth(pdClient, opt.MaxUnhealthyRegions)
+		case HealthGateLeaderBalance:
+			err = checkLeaderBalance(pdClient, opt.MinLeaderRatio)
+		case HealthGateTiDB:
+			err = checkTiDBAlive(spec, opt.TiDBUser, opt.TiDBPassword)
+		}
+		if err != nil {
+			return errors.Annotatef(err, "health gate %s failed", gate)
+		}
+	}
+	return nil
+}
+
+// waitHealthGates waits until the gates keep passing for the stable time. The
+// stable time should be longer than the time PD takes to find a store
+// disconnected, so that an instance which crashes right after being started
+// is caught.
+func waitHealthGates(spec *meta.ClusterSpecification, opt HealthGateOptions, tlsCfg *tls.Config, stable time.Duration) error {
+	if len(opt.Gates) == 0 {
+		return nil
+	}
+
+	const interval = 5 * time.Second
+	deadline := time.Now().Add(time.Duration(opt.Timeout) * time.Second)
+	var passedSince time.Time
+	var lastErr error
+	for {
+		err := CheckHealthGates(spec, opt, tlsCfg)
+		now := time.Now()
+		if err == nil {
+			if passedSince.IsZero() {
+				passedSince = now
+			}
+			if now.Sub(passedSince) >= stable {
+				return nil
+			}
+		} else {
+			log.Debugf("Waiting for the health gates: %s", err)
+			passedSince = time.Time{}
+			lastErr = err
+		}
+
+		if now.After(deadline) {
+			if err == nil {
+				err = errors.Errorf("the health gates are not stable for %s, the last failure: %s", stable, lastErr)
+			}
+			return ErrHealthGateFailed.Wrap(err, "the cluster is not healthy after waiting for %ds", opt.Timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func checkPDHealth(pdClient *api.PDClient) error {
+	healths, err := pdClient.GetHealth()
+	if err != nil {
+		return err
+	}
+	for _, h := range healths.Healths {
+		if !h.Health {
+			return errors.Errorf("PD member %s is unhealthy", h.Name)
+		}
+	}
+	return nil
+}
+
+func checkStoresUp(pdClient *api.PDClient) error {
+	stores, err := pdClient.GetStores()
+	if err != nil {
+		return err
+	}
+	for _, s := range stores.Stores {
+		// the offline stores are being scaled in and the tombstone ones are removed
+		switch s.Store.StateName {
+		case "Disconnected", "Down":
+			return errors.Errorf("store %s is %s", s.Store.Address, s.Store.StateName)
+		}
+	}
+	return nil
+}
+
+// checkRegionHealth counts the regions with down or pending peers, the ones
+// missing peers are not counted as it is expected if there are less stores
+// than the replicas
+func checkRegionHealth(pdClient *api.PDClient, maxUnhealthy int) error {
+	total := 0
+	for _, state := range []string{"down-peer", "pending-peer"} {
+		count, err := pdClient.GetRegionsCheckCount(state)
+		if err != nil {
+			return err
+		}
+		total += count
+	}
+	if total > maxUnhealthy {
+		return errors.Errorf("%d regions have down or pending peers, more than %d", total, maxUnhealthy)
+	}
+	return nil
+}
+
+func checkLeaderBalance(pdClient *api.PDClient, minRatio float64) error {
+	stores, err := pdClient.GetStores()
+	if err != nil {
+		return err
+	}
+
+	leaders := make(map[string]int)
+	total := 0
+	for _, s := range stores.Stores {
+		if s.Store.StateName != "Up" || s.Status == nil || isTiFlashStore(s.Store.Labels) {
+			continue
+		}
+		leaders[s.Store.Address] = s.Status.LeaderCount
+		total += s.Status.LeaderCount
+	}
+	if len(leaders) == 0 {
+		return nil
+	}
+
+	avg := float64(total) / float64(len(leaders))
+	for addr, count := range leaders {
+		if float64(count) < avg*minRatio {
+			return errors.Errorf("store %s has %d leaders, less than %.0f%% of the average %.0f", addr, count, minRatio*100, avg)
+		}
+	}
+	return nil
+}
+
+func isTiFlashStore(labels []*metapb.StoreLabel) bool {
+	for _, l := range labels {
+		if l.Key == "engine" && l.Value == "tiflash" {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTiDBAlive(spec *meta.ClusterSpecification, user, password string) error {
+	for _, s := range spec.TiDBServers {
+		addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
+		dsn := fmt.Sprintf("%s:%s@tcp(%s)/?timeout=5s&readTimeout=5s", user, password, addr)
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		var one int
+		err = db.QueryRow("SELECT 1").Scan(&one)
+		db.Close()
+		if err != nil {
+			return errors.Annotatef(err, "TiDB %s is not serving", addr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+)
+
+type healthSuite struct {
+	server      *httptest.Server
+	spec        *meta.ClusterSpecification
+	pdHealthy   bool
+	storeState  string
+	downPeers   int
+	leaderCount int
+}
+
+var _ = check.Suite(&healthSuite{})
+
+func (s *healthSuite) SetUpTest(c *check.C) {
+	s.pdHealthy = true
+	s.storeState = "Up"
+	s.downPeers = 0
+	s.leaderCount = 100
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pd/health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"name": "pd-1", "health": %v}]`, s.pdHealthy)
+	})
+	mux.HandleFunc("/pd/api/v1/stores", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"count": 3, "stores": [
+			{"store": {"id": 1, "address": "172.16.5.1:20160", "state_name": "Up"}, "status": {"leader_count": 100}},
+			{"store": {"id": 2, "address": "172.16.5.2:20160", "state_name": "%s"}, "status": {"leader_count": %d}},
+			{"store": {"id": 3, "address": "172.16.5.3:3930", "state_name": "Up", "labels": [{"key": "engine", "value": "tiflash"}]}, "status": {"leader_count": 0}}
+		]}`, s.storeState, s.leaderCount)
+	})
+	mux.HandleFunc("/pd/api/v1/regions/check/down-peer", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"count": %d}`, s.downPeers)
+	})
+	mux.HandleFunc("/pd/api/v1/regions/check/pending-peer", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"count": 1}`)
+	})
+	s.server = httptest.NewServer(mux)
+
+	host, port, err := net.SplitHostPort(s.server.Listener.Addr().String())
+	c.Assert(err, check.IsNil)
+	clientPort, err := strconv.Atoi(port)
+	c.Assert(err, check.IsNil)
+	s.spec = &meta.ClusterSpecification{
+		PDServers: []meta.PDSpec{{Host: host, ClientPort: clientPort}},
+	}
+}
+
+func (s *healthSuite) TearDownTest(c *check.C) {
+	s.server.Close()
+}
+
+func (s *healthSuite) TestValidateHealthGates(c *check.C) {
+	c.Assert(ValidateHealthGates(HealthGates), check.IsNil)
+	c.Assert(ValidateHealthGates(nil), check.IsNil)
+	c.Assert(ValidateHealthGates([]string{HealthGatePD, "unknown"}), check.NotNil)
+}
+
+func (s *healthSuite) TestCheckHealthGates(c *check.C) {
+	opt := HealthGateOptions{
+		Gates:               []string{HealthGatePD, HealthGateStoresUp, HealthGateRegionHealth, HealthGateLeaderBalance},
+		MaxUnhealthyRegions: 1,
+		MinLeaderRatio:      0.5,
+	}
+	c.Assert(CheckHealthGates(s.spec, opt, nil), check.IsNil)
+
+	s.pdHealthy = false
+	c.Assert(CheckHealthGates(s.spec, opt, nil), check.ErrorMatches, "health gate pd-health failed.*")
+	s.pdHealthy = true
+
+	// a crash-looping store is found disconnected or down
+	for _, state := range []string{"Disconnected", "Down"} {
+		s.storeState = state
+		c.Assert(CheckHealthGates(s.spec, opt, nil), check.ErrorMatches, "health gate stores-up failed.*")
+	}
+	// the offline store is being scaled in
+	s.storeState = "Offline"
+	c.Assert(CheckHealthGates(s.spec, opt, nil), check.IsNil)
+	s.storeState = "Up"
+
+	// one pending peer is allowed, a down peer makes it two
+	s.downPeers = 1
+	c.Assert(CheckHealthGates(s.spec, opt, nil), check.ErrorMatches, "health gate region-health failed.*")
+	s.downPeers = 0
+
+	// the TiFlash store holding no leader is ignored
+	s.leaderCount = 20
+	c.Assert(CheckHealthGates(s.spec, opt, nil), check.ErrorMatches, "health gate leader-balance failed.*")
+	opt.MinLeaderRatio = 0.3
+	c.Assert(CheckHealthGates(s.spec, opt, nil), check.IsNil)
+}
+
+func (s *healthSuite) TestWaitHealthGates(c *check.C) {
+	opt := HealthGateOptions{Gates: []string{HealthGateStoresUp}}
+	c.Assert(waitHealthGates(s.spec, opt, nil, 0), check.IsNil)
+
+	s.storeState = "Down"
+	err := waitHealthGates(s.spec, opt, nil, 0)
+	c.Assert(err, check.NotNil)
+	c.Assert(errorx.IsOfType(err, ErrHealthGateFailed), check.IsTrue)
+
+	// no gate is checked
+	c.Assert(waitHealthGates(s.spec, HealthGateOptions{}, nil, 0), check.IsNil)
+}
@@ -0,0 +1,174 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+var (
+	errNSHook = errorx.NewNamespace("hook")
+	// ErrHookFailed means a hook exits with non-zero code, the operation is aborted
+	ErrHookFailed = errNSHook.NewType("failed")
+)
+
+// HookOptions describe the operation the hooks run around
+type HookOptions struct {
+	ClusterName    string
+	ClusterVersion string
+	Operation      string // one of meta.HookOperations
+}
+
+// hookEnv returns the environment variables passed to the hooks of the stage
+func (opt HookOptions) hookEnv(pre bool, instances []meta.Instance) []string {
+	ids := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		ids = append(ids, inst.ID())
+	}
+	return []string{
+		"TIUP_CLUSTER_NAME=" + opt.ClusterName,
+		"TIUP_CLUSTER_VERSION=" + opt.ClusterVersion,
+		"TIUP_HOOK_OPERATION=" + opt.Operation,
+		"TIUP_HOOK_STAGE=" + meta.HookStage(opt.Operation, pre),
+		"TIUP_INSTANCES=" + strings.Join(ids, ","),
+	}
+}
+
+// instanceEnv returns the environment variables describing the instance
+func instanceEnv(inst meta.Instance) []string {
+	return []string{
+		"TIUP_INSTANCE_ID=" + inst.ID(),
+		"TIUP_INSTANCE_COMPONENT=" + inst.ComponentName(),
+		"TIUP_INSTANCE_HOST=" + inst.GetHost(),
+		"TIUP_INSTANCE_PORT=" + strconv.Itoa(inst.GetPort()),
+		"TIUP_INSTANCE_DEPLOY_DIR=" + inst.DeployDir(),
+	}
+}
+
+// RunGlobalHooks runs the hooks declared in the global options for the
+// instances operated, the hooks targeting the hosts run once on each host
+func RunGlobalHooks(getter ExecutorGetter, hooks meta.Hooks, opt HookOptions, pre bool, instances []meta.Instance) error {
+	stage := meta.HookStage(opt.Operation, pre)
+	env := opt.hookEnv(pre, instances)
+	for _, hook := range hooks.Get(opt.Operation, pre) {
+		if !hook.RunOnHost() {
+			log.Infof("\tRunning hook %s: %s", stage, hook.Command)
+			if err := runLocalHook(hook.Command, env); err != nil {
+				return ErrHookFailed.Wrap(err, "the hook of %s failed", stage)
+			}
+			continue
+		}
+
+		hosts := make(map[string]struct{})
+		for _, inst := range instances {
+			hosts[inst.GetHost()] = struct{}{}
+		}
+		sorted := make([]string, 0, len(hosts))
+		for host := range hosts {
+			sorted = append(sorted, host)
+		}
+		sort.Strings(sorted)
+		for _, host := range sorted {
+			log.Infof("\tRunning hook %s on %s: %s", stage, host, hook.Command)
+			if err := runRemoteHook(getter, host, hook.Command, env); err != nil {
+				return ErrHookFailed.Wrap(err, "the hook of %s failed on %s", stage, host)
+			}
+		}
+	}
+	return nil
+}
+
+// RunInstanceHooks runs the hooks declared for the instance
+func RunInstanceHooks(getter ExecutorGetter, opt HookOptions, pre bool, inst meta.Instance) error {
+	stage := meta.HookStage(opt.Operation, pre)
+	env := append(opt.hookEnv(pre, []meta.Instance{inst}), instanceEnv(inst)...)
+	for _, hook := range inst.Hooks().Get(opt.Operation, pre) {
+		log.Infof("\tRunning hook %s of %s: %s", stage, inst.ID(), hook.Command)
+		var err error
+		if hook.RunOnHost() {
+			err = runRemoteHook(getter, inst.GetHost(), hook.Command, env)
+		} else {
+			err = runLocalHook(hook.Command, env)
+		}
+		if err != nil {
+			return ErrHookFailed.Wrap(err, "the hook of %s failed for %s", stage, inst.ID())
+		}
+	}
+	return nil
+}
+
+// RunHooks runs the hooks of the stage for the instances, the global hooks
+// run first before the operation and last after it
+func RunHooks(getter ExecutorGetter, hooks meta.Hooks, opt HookOptions, pre bool, instances []meta.Instance) error {
+	if pre {
+		if err := RunGlobalHooks(getter, hooks, opt, pre, instances); err != nil {
+			return err
+		}
+	}
+	for _, inst := range instances {
+		if err := RunInstanceHooks(getter, opt, pre, inst); err != nil {
+			return err
+		}
+	}
+	if !pre {
+		return RunGlobalHooks(getter, hooks, opt, pre, instances)
+	}
+	return nil
+}
+
+func runLocalHook(command string, env []string) error {
+	c := exec.Command("sh", "-c", command)
+	c.Env = append(os.Environ(), env...)
+	output, err := c.CombinedOutput()
+	if err != nil {
+		return errors.Errorf("%s, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	if len(output) > 0 {
+		log.Debugf("%s", output)
+	}
+	return nil
+}
+
+func runRemoteHook(getter ExecutorGetter, host, command string, env []string) error {
+	e := getter.Get(host)
+	if e == nil {
+		return errors.Errorf("no executor of host %s", host)
+	}
+
+	assignments := make([]string, 0, len(env))
+	for _, kv := range env {
+		i := strings.Index(kv, "=")
+		assignments = append(assignments, kv[:i+1]+utils.ShellQuote(kv[i+1:]))
+	}
+	cmd := fmt.Sprintf("env %s sh -c %s", strings.Join(assignments, " "), utils.ShellQuote(command))
+	stdout, stderr, err := e.Execute(cmd, false)
+	if err != nil {
+		return errors.Errorf("%s, stdout: %s, stderr: %s", err, strings.TrimSpace(string(stdout)), strings.TrimSpace(string(stderr)))
+	}
+	if len(stdout) > 0 {
+		log.Debugf("%s", stdout)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"gopkg.in/yaml.v2"
+)
+
+type hookSuite struct {
+	dir string
+}
+
+var _ = check.Suite(&hookSuite{})
+
+func (s *hookSuite) SetUpTest(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-hook")
+	c.Assert(err, check.IsNil)
+	s.dir = dir
+}
+
+func (s *hookSuite) TearDownTest(c *check.C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *hookSuite) TestRunHooks(c *check.C) {
+	out := filepath.Join(s.dir, "out")
+	topo := meta.TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  hooks:
+    pre_stop:
+      - command: "echo global $TIUP_HOOK_STAGE $TIUP_INSTANCES >> `+out+`"
+    post_stop:
+      - command: "echo global $TIUP_HOOK_STAGE >> `+out+`"
+tidb_servers:
+  - host: 172.16.5.138
+    hooks:
+      pre_stop:
+        - command: "echo $TIUP_CLUSTER_NAME $TIUP_CLUSTER_VERSION $TIUP_INSTANCE_ID $TIUP_INSTANCE_COMPONENT $TIUP_INSTANCE_PORT >> `+out+`"
+      post_stop:
+        - command: "echo instance $TIUP_HOOK_STAGE >> `+out+`"
+  - host: 172.16.5.139
+`), &topo)
+	c.Assert(err, check.IsNil)
+
+	var instances []meta.Instance
+	topo.IterInstance(func(inst meta.Instance) {
+		instances = append(instances, inst)
+	})
+	opt := HookOptions{ClusterName: "test", ClusterVersion: "v4.0.0", Operation: meta.HookOperationStop}
+	c.Assert(RunHooks(nil, topo.GlobalOptions.Hooks, opt, true, instances), check.IsNil)
+	c.Assert(RunHooks(nil, topo.GlobalOptions.Hooks, opt, false, instances), check.IsNil)
+
+	data, err := ioutil.ReadFile(out)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Split(strings.TrimSpace(string(data)), "\n"), check.DeepEquals, []string{
+		"global pre_stop 172.16.5.138:4000,172.16.5.139:4000",
+		"test v4.0.0 172.16.5.138:4000 tidb 4000",
+		"instance post_stop",
+		"global post_stop",
+	})
+}
+
+func (s *hookSuite) TestHookFailed(c *check.C) {
+	hooks := meta.Hooks{
+		"pre_start": {{Command: "echo oops; exit 3"}},
+	}
+	opt := HookOptions{ClusterName: "test", Operation: meta.HookOperationStart}
+	err := RunGlobalHooks(nil, hooks, opt, true, nil)
+	c.Assert(errorx.IsOfType(err, ErrHookFailed), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*the hook of pre_start failed.*exit status 3, output: oops")
+
+	// no hooks of the stage
+	c.Assert(RunGlobalHooks(nil, hooks, opt, false, nil), check.IsNil)
+}
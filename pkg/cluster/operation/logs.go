@@ -0,0 +1,250 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// LogLevel is the level of a log line
+type LogLevel int
+
+// The levels of log lines, the level of a line not recognized is unknown
+const (
+	LogLevelUnknown LogLevel = iota
+	LogLevelDebug
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+	LogLevelFatal
+)
+
+var logLevelNames = map[string]LogLevel{
+	"trace":       LogLevelDebug,
+	"debug":       LogLevelDebug,
+	"info":        LogLevelInfo,
+	"information": LogLevelInfo,
+	"notice":      LogLevelInfo,
+	"warn":        LogLevelWarn,
+	"warning":     LogLevelWarn,
+	"error":       LogLevelError,
+	"eror":        LogLevelError,
+	"critical":    LogLevelError,
+	"fatal":       LogLevelFatal,
+	"crit":        LogLevelFatal,
+	"panic":       LogLevelFatal,
+}
+
+// ParseLogLevel parses the name of a log level case-insensitively
+func ParseLogLevel(name string) (LogLevel, error) {
+	if level, found := logLevelNames[strings.ToLower(name)]; found {
+		return level, nil
+	}
+	return LogLevelUnknown, errors.Errorf("unknown log level '%s', supported levels are debug, info, warn, error and fatal", name)
+}
+
+// String implements the fmt.Stringer interface
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	case LogLevelFatal:
+		return "fatal"
+	default:
+		return "unknown"
+	}
+}
+
+// the formats of the logs of the components, the first group is the time
+// and the second is the level
+var logFormats = []struct {
+	re     *regexp.Regexp
+	layout string
+	swap   bool // the level is before the time
+}{
+	// the unified log format of TiDB, TiKV, PD, etc.
+	{regexp.MustCompile(`^\[(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}\.\d{3} [+-]\d{2}:\d{2})\] \[(\w+)\]`), "2006/01/02 15:04:05.000 -07:00", false},
+	// the log of TiFlash, the time is in the local time zone
+	{regexp.MustCompile(`^(\d{4}\.\d{2}\.\d{2} \d{2}:\d{2}:\d{2}\.\d+) \[ *\d+ *\] <(\w+)>`), "2006.01.02 15:04:05.000000", false},
+	// the logfmt of Prometheus and Alertmanager
+	{regexp.MustCompile(`^level=(\w+) ts=(\S+)`), time.RFC3339Nano, true},
+	// the log of Grafana
+	{regexp.MustCompile(`^t=(\S+) lvl=(\w+)`), "2006-01-02T15:04:05-0700", false},
+}
+
+// ParseLogLine parses the time and level of a log line, ok is false if the
+// line is not in any known format, e.g., a line of a stack trace
+func ParseLogLine(text string) (t time.Time, level LogLevel, ok bool) {
+	for _, f := range logFormats {
+		m := f.re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		ts, lv := m[1], m[2]
+		if f.swap {
+			ts, lv = lv, ts
+		}
+		t, err := time.ParseInLocation(f.layout, ts, time.Local)
+		if err != nil {
+			continue
+		}
+		level, _ = ParseLogLevel(lv)
+		return t, level, true
+	}
+	return time.Time{}, LogLevelUnknown, false
+}
+
+// LogLine is a line of the log of an instance
+type LogLine struct {
+	Instance string
+	Time     time.Time
+	Level    LogLevel
+	Text     string
+}
+
+// LogFilter filters the log lines by the level, time and pattern, the lines
+// whose level or time is unknown are not filtered by them
+type LogFilter struct {
+	Level   LogLevel
+	Since   time.Time
+	Until   time.Time
+	Pattern *regexp.Regexp
+}
+
+// Match returns true if the line passes the filter
+func (f *LogFilter) Match(l *LogLine) bool {
+	if l.Level != LogLevelUnknown && l.Level < f.Level {
+		return false
+	}
+	if !l.Time.IsZero() {
+		if !f.Since.IsZero() && l.Time.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && l.Time.After(f.Until) {
+			return false
+		}
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(l.Text)
+}
+
+// LogFile returns the path of the main log file of the instance
+func LogFile(inst meta.Instance, deployUser string) string {
+	return filepath.Join(clusterutil.Abs(deployUser, inst.LogDir()), inst.ComponentName()+".log")
+}
+
+// TailLogCommand returns the command printing the last lines of the files,
+// all the lines are printed if lines is not positive. If follow is true, the
+// command keeps printing the lines appended until its stdin is closed.
+func TailLogCommand(files []string, lines int, follow bool) string {
+	var quoted []string
+	for _, f := range files {
+		quoted = append(quoted, utils.ShellQuote(f))
+	}
+
+	n := fmt.Sprintf("-n %d", lines)
+	if lines <= 0 {
+		n = "-n +1"
+	}
+	// the headers are always printed to tell which file the lines are from
+	cmd := fmt.Sprintf("tail -v %s", n)
+	if !follow {
+		return fmt.Sprintf("%s %s", cmd, strings.Join(quoted, " "))
+	}
+	return fmt.Sprintf("%s -F %s & pid=$!; cat >/dev/null; kill $pid", cmd, strings.Join(quoted, " "))
+}
+
+// LogStream splits the output of TailLogCommand into the log lines of the
+// instances, the files are distinguished by the headers printed by tail. The
+// lines not in a known format (e.g., stack traces) inherit the time and level
+// of the previous line of the same instance.
+type LogStream struct {
+	files   map[string]string // file -> instance
+	fn      func(*LogLine)
+	buf     []byte
+	current string
+	last    map[string]*LogLine
+}
+
+// NewLogStream returns a LogStream of the files, fn is called with each line
+func NewLogStream(files map[string]string, fn func(*LogLine)) *LogStream {
+	return &LogStream{
+		files: files,
+		fn:    fn,
+		last:  make(map[string]*LogLine),
+	}
+}
+
+// Write implements the io.Writer interface
+func (s *LogStream) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			break
+		}
+		s.line(string(s.buf[:i]))
+		s.buf = s.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush handles the last line which is not terminated by a newline
+func (s *LogStream) Flush() {
+	if len(s.buf) > 0 {
+		s.line(string(s.buf))
+		s.buf = nil
+	}
+}
+
+func (s *LogStream) line(text string) {
+	text = strings.TrimRight(text, "\r")
+	if text == "" {
+		return
+	}
+	if strings.HasPrefix(text, "==> ") && strings.HasSuffix(text, " <==") {
+		file := strings.TrimSuffix(strings.TrimPrefix(text, "==> "), " <==")
+		if inst, found := s.files[file]; found {
+			s.current = inst
+			return
+		}
+	}
+	if s.current == "" {
+		return
+	}
+
+	l := &LogLine{Instance: s.current, Text: text}
+	if t, level, ok := ParseLogLine(text); ok {
+		l.Time, l.Level = t, level
+	} else if last := s.last[s.current]; last != nil {
+		l.Time, l.Level = last.Time, last.Level
+	}
+	s.last[s.current] = l
+	s.fn(l)
+}
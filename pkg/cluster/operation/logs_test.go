@@ -0,0 +1,134 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+type logsSuite struct{}
+
+var _ = check.Suite(&logsSuite{})
+
+func (s *logsSuite) TestParseLogLine(c *check.C) {
+	for _, t := range []struct {
+		text  string
+		time  string
+		level LogLevel
+	}{
+		{`[2020/10/17 23:19:07.541 +08:00] [WARN] [server.go:123] ["slow query"]`, "2020-10-17T15:19:07.541Z", LogLevelWarn},
+		{`level=error ts=2020-10-17T15:19:07.541Z caller=main.go:1 msg="oops"`, "2020-10-17T15:19:07.541Z", LogLevelError},
+		{`t=2020-10-17T23:19:07+0800 lvl=info msg="HTTP Server Listen"`, "2020-10-17T15:19:07Z", LogLevelInfo},
+	} {
+		tm, level, ok := ParseLogLine(t.text)
+		c.Assert(ok, check.IsTrue, check.Commentf(t.text))
+		c.Assert(tm.UTC().Format(time.RFC3339Nano), check.Equals, t.time)
+		c.Assert(level, check.Equals, t.level)
+	}
+
+	tm, level, ok := ParseLogLine("2020.10.17 23:19:07.541123 [ 12 ] <Information> Application: Ready")
+	c.Assert(ok, check.IsTrue)
+	c.Assert(tm.Equal(time.Date(2020, 10, 17, 23, 19, 7, 541123000, time.Local)), check.IsTrue)
+	c.Assert(level, check.Equals, LogLevelInfo)
+
+	_, _, ok = ParseLogLine("goroutine 1 [running]:")
+	c.Assert(ok, check.IsFalse)
+
+	_, err := ParseLogLevel("verbose")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *logsSuite) TestLogStream(c *check.C) {
+	var lines []*LogLine
+	stream := NewLogStream(map[string]string{
+		"/tidb/log/tidb.log": "172.16.5.1:4000",
+		"/tidb/log/pd.log":   "172.16.5.1:2379",
+	}, func(l *LogLine) {
+		lines = append(lines, l)
+	})
+
+	// the output is split at arbitrary positions
+	output := "==> /tidb/log/tidb.log <==\n" +
+		"[2020/10/17 23:19:07.541 +08:00] [ERROR] [a.go:1] [panic]\n" +
+		"goroutine 1 [running]:\n" +
+		"\n==> /tidb/log/pd.log <==\n" +
+		"[2020/10/17 23:19:08.000 +08:00] [INFO] [b.go:1] [ready]"
+	for i := 0; i < len(output); i += 7 {
+		end := i + 7
+		if end > len(output) {
+			end = len(output)
+		}
+		_, err := stream.Write([]byte(output[i:end]))
+		c.Assert(err, check.IsNil)
+	}
+	c.Assert(lines, check.HasLen, 2)
+	stream.Flush()
+	c.Assert(lines, check.HasLen, 3)
+
+	c.Assert(lines[0].Instance, check.Equals, "172.16.5.1:4000")
+	c.Assert(lines[0].Level, check.Equals, LogLevelError)
+	// the line of the stack trace inherits the time and level
+	c.Assert(lines[1].Text, check.Equals, "goroutine 1 [running]:")
+	c.Assert(lines[1].Level, check.Equals, LogLevelError)
+	c.Assert(lines[1].Time.Equal(lines[0].Time), check.IsTrue)
+	c.Assert(lines[2].Instance, check.Equals, "172.16.5.1:2379")
+	c.Assert(lines[2].Level, check.Equals, LogLevelInfo)
+
+	filter := LogFilter{Level: LogLevelWarn}
+	c.Assert(filter.Match(lines[1]), check.IsTrue)
+	c.Assert(filter.Match(lines[2]), check.IsFalse)
+	filter = LogFilter{Since: lines[2].Time}
+	c.Assert(filter.Match(lines[0]), check.IsFalse)
+	c.Assert(filter.Match(lines[2]), check.IsTrue)
+	filter = LogFilter{Until: lines[0].Time, Pattern: regexp.MustCompile("goroutine")}
+	c.Assert(filter.Match(lines[0]), check.IsFalse)
+	c.Assert(filter.Match(lines[1]), check.IsTrue)
+	c.Assert(filter.Match(&LogLine{Text: "goroutine 2"}), check.IsTrue)
+}
+
+func (s *logsSuite) TestTailLogCommand(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-logs")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		filepath.Join(dir, "tidb.log"): "tidb",
+		filepath.Join(dir, "tikv.log"): "tikv",
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "tidb.log"), []byte("1\n2\n3\n"), 0644), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "tikv.log"), []byte("a\n"), 0644), check.IsNil)
+
+	got := make(map[string][]string)
+	stream := NewLogStream(files, func(l *LogLine) {
+		got[l.Instance] = append(got[l.Instance], l.Text)
+	})
+	cmd := exec.Command("sh", "-c", TailLogCommand([]string{filepath.Join(dir, "tidb.log"), filepath.Join(dir, "tikv.log")}, 2, false))
+	cmd.Stdout = stream
+	c.Assert(cmd.Run(), check.IsNil)
+	stream.Flush()
+	c.Assert(got, check.DeepEquals, map[string][]string{
+		"tidb": {"2", "3"},
+		"tikv": {"a"},
+	})
+
+	c.Assert(TailLogCommand([]string{"/log/tidb.log"}, 0, true), check.Equals,
+		"tail -v -n +1 -F '/log/tidb.log' & pid=$!; cat >/dev/null; kill $pid")
+}
@@ -29,6 +29,16 @@ type Options struct {
 	SSHTimeout int64 // timeout in seconds when connecting an SSH server
 	OptTimeout int64 // timeout in seconds for operations that support it, not to confuse with SSH timeout
 	APITimeout int64 // timeout in seconds for API operations that support it, like transfering store leader
+
+	HealthGate HealthGateOptions // gates checked between the instances in upgrade, ignored if Force
+	Hooks      *HookOptions      // the hooks of the instances run around each restart in upgrade if not nil
+}
+
+// Progress records the finished steps of an operation, the steps recorded are
+// skipped when the interrupted operation is resumed
+type Progress interface {
+	StepDone(step string) bool
+	RecordStep(step string) error
 }
 
 // Operation represents the type of cluster operation
@@ -44,6 +54,7 @@ const (
 	ScaleInOperation
 	ScaleOutOperation
 	DestroyTombstoneOperation
+	DowngradeOperation
 )
 
 var opStringify = [...]string{
@@ -55,10 +66,11 @@ var opStringify = [...]string{
 	"ScaleInOperation",
 	"ScaleOutOperation",
 	"DestroyTombstoneOperation",
+	"DowngradeOperation",
 }
 
 func (op Operation) String() string {
-	if op <= DestroyTombstoneOperation {
+	if op <= DowngradeOperation {
 		return opStringify[op]
 	}
 	return fmt.Sprintf("unknonw-op(%d)", op)
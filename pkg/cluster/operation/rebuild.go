@@ -0,0 +1,567 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	pdserverapi "github.com/pingcap/pd/v4/server/api"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+	"gopkg.in/yaml.v2"
+)
+
+// the markers of the sections in the output of scanUnitsScript
+const (
+	scanMarkerArch    = "@@tiup-arch "
+	scanMarkerUnit    = "@@tiup-unit "
+	scanMarkerScript  = "@@tiup-script"
+	scanMarkerTiFlash = "@@tiup-tiflash"
+	scanMarkerLearner = "@@tiup-tiflash-learner"
+	scanMarkerProm    = "@@tiup-prometheus"
+)
+
+// scanUnitsScript prints the systemd units of the instances on a host, with
+// their run scripts, the config files of TiFlash as its ports and dirs are not
+// in the run script, and the config of Prometheus to find its targets
+var scanUnitsScript = fmt.Sprintf(`echo "%[1]s$(uname -m)"
+cd /etc/systemd/system 2>/dev/null || exit 0
+for unit in *-*.service; do
+    [ -f "$unit" ] || continue
+    echo "%[2]s$unit"
+    grep '^User=' "$unit"
+    script=$(sed -n 's/^ExecStart=//p' "$unit")
+    echo "%[3]s"
+    cat "$script" 2>/dev/null
+    dir=$(dirname "$(dirname "$script")")
+    case "$unit" in
+    tiflash-*)
+        echo "%[4]s"
+        cat "$dir/conf/tiflash.toml" 2>/dev/null
+        echo "%[5]s"
+        cat "$dir/conf/tiflash-learner.toml" 2>/dev/null;;
+    prometheus-*)
+        echo "%[6]s"
+        cat "$dir/conf/prometheus.yml" 2>/dev/null;;
+    esac
+done`, scanMarkerArch, scanMarkerUnit, scanMarkerScript, scanMarkerTiFlash, scanMarkerLearner, scanMarkerProm)
+
+var (
+	unitNameRegexp = regexp.MustCompile(`^([a-z_-]+)-(\d+)\.service$`)
+	// the targets are quoted in the config of Prometheus generated by tiup
+	promTargetRegexp = regexp.MustCompile(`'([^'\s]+:\d+)'`)
+)
+
+// RebuildOptions are the options to rebuild the meta of a cluster
+type RebuildOptions struct {
+	PDAddrs   []string           // the client addresses of PD to query the members and stores
+	Hosts     []string           // the hosts to scan besides the hosts of PD members and stores
+	Version   string             // the version of the cluster if it can't be detected from PD
+	TLSConfig *tls.Config        // the TLS config to query PD
+	SSH       executor.SSHConfig // the SSH config to connect the hosts, the Host is ignored
+}
+
+// serviceUnit is the systemd unit of an instance found on a host
+type serviceUnit struct {
+	host      string
+	component string
+	port      int
+	user      string
+	args      map[string]string      // the flags of the run script and DEPLOY_DIR
+	tiflash   map[string]interface{} // conf/tiflash.toml of TiFlash
+	learner   map[string]interface{} // conf/tiflash-learner.toml of TiFlash
+	targets   []string               // the targets in conf/prometheus.yml of Prometheus
+}
+
+// RebuildMeta rebuilds the meta of a cluster from the live cluster. The
+// members and stores are queried from PD, the systemd units and run scripts
+// of the instances are scanned on their hosts, and the hosts in the options,
+// which is needed for the hosts running TiDB or monitoring components only.
+func RebuildMeta(opt RebuildOptions) (*meta.ClusterMeta, error) {
+	pdClient := api.NewPDClient(opt.PDAddrs, 10*time.Second, opt.TLSConfig)
+	members, err := pdClient.GetMembers()
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to query the members of PD")
+	}
+	stores, err := pdClient.GetStores()
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to query the stores of PD")
+	}
+
+	hosts := set.NewStringSet(opt.Hosts...)
+	for _, m := range members.Members {
+		for _, u := range m.ClientUrls {
+			if host, _, err := parseURLHostPort(u); err == nil {
+				hosts.Insert(host)
+			}
+		}
+	}
+	for _, s := range stores.Stores {
+		if host, _, err := net.SplitHostPort(s.Store.Address); err == nil {
+			hosts.Insert(host)
+		}
+	}
+
+	scans := make(map[string]string)
+	for _, host := range hosts.Slice() {
+		log.Infof("Scanning the systemd units on %s...", host)
+		cfg := opt.SSH
+		cfg.Host = host
+		e, err := executor.New(cfg, false)
+		if err != nil {
+			return nil, err
+		}
+		stdout, stderr, err := e.Execute(scanUnitsScript, false)
+		if err != nil {
+			return nil, errors.Annotatef(err, "failed to scan the systemd units on %s: %s", host, stderr)
+		}
+		scans[host] = string(stdout)
+	}
+
+	return buildRebuiltMeta(opt, members.Members, stores.Stores, scans)
+}
+
+// buildRebuiltMeta assembles the meta from the PD members and stores and the
+// outputs of scanUnitsScript on the hosts, the instances which are removed
+// from PD but still have units on the host are skipped
+func buildRebuiltMeta(opt RebuildOptions, members []*pdpb.Member, stores []*pdserverapi.StoreInfo, scans map[string]string) (*meta.ClusterMeta, error) {
+	version := opt.Version
+	pdMembers := set.NewStringSet()
+	tlsEnabled := false
+	for _, m := range members {
+		if version == "" && m.BinaryVersion != "" {
+			version = m.BinaryVersion
+		}
+		for _, u := range m.ClientUrls {
+			tlsEnabled = tlsEnabled || strings.HasPrefix(u, "https://")
+			if host, port, err := parseURLHostPort(u); err == nil {
+				pdMembers.Insert(fmt.Sprintf("%s:%d", host, port))
+			}
+		}
+	}
+	if version == "" {
+		return nil, errors.New("the version of the cluster can't be detected from PD, please specify it")
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+
+	liveStores := set.NewStringSet()
+	for _, s := range stores {
+		if s.Store.State != metapb.StoreState_Tombstone {
+			liveStores.Insert(s.Store.Address)
+		}
+	}
+
+	topo := &meta.TopologySpecification{}
+	topo.GlobalOptions.TLSEnabled = tlsEnabled
+	topo.GlobalOptions.SSHPort = opt.SSH.Port
+	users := set.NewStringSet()
+	found := set.NewStringSet()
+
+	var hosts []string
+	for host := range scans {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	archs := make(map[string]string)
+	var units []*serviceUnit
+	for _, host := range hosts {
+		arch, hostUnits, err := parseScanOutput(host, scans[host])
+		if err != nil {
+			return nil, err
+		}
+		archs[host] = arch
+		units = append(units, hostUnits...)
+	}
+
+	// the hosts may run the instances of other clusters, the instances of the
+	// cluster are recognized by PD, or the PD endpoints in their run scripts
+	var kept []*serviceUnit
+	clusterHosts := set.NewStringSet()
+	promTargets := set.NewStringSet()
+	for _, unit := range units {
+		id := fmt.Sprintf("%s:%d", unit.host, unit.port)
+		var endpoints string
+		switch unit.component {
+		case meta.ComponentPD:
+			if !pdMembers.Exist(id) {
+				log.Warnf("Skip the stale unit of pd %s as it is not a member of PD", id)
+				continue
+			}
+		case meta.ComponentTiKV:
+			if !liveStores.Exist(id) {
+				log.Warnf("Skip the stale unit of tikv %s as it is not a store of PD", id)
+				continue
+			}
+		case meta.ComponentTiFlash:
+			// the store address of TiFlash is the address of its proxy
+			addr := tomlString(unit.learner, "server", "advertise-addr")
+			if !liveStores.Exist(addr) {
+				log.Warnf("Skip the stale unit of tiflash %s as it is not a store of PD", id)
+				continue
+			}
+			found.Insert(addr)
+		case meta.ComponentTiDB:
+			endpoints = unit.args["--path"]
+		case meta.ComponentPump, meta.ComponentDrainer:
+			endpoints = unit.args["--pd-urls"]
+		case meta.ComponentCDC:
+			endpoints = unit.args["--pd"]
+		case meta.ComponentPrometheus:
+			endpoints = strings.Join(unit.targets, ",")
+		default:
+			// the other monitoring components are checked by the targets
+			continue
+		}
+		if endpoints != "" && !hasPDMember(endpoints, pdMembers) {
+			log.Warnf("Skip the unit of %s %s as it doesn't belong to the cluster", unit.component, id)
+			continue
+		}
+		if unit.component == meta.ComponentPrometheus {
+			for _, target := range unit.targets {
+				promTargets.Insert(target)
+			}
+		}
+		clusterHosts.Insert(unit.host)
+		kept = append(kept, unit)
+	}
+	// the monitoring components are the targets of the Prometheus of the
+	// cluster, or on the hosts of the cluster if there is no Prometheus
+	for _, unit := range units {
+		id := fmt.Sprintf("%s:%d", unit.host, unit.port)
+		switch unit.component {
+		case meta.ComponentGrafana, meta.ComponentAlertManager,
+			meta.ComponentNodeExporter, meta.ComponentBlackboxExporter:
+			if (len(promTargets) > 0 && !promTargets.Exist(id)) ||
+				(len(promTargets) == 0 && !clusterHosts.Exist(unit.host)) {
+				log.Warnf("Skip the unit of %s %s as it doesn't belong to the cluster", unit.component, id)
+				continue
+			}
+			kept = append(kept, unit)
+		}
+	}
+
+	for _, unit := range kept {
+		found.Insert(fmt.Sprintf("%s:%d", unit.host, unit.port))
+		if unit.user != "" {
+			users.Insert(unit.user)
+		}
+		if err := addRebuiltInstance(topo, unit, archs[unit.host]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range append(pdMembers.Slice(), liveStores.Slice()...) {
+		if !found.Exist(id) {
+			log.Warnf("No systemd unit of %s is found, it is not added to the meta", id)
+		}
+	}
+	if len(users) != 1 {
+		return nil, errors.Errorf("the instances should be run by one user, but found %v", users.Slice())
+	}
+	topo.GlobalOptions.User = users.Slice()[0]
+
+	// fill the default values and validate the topology
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rebuilt := &meta.TopologySpecification{}
+	if err := yaml.Unmarshal(data, rebuilt); err != nil {
+		return nil, err
+	}
+
+	return &meta.ClusterMeta{
+		User:     topo.GlobalOptions.User,
+		Version:  version,
+		Topology: rebuilt,
+	}, nil
+}
+
+// parseScanOutput parses the output of scanUnitsScript on the host
+func parseScanOutput(host, output string) (arch string, units []*serviceUnit, err error) {
+	var unit *serviceUnit
+	var section string
+	var content []string
+	flush := func() error {
+		if unit == nil {
+			return nil
+		}
+		var err error
+		switch section {
+		case scanMarkerScript:
+			unit.args = parseRunScript(content)
+		case scanMarkerTiFlash:
+			_, err = toml.Decode(strings.Join(content, "\n"), &unit.tiflash)
+		case scanMarkerLearner:
+			_, err = toml.Decode(strings.Join(content, "\n"), &unit.learner)
+		case scanMarkerProm:
+			for _, m := range promTargetRegexp.FindAllStringSubmatch(strings.Join(content, "\n"), -1) {
+				unit.targets = append(unit.targets, m[1])
+			}
+		}
+		content = nil
+		return errors.Annotatef(err, "invalid config of %s %s:%d", unit.component, host, unit.port)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, scanMarkerArch):
+			arch = strings.TrimSpace(strings.TrimPrefix(line, scanMarkerArch))
+		case strings.HasPrefix(line, scanMarkerUnit):
+			if err := flush(); err != nil {
+				return "", nil, err
+			}
+			unit, section = nil, ""
+			matches := unitNameRegexp.FindStringSubmatch(strings.TrimPrefix(line, scanMarkerUnit))
+			if matches == nil || !isRebuildComponent(matches[1]) {
+				continue
+			}
+			port, _ := strconv.Atoi(matches[2])
+			unit = &serviceUnit{host: host, component: matches[1], port: port}
+			units = append(units, unit)
+		case line == scanMarkerScript || line == scanMarkerTiFlash || line == scanMarkerLearner || line == scanMarkerProm:
+			if err := flush(); err != nil {
+				return "", nil, err
+			}
+			section = line
+		case unit != nil && section == "" && strings.HasPrefix(line, "User="):
+			unit.user = strings.TrimPrefix(line, "User=")
+		default:
+			content = append(content, line)
+		}
+	}
+	if err := flush(); err != nil {
+		return "", nil, err
+	}
+
+	switch normalized := meta.NormalizeArch(arch); normalized {
+	case "amd64", "arm64":
+		return normalized, units, nil
+	default:
+		return "", nil, errors.Errorf("unsupported arch %s of %s", arch, host)
+	}
+}
+
+func isRebuildComponent(name string) bool {
+	switch name {
+	case meta.ComponentPD, meta.ComponentTiKV, meta.ComponentTiDB, meta.ComponentTiFlash,
+		meta.ComponentPump, meta.ComponentDrainer, meta.ComponentCDC,
+		meta.ComponentPrometheus, meta.ComponentGrafana, meta.ComponentAlertManager,
+		meta.ComponentNodeExporter, meta.ComponentBlackboxExporter:
+		return true
+	}
+	return false
+}
+
+// parseRunScript returns the flags of the command in a run script generated
+// by tiup, the deploy dir is stored as DEPLOY_DIR and the log file the output
+// is redirected to as LOG_FILE
+func parseRunScript(lines []string) map[string]string {
+	args := make(map[string]string)
+	for _, line := range lines {
+		line = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "\\"))
+		switch {
+		case strings.HasPrefix(line, "DEPLOY_DIR="):
+			args["DEPLOY_DIR"] = strings.Trim(strings.TrimPrefix(line, "DEPLOY_DIR="), `"`)
+			continue
+		case strings.HasPrefix(line, `cd "`) && !strings.HasPrefix(line, `cd "${DEPLOY_DIR}"`):
+			args["DEPLOY_DIR"] = strings.Trim(strings.Fields(line)[1], `"`)
+			continue
+		case strings.HasPrefix(line, "exec > >(tee"):
+			fields := strings.Fields(line)
+			args["LOG_FILE"] = strings.Trim(fields[len(fields)-1], `")`)
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			if !strings.HasPrefix(fields[i], "-") {
+				continue
+			}
+			if kv := strings.SplitN(fields[i], "=", 2); len(kv) == 2 {
+				args[kv[0]] = strings.Trim(kv[1], `"`)
+			} else if i+1 < len(fields) && !strings.HasPrefix(fields[i+1], "-") && !strings.HasPrefix(fields[i+1], "2>") {
+				args[fields[i]] = strings.Trim(fields[i+1], `"`)
+				i++
+			}
+		}
+	}
+	return args
+}
+
+// addRebuiltInstance adds the instance of the unit to the topology
+func addRebuiltInstance(topo *meta.TopologySpecification, unit *serviceUnit, arch string) error {
+	host := unit.host
+	args := unit.args
+	deployDir := args["DEPLOY_DIR"]
+	logDir := filepath.Dir(args["--log-file"])
+	if args["--log-file"] == "" {
+		logDir = filepath.Dir(args["LOG_FILE"])
+	}
+	if deployDir == "" {
+		return errors.Errorf("failed to detect the deploy dir of %s %s:%d", unit.component, host, unit.port)
+	}
+
+	switch unit.component {
+	case meta.ComponentPD:
+		_, peerPort, err := parseURLHostPort(args["--peer-urls"])
+		if err != nil {
+			return errors.Annotatef(err, "failed to detect the peer port of pd %s:%d", host, unit.port)
+		}
+		topo.PDServers = append(topo.PDServers, meta.PDSpec{
+			Host: host, Name: args["--name"], ClientPort: unit.port, PeerPort: peerPort,
+			DeployDir: deployDir, DataDir: args["--data-dir"], LogDir: logDir, Arch: arch,
+		})
+	case meta.ComponentTiKV:
+		topo.TiKVServers = append(topo.TiKVServers, meta.TiKVSpec{
+			Host: host, Port: unit.port, StatusPort: addrPort(args["--status-addr"]),
+			DeployDir: deployDir, DataDir: args["--data-dir"], LogDir: logDir, Arch: arch,
+		})
+	case meta.ComponentTiDB:
+		status, _ := strconv.Atoi(args["--status"])
+		topo.TiDBServers = append(topo.TiDBServers, meta.TiDBSpec{
+			Host: host, Port: unit.port, StatusPort: status,
+			DeployDir: deployDir, LogDir: logDir, Arch: arch,
+		})
+	case meta.ComponentTiFlash:
+		spec := meta.TiFlashSpec{Host: host, TCPPort: unit.port, DeployDir: deployDir, Arch: arch}
+		spec.DataDir, _ = unit.tiflash["path"].(string)
+		spec.TmpDir, _ = unit.tiflash["tmp_path"].(string)
+		spec.HTTPPort = tomlInt(unit.tiflash, "http_port")
+		spec.StatusPort = tomlInt(unit.tiflash, "status", "metrics_port")
+		spec.FlashServicePort = addrPort(tomlString(unit.tiflash, "flash", "service_addr"))
+		spec.LogDir = filepath.Dir(tomlString(unit.tiflash, "logger", "log"))
+		spec.FlashProxyPort = addrPort(tomlString(unit.learner, "server", "advertise-addr"))
+		spec.FlashProxyStatusPort = addrPort(tomlString(unit.learner, "server", "status-addr"))
+		topo.TiFlashServers = append(topo.TiFlashServers, spec)
+	case meta.ComponentPump:
+		topo.PumpServers = append(topo.PumpServers, meta.PumpSpec{
+			Host: host, Port: unit.port,
+			DeployDir: deployDir, DataDir: args["--data-dir"], LogDir: logDir, Arch: arch,
+		})
+	case meta.ComponentDrainer:
+		commitTS, _ := strconv.ParseInt(args["--initial-commit-ts"], 10, 64)
+		topo.Drainers = append(topo.Drainers, meta.DrainerSpec{
+			Host: host, Port: unit.port, CommitTS: commitTS,
+			DeployDir: deployDir, DataDir: args["--data-dir"], LogDir: logDir, Arch: arch,
+		})
+	case meta.ComponentCDC:
+		topo.CDCServers = append(topo.CDCServers, meta.CDCSpec{
+			Host: host, Port: unit.port, DeployDir: deployDir, LogDir: logDir, Arch: arch,
+		})
+	case meta.ComponentPrometheus:
+		topo.Monitors = append(topo.Monitors, meta.PrometheusSpec{
+			Host: host, Port: unit.port, Retention: args["--storage.tsdb.retention"],
+			DeployDir: deployDir, DataDir: args["--storage.tsdb.path"], LogDir: logDir, Arch: arch,
+		})
+	case meta.ComponentGrafana:
+		topo.Grafana = append(topo.Grafana, meta.GrafanaSpec{
+			Host: host, Port: unit.port, DeployDir: deployDir, Arch: arch,
+		})
+	case meta.ComponentAlertManager:
+		topo.Alertmanager = append(topo.Alertmanager, meta.AlertManagerSpec{
+			Host: host, WebPort: unit.port, ClusterPort: addrPort(args["--cluster.listen-address"]),
+			DeployDir: deployDir, DataDir: args["--storage.path"], LogDir: logDir, Arch: arch,
+		})
+	case meta.ComponentNodeExporter:
+		topo.MonitoredOptions.NodeExporterPort = unit.port
+		topo.MonitoredOptions.DeployDir = deployDir
+		topo.MonitoredOptions.LogDir = logDir
+	case meta.ComponentBlackboxExporter:
+		topo.MonitoredOptions.BlackboxExporterPort = unit.port
+	}
+	return nil
+}
+
+// hasPDMember returns true if any of the endpoints, which are separated by
+// commas and may be URLs, is a member of PD
+func hasPDMember(endpoints string, pdMembers set.StringSet) bool {
+	for _, ep := range strings.Split(endpoints, ",") {
+		ep = strings.TrimSpace(ep)
+		if strings.Contains(ep, "://") {
+			host, port, err := parseURLHostPort(ep)
+			if err != nil {
+				continue
+			}
+			ep = fmt.Sprintf("%s:%d", host, port)
+		}
+		if pdMembers.Exist(ep) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseURLHostPort returns the host and port of an URL like http://host:port
+func parseURLHostPort(s string) (string, int, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return "", 0, errors.Errorf("invalid port in %s", s)
+	}
+	return u.Hostname(), port, nil
+}
+
+// addrPort returns the port of an address like host:port or :port
+func addrPort(addr string) int {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+func tomlValue(conf map[string]interface{}, keys ...string) interface{} {
+	var v interface{} = conf
+	for _, key := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func tomlString(conf map[string]interface{}, keys ...string) string {
+	s, _ := tomlValue(conf, keys...).(string)
+	return s
+}
+
+func tomlInt(conf map[string]interface{}, keys ...string) int {
+	i, _ := tomlValue(conf, keys...).(int64)
+	return int(i)
+}
@@ -0,0 +1,256 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	pdserverapi "github.com/pingcap/pd/v4/server/api"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+)
+
+func TestOperation(t *testing.T) {
+	check.TestingT(t)
+}
+
+type rebuildSuite struct{}
+
+var _ = check.Suite(&rebuildSuite{})
+
+const scanPDHost = `@@tiup-arch x86_64
+@@tiup-unit pd-2379.service
+User=tidb
+@@tiup-script
+#!/bin/bash
+set -e
+
+# WARNING: This file was auto-generated. Do not edit!
+#          All your edit might be overwritten!
+DEPLOY_DIR=/home/tidb/deploy/pd-2379
+
+cd "${DEPLOY_DIR}" || exit 1
+exec bin/pd-server \
+    --name="pd-172.16.5.1-2379" \
+    --client-urls="http://172.16.5.1:2379" \
+    --advertise-client-urls="http://172.16.5.1:2379" \
+    --peer-urls="http://172.16.5.1:2380" \
+    --advertise-peer-urls="http://172.16.5.1:2380" \
+    --data-dir="/data/pd-2379" \
+    --initial-cluster="pd-172.16.5.1-2379=http://172.16.5.1:2380" \
+    --config=conf/pd.toml \
+    --log-file="/home/tidb/deploy/pd-2379/log/pd.log" 2>> "/home/tidb/deploy/pd-2379/log/pd_stderr.log"
+@@tiup-unit tikv-20160.service
+User=tidb
+@@tiup-script
+#!/bin/bash
+set -e
+cd "/home/tidb/deploy/tikv-20160" || exit 1
+exec bin/tikv-server \
+    --addr "0.0.0.0:20160" \
+    --advertise-addr "172.16.5.1:20160" \
+    --status-addr "172.16.5.1:20180" \
+    --pd "172.16.5.1:2379" \
+    --data-dir "/data/tikv-20160" \
+    --config conf/tikv.toml \
+    --log-file "/home/tidb/deploy/tikv-20160/log/tikv.log" 2>> "/home/tidb/deploy/tikv-20160/log/tikv_stderr.log"
+@@tiup-unit tikv-20161.service
+User=tidb
+@@tiup-script
+cd "/home/tidb/deploy/tikv-20161" || exit 1
+exec bin/tikv-server \
+    --data-dir "/data/tikv-20161" \
+    --log-file "/home/tidb/deploy/tikv-20161/log/tikv.log"
+@@tiup-unit node_exporter-9100.service
+User=tidb
+@@tiup-script
+DEPLOY_DIR=/home/tidb/deploy/monitor-9100
+cd "${DEPLOY_DIR}" || exit 1
+exec > >(tee -i -a "/home/tidb/deploy/monitor-9100/log/node_exporter.log")
+exec 2>&1
+exec bin/node_exporter/node_exporter \
+    --web.listen-address=":9100" \
+    --log.level="info"
+@@tiup-unit mysql-3306.service
+User=mysql
+@@tiup-script
+`
+
+const scanTiFlashHost = `@@tiup-arch aarch64
+@@tiup-unit tiflash-9000.service
+User=tidb
+@@tiup-script
+cd "/home/tidb/deploy/tiflash-9000" || exit 1
+exec bin/tiflash/tiflash server --config-file conf/tiflash.toml
+@@tiup-tiflash
+path = "/data/tiflash-9000"
+tcp_port = 9000
+http_port = 8123
+[flash]
+service_addr = "172.16.5.2:3930"
+[logger]
+log = "/home/tidb/deploy/tiflash-9000/log/tiflash.log"
+[status]
+metrics_port = 8234
+@@tiup-tiflash-learner
+[server]
+advertise-addr = "172.16.5.2:20170"
+status-addr = "172.16.5.2:20292"
+`
+
+const scanMonitorHost = `@@tiup-arch x86_64
+@@tiup-unit tidb-4000.service
+User=tidb
+@@tiup-script
+DEPLOY_DIR=/home/tidb/deploy/tidb-4000
+cd "${DEPLOY_DIR}" || exit 1
+exec bin/tidb-server \
+    -P 4000 \
+    --status="10080" \
+    --advertise-address="172.16.5.3" \
+    --store="tikv" \
+    --path="172.16.5.1:2379" \
+    --log-file="/home/tidb/deploy/tidb-4000/log/tidb.log"
+@@tiup-unit tidb-4001.service
+User=tidb
+@@tiup-script
+DEPLOY_DIR=/home/tidb/deploy/tidb-4001
+cd "${DEPLOY_DIR}" || exit 1
+exec bin/tidb-server \
+    -P 4001 \
+    --status="10081" \
+    --path="172.16.5.9:2379" \
+    --log-file="/home/tidb/deploy/tidb-4001/log/tidb.log"
+@@tiup-unit prometheus-9090.service
+User=tidb
+@@tiup-script
+DEPLOY_DIR=/home/tidb/deploy/prometheus-9090
+cd "${DEPLOY_DIR}" || exit 1
+exec > >(tee -i -a "/home/tidb/deploy/prometheus-9090/log/prometheus.log")
+exec bin/prometheus/prometheus \
+    --config.file="/home/tidb/deploy/prometheus-9090/conf/prometheus.yml" \
+    --storage.tsdb.path="/data/prometheus-9090"
+@@tiup-prometheus
+scrape_configs:
+  - job_name: "overwritten-nodes"
+    static_configs:
+    - targets:
+      - '172.16.5.1:9100'
+      - '172.16.5.3:9100'
+  - job_name: "pd"
+    static_configs:
+    - targets:
+      - '172.16.5.1:2379'
+@@tiup-unit node_exporter-9100.service
+User=tidb
+@@tiup-script
+DEPLOY_DIR=/home/tidb/deploy/monitor-9100
+cd "${DEPLOY_DIR}" || exit 1
+exec > >(tee -i -a "/home/tidb/deploy/monitor-9100/log/node_exporter.log")
+exec bin/node_exporter/node_exporter --web.listen-address=":9100"
+@@tiup-unit node_exporter-9101.service
+User=tidb
+@@tiup-script
+DEPLOY_DIR=/home/tidb/deploy/monitor-9101
+cd "${DEPLOY_DIR}" || exit 1
+exec > >(tee -i -a "/home/tidb/deploy/monitor-9101/log/node_exporter.log")
+exec bin/node_exporter/node_exporter --web.listen-address=":9101"
+`
+
+func (s *rebuildSuite) TestBuildRebuiltMeta(c *check.C) {
+	members := []*pdpb.Member{{
+		Name:          "pd-172.16.5.1-2379",
+		ClientUrls:    []string{"http://172.16.5.1:2379"},
+		BinaryVersion: "4.0.0",
+	}}
+	stores := []*pdserverapi.StoreInfo{
+		{Store: &pdserverapi.MetaStore{Store: &metapb.Store{Address: "172.16.5.1:20160"}}},
+		{Store: &pdserverapi.MetaStore{Store: &metapb.Store{Address: "172.16.5.1:20161", State: metapb.StoreState_Tombstone}}},
+		{Store: &pdserverapi.MetaStore{Store: &metapb.Store{Address: "172.16.5.2:20170"}}},
+	}
+	scans := map[string]string{
+		"172.16.5.1": scanPDHost,
+		"172.16.5.2": scanTiFlashHost,
+	}
+
+	metadata, err := buildRebuiltMeta(RebuildOptions{}, members, stores, scans)
+	c.Assert(err, check.IsNil)
+	c.Assert(metadata.Version, check.Equals, "v4.0.0")
+	c.Assert(metadata.User, check.Equals, "tidb")
+
+	topo := metadata.Topology
+	c.Assert(topo.PDServers, check.HasLen, 1)
+	pd := topo.PDServers[0]
+	c.Assert(pd.Name, check.Equals, "pd-172.16.5.1-2379")
+	c.Assert(pd.ClientPort, check.Equals, 2379)
+	c.Assert(pd.PeerPort, check.Equals, 2380)
+	c.Assert(pd.DeployDir, check.Equals, "/home/tidb/deploy/pd-2379")
+	c.Assert(pd.DataDir, check.Equals, "/data/pd-2379")
+	c.Assert(pd.LogDir, check.Equals, "/home/tidb/deploy/pd-2379/log")
+
+	// the tombstone store is skipped
+	c.Assert(topo.TiKVServers, check.HasLen, 1)
+	tikv := topo.TiKVServers[0]
+	c.Assert(tikv.Port, check.Equals, 20160)
+	c.Assert(tikv.StatusPort, check.Equals, 20180)
+	c.Assert(tikv.DeployDir, check.Equals, "/home/tidb/deploy/tikv-20160")
+	c.Assert(tikv.DataDir, check.Equals, "/data/tikv-20160")
+
+	c.Assert(topo.TiFlashServers, check.HasLen, 1)
+	c.Assert(topo.TiFlashServers[0], check.DeepEquals, meta.TiFlashSpec{
+		Host:                 "172.16.5.2",
+		SSHPort:              22,
+		TCPPort:              9000,
+		HTTPPort:             8123,
+		FlashServicePort:     3930,
+		FlashProxyPort:       20170,
+		FlashProxyStatusPort: 20292,
+		StatusPort:           8234,
+		DeployDir:            "/home/tidb/deploy/tiflash-9000",
+		DataDir:              "/data/tiflash-9000",
+		LogDir:               "/home/tidb/deploy/tiflash-9000/log",
+		Arch:                 "arm64",
+		OS:                   "linux",
+	})
+
+	c.Assert(topo.MonitoredOptions.NodeExporterPort, check.Equals, 9100)
+	c.Assert(topo.MonitoredOptions.DeployDir, check.Equals, "/home/tidb/deploy/monitor-9100")
+	c.Assert(topo.MonitoredOptions.LogDir, check.Equals, "/home/tidb/deploy/monitor-9100/log")
+
+	// the instances of other clusters are skipped
+	scans["172.16.5.3"] = scanMonitorHost
+	metadata, err = buildRebuiltMeta(RebuildOptions{}, members, stores, scans)
+	c.Assert(err, check.IsNil)
+	topo = metadata.Topology
+	c.Assert(topo.TiDBServers, check.HasLen, 1)
+	c.Assert(topo.TiDBServers[0].Port, check.Equals, 4000)
+	c.Assert(topo.TiDBServers[0].StatusPort, check.Equals, 10080)
+	c.Assert(topo.Monitors, check.HasLen, 1)
+	c.Assert(topo.Monitors[0].DataDir, check.Equals, "/data/prometheus-9090")
+	c.Assert(topo.MonitoredOptions.NodeExporterPort, check.Equals, 9100)
+	delete(scans, "172.16.5.3")
+
+	// the unknown arch is rejected
+	scans["172.16.5.4"] = "@@tiup-arch mips64\n"
+	_, err = buildRebuiltMeta(RebuildOptions{}, members, stores, scans)
+	c.Assert(err, check.NotNil)
+	delete(scans, "172.16.5.4")
+
+	// the version is required
+	members[0].BinaryVersion = ""
+	_, err = buildRebuiltMeta(RebuildOptions{}, members, stores, scans)
+	c.Assert(err, check.NotNil)
+}
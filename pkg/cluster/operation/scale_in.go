@@ -14,6 +14,7 @@
 package operator
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -70,9 +71,10 @@ func ScaleIn(
 	getter ExecutorGetter,
 	spec meta.Specification,
 	options Options,
+	tlsCfg *tls.Config,
 ) error {
 	if clusterSpec := spec.GetClusterSpecification(); clusterSpec != nil {
-		return ScaleInCluster(getter, clusterSpec, options)
+		return ScaleInCluster(getter, clusterSpec, options, tlsCfg)
 	} else if dmSpec := spec.GetDMSpecification(); dmSpec != nil {
 		return ScaleInDMCluster(getter, dmSpec, options)
 	}
@@ -84,6 +86,7 @@ func ScaleInCluster(
 	getter ExecutorGetter,
 	spec *meta.ClusterSpecification,
 	options Options,
+	tlsCfg *tls.Config,
 ) error {
 	// instances by uuid
 	instances := map[string]meta.Instance{}
@@ -151,9 +154,9 @@ func ScaleInCluster(
 		return errors.New("cannot find available PD instance")
 	}
 
-	pdClient = api.NewPDClient(pdEndpoint, 10*time.Second, nil)
+	pdClient = api.NewPDClient(pdEndpoint, 10*time.Second, tlsCfg)
 
-	binlogClient, err := api.NewBinlogClient(pdEndpoint, nil /* tls.Config */)
+	binlogClient, err := api.NewBinlogClient(pdEndpoint, tlsCfg)
 	if err != nil {
 		return err
 	}
@@ -14,7 +14,10 @@
 package operator
 
 import (
+	"crypto/tls"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -25,22 +28,67 @@ import (
 	"github.com/pingcap/tiup/pkg/set"
 )
 
-// Upgrade the cluster.
+// Upgrade the cluster. The instances are restarted one by one, and unless
+// forced, the enabled health gates are checked before the first restart and after
+// each restart, the upgrade is halted if they don't pass. The instances
+// upgraded are recorded in the progress, if it is not nil, and skipped when
+// the upgrade is resumed.
 func Upgrade(
 	getter ExecutorGetter,
 	spec meta.Specification,
 	options Options,
+	tlsCfg *tls.Config,
+	progress Progress,
+) error {
+	return rollingUpdate(getter, spec, spec.ComponentsByUpdateOrder(), "upgrade", options, tlsCfg, progress)
+}
+
+// Downgrade rolls the cluster back to the binaries restored, it is the same
+// as Upgrade except that the components are restarted in the reverse order.
+func Downgrade(
+	getter ExecutorGetter,
+	spec meta.Specification,
+	options Options,
+	tlsCfg *tls.Config,
+	progress Progress,
+) error {
+	return rollingUpdate(getter, spec, ComponentsByDowngradeOrder(spec), "rollback", options, tlsCfg, progress)
+}
+
+// ComponentsByDowngradeOrder returns the components in the reverse of the update order
+func ComponentsByDowngradeOrder(spec meta.Specification) []meta.Component {
+	comps := spec.ComponentsByUpdateOrder()
+	for i, j := 0, len(comps)-1; i < j; i, j = i+1, j-1 {
+		comps[i], comps[j] = comps[j], comps[i]
+	}
+	return comps
+}
+
+// rollingUpdate restarts the instances of the components one by one, action
+// is the name of the operation used in the logs and the progress
+func rollingUpdate(
+	getter ExecutorGetter,
+	spec meta.Specification,
+	components []meta.Component,
+	action string,
+	options Options,
+	tlsCfg *tls.Config,
+	progress Progress,
 ) error {
 	roleFilter := set.NewStringSet(options.Roles...)
 	nodeFilter := set.NewStringSet(options.Nodes...)
-	components := spec.ComponentsByUpdateOrder()
 	components = FilterComponent(components, roleFilter)
 
-	leaderAware := set.NewStringSet(meta.ComponentPD, meta.ComponentTiKV, meta.ComponentDMMaster)
-
-	timeoutOpt := &clusterutil.RetryOption{
-		Timeout: time.Second * time.Duration(options.APITimeout),
-		Delay:   time.Second * 2,
+	clusterSpec := spec.GetClusterSpecification()
+	gates := options.HealthGate
+	if options.Force || clusterSpec == nil {
+		gates.Gates = nil
+	}
+	if len(gates.Gates) > 0 {
+		log.Infof("Checking the health gates: %s", strings.Join(gates.Gates, ", "))
+		if err := waitHealthGates(clusterSpec, gates, tlsCfg, 0); err != nil {
+			return errors.Annotatef(err, "the cluster is not healthy before the %s", action)
+		}
 	}
 
 	for _, component := range components {
@@ -49,103 +97,135 @@ func Upgrade(
 			continue
 		}
 
-		if clusterSpec := spec.GetClusterSpecification(); clusterSpec != nil {
-			// Transfer leader of evict leader if the component is TiKV/PD in non-force mode
-			if !options.Force && leaderAware.Exist(component.Name()) {
-				pdClient := api.NewPDClient(clusterSpec.GetPDList(), 5*time.Second, nil)
-				switch component.Name() {
-				case meta.ComponentPD:
-					log.Infof("Restarting component %s", component.Name())
-
-					for _, instance := range instances {
-						leader, err := pdClient.GetLeader()
-						if err != nil {
-							return errors.Annotatef(err, "failed to get PD leader %s", instance.GetHost())
-						}
-
-						if len(clusterSpec.PDServers) > 1 && leader.Name == instance.(*meta.PDInstance).Name {
-							if err := pdClient.EvictPDLeader(timeoutOpt); err != nil {
-								return errors.Annotatef(err, "failed to evict PD leader %s", instance.GetHost())
-							}
-						}
-
-						if err := stopInstance(getter, instance); err != nil {
-							return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
-						}
-						if err := startInstance(getter, instance, options.OptTimeout); err != nil {
-							return errors.Annotatef(err, "failed to start %s", instance.GetHost())
-						}
-					}
-
-				case meta.ComponentTiKV:
-					log.Infof("Restarting component %s", component.Name())
-					pdClient := api.NewPDClient(clusterSpec.GetPDList(), 5*time.Second, nil)
-					// Make sure there's leader of PD.
-					// Although we evict pd leader when restart pd,
-					// But when there's only one PD instance the pd might not serve request right away after restart.
-					err := pdClient.WaitLeader(timeoutOpt)
-					if err != nil {
-						return errors.Annotate(err, "failed to wait leader")
-					}
-
-					for _, instance := range instances {
-						if err := pdClient.EvictStoreLeader(addr(instance), timeoutOpt); err != nil {
-							if clusterutil.IsTimeoutOrMaxRetry(err) {
-								log.Warnf("Ignore evicting store leader from %s, %v", instance.ID(), err)
-							} else {
-								return errors.Annotatef(err, "failed to evict store leader %s", instance.GetHost())
-							}
-						}
-
-						if err := stopInstance(getter, instance); err != nil {
-							return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
-						}
-						if err := startInstance(getter, instance, options.OptTimeout); err != nil {
-							return errors.Annotatef(err, "failed to start %s", instance.GetHost())
-						}
-						// remove store leader evict scheduler after restart
-						if err := pdClient.RemoveStoreEvict(addr(instance)); err != nil {
-							return errors.Annotatef(err, "failed to remove evict store scheduler for %s", instance.GetHost())
-						}
-					}
-				}
+		log.Infof("Restarting component %s", component.Name())
+		for _, instance := range instances {
+			step := fmt.Sprintf("%s %s %s", action, instance.ComponentName(), instance.ID())
+			if progress != nil && progress.StepDone(step) {
+				log.Infof("\tSkip the instance %s done in the previous %s", instance.ID(), action)
 				continue
 			}
-		} else if dmSpec := spec.GetDMSpecification(); dmSpec != nil {
-			// Transfer leader of evict leader if the component is TiKV/PD in non-force mode
-			if !options.Force && leaderAware.Exist(component.Name()) {
-				dmMasterClient := api.NewDMMasterClient(dmSpec.GetMasterList(), 5*time.Second, nil)
-				if component.Name() == meta.ComponentDMMaster {
-					log.Infof("Restarting component %s", component.Name())
-
-					for _, instance := range instances {
-						leader, err := dmMasterClient.GetLeader(timeoutOpt)
-						if err != nil {
-							return errors.Annotatef(err, "failed to get dm-master leader %s", instance.GetHost())
-						}
-
-						if len(dmSpec.Masters) > 1 && leader == instance.(*meta.DMMasterInstance).Name {
-							if err := dmMasterClient.EvictDMMasterLeader(timeoutOpt); err != nil {
-								return errors.Annotatef(err, "failed to dm-master PD leader %s", instance.GetHost())
-							}
-						}
-
-						if err := stopInstance(getter, instance); err != nil {
-							return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
-						}
-						if err := startInstance(getter, instance, options.OptTimeout); err != nil {
-							return errors.Annotatef(err, "failed to start %s", instance.GetHost())
-						}
-					}
+
+			if options.Hooks != nil {
+				if err := RunInstanceHooks(getter, *options.Hooks, true, instance); err != nil {
+					return err
+				}
+			}
+
+			if err := upgradeInstance(getter, spec, instance, options, tlsCfg); err != nil {
+				return err
+			}
+
+			if len(gates.Gates) > 0 {
+				log.Infof("\tWaiting for the health gates after restarting %s", instance.ID())
+				stable := time.Duration(gates.StableTime) * time.Second
+				if err := waitHealthGates(clusterSpec, gates, tlsCfg, stable); err != nil {
+					return errors.Annotatef(err, "the %s is halted after restarting %s", action, instance.ID())
+				}
+			}
+
+			if options.Hooks != nil {
+				if err := RunInstanceHooks(getter, *options.Hooks, false, instance); err != nil {
+					return err
+				}
+			}
+
+			if progress != nil {
+				if err := progress.RecordStep(step); err != nil {
+					return err
 				}
 			}
 		}
+	}
+
+	return nil
+}
 
-		if err := RestartComponent(getter, instances, options.OptTimeout); err != nil {
-			return errors.Annotatef(err, "failed to restart %s", component.Name())
+// upgradeInstance restarts the instance, the leaders are transferred out of
+// PD, TiKV and DM master before restarting them in non-force mode
+func upgradeInstance(getter ExecutorGetter, spec meta.Specification, instance meta.Instance, options Options, tlsCfg *tls.Config) error {
+	timeoutOpt := &clusterutil.RetryOption{
+		Timeout: time.Second * time.Duration(options.APITimeout),
+		Delay:   time.Second * 2,
+	}
+
+	if clusterSpec := spec.GetClusterSpecification(); clusterSpec != nil && !options.Force {
+		pdClient := api.NewPDClient(clusterSpec.GetPDList(), 5*time.Second, tlsCfg)
+		switch instance.ComponentName() {
+		case meta.ComponentPD:
+			leader, err := pdClient.GetLeader()
+			if err != nil {
+				return errors.Annotatef(err, "failed to get PD leader %s", instance.GetHost())
+			}
+
+			if len(clusterSpec.PDServers) > 1 && leader.Name == instance.(*meta.PDInstance).Name {
+				if err := pdClient.EvictPDLeader(timeoutOpt); err != nil {
+					return errors.Annotatef(err, "failed to evict PD leader %s", instance.GetHost())
+				}
+			}
+
+			if err := stopInstance(getter, instance); err != nil {
+				return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
+			}
+			if err := startInstance(getter, instance, options.OptTimeout); err != nil {
+				return errors.Annotatef(err, "failed to start %s", instance.GetHost())
+			}
+			return nil
+
+		case meta.ComponentTiKV:
+			// Make sure there's leader of PD.
+			// Although we evict pd leader when restart pd,
+			// But when there's only one PD instance the pd might not serve request right away after restart.
+			err := pdClient.WaitLeader(timeoutOpt)
+			if err != nil {
+				return errors.Annotate(err, "failed to wait leader")
+			}
+
+			if err := pdClient.EvictStoreLeader(addr(instance), timeoutOpt); err != nil {
+				if clusterutil.IsTimeoutOrMaxRetry(err) {
+					log.Warnf("Ignore evicting store leader from %s, %v", instance.ID(), err)
+				} else {
+					return errors.Annotatef(err, "failed to evict store leader %s", instance.GetHost())
+				}
+			}
+
+			if err := stopInstance(getter, instance); err != nil {
+				return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
+			}
+			if err := startInstance(getter, instance, options.OptTimeout); err != nil {
+				return errors.Annotatef(err, "failed to start %s", instance.GetHost())
+			}
+			// remove store leader evict scheduler after restart
+			if err := pdClient.RemoveStoreEvict(addr(instance)); err != nil {
+				return errors.Annotatef(err, "failed to remove evict store scheduler for %s", instance.GetHost())
+			}
+			return nil
 		}
+	} else if dmSpec := spec.GetDMSpecification(); dmSpec != nil && !options.Force &&
+		instance.ComponentName() == meta.ComponentDMMaster {
+		dmMasterClient := api.NewDMMasterClient(dmSpec.GetMasterList(), 5*time.Second, nil)
+		leader, err := dmMasterClient.GetLeader(timeoutOpt)
+		if err != nil {
+			return errors.Annotatef(err, "failed to get dm-master leader %s", instance.GetHost())
+		}
+
+		if len(dmSpec.Masters) > 1 && leader == instance.(*meta.DMMasterInstance).Name {
+			if err := dmMasterClient.EvictDMMasterLeader(timeoutOpt); err != nil {
+				return errors.Annotatef(err, "failed to dm-master PD leader %s", instance.GetHost())
+			}
+		}
+
+		if err := stopInstance(getter, instance); err != nil {
+			return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
+		}
+		if err := startInstance(getter, instance, options.OptTimeout); err != nil {
+			return errors.Annotatef(err, "failed to start %s", instance.GetHost())
+		}
+		return nil
 	}
 
+	if err := restartInstance(getter, instance, options.OptTimeout); err != nil {
+		return errors.Annotatef(err, "failed to restart %s", instance.ComponentName())
+	}
 	return nil
 }
 
@@ -14,11 +14,14 @@
 package task
 
 import (
+	"crypto/tls"
 	"fmt"
+	"strings"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/set"
 )
 
 // ClusterOperate represents the cluster operation task.
@@ -26,13 +29,14 @@ type ClusterOperate struct {
 	spec    meta.Specification
 	op      operator.Operation
 	options operator.Options
+	tlsCfg  *tls.Config
 }
 
 // Execute implements the Task interface
 func (c *ClusterOperate) Execute(ctx *Context) error {
 	switch c.op {
 	case operator.StartOperation:
-		err := operator.Start(ctx, c.spec, c.options)
+		err := operator.Start(ctx, c.spec, c.options, c.tlsCfg)
 		if err != nil {
 			return errors.Annotate(err, "failed to start")
 		}
@@ -44,30 +48,45 @@ func (c *ClusterOperate) Execute(ctx *Context) error {
 		}
 		operator.PrintClusterStatus(ctx, c.spec)
 	case operator.RestartOperation:
-		err := operator.Restart(ctx, c.spec, c.options)
+		err := operator.Restart(ctx, c.spec, c.options, c.tlsCfg)
 		if err != nil {
 			return errors.Annotate(err, "failed to restart")
 		}
 		operator.PrintClusterStatus(ctx, c.spec)
 	case operator.UpgradeOperation:
-		err := operator.Upgrade(ctx, c.spec, c.options)
+		// the instances upgraded are recorded in the checkpoint log if it is enabled
+		var progress operator.Progress
+		if ctx.checkpoint != nil {
+			progress = ctx.checkpoint
+		}
+		err := operator.Upgrade(ctx, c.spec, c.options, c.tlsCfg, progress)
 		if err != nil {
 			return errors.Annotate(err, "failed to upgrade")
 		}
 		operator.PrintClusterStatus(ctx, c.spec)
+	case operator.DowngradeOperation:
+		var progress operator.Progress
+		if ctx.checkpoint != nil {
+			progress = ctx.checkpoint
+		}
+		err := operator.Downgrade(ctx, c.spec, c.options, c.tlsCfg, progress)
+		if err != nil {
+			return errors.Annotate(err, "failed to roll back")
+		}
+		operator.PrintClusterStatus(ctx, c.spec)
 	case operator.DestroyOperation:
 		err := operator.Destroy(ctx, c.spec, c.options)
 		if err != nil {
 			return errors.Annotate(err, "failed to destroy")
 		}
 	case operator.DestroyTombstoneOperation:
-		_, err := operator.DestroyTombstone(ctx, c.spec, false, c.options)
+		_, err := operator.DestroyTombstone(ctx, c.spec, false, c.options, c.tlsCfg)
 		if err != nil {
 			return errors.Annotate(err, "failed to destroy")
 		}
 	// print nothing
 	case operator.ScaleInOperation:
-		err := operator.ScaleIn(ctx, c.spec, c.options)
+		err := operator.ScaleIn(ctx, c.spec, c.options, c.tlsCfg)
 		if err != nil {
 			return errors.Annotate(err, "failed to scale in")
 		}
@@ -80,6 +99,11 @@ func (c *ClusterOperate) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (c *ClusterOperate) Rollback(ctx *Context) error {
+	// the services newly started are stopped while reverting their systemd
+	// units, leaving the existing ones running is expected
+	if c.op == operator.StartOperation {
+		return nil
+	}
 	return ErrUnsupportedRollback
 }
 
@@ -87,3 +111,96 @@ func (c *ClusterOperate) Rollback(ctx *Context) error {
 func (c *ClusterOperate) String() string {
 	return fmt.Sprintf("ClusterOperate: operation=%s, options=%+v", c.op, c.options)
 }
+
+// Plan implements the Planner interface, the operations are described per
+// instance in the order they are performed
+func (c *ClusterOperate) Plan(p *Plan) {
+	var comps []meta.Component
+	switch c.op {
+	case operator.StartOperation:
+		comps = c.spec.ComponentsByStartOrder()
+	case operator.UpgradeOperation:
+		comps = c.spec.ComponentsByUpdateOrder()
+	case operator.DowngradeOperation:
+		comps = operator.ComponentsByDowngradeOrder(c.spec)
+	default:
+		comps = c.spec.ComponentsByStopOrder()
+	}
+	nodes := set.NewStringSet(c.options.Nodes...)
+	if c.op != operator.DestroyOperation {
+		comps = operator.FilterComponent(comps, set.NewStringSet(c.options.Roles...))
+	} else {
+		nodes = set.NewStringSet()
+	}
+	asyncNodes := set.NewStringSet(operator.AsyncNodes(c.spec, c.options.Nodes, true)...)
+	leaderAware := set.NewStringSet(meta.ComponentPD, meta.ComponentTiKV, meta.ComponentDMMaster)
+
+	monitoredHosts := set.NewStringSet()
+	for _, comp := range comps {
+		for _, inst := range operator.FilterInstance(comp.Instances(), nodes) {
+			host := inst.GetHost()
+			service := inst.ServiceName()
+			switch c.op {
+			case operator.StartOperation:
+				p.Add(host, PlanActionSystemd, "systemctl start %s", service)
+			case operator.StopOperation:
+				p.Add(host, PlanActionSystemd, "systemctl stop %s", service)
+			case operator.RestartOperation:
+				p.Add(host, PlanActionSystemd, "systemctl restart %s", service)
+			case operator.UpgradeOperation, operator.DowngradeOperation:
+				if c.options.Hooks != nil {
+					planInstanceHooks(p, meta.HookStage(c.options.Hooks.Operation, true), inst)
+				}
+				if !c.options.Force && leaderAware.Exist(comp.Name()) {
+					p.Add(host, PlanActionAPI, "evict the leaders from %s %s", comp.Name(), inst.ID())
+				}
+				p.Add(host, PlanActionSystemd, "systemctl restart %s", service)
+				if gates := c.options.HealthGate.Gates; !c.options.Force && len(gates) > 0 && c.spec.GetClusterSpecification() != nil {
+					p.Add(host, PlanActionAPI, "wait for the health gates: %s", strings.Join(gates, ", "))
+				}
+				if c.options.Hooks != nil {
+					planInstanceHooks(p, meta.HookStage(c.options.Hooks.Operation, false), inst)
+				}
+			case operator.DestroyOperation:
+				planDestroyInstance(p, inst)
+			case operator.DestroyTombstoneOperation:
+				p.Add(host, PlanActionAPI, "destroy %s %s if it is tombstone", comp.Name(), inst.ID())
+			case operator.ScaleInOperation:
+				if !c.options.Force && asyncNodes.Exist(inst.ID()) {
+					p.Add(host, PlanActionAPI, "take %s %s offline, it is destroyed after becoming tombstone", comp.Name(), inst.ID())
+					continue
+				}
+				p.Add(host, PlanActionAPI, "remove %s %s from the cluster", comp.Name(), inst.ID())
+				p.Add(host, PlanActionSystemd, "systemctl stop %s", service)
+				planDestroyInstance(p, inst)
+			}
+
+			if monitoredHosts.Exist(host) {
+				continue
+			}
+			monitoredHosts.Insert(host)
+			clusterSpec := c.spec.GetClusterSpecification()
+			if clusterSpec == nil {
+				continue
+			}
+			services := []string{
+				fmt.Sprintf("%s-%d.service", meta.ComponentNodeExporter, clusterSpec.MonitoredOptions.NodeExporterPort),
+				fmt.Sprintf("%s-%d.service", meta.ComponentBlackboxExporter, clusterSpec.MonitoredOptions.BlackboxExporterPort),
+			}
+			switch c.op {
+			case operator.StartOperation:
+				p.Add(host, PlanActionSystemd, "systemctl start %s", strings.Join(services, " "))
+			case operator.StopOperation:
+				p.Add(host, PlanActionSystemd, "systemctl stop %s if no other instance is on the host", strings.Join(services, " "))
+			case operator.DestroyOperation:
+				p.Add(host, PlanActionSystemd, "remove %s if no other instance is on the host", strings.Join(services, " "))
+			}
+		}
+	}
+}
+
+// planDestroyInstance describes the removing of the directories and the
+// systemd unit of an instance
+func planDestroyInstance(p *Plan, inst meta.Instance) {
+	p.Add(inst.GetHost(), PlanActionCommand, "rm -rf %s", strings.Join(operator.DestroyPaths(inst), " "))
+}
@@ -28,6 +28,7 @@ type BackupComponent struct {
 	fromVer   string
 	host      string
 	deployDir string
+	backedUp  bool
 }
 
 // Execute implements the Task interface
@@ -55,12 +56,27 @@ func (c *BackupComponent) Execute(ctx *Context) error {
 			bytes.Contains(stderr, []byte("File exists"))) {
 			return errors.Annotate(err, cmd)
 		}
+		return nil
 	}
+	c.backedUp = true
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, the binaries are restored from the backup
 func (c *BackupComponent) Rollback(ctx *Context) error {
+	if !c.backedUp {
+		return nil
+	}
+	exec, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	binDir := filepath.Join(c.deployDir, "bin")
+	cmd := fmt.Sprintf(`rm -rf %[1]s && cp -r %[2]s %[1]s`, binDir, binDir+".old."+c.fromVer)
+	if _, stderr, err := exec.Execute(cmd, false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
 	return nil
 }
 
@@ -69,3 +85,79 @@ func (c *BackupComponent) String() string {
 	return fmt.Sprintf("BackupComponent: component=%s, currentVersion=%s, remote=%s:%s",
 		c.component, c.fromVer, c.host, c.deployDir)
 }
+
+// Plan implements the Planner interface
+func (c *BackupComponent) Plan(p *Plan) {
+	binDir := filepath.Join(c.deployDir, "bin")
+	p.Add(c.host, PlanActionCommand, "test -d %[2]s || cp -r %[1]s %[2]s", binDir, binDir+".old."+c.fromVer)
+}
+
+// RestoreComponent is used to restore the files of a component backed up by
+// BackupComponent, which is the reverse of the upgrade or patch. The current
+// files are backed up as well if the version is changed.
+type RestoreComponent struct {
+	component string
+	fromVer   string
+	toVer     string
+	host      string
+	deployDir string
+	restored  bool
+}
+
+// Execute implements the Task interface
+func (c *RestoreComponent) Execute(ctx *Context) error {
+	exec, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	binDir := filepath.Join(c.deployDir, "bin")
+	backupDir := binDir + ".old." + c.toVer
+	if _, _, err := exec.Execute(fmt.Sprintf("test -d %s", backupDir), false); err != nil {
+		return errors.Errorf("no backup of %s %s found in %s:%s", c.component, c.toVer, c.host, backupDir)
+	}
+
+	cmd := fmt.Sprintf(`rm -rf %[1]s && cp -r %[2]s %[1]s`, binDir, backupDir)
+	if c.fromVer != c.toVer {
+		cmd = fmt.Sprintf(`(test -d %[2]s || cp -r %[1]s %[2]s) && %[3]s`, binDir, binDir+".old."+c.fromVer, cmd)
+	}
+	if _, stderr, err := exec.Execute(cmd, false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	c.restored = true
+	return nil
+}
+
+// Rollback implements the Task interface, the files of the version rolled
+// back from are restored if they are backed up
+func (c *RestoreComponent) Rollback(ctx *Context) error {
+	if !c.restored || c.fromVer == c.toVer {
+		return nil
+	}
+	exec, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	binDir := filepath.Join(c.deployDir, "bin")
+	cmd := fmt.Sprintf(`rm -rf %[1]s && cp -r %[2]s %[1]s`, binDir, binDir+".old."+c.fromVer)
+	if _, stderr, err := exec.Execute(cmd, false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	return nil
+}
+
+// String implements the fmt.Stringer interface
+func (c *RestoreComponent) String() string {
+	return fmt.Sprintf("RestoreComponent: component=%s, currentVersion=%s, version=%s, remote=%s:%s",
+		c.component, c.fromVer, c.toVer, c.host, c.deployDir)
+}
+
+// Plan implements the Planner interface
+func (c *RestoreComponent) Plan(p *Plan) {
+	binDir := filepath.Join(c.deployDir, "bin")
+	if c.fromVer != c.toVer {
+		p.Add(c.host, PlanActionCommand, "test -d %[2]s || cp -r %[1]s %[2]s", binDir, binDir+".old."+c.fromVer)
+	}
+	p.Add(c.host, PlanActionCommand, "rm -rf %[1]s && cp -r %[2]s %[1]s", binDir, binDir+".old."+c.toVer)
+}
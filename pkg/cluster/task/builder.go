@@ -14,8 +14,12 @@
 package task
 
 import (
+	"crypto/tls"
+	"path/filepath"
+
 	"github.com/pingcap/tiup/pkg/cluster/meta"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/crypto"
 )
 
 // Builder is used to build TiOps task
@@ -67,6 +71,16 @@ func (b *Builder) Func(name string, fn func(ctx *Context) error) *Builder {
 	return b
 }
 
+// FuncWithRollback append a func task which is reverted by the rollback closure.
+func (b *Builder) FuncWithRollback(name string, fn, rollback func(ctx *Context) error) *Builder {
+	b.tasks = append(b.tasks, &Func{
+		name:     name,
+		fn:       fn,
+		rollback: rollback,
+	})
+	return b
+}
+
 // ClusterSSH init all UserSSH need for the cluster.
 func (b *Builder) ClusterSSH(spec meta.Specification, deployUser string, sshTimeout int64) *Builder {
 	var tasks []Task
@@ -166,6 +180,18 @@ func (b *Builder) BackupComponent(component, fromVer string, host, deployDir str
 	return b
 }
 
+// RestoreComponent appends a RestoreComponent task to the current task collection
+func (b *Builder) RestoreComponent(component, fromVer, toVer string, host, deployDir string) *Builder {
+	b.tasks = append(b.tasks, &RestoreComponent{
+		component: component,
+		fromVer:   fromVer,
+		toVer:     toVer,
+		host:      host,
+		deployDir: deployDir,
+	})
+	return b
+}
+
 // InitConfig appends a CopyComponent task to the current task collection
 func (b *Builder) InitConfig(clusterName, clusterVersion string, inst meta.Instance, deployUser string, paths meta.DirPaths) *Builder {
 	b.tasks = append(b.tasks, &InitConfig{
@@ -192,19 +218,40 @@ func (b *Builder) ScaleConfig(clusterName, clusterVersion string, base meta.Spec
 }
 
 // MonitoredConfig appends a CopyComponent task to the current task collection
-func (b *Builder) MonitoredConfig(name, comp, host string, globResCtl meta.ResourceControl, options meta.MonitoredOptions, deployUser string, paths meta.DirPaths) *Builder {
+func (b *Builder) MonitoredConfig(name, comp, host string, globResCtl meta.ResourceControl, options meta.MonitoredOptions, tlsEnabled bool, deployUser string, paths meta.DirPaths) *Builder {
 	b.tasks = append(b.tasks, &MonitoredConfig{
 		name:       name,
 		component:  comp,
 		host:       host,
 		globResCtl: globResCtl,
 		options:    options,
+		tlsEnabled: tlsEnabled,
 		deployUser: deployUser,
 		paths:      paths,
 	})
 	return b
 }
 
+// TLSCert generates the certificate for the instance and copies it to the
+// tls sub directory of the deploy dir on the target host
+func (b *Builder) TLSCert(inst meta.Instance, ca *crypto.CertificateAuthority, deployUser string, paths meta.DirPaths) *Builder {
+	t := &TLSCert{
+		comp:  inst.ComponentName(),
+		host:  inst.GetHost(),
+		port:  inst.GetPort(),
+		ca:    ca,
+		paths: paths,
+	}
+	b.tasks = append(b.tasks, t)
+
+	certDir := filepath.Join(paths.Deploy, meta.TLSCertKeyDir)
+	b.Mkdir(deployUser, inst.GetHost(), certDir)
+	for _, name := range t.fileNames() {
+		b.CopyFile(filepath.Join(t.localDir(), name), filepath.Join(certDir, name), inst.GetHost(), false)
+	}
+	return b
+}
+
 // SSHKeyGen appends a SSHKeyGen task to the current task collection
 func (b *Builder) SSHKeyGen(keypath string) *Builder {
 	b.tasks = append(b.tasks, &SSHKeyGen{
@@ -237,13 +284,40 @@ func (b *Builder) ClusterOperate(
 	spec meta.Specification,
 	op operator.Operation,
 	options operator.Options,
+	tlsCfg *tls.Config,
 ) *Builder {
 	b.tasks = append(b.tasks, &ClusterOperate{
 		spec:    spec,
 		op:      op,
 		options: options,
+		tlsCfg:  tlsCfg,
+	})
+
+	return b
+}
+
+// Hook appends a task running the hooks of the operation before or after it,
+// the global hooks are skipped if they are nil
+func (b *Builder) Hook(hooks meta.Hooks, opt operator.HookOptions, pre bool, instances []meta.Instance) *Builder {
+	b.tasks = append(b.tasks, &Hook{
+		hooks:     hooks,
+		opt:       opt,
+		pre:       pre,
+		instances: instances,
 	})
+	return b
+}
 
+// GlobalHook appends a task running only the global hooks of the operation,
+// the hooks of the instances are run by the operation itself
+func (b *Builder) GlobalHook(hooks meta.Hooks, opt operator.HookOptions, pre bool, instances []meta.Instance) *Builder {
+	b.tasks = append(b.tasks, &Hook{
+		hooks:      hooks,
+		opt:        opt,
+		pre:        pre,
+		instances:  instances,
+		globalOnly: true,
+	})
 	return b
 }
 
@@ -133,7 +133,8 @@ func (c *CheckSys) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (c *CheckSys) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	// nothing is changed by checking
+	return nil
 }
 
 // String implements the fmt.Stringer interface
@@ -141,6 +142,11 @@ func (c *CheckSys) String() string {
 	return fmt.Sprintf("CheckSys: host=%s type=%s", c.host, c.check)
 }
 
+// Plan implements the Planner interface
+func (c *CheckSys) Plan(p *Plan) {
+	p.Add(c.host, PlanActionCommand, "check %s", c.check)
+}
+
 // runFIO performs FIO checks
 func (c *CheckSys) runFIO(ctx *Context) (outRR []byte, outRW []byte, outLat []byte, err error) {
 	e, ok := ctx.GetExecutor(c.host)
@@ -0,0 +1,222 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+)
+
+// CheckPoint is a persisted log of finished tasks, it is used to skip the
+// tasks already done when resuming an interrupted operation. Each line of
+// the log is the digest of a finished task followed by its description.
+type CheckPoint struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	done map[string]string // digest -> description
+	// previous are the tasks finished by the previous run, they are not
+	// rolled back if the resumed run fails
+	previous map[string]struct{}
+	// dirty is set if some tasks are removed from done, the log is rewritten
+	// when it's closed
+	dirty bool
+}
+
+// NewCheckPoint opens the checkpoint log at path, the tasks recorded in it
+// are loaded if resume is true, otherwise the log is truncated
+func NewCheckPoint(path string, resume bool) (*CheckPoint, error) {
+	cp := &CheckPoint{
+		path:     path,
+		done:     make(map[string]string),
+		previous: make(map[string]struct{}),
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		if err := cp.load(); err != nil {
+			return nil, err
+		}
+	} else {
+		flag |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, errors.Annotatef(err, "open checkpoint file %s", path)
+	}
+	cp.file = f
+	return cp, nil
+}
+
+func (cp *CheckPoint) load() error {
+	f, err := os.Open(cp.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Annotatef(err, "open checkpoint file %s", cp.path)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 2)
+		// a line may be partially written if we were killed
+		if len(fields) != 2 {
+			continue
+		}
+		cp.done[fields[0]] = fields[1]
+		cp.previous[fields[0]] = struct{}{}
+	}
+	return errors.AddStack(scanner.Err())
+}
+
+// Done returns true if the task with identical inputs has been finished
+func (cp *CheckPoint) Done(t Task) bool {
+	return cp.isDone(checkPointKey(t))
+}
+
+// Record persists the completion of the task
+func (cp *CheckPoint) Record(t Task) error {
+	return cp.record(checkPointKey(t), t.String())
+}
+
+// StepDone returns true if the step of an operation has been finished, it
+// implements the operator.Progress interface
+func (cp *CheckPoint) StepDone(step string) bool {
+	return cp.isDone(checkPointStepKey(step))
+}
+
+// RecordStep persists the completion of the step of an operation, it
+// implements the operator.Progress interface
+func (cp *CheckPoint) RecordStep(step string) error {
+	return cp.record(checkPointStepKey(step), step)
+}
+
+func (cp *CheckPoint) isDone(key string) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	_, ok := cp.done[key]
+	return ok
+}
+
+func (cp *CheckPoint) record(key, desc string) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if _, ok := cp.done[key]; ok {
+		return nil
+	}
+	line := fmt.Sprintf("%s %s\n", key, strings.ReplaceAll(desc, "\n", " "))
+	if _, err := cp.file.WriteString(line); err != nil {
+		return errors.Annotatef(err, "write checkpoint file %s", cp.path)
+	}
+	if err := cp.file.Sync(); err != nil {
+		return errors.AddStack(err)
+	}
+	cp.done[key] = desc
+	return nil
+}
+
+// finishedBefore returns true if the task was finished by the previous run
+func (cp *CheckPoint) finishedBefore(t Task) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	_, ok := cp.previous[checkPointKey(t)]
+	return ok
+}
+
+// forget removes the task reverted by the rollback from the log, so that it
+// is executed again when resuming
+func (cp *CheckPoint) forget(t Task) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	key := checkPointKey(t)
+	if _, ok := cp.done[key]; ok {
+		delete(cp.done, key)
+		cp.dirty = true
+	}
+}
+
+// Close closes the checkpoint log and keeps it on disk for resuming, the
+// tasks reverted by the rollback are removed from it
+func (cp *CheckPoint) Close() error {
+	if err := cp.file.Close(); err != nil {
+		return errors.AddStack(err)
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if !cp.dirty {
+		return nil
+	}
+
+	var buf strings.Builder
+	for key, desc := range cp.done {
+		fmt.Fprintf(&buf, "%s %s\n", key, strings.ReplaceAll(desc, "\n", " "))
+	}
+	tmp := cp.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(buf.String()), 0644); err != nil {
+		return errors.Annotatef(err, "write checkpoint file %s", cp.path)
+	}
+	return errors.AddStack(os.Rename(tmp, cp.path))
+}
+
+// Remove closes and deletes the checkpoint log, it should be called once
+// the whole operation succeeds
+func (cp *CheckPoint) Remove() error {
+	_ = cp.file.Close()
+	if err := os.Remove(cp.path); err != nil && !os.IsNotExist(err) {
+		return errors.AddStack(err)
+	}
+	return nil
+}
+
+// checkPointKey is the digest of the type and the description of the task,
+// the description of a task contains all its inputs
+func checkPointKey(t Task) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%T %s", t, t.String())))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkPointStepKey is the digest of the step of an operation
+func checkPointStepKey(step string) string {
+	sum := sha256.Sum256([]byte("step " + step))
+	return hex.EncodeToString(sum[:])
+}
+
+// isCheckPointTask returns true if the task can be skipped when it is found in
+// the checkpoint log. Tasks setting up the Context (e.g. the SSH executors)
+// must always be executed, and so are tasks whose result depends on more
+// than their description, such as generating the configurations.
+func isCheckPointTask(t Task) bool {
+	switch t.(type) {
+	case *Downloader,
+		*CopyComponent,
+		*CopyFile,
+		*InstallPackage,
+		*BackupComponent,
+		*EnvInit,
+		*Mkdir,
+		*TLSCert:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+type checkPointSuite struct {
+	dir string
+}
+
+var _ = Suite(&checkPointSuite{})
+
+func TestTask(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *checkPointSuite) SetUpTest(c *C) {
+	dir, err := ioutil.TempDir("", "tiup-checkpoint")
+	c.Assert(err, IsNil)
+	s.dir = dir
+}
+
+func (s *checkPointSuite) TearDownTest(c *C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *checkPointSuite) TestResume(c *C) {
+	path := filepath.Join(s.dir, "checkpoint.log")
+	mkdir := &Mkdir{user: "tidb", host: "172.16.5.1", dirs: []string{"/data"}}
+	other := &Mkdir{user: "tidb", host: "172.16.5.2", dirs: []string{"/data"}}
+
+	cp, err := NewCheckPoint(path, false)
+	c.Assert(err, IsNil)
+	c.Assert(cp.Done(mkdir), IsFalse)
+	c.Assert(cp.Record(mkdir), IsNil)
+	c.Assert(cp.Done(mkdir), IsTrue)
+	c.Assert(cp.Close(), IsNil)
+
+	// finished tasks are loaded when resuming
+	cp, err = NewCheckPoint(path, true)
+	c.Assert(err, IsNil)
+	c.Assert(cp.Done(mkdir), IsTrue)
+	c.Assert(cp.Done(other), IsFalse)
+	c.Assert(cp.Close(), IsNil)
+
+	// the log is truncated if not resuming
+	cp, err = NewCheckPoint(path, false)
+	c.Assert(err, IsNil)
+	c.Assert(cp.Done(mkdir), IsFalse)
+	c.Assert(cp.Remove(), IsNil)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
+
+func (s *checkPointSuite) TestSkipFinished(c *C) {
+	cp, err := NewCheckPoint(filepath.Join(s.dir, "checkpoint.log"), false)
+	c.Assert(err, IsNil)
+	defer cp.Close()
+
+	executed := 0
+	fn := &Func{name: "count", fn: func(ctx *Context) error {
+		executed++
+		return nil
+	}}
+	finished := &Mkdir{user: "tidb", host: "172.16.5.1", dirs: []string{"/data"}}
+	c.Assert(cp.Record(finished), IsNil)
+
+	ctx := NewContext()
+	ctx.SetCheckPoint(cp)
+	// the Mkdir would fail without an executor if it is not skipped, while
+	// tasks not supporting checkpoint are always executed
+	t := &Serial{inner: []Task{fn, finished, fn}}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(executed, Equals, 2)
+}
+
+func (s *checkPointSuite) TestSteps(c *C) {
+	path := filepath.Join(s.dir, "checkpoint.log")
+	cp, err := NewCheckPoint(path, false)
+	c.Assert(err, IsNil)
+	c.Assert(cp.StepDone("upgrade tikv 172.16.5.1:20160"), IsFalse)
+	c.Assert(cp.RecordStep("upgrade tikv 172.16.5.1:20160"), IsNil)
+	c.Assert(cp.Close(), IsNil)
+
+	cp, err = NewCheckPoint(path, true)
+	c.Assert(err, IsNil)
+	defer cp.Close()
+	c.Assert(cp.StepDone("upgrade tikv 172.16.5.1:20160"), IsTrue)
+	c.Assert(cp.StepDone("upgrade tikv 172.16.5.2:20160"), IsFalse)
+}
+
+func (s *checkPointSuite) TestRollbackResumed(c *C) {
+	path := filepath.Join(s.dir, "checkpoint.log")
+	// the Mkdir would fail to roll back without an executor if it is not skipped
+	previous := &Mkdir{user: "tidb", host: "172.16.5.1", dirs: []string{"/data"}, created: []string{"/data"}}
+	current := &Mkdir{user: "tidb", host: "172.16.5.2", dirs: []string{"/data"}}
+
+	cp, err := NewCheckPoint(path, false)
+	c.Assert(err, IsNil)
+	c.Assert(cp.Record(previous), IsNil)
+	c.Assert(cp.Close(), IsNil)
+
+	cp, err = NewCheckPoint(path, true)
+	c.Assert(err, IsNil)
+	c.Assert(cp.Record(current), IsNil)
+	ctx := NewContext()
+	ctx.SetCheckPoint(cp)
+	t := &Serial{inner: []Task{previous, current}, executed: 2}
+	c.Assert(t.Rollback(ctx), IsNil)
+	c.Assert(cp.Close(), IsNil)
+
+	// the reverted task is executed again when resuming
+	cp, err = NewCheckPoint(path, true)
+	c.Assert(err, IsNil)
+	defer cp.Close()
+	c.Assert(cp.Done(previous), IsTrue)
+	c.Assert(cp.Done(current), IsFalse)
+}
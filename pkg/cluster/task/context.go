@@ -44,7 +44,10 @@ func (ctx *Context) SetClusterSSH(topo meta.Specification, deployUser string, ss
 				Timeout: time.Second * time.Duration(sshTimeout),
 			}
 
-			e := executor.NewSSHExecutor(cf, false /* sudo */)
+			e, err := executor.New(cf, false /* sudo */)
+			if err != nil {
+				return err
+			}
 			ctx.SetExecutor(in.GetHost(), e)
 		}
 	}
@@ -28,6 +28,7 @@ type CopyComponent struct {
 	version   string
 	host      string
 	dstDir    string
+	install   *InstallPackage
 }
 
 // Execute implements the Task interface
@@ -37,18 +38,21 @@ func (c *CopyComponent) Execute(ctx *Context) error {
 	fileName := fmt.Sprintf("%s-%s-%s.tar.gz", resName, c.os, c.arch)
 	srcPath := meta.ProfilePath(meta.TiOpsPackageCacheDir, fileName)
 
-	install := &InstallPackage{
+	c.install = &InstallPackage{
 		srcPath: srcPath,
 		host:    c.host,
 		dstDir:  c.dstDir,
 	}
 
-	return install.Execute(ctx)
+	return c.install.Execute(ctx)
 }
 
 // Rollback implements the Task interface
 func (c *CopyComponent) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if c.install == nil {
+		return nil
+	}
+	return c.install.Rollback(ctx)
 }
 
 // String implements the fmt.Stringer interface
@@ -56,3 +60,13 @@ func (c *CopyComponent) String() string {
 	return fmt.Sprintf("CopyComponent: component=%s, version=%s, remote=%s:%s os=%s, arch=%s",
 		c.component, c.version, c.host, c.dstDir, c.os, c.arch)
 }
+
+// Plan implements the Planner interface
+func (c *CopyComponent) Plan(p *Plan) {
+	fileName := fmt.Sprintf("%s-%s-%s-%s.tar.gz", c.component, c.version, c.os, c.arch)
+	(&InstallPackage{
+		srcPath: meta.ProfilePath(meta.TiOpsPackageCacheDir, fileName),
+		host:    c.host,
+		dstDir:  c.dstDir,
+	}).Plan(p)
+}
@@ -15,8 +15,11 @@ package task
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
 )
 
 // CopyFile will copy a local file to the target host
@@ -25,6 +28,7 @@ type CopyFile struct {
 	dst      string
 	remote   string
 	download bool
+	existed  bool // whether dst existed before the copy
 }
 
 // Execute implements the Task interface
@@ -34,6 +38,17 @@ func (c *CopyFile) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
+	if c.download {
+		c.existed = utils.IsExist(c.dst)
+	} else {
+		cmd := fmt.Sprintf("if [ -e %[1]s ]; then echo yes; else echo no; fi", c.dst)
+		stdout, stderr, err := e.Execute(cmd, false)
+		if err != nil {
+			return errors.Annotatef(err, "stderr: %s", string(stderr))
+		}
+		c.existed = strings.TrimSpace(string(stdout)) == "yes"
+	}
+
 	err := e.Transfer(c.src, c.dst, c.download)
 	if err != nil {
 		return errors.Annotate(err, "failed to transfer file")
@@ -44,7 +59,26 @@ func (c *CopyFile) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (c *CopyFile) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	// the overwritten file can not be restored
+	if c.existed {
+		return ErrUnsupportedRollback
+	}
+
+	if c.download {
+		if err := os.Remove(c.dst); err != nil && !os.IsNotExist(err) {
+			return errors.AddStack(err)
+		}
+		return nil
+	}
+
+	e, ok := ctx.GetExecutor(c.remote)
+	if !ok {
+		return ErrNoExecutor
+	}
+	if _, stderr, err := e.Execute(fmt.Sprintf("rm -f %s", c.dst), false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	return nil
 }
 
 // String implements the fmt.Stringer interface
@@ -54,3 +88,12 @@ func (c *CopyFile) String() string {
 	}
 	return fmt.Sprintf("CopyFile: local=%s, remote=%s:%s", c.src, c.remote, c.dst)
 }
+
+// Plan implements the Planner interface
+func (c *CopyFile) Plan(p *Plan) {
+	if c.download {
+		p.Add(c.remote, PlanActionCopy, "%s -> local:%s", c.src, c.dst)
+		return
+	}
+	p.Add(c.remote, PlanActionCopy, "local:%s -> %s", c.src, c.dst)
+}
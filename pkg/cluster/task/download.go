@@ -54,3 +54,8 @@ func (d *Downloader) String() string {
 	return fmt.Sprintf("Download: component=%s, version=%s, os=%s, arch=%s",
 		d.component, d.version, d.os, d.arch)
 }
+
+// Plan implements the Planner interface
+func (d *Downloader) Plan(p *Plan) {
+	p.Add(PlanHostLocal, PlanActionFile, "download %s:%s (%s/%s) if not cached", d.component, d.version, d.os, d.arch)
+}
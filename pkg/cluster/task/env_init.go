@@ -121,3 +121,9 @@ func (e *EnvInit) Rollback(ctx *Context) error {
 func (e *EnvInit) String() string {
 	return fmt.Sprintf("EnvInit: user=%s, host=%s", e.deployUser, e.host)
 }
+
+// Plan implements the Planner interface
+func (e *EnvInit) Plan(p *Plan) {
+	p.Add(e.host, PlanActionCommand, "create user %s with sudo privilege if not exist", e.deployUser)
+	p.Add(e.host, PlanActionCommand, "authorize the cluster SSH public key for user %s", e.deployUser)
+}
@@ -15,8 +15,9 @@ package task
 
 // Func wrap a closure.
 type Func struct {
-	name string
-	fn   func(ctx *Context) error
+	name     string
+	fn       func(ctx *Context) error
+	rollback func(ctx *Context) error
 }
 
 // NewFunc create a Func task
@@ -33,8 +34,11 @@ func (m *Func) Execute(ctx *Context) error {
 }
 
 // Rollback implements the Task interface
-func (m *Func) Rollback(_ *Context) error {
-	return ErrUnsupportedRollback
+func (m *Func) Rollback(ctx *Context) error {
+	if m.rollback == nil {
+		return ErrUnsupportedRollback
+	}
+	return m.rollback(ctx)
 }
 
 // String implements the fmt.Stringer interface
@@ -0,0 +1,95 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// Hook runs the hooks declared in the topology before or after an operation
+type Hook struct {
+	hooks      meta.Hooks // the hooks of the global options
+	opt        operator.HookOptions
+	pre        bool
+	instances  []meta.Instance
+	globalOnly bool // the hooks of the instances are run by the operation itself
+}
+
+// Execute implements the Task interface
+func (h *Hook) Execute(ctx *Context) error {
+	if h.globalOnly {
+		return operator.RunGlobalHooks(ctx, h.hooks, h.opt, h.pre, h.instances)
+	}
+	return operator.RunHooks(ctx, h.hooks, h.opt, h.pre, h.instances)
+}
+
+// Rollback implements the Task interface
+func (h *Hook) Rollback(ctx *Context) error {
+	// the hooks are run as they are, nothing is reverted for them
+	return nil
+}
+
+// String implements the fmt.Stringer interface
+func (h *Hook) String() string {
+	return fmt.Sprintf("Hook: cluster=%s, stage=%s, instances=%d",
+		h.opt.ClusterName, meta.HookStage(h.opt.Operation, h.pre), len(h.instances))
+}
+
+// Plan implements the Planner interface, the hooks are described in the
+// order they run
+func (h *Hook) Plan(p *Plan) {
+	stage := meta.HookStage(h.opt.Operation, h.pre)
+	if h.pre {
+		planGlobalHooks(p, h.hooks.Get(h.opt.Operation, h.pre), stage, h.instances)
+	}
+	if !h.globalOnly {
+		for _, inst := range h.instances {
+			planInstanceHooks(p, stage, inst)
+		}
+	}
+	if !h.pre {
+		planGlobalHooks(p, h.hooks.Get(h.opt.Operation, h.pre), stage, h.instances)
+	}
+}
+
+func planGlobalHooks(p *Plan, hooks []meta.HookSpec, stage string, instances []meta.Instance) {
+	for _, hook := range hooks {
+		if !hook.RunOnHost() {
+			p.Add(PlanHostLocal, PlanActionCommand, "run hook %s: %s", stage, hook.Command)
+			continue
+		}
+		hosts := set.NewStringSet()
+		for _, inst := range instances {
+			if hosts.Exist(inst.GetHost()) {
+				continue
+			}
+			hosts.Insert(inst.GetHost())
+			p.Add(inst.GetHost(), PlanActionCommand, "run hook %s: %s", stage, hook.Command)
+		}
+	}
+}
+
+func planInstanceHooks(p *Plan, stage string, inst meta.Instance) {
+	for _, hook := range inst.Hooks()[stage] {
+		host := PlanHostLocal
+		if hook.RunOnHost() {
+			host = inst.GetHost()
+		}
+		p.Add(host, PlanActionCommand, "run hook %s of %s: %s", stage, inst.ID(), hook.Command)
+	}
+}
@@ -29,6 +29,7 @@ type InitConfig struct {
 	instance       meta.Instance
 	deployUser     string
 	paths          meta.DirPaths
+	snapshot       *serviceSnapshot
 }
 
 // Execute implements the Task interface
@@ -43,7 +44,13 @@ func (c *InitConfig) Execute(ctx *Context) error {
 		return errors.Annotatef(err, "create cache directory failed: %s", c.paths.Cache)
 	}
 
-	err := c.instance.InitConfig(exec, c.clusterName, c.clusterVersion, c.deployUser, c.paths)
+	snapshot, err := newServiceSnapshot(ctx, exec, c.instance.ServiceName(), c.paths.Deploy)
+	if err != nil {
+		return errors.Annotatef(err, "backup config failed: %s:%d", c.instance.GetHost(), c.instance.GetPort())
+	}
+	c.snapshot = snapshot
+
+	err = c.instance.InitConfig(exec, c.clusterName, c.clusterVersion, c.deployUser, c.paths)
 	if err != nil {
 		return errors.Annotatef(err, "init config failed: %s:%d", c.instance.GetHost(), c.instance.GetPort())
 	}
@@ -52,7 +59,14 @@ func (c *InitConfig) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (c *InitConfig) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if c.snapshot == nil {
+		return nil
+	}
+	exec, found := ctx.GetExecutor(c.instance.GetHost())
+	if !found {
+		return ErrNoExecutor
+	}
+	return c.snapshot.restore(exec)
 }
 
 // String implements the fmt.Stringer interface
@@ -61,3 +75,8 @@ func (c *InitConfig) String() string {
 		c.clusterName, c.deployUser, c.instance.GetHost(),
 		filepath.Join(meta.ClusterPath(c.clusterName, meta.TempConfigPath, c.instance.ServiceName())), c.paths)
 }
+
+// Plan implements the Planner interface
+func (c *InitConfig) Plan(p *Plan) {
+	planServiceConfig(p, c.instance, c.paths)
+}
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/pingcap/errors"
 )
@@ -27,6 +28,11 @@ type InstallPackage struct {
 	srcPath string
 	host    string
 	dstDir  string
+	// whether the bin dir is non-empty before installing, the files in it
+	// may be overwritten and can not be restored then
+	overwritten bool
+	// whether the package is transferred to the bin dir, which is probed before
+	transferred bool
 }
 
 // Execute implements the Task interface
@@ -40,14 +46,22 @@ func (c *InstallPackage) Execute(ctx *Context) error {
 	dstDir := filepath.Join(c.dstDir, "bin")
 	dstPath := filepath.Join(dstDir, path.Base(c.srcPath))
 
-	err := exec.Transfer(c.srcPath, dstPath, false)
+	stdout, stderr, err := exec.Execute(fmt.Sprintf("ls -A %s 2>/dev/null || true", dstDir), false)
+	if err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	c.overwritten = len(strings.TrimSpace(string(stdout))) > 0
+
+	// a partially transferred package is also cleaned up on rollback
+	c.transferred = true
+	err = exec.Transfer(c.srcPath, dstPath, false)
 	if err != nil {
 		return errors.Trace(err)
 	}
 
 	cmd := fmt.Sprintf(`tar -xzf %s -C %s && rm %s`, dstPath, dstDir, dstPath)
 
-	_, stderr, err := exec.Execute(cmd, false)
+	_, stderr, err = exec.Execute(cmd, false)
 	if err != nil {
 		return errors.Annotatef(err, "stderr: %s", string(stderr))
 	}
@@ -56,10 +70,36 @@ func (c *InstallPackage) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (c *InstallPackage) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	// nothing is installed if the task fails before the transfer
+	if !c.transferred {
+		return nil
+	}
+	// the binaries replaced can only be restored from the backup made by
+	// BackupComponent if there is one
+	if c.overwritten {
+		return ErrUnsupportedRollback
+	}
+
+	exec, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+	cmd := fmt.Sprintf(`rm -rf %s/*`, filepath.Join(c.dstDir, "bin"))
+	if _, stderr, err := exec.Execute(cmd, false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	return nil
 }
 
 // String implements the fmt.Stringer interface
 func (c *InstallPackage) String() string {
 	return fmt.Sprintf("InstallPackage: srcPath=%s, remote=%s:%s", c.srcPath, c.host, c.dstDir)
 }
+
+// Plan implements the Planner interface
+func (c *InstallPackage) Plan(p *Plan) {
+	dstDir := filepath.Join(c.dstDir, "bin")
+	dstPath := filepath.Join(dstDir, path.Base(c.srcPath))
+	p.Add(c.host, PlanActionCopy, "%s -> %s", c.srcPath, dstPath)
+	p.Add(c.host, PlanActionCommand, "tar -xzf %s -C %s && rm %s", dstPath, dstDir, dstPath)
+}
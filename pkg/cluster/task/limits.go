@@ -66,3 +66,8 @@ func (l *Limit) Rollback(ctx *Context) error {
 func (l *Limit) String() string {
 	return fmt.Sprintf("Limit: host=%s %s %s %s %s", l.host, l.domain, l.limit, l.item, l.value)
 }
+
+// Plan implements the Planner interface
+func (l *Limit) Plan(p *Plan) {
+	p.Add(l.host, PlanActionCommand, "set '%s %s %s %s' in %s", l.domain, l.limit, l.item, l.value, limitsFilePath)
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// localSuite runs tasks against the local host with the local executor
+type localSuite struct {
+	dir string
+}
+
+var _ = Suite(&localSuite{})
+
+func (s *localSuite) SetUpTest(c *C) {
+	// the tasks run commands with sudo, which may ask for a password
+	if utils.CurrentUser() != "root" {
+		c.Skip("the local executor tests require root")
+	}
+	s.dir = c.MkDir()
+}
+
+func (s *localSuite) TestRootSSHOnLocalHost(c *C) {
+	ctx := NewContext()
+	t := NewBuilder().RootSSH("127.0.0.1", 22, "root", "", "", "", 5).Build()
+	c.Assert(t.Execute(ctx), IsNil)
+
+	e, ok := ctx.GetExecutor("127.0.0.1")
+	c.Assert(ok, IsTrue)
+	_, ok = e.(*executor.LocalExecutor)
+	c.Assert(ok, IsTrue)
+}
+
+func (s *localSuite) TestExecuteAndRollback(c *C) {
+	src := filepath.Join(s.dir, "src")
+	c.Assert(ioutil.WriteFile(src, []byte("content"), 0644), IsNil)
+	deployDir := filepath.Join(s.dir, "deploy")
+	dst := filepath.Join(deployDir, "conf", "dst")
+
+	ctx := NewContext()
+	t := NewBuilder().
+		RootSSH("localhost", 22, "root", "", "", "", 5).
+		Mkdir("root", "localhost", filepath.Join(deployDir, "conf")).
+		CopyFile(src, dst, "localhost", false).
+		Build()
+	c.Assert(t.Execute(ctx), IsNil)
+	data, err := ioutil.ReadFile(dst)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+
+	c.Assert(t.Rollback(ctx), IsNil)
+	// the parent created along with the dir is removed as well
+	_, err = os.Stat(deployDir)
+	c.Assert(os.IsNotExist(err), IsTrue)
+	_, err = os.Stat(s.dir)
+	c.Assert(err, IsNil)
+}
@@ -18,13 +18,15 @@ import (
 	"strings"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
 )
 
 // Mkdir is used to create directory on the target host
 type Mkdir struct {
-	user string
-	host string
-	dirs []string
+	user    string
+	host    string
+	dirs    []string
+	created []string // dirs and parent dirs which did not exist before
 }
 
 // Execute implements the Task interface
@@ -39,12 +41,24 @@ func (m *Mkdir) Execute(ctx *Context) error {
 		}
 	}
 
+	// record the dirs to be created along with their missing parents, so that
+	// the pre-existing ones are kept on rollback
 	cmd := fmt.Sprintf(
+		`for d in %s; do while [ ! -e $d ]; do echo $d; d=$(dirname $d); done; done`,
+		strings.Join(m.dirs, " "),
+	)
+	stdout, _, err := exec.Execute(cmd, true)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	m.created = outermostDirs(strings.Fields(string(stdout)))
+
+	cmd = fmt.Sprintf(
 		`mkdir -p %[1]s && chown -R %[2]s:%[2]s %[1]s`,
 		strings.Join(m.dirs, " "),
 		m.user,
 	)
-	_, _, err := exec.Execute(cmd, true) // use root to create the dir
+	_, _, err = exec.Execute(cmd, true) // use root to create the dir
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -54,10 +68,47 @@ func (m *Mkdir) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (m *Mkdir) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if len(m.created) == 0 {
+		return nil
+	}
+	exec, found := ctx.GetExecutor(m.host)
+	if !found {
+		return ErrNoExecutor
+	}
+	cmd := fmt.Sprintf(`rm -rf %s`, strings.Join(m.created, " "))
+	if _, stderr, err := exec.Execute(cmd, true); err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	return nil
+}
+
+// outermostDirs returns the dirs which are not inside any other of them,
+// removing those removes all the dirs
+func outermostDirs(dirs []string) []string {
+	var result []string
+	seen := set.NewStringSet()
+	for _, dir := range dirs {
+		inner := false
+		for _, other := range dirs {
+			if other != dir && strings.HasPrefix(dir, strings.TrimSuffix(other, "/")+"/") {
+				inner = true
+				break
+			}
+		}
+		if !inner && !seen.Exist(dir) {
+			seen.Insert(dir)
+			result = append(result, dir)
+		}
+	}
+	return result
 }
 
 // String implements the fmt.Stringer interface
 func (m *Mkdir) String() string {
 	return fmt.Sprintf("Mkdir: host=%s, directories='%s'", m.host, strings.Join(m.dirs, "','"))
 }
+
+// Plan implements the Planner interface
+func (m *Mkdir) Plan(p *Plan) {
+	p.Add(m.host, PlanActionCommand, "mkdir -p %[1]s && chown -R %[2]s:%[2]s %[1]s", strings.Join(m.dirs, " "), m.user)
+}
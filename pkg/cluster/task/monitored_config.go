@@ -35,8 +35,10 @@ type MonitoredConfig struct {
 	host       string
 	globResCtl meta.ResourceControl
 	options    meta.MonitoredOptions
+	tlsEnabled bool
 	deployUser string
 	paths      meta.DirPaths
+	snapshot   *serviceSnapshot
 }
 
 // Execute implements the Task interface
@@ -55,13 +57,19 @@ func (m *MonitoredConfig) Execute(ctx *Context) error {
 		return err
 	}
 
+	snapshot, err := newServiceSnapshot(ctx, exec, fmt.Sprintf("%s-%d.service", m.component, ports[m.component]), m.paths.Deploy)
+	if err != nil {
+		return err
+	}
+	m.snapshot = snapshot
+
 	if err := m.syncMonitoredSystemConfig(exec, m.component, ports[m.component]); err != nil {
 		return err
 	}
 
 	var cfg template.ConfigGenerator
 	if m.component == meta.ComponentNodeExporter {
-		if err := m.syncBlackboxConfig(exec, config.NewBlackboxConfig()); err != nil {
+		if err := m.syncBlackboxConfig(exec, config.NewBlackboxConfig(m.paths.Deploy).WithTLSEnabled(m.tlsEnabled)); err != nil {
 			return err
 		}
 		cfg = scripts.NewNodeExporterScript(
@@ -137,7 +145,14 @@ func (m *MonitoredConfig) syncBlackboxConfig(exec executor.TiOpsExecutor, cfg te
 
 // Rollback implements the Task interface
 func (m *MonitoredConfig) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if m.snapshot == nil {
+		return nil
+	}
+	exec, found := ctx.GetExecutor(m.host)
+	if !found {
+		return ErrNoExecutor
+	}
+	return m.snapshot.restore(exec)
 }
 
 // String implements the fmt.Stringer interface
@@ -145,3 +160,14 @@ func (m *MonitoredConfig) String() string {
 	return fmt.Sprintf("MonitoredConfig: cluster=%s, user=%s, node_exporter_port=%d, blackbox_exporter_port=%d, %v",
 		m.name, m.deployUser, m.options.NodeExporterPort, m.options.BlackboxExporterPort, m.paths)
 }
+
+// Plan implements the Planner interface
+func (m *MonitoredConfig) Plan(p *Plan) {
+	port := m.options.BlackboxExporterPort
+	if m.component == meta.ComponentNodeExporter {
+		port = m.options.NodeExporterPort
+		p.Add(m.host, PlanActionCopy, "blackbox config -> %s", filepath.Join(m.paths.Deploy, "conf", "blackbox.yml"))
+	}
+	p.Add(m.host, PlanActionSystemd, "install unit %s-%d.service", m.component, port)
+	p.Add(m.host, PlanActionCopy, "run script -> %s", filepath.Join(m.paths.Deploy, "scripts", fmt.Sprintf("run_%s.sh", m.component)))
+}
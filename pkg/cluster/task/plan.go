@@ -0,0 +1,148 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+)
+
+// PlanHostLocal is the host of the operations performed on the control
+// machine, e.g. downloading components and writing the meta
+const PlanHostLocal = "local"
+
+// The actions of the operations in a plan
+const (
+	PlanActionConnect = "connect" // connect to the host via SSH
+	PlanActionCommand = "command" // run a command on the host
+	PlanActionCopy    = "copy"    // transfer a file to or from the host
+	PlanActionSystemd = "systemd" // manage the systemd units
+	PlanActionAPI     = "api"     // call the API of the cluster components
+	PlanActionFile    = "file"    // write local files, e.g. keys and packages
+	PlanActionMeta    = "meta"    // write the meta of the cluster
+	PlanActionTask    = "task"    // a task which does not describe its operations
+)
+
+// PlanStep is an operation which would be performed by a task
+type PlanStep struct {
+	Host   string `json:"-" yaml:"-"`
+	Action string `json:"action" yaml:"action"`
+	Detail string `json:"detail" yaml:"detail"`
+}
+
+// Plan is the operations which would be performed by executing a task, it is
+// built without connecting to any host
+type Plan struct {
+	Steps []PlanStep
+}
+
+// Planner is implemented by the tasks which describe the operations they
+// perform, the tasks not implementing it are described by String()
+type Planner interface {
+	Plan(p *Plan)
+}
+
+// BuildPlan walks the task tree and collects the operations of every task
+func BuildPlan(t Task) *Plan {
+	p := &Plan{}
+	p.AddTask(t)
+	return p
+}
+
+// AddTask appends the operations of the task to the plan
+func (p *Plan) AddTask(t Task) {
+	if planner, ok := t.(Planner); ok {
+		planner.Plan(p)
+		return
+	}
+	p.Add(PlanHostLocal, PlanActionTask, "%s", t.String())
+}
+
+// Add appends an operation to the plan, connecting to a host already
+// connected is omitted as the connection is reused
+func (p *Plan) Add(host, action, format string, args ...interface{}) {
+	step := PlanStep{
+		Host:   host,
+		Action: action,
+		Detail: fmt.Sprintf(format, args...),
+	}
+	if action == PlanActionConnect {
+		for _, s := range p.Steps {
+			if s == step {
+				return
+			}
+		}
+	}
+	p.Steps = append(p.Steps, step)
+}
+
+// Hosts returns the hosts of the operations in the order they first appear
+func (p *Plan) Hosts() []string {
+	var hosts []string
+	seen := map[string]struct{}{}
+	for _, s := range p.Steps {
+		if _, ok := seen[s.Host]; ok {
+			continue
+		}
+		seen[s.Host] = struct{}{}
+		hosts = append(hosts, s.Host)
+	}
+	return hosts
+}
+
+// HostSteps returns the operations performed on the host in order
+func (p *Plan) HostSteps(host string) []PlanStep {
+	var steps []PlanStep
+	for _, s := range p.Steps {
+		if s.Host == host {
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+// Plan implements the Planner interface
+func (s *Serial) Plan(p *Plan) {
+	for _, t := range s.inner {
+		p.AddTask(t)
+	}
+}
+
+// Plan implements the Planner interface
+func (pt *Parallel) Plan(p *Plan) {
+	for _, t := range pt.inner {
+		p.AddTask(t)
+	}
+}
+
+// Plan implements the Planner interface
+func (s *StepDisplay) Plan(p *Plan) {
+	p.AddTask(s.inner)
+}
+
+// Plan implements the Planner interface
+func (ps *ParallelStepDisplay) Plan(p *Plan) {
+	p.AddTask(ps.inner)
+}
+
+// planServiceConfig describes the generating of the systemd unit, run script
+// and config of an instance
+func planServiceConfig(p *Plan, inst meta.Instance, paths meta.DirPaths) {
+	host := inst.GetHost()
+	p.Add(host, PlanActionSystemd, "install unit %s", filepath.Join(systemdUnitDir, inst.ServiceName()))
+	p.Add(host, PlanActionCopy, "run script -> %s", filepath.Join(paths.Deploy, "scripts"))
+	p.Add(host, PlanActionCopy, "config -> %s", filepath.Join(paths.Deploy, "conf"))
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+type planSuite struct{}
+
+var _ = Suite(&planSuite{})
+
+func (s *planSuite) TestBuildPlan(c *C) {
+	executed := false
+	t := NewBuilder().
+		SSHKeySet("id_rsa", "id_rsa.pub").
+		Download("tidb", "linux", "amd64", "v4.0.0").
+		Parallel(
+			NewBuilder().
+				UserSSH("172.16.5.1", 22, "tidb", 5).
+				Mkdir("tidb", "172.16.5.1", "/deploy", "/data").
+				BuildAsStep("Prepare 172.16.5.1"),
+			NewBuilder().
+				UserSSH("172.16.5.2", 22, "tidb", 5).
+				UserSSH("172.16.5.2", 22, "tidb", 5).
+				SystemCtl("172.16.5.2", "tidb-4000.service", "restart").
+				Build(),
+		).
+		Func("Custom", func(ctx *Context) error {
+			executed = true
+			return nil
+		}).
+		Build()
+
+	p := BuildPlan(t)
+	c.Assert(executed, IsFalse)
+	c.Assert(p.Hosts(), DeepEquals, []string{PlanHostLocal, "172.16.5.1", "172.16.5.2"})
+	c.Assert(p.HostSteps(PlanHostLocal), DeepEquals, []PlanStep{
+		{Host: PlanHostLocal, Action: PlanActionFile, Detail: "download tidb:v4.0.0 (linux/amd64) if not cached"},
+		{Host: PlanHostLocal, Action: PlanActionTask, Detail: "Custom"},
+	})
+	c.Assert(p.HostSteps("172.16.5.1"), DeepEquals, []PlanStep{
+		{Host: "172.16.5.1", Action: PlanActionConnect, Detail: "ssh tidb@172.16.5.1:22"},
+		{Host: "172.16.5.1", Action: PlanActionCommand, Detail: "mkdir -p /deploy /data && chown -R tidb:tidb /deploy /data"},
+	})
+	c.Assert(p.HostSteps("172.16.5.2")[1], DeepEquals,
+		PlanStep{Host: "172.16.5.2", Action: PlanActionSystemd, Detail: "systemctl restart tidb-4000.service"})
+}
+
+func (s *planSuite) TestClusterOperatePlan(c *C) {
+	topo := &meta.ClusterSpecification{
+		PDServers:   []meta.PDSpec{{Host: "172.16.5.1", ClientPort: 2379, DeployDir: "/deploy/pd"}},
+		TiKVServers: []meta.TiKVSpec{{Host: "172.16.5.2", Port: 20160, DeployDir: "/deploy/tikv", DataDir: "/data/tikv"}},
+	}
+
+	p := &Plan{}
+	(&ClusterOperate{spec: topo, op: operator.UpgradeOperation}).Plan(p)
+	c.Assert(p.HostSteps("172.16.5.2"), DeepEquals, []PlanStep{
+		{Host: "172.16.5.2", Action: PlanActionAPI, Detail: "evict the leaders from tikv 172.16.5.2:20160"},
+		{Host: "172.16.5.2", Action: PlanActionSystemd, Detail: "systemctl restart tikv-20160.service"},
+	})
+
+	p = &Plan{}
+	(&ClusterOperate{spec: topo, op: operator.ScaleInOperation, options: operator.Options{Nodes: []string{"172.16.5.2:20160"}}}).Plan(p)
+	c.Assert(p.Hosts(), DeepEquals, []string{"172.16.5.2"})
+	c.Assert(p.Steps, HasLen, 1)
+	c.Assert(p.Steps[0].Action, Equals, PlanActionAPI)
+
+	p = &Plan{}
+	(&ClusterOperate{spec: topo, op: operator.ScaleInOperation, options: operator.Options{Nodes: []string{"172.16.5.2:20160"}, Force: true}}).Plan(p)
+	c.Assert(p.Steps, HasLen, 3)
+	c.Assert(p.Steps[1].Detail, Equals, "systemctl stop tikv-20160.service")
+	c.Assert(p.Steps[2], DeepEquals, PlanStep{Host: "172.16.5.2", Action: PlanActionCommand,
+		Detail: "rm -rf /data/tikv /deploy/tikv /deploy/tikv/log /etc/systemd/system/tikv-20160.service"})
+}
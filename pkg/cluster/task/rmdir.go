@@ -51,3 +51,8 @@ func (r *Rmdir) Rollback(ctx *Context) error {
 func (r *Rmdir) String() string {
 	return fmt.Sprintf("Rmdir: host=%s, directories='%s'", r.host, strings.Join(r.dirs, "','"))
 }
+
+// Plan implements the Planner interface
+func (r *Rmdir) Plan(p *Plan) {
+	p.Add(r.host, PlanActionCommand, "rm -rf %s", strings.Join(r.dirs, " "))
+}
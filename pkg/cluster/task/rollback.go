@@ -0,0 +1,155 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+)
+
+// RollbackFailure is a task which could not be reverted
+type RollbackFailure struct {
+	Task string
+	Err  error
+}
+
+// RollbackError is returned by Rollback if some of the tasks could not be
+// reverted, the rollback of other tasks goes on when one of them fails
+type RollbackError struct {
+	Failures []RollbackFailure
+}
+
+// Error implements the error interface
+func (e *RollbackError) Error() string {
+	var lines []string
+	for _, f := range e.Failures {
+		lines = append(lines, fmt.Sprintf("%s: %s", f.Task, f.Err))
+	}
+	return fmt.Sprintf("failed to rollback %d task(s):\n%s", len(e.Failures), strings.Join(lines, "\n"))
+}
+
+// add records the result of rolling back t, the failures of inner tasks are
+// flattened so that every leaf task is reported
+func (e *RollbackError) add(t Task, err error) {
+	if err == nil {
+		return
+	}
+	if inner, ok := err.(*RollbackError); ok {
+		e.Failures = append(e.Failures, inner.Failures...)
+		return
+	}
+	e.Failures = append(e.Failures, RollbackFailure{Task: t.String(), Err: err})
+}
+
+// result returns nil if every task has been reverted
+func (e *RollbackError) result() error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+// systemdUnitDir is where the systemd units of services are installed
+const systemdUnitDir = "/etc/systemd/system"
+
+// serviceSnapshot is the state of a service taken before its systemd unit and
+// config files are (re)generated, it is used to revert them
+type serviceSnapshot struct {
+	service   string
+	deployDir string
+	existed   bool   // whether the systemd unit was installed before
+	files     []byte // the conf and scripts directories in tar.gz format
+}
+
+// newServiceSnapshot saves the state of the service on the host of e, nothing
+// is saved if the tasks of ctx are not rolled back on failure
+func newServiceSnapshot(ctx *Context, e executor.TiOpsExecutor, service, deployDir string) (*serviceSnapshot, error) {
+	if !ctx.rollbackEnabled {
+		return nil, nil
+	}
+	s := &serviceSnapshot{
+		service:   service,
+		deployDir: deployDir,
+	}
+
+	unit := filepath.Join(systemdUnitDir, service)
+	cmd := fmt.Sprintf("if [ -f %[1]s ]; then echo yes; else echo no; fi", unit)
+	stdout, stderr, err := e.Execute(cmd, false)
+	if err != nil {
+		return nil, errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	s.existed = strings.TrimSpace(string(stdout)) == "yes"
+	if !s.existed {
+		return s, nil
+	}
+
+	// tar refuses to create an empty archive, so skip it if there is nothing to save
+	cmd = fmt.Sprintf(`cd %s && files=$(ls -d conf scripts 2>/dev/null); [ -z "$files" ] || tar -czf - $files | base64`, deployDir)
+	stdout, stderr, err = e.Execute(cmd, false)
+	if err != nil {
+		return nil, errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	if len(strings.TrimSpace(string(stdout))) == 0 {
+		return s, nil
+	}
+	if s.files, err = base64.StdEncoding.DecodeString(string(stdout)); err != nil {
+		return nil, errors.Annotatef(err, "backup config of %s", service)
+	}
+	return s, nil
+}
+
+// restore disables and removes the systemd unit if it is newly installed,
+// otherwise the saved config files are put back
+func (s *serviceSnapshot) restore(e executor.TiOpsExecutor) error {
+	if !s.existed {
+		cmd := fmt.Sprintf("systemctl disable --now %[1]s; rm -f %[2]s && systemctl daemon-reload",
+			s.service, filepath.Join(systemdUnitDir, s.service))
+		if _, stderr, err := e.Execute(cmd, true); err != nil {
+			return errors.Annotatef(err, "stderr: %s", string(stderr))
+		}
+		return nil
+	}
+	if len(s.files) == 0 {
+		return nil
+	}
+
+	f, err := ioutil.TempFile("", "tiup-rollback-")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(s.files); err != nil {
+		f.Close()
+		return errors.AddStack(err)
+	}
+	f.Close()
+
+	tgt := filepath.Join("/tmp", s.service+"_"+uuid.New().String()+".tar.gz")
+	if err := e.Transfer(f.Name(), tgt, false); err != nil {
+		return errors.Annotatef(err, "transfer from %s to %s failed", f.Name(), tgt)
+	}
+	cmd := fmt.Sprintf("tar -xzf %[1]s -C %[2]s; rc=$?; rm -f %[1]s; exit $rc", tgt, s.deployDir)
+	if _, stderr, err := e.Execute(cmd, false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	. "github.com/pingcap/check"
+)
+
+type rollbackSuite struct{}
+
+var _ = Suite(&rollbackSuite{})
+
+func (s *rollbackSuite) TestSerialRollback(c *C) {
+	var reverted []string
+	newTask := func(name string, fail bool) *Func {
+		return &Func{
+			name: name,
+			fn: func(ctx *Context) error {
+				if fail {
+					return errors.New("failed")
+				}
+				return nil
+			},
+			rollback: func(ctx *Context) error {
+				reverted = append(reverted, name)
+				return nil
+			},
+		}
+	}
+
+	b := NewBuilder().
+		Serial(newTask("t1", false), newTask("t2", false)).
+		Parallel(&Func{name: "no-rollback", fn: func(ctx *Context) error { return nil }}).
+		Serial(newTask("t3", true), newTask("t4", false))
+	t := b.Build()
+
+	ctx := NewContext()
+	c.Assert(t.Execute(ctx), NotNil)
+
+	err := t.Rollback(ctx)
+	c.Assert(err, NotNil)
+	// the failed task is reverted while the one not executed is skipped
+	c.Assert(reverted, DeepEquals, []string{"t3", "t2", "t1"})
+
+	rbErr, ok := err.(*RollbackError)
+	c.Assert(ok, IsTrue)
+	c.Assert(rbErr.Failures, HasLen, 1)
+	c.Assert(rbErr.Failures[0].Task, Equals, "no-rollback")
+	c.Assert(rbErr.Failures[0].Err, Equals, ErrUnsupportedRollback)
+}
+
+func (s *rollbackSuite) TestInstallPackageRollback(c *C) {
+	// the bin dir is not touched if the task fails before the transfer
+	t := &InstallPackage{srcPath: "/tmp/tidb.tar.gz", host: "172.16.5.1", dstDir: "/deploy/tidb-4000"}
+	c.Assert(t.Rollback(NewContext()), IsNil)
+
+	t.transferred = true
+	c.Assert(t.Rollback(NewContext()), Equals, ErrNoExecutor)
+	t.overwritten = true
+	c.Assert(t.Rollback(NewContext()), Equals, ErrUnsupportedRollback)
+}
+
+func (s *rollbackSuite) TestOutermostDirs(c *C) {
+	dirs := outermostDirs([]string{
+		"/data/deploy/conf", "/data/deploy", "/data",
+		"/data/log", "/data",
+		"/home/tidb/deploy", "/home/tidb/deploy-1",
+	})
+	c.Assert(dirs, DeepEquals, []string{"/data", "/home/tidb/deploy", "/home/tidb/deploy-1"})
+}
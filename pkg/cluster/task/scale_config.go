@@ -28,6 +28,7 @@ type ScaleConfig struct {
 	base           meta.Specification
 	deployUser     string
 	paths          meta.DirPaths
+	snapshot       *serviceSnapshot
 }
 
 // Execute implements the Task interface
@@ -43,12 +44,25 @@ func (c *ScaleConfig) Execute(ctx *Context) error {
 		return err
 	}
 
+	snapshot, err := newServiceSnapshot(ctx, exec, c.instance.ServiceName(), c.paths.Deploy)
+	if err != nil {
+		return err
+	}
+	c.snapshot = snapshot
+
 	return c.instance.ScaleConfig(exec, c.base, c.clusterName, c.clusterVersion, c.deployUser, c.paths)
 }
 
 // Rollback implements the Task interface
 func (c *ScaleConfig) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if c.snapshot == nil {
+		return nil
+	}
+	exec, found := ctx.GetExecutor(c.instance.GetHost())
+	if !found {
+		return ErrNoExecutor
+	}
+	return c.snapshot.restore(exec)
 }
 
 // String implements the fmt.Stringer interface
@@ -56,3 +70,8 @@ func (c *ScaleConfig) String() string {
 	return fmt.Sprintf("ScaleConfig: cluster=%s, user=%s, host=%s, service=%s, %s",
 		c.clusterName, c.deployUser, c.instance.GetHost(), c.instance.ServiceName(), c.paths)
 }
+
+// Plan implements the Planner interface
+func (c *ScaleConfig) Plan(p *Plan) {
+	planServiceConfig(p, c.instance, c.paths)
+}
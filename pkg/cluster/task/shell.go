@@ -54,3 +54,12 @@ func (m *Shell) Rollback(ctx *Context) error {
 func (m *Shell) String() string {
 	return fmt.Sprintf("Shell: host=%s, sudo=%v, command=`%s`", m.host, m.sudo, m.command)
 }
+
+// Plan implements the Planner interface
+func (m *Shell) Plan(p *Plan) {
+	if m.sudo {
+		p.Add(m.host, PlanActionCommand, "(sudo) %s", m.command)
+		return
+	}
+	p.Add(m.host, PlanActionCommand, "%s", m.command)
+}
@@ -38,7 +38,7 @@ type RootSSH struct {
 
 // Execute implements the Task interface
 func (s *RootSSH) Execute(ctx *Context) error {
-	e := executor.NewSSHExecutor(executor.SSHConfig{
+	e, err := executor.New(executor.SSHConfig{
 		Host:       s.host,
 		Port:       s.port,
 		User:       s.user,
@@ -47,6 +47,9 @@ func (s *RootSSH) Execute(ctx *Context) error {
 		Passphrase: s.passphrase,
 		Timeout:    time.Second * time.Duration(s.timeout),
 	}, s.user != "root") // using sudo by default if user is not root
+	if err != nil {
+		return err
+	}
 
 	ctx.SetExecutor(s.host, e)
 	return nil
@@ -54,9 +57,7 @@ func (s *RootSSH) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (s *RootSSH) Rollback(ctx *Context) error {
-	ctx.exec.Lock()
-	delete(ctx.exec.executors, s.host)
-	ctx.exec.Unlock()
+	// the executor is kept as rolling back the other tasks on the host needs it
 	return nil
 }
 
@@ -68,6 +69,11 @@ func (s RootSSH) String() string {
 	return fmt.Sprintf("RootSSH: user=%s, host=%s, port=%d", s.user, s.host, s.port)
 }
 
+// Plan implements the Planner interface
+func (s *RootSSH) Plan(p *Plan) {
+	p.Add(s.host, PlanActionConnect, "ssh %s@%s:%d", s.user, s.host, s.port)
+}
+
 // UserSSH is used to establish a SSH connection to the target host with generated key
 type UserSSH struct {
 	host       string
@@ -78,13 +84,16 @@ type UserSSH struct {
 
 // Execute implements the Task interface
 func (s *UserSSH) Execute(ctx *Context) error {
-	e := executor.NewSSHExecutor(executor.SSHConfig{
+	e, err := executor.New(executor.SSHConfig{
 		Host:    s.host,
 		Port:    s.port,
 		KeyFile: ctx.PrivateKeyPath,
 		User:    s.deployUser,
 		Timeout: time.Second * time.Duration(s.timeout),
 	}, false) // not using sudo by default
+	if err != nil {
+		return err
+	}
 
 	ctx.SetExecutor(s.host, e)
 	return nil
@@ -92,9 +101,7 @@ func (s *UserSSH) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (s *UserSSH) Rollback(ctx *Context) error {
-	ctx.exec.Lock()
-	delete(ctx.exec.executors, s.host)
-	ctx.exec.Unlock()
+	// the executor is kept as rolling back the other tasks on the host needs it
 	return nil
 }
 
@@ -102,3 +109,8 @@ func (s *UserSSH) Rollback(ctx *Context) error {
 func (s UserSSH) String() string {
 	return fmt.Sprintf("UserSSH: user=%s, host=%s", s.deployUser, s.host)
 }
+
+// Plan implements the Planner interface
+func (s *UserSSH) Plan(p *Plan) {
+	p.Add(s.host, PlanActionConnect, "ssh %s@%s:%d", s.deployUser, s.host, s.port)
+}
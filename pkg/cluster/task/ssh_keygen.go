@@ -30,7 +30,8 @@ import (
 
 // SSHKeyGen is used to generate SSH key
 type SSHKeyGen struct {
-	keypath string
+	keypath   string
+	generated bool // the keys did not exist and are generated by the task
 }
 
 // Execute implements the Task interface
@@ -74,6 +75,7 @@ func (s *SSHKeyGen) Execute(ctx *Context) error {
 		return errors.Trace(err)
 	}
 
+	s.generated = true
 	ctx.PublicKeyPath = savePublicFileTo
 	ctx.PrivateKeyPath = savePrivateFileTo
 	return nil
@@ -133,10 +135,24 @@ func (s *SSHKeyGen) writeKeyToFile(keyBytes []byte, saveFileTo string) error {
 
 // Rollback implements the Task interface
 func (s *SSHKeyGen) Rollback(ctx *Context) error {
-	return os.Remove(s.keypath)
+	if !s.generated {
+		return nil
+	}
+	if err := os.Remove(s.keypath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.keypath + ".pub"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
 
 // String implements the fmt.Stringer interface
 func (s *SSHKeyGen) String() string {
 	return fmt.Sprintf("SSHKeyGen: path=%s", s.keypath)
 }
+
+// Plan implements the Planner interface
+func (s *SSHKeyGen) Plan(p *Plan) {
+	p.Add(PlanHostLocal, PlanActionFile, "generate SSH key pair %s if not exist", s.keypath)
+}
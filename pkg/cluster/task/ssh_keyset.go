@@ -39,3 +39,6 @@ func (s *SSHKeySet) Rollback(ctx *Context) error {
 func (s *SSHKeySet) String() string {
 	return fmt.Sprintf("SSHKeySet: privateKey=%s, publicKey=%s", s.privateKeyPath, s.publicKeyPath)
 }
+
+// Plan implements the Planner interface, the keys are only used in memory
+func (s *SSHKeySet) Plan(p *Plan) {}
@@ -63,3 +63,8 @@ func (s *Sysctl) Rollback(ctx *Context) error {
 func (s *Sysctl) String() string {
 	return fmt.Sprintf("Sysctl: host=%s %s = %s", s.host, s.key, s.val)
 }
+
+// Plan implements the Planner interface
+func (s *Sysctl) Plan(p *Plan) {
+	p.Add(s.host, PlanActionCommand, "set %s = %s in %s and reload", s.key, s.val, sysctlFilePath)
+}
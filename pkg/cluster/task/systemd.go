@@ -56,3 +56,8 @@ func (c *SystemCtl) Rollback(ctx *Context) error {
 func (c *SystemCtl) String() string {
 	return fmt.Sprintf("SystemCtl: host=%s action=%s %s", c.host, c.action, c.unit)
 }
+
+// Plan implements the Planner interface
+func (c *SystemCtl) Plan(p *Plan) {
+	p.Add(c.host, PlanActionSystemd, "systemctl %s %s", c.action, c.unit)
+}
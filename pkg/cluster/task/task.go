@@ -58,12 +58,20 @@ type (
 		// The public/private key is used to access remote server via the user `tidb`
 		PrivateKeyPath string
 		PublicKeyPath  string
+
+		// checkpoint records the finished tasks, it is nil if not enabled
+		checkpoint *CheckPoint
+
+		// rollbackEnabled is set if the tasks are rolled back on failure, the
+		// state needed to revert them is only saved in that case
+		rollbackEnabled bool
 	}
 
 	// Serial will execute a bundle of task in serialized way
 	Serial struct {
 		hideDetailDisplay bool
 		inner             []Task
+		executed          int // number of inner tasks which have been executed
 	}
 
 	// Parallel will execute a bundle of task in parallelism way
@@ -92,6 +100,52 @@ func NewContext() *Context {
 	}
 }
 
+// SetCheckPoint enables recording finished tasks to the checkpoint log, the
+// tasks already recorded in it are skipped
+func (ctx *Context) SetCheckPoint(cp *CheckPoint) {
+	ctx.checkpoint = cp
+}
+
+// EnableRollback tells the tasks to save the state needed to revert them, it
+// must be called if the tasks are rolled back on failure
+func (ctx *Context) EnableRollback() {
+	ctx.rollbackEnabled = true
+}
+
+// execute runs the task, or skips it if it was finished according to the checkpoint log
+func (ctx *Context) execute(t Task) error {
+	cp := ctx.checkpoint
+	if cp == nil || !isCheckPointTask(t) {
+		return t.Execute(ctx)
+	}
+	if cp.Done(t) {
+		log.Debugf("Skip finished task: %s", t.String())
+		return nil
+	}
+	if err := t.Execute(ctx); err != nil {
+		return err
+	}
+	return cp.Record(t)
+}
+
+// rollback reverts the task unless it was finished by the previous run
+// according to the checkpoint log, the reverted task is removed from the log
+func (ctx *Context) rollback(t Task) error {
+	cp := ctx.checkpoint
+	if cp == nil || !isCheckPointTask(t) {
+		return t.Rollback(ctx)
+	}
+	if cp.finishedBefore(t) {
+		log.Debugf("Skip rolling back the task finished before: %s", t.String())
+		return nil
+	}
+	if err := t.Rollback(ctx); err != nil {
+		return err
+	}
+	cp.forget(t)
+	return nil
+}
+
 // Get implements operation ExecutorGetter interface.
 func (ctx *Context) Get(host string) (e executor.TiOpsExecutor) {
 	ctx.exec.Lock()
@@ -173,14 +227,16 @@ func isDisplayTask(t Task) bool {
 
 // Execute implements the Task interface
 func (s *Serial) Execute(ctx *Context) error {
-	for _, t := range s.inner {
+	for i, t := range s.inner {
 		if !isDisplayTask(t) {
 			if !s.hideDetailDisplay {
 				log.Infof("+ [ Serial ] - %s", t.String())
 			}
 		}
 		ctx.ev.PublishTaskBegin(t)
-		err := t.Execute(ctx)
+		// the failed task is also rolled back as it may be partially done
+		s.executed = i + 1
+		err := ctx.execute(t)
 		ctx.ev.PublishTaskFinish(t, err)
 		if err != nil {
 			return err
@@ -189,16 +245,14 @@ func (s *Serial) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, only the executed tasks are rolled
+// back in reverse order, a *RollbackError is returned if some of them fail
 func (s *Serial) Rollback(ctx *Context) error {
-	// Rollback in reverse order
-	for i := len(s.inner) - 1; i >= 0; i-- {
-		err := s.inner[i].Rollback(ctx)
-		if err != nil {
-			return err
-		}
+	rbErr := &RollbackError{}
+	for i := s.executed - 1; i >= 0; i-- {
+		rbErr.add(s.inner[i], ctx.rollback(s.inner[i]))
 	}
-	return nil
+	return rbErr.result()
 }
 
 // String implements the fmt.Stringer interface
@@ -233,7 +287,7 @@ func (pt *Parallel) Execute(ctx *Context) error {
 				}
 			}
 			ctx.ev.PublishTaskBegin(t)
-			err := t.Execute(ctx)
+			err := ctx.execute(t)
 			ctx.ev.PublishTaskFinish(t, err)
 			if err != nil {
 				mu.Lock()
@@ -248,27 +302,24 @@ func (pt *Parallel) Execute(ctx *Context) error {
 	return firstError
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, a *RollbackError is returned if
+// some of the tasks fail
 func (pt *Parallel) Rollback(ctx *Context) error {
-	var firstError error
+	rbErr := &RollbackError{}
 	var mu sync.Mutex
 	wg := sync.WaitGroup{}
 	for _, t := range pt.inner {
 		wg.Add(1)
 		go func(t Task) {
 			defer wg.Done()
-			err := t.Rollback(ctx)
-			if err != nil {
-				mu.Lock()
-				if firstError == nil {
-					firstError = err
-				}
-				mu.Unlock()
-			}
+			err := ctx.rollback(t)
+			mu.Lock()
+			rbErr.add(t, err)
+			mu.Unlock()
 		}(t)
 	}
 	wg.Wait()
-	return firstError
+	return rbErr.result()
 }
 
 // String implements the fmt.Stringer interface
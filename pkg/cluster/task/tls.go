@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/crypto"
+)
+
+// TLSCert generates a certificate signed by the cluster CA for an instance,
+// the files are saved to the cache dir and copied to the host later
+type TLSCert struct {
+	comp  string
+	host  string
+	port  int
+	ca    *crypto.CertificateAuthority
+	paths meta.DirPaths
+}
+
+// Execute implements the Task interface
+func (c *TLSCert) Execute(ctx *Context) error {
+	key, err := crypto.NewPrivateKey()
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	cert, err := c.ca.Sign(c.comp, []string{c.host}, &key.PublicKey)
+	if err != nil {
+		return errors.Annotatef(err, "failed to sign certificate for %s", c.host)
+	}
+
+	dir := c.localDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.AddStack(err)
+	}
+	files := map[string][]byte{
+		meta.TLSCACert:                c.ca.CertPEM(),
+		fmt.Sprintf("%s.crt", c.comp): cert,
+		fmt.Sprintf("%s.pem", c.comp): crypto.EncodePrivateKey(key),
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			return errors.AddStack(err)
+		}
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (c *TLSCert) Rollback(ctx *Context) error {
+	return os.RemoveAll(c.localDir())
+}
+
+// String implements the fmt.Stringer interface
+func (c *TLSCert) String() string {
+	return fmt.Sprintf("TLSCert: host=%s, component=%s, port=%d", c.host, c.comp, c.port)
+}
+
+// Plan implements the Planner interface
+func (c *TLSCert) Plan(p *Plan) {
+	p.Add(PlanHostLocal, PlanActionFile, "sign certificate of %s for %s to %s", c.comp, c.host, c.localDir())
+}
+
+// localDir is the directory in the cache dir to save the files of the instance
+func (c *TLSCert) localDir() string {
+	return filepath.Join(c.paths.Cache, meta.TLSCertKeyDir, fmt.Sprintf("%s-%s-%d", c.comp, c.host, c.port))
+}
+
+// fileNames returns the names of the files generated for the instance
+func (c *TLSCert) fileNames() []string {
+	return []string{
+		meta.TLSCACert,
+		fmt.Sprintf("%s.crt", c.comp),
+		fmt.Sprintf("%s.pem", c.comp),
+	}
+}
@@ -73,3 +73,12 @@ func (u *UpdateDMMeta) Rollback(ctx *Context) error {
 func (u *UpdateDMMeta) String() string {
 	return fmt.Sprintf("UpdateMeta: cluster=%s, deleted=`'%s'`", u.cluster, strings.Join(u.deletedNodesID, "','"))
 }
+
+// Plan implements the Planner interface
+func (u *UpdateDMMeta) Plan(p *Plan) {
+	if len(u.deletedNodesID) == 0 {
+		p.Add(PlanHostLocal, PlanActionMeta, "save meta of cluster %s", u.cluster)
+		return
+	}
+	p.Add(PlanHostLocal, PlanActionMeta, "save meta of cluster %s without '%s'", u.cluster, strings.Join(u.deletedNodesID, "','"))
+}
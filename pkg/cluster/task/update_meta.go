@@ -113,3 +113,12 @@ func (u *UpdateMeta) Rollback(ctx *Context) error {
 func (u *UpdateMeta) String() string {
 	return fmt.Sprintf("UpdateMeta: cluster=%s, deleted=`'%s'`", u.cluster, strings.Join(u.deletedNodesID, "','"))
 }
+
+// Plan implements the Planner interface
+func (u *UpdateMeta) Plan(p *Plan) {
+	if len(u.deletedNodesID) == 0 {
+		p.Add(PlanHostLocal, PlanActionMeta, "save meta of cluster %s", u.cluster)
+		return
+	}
+	p.Add(PlanHostLocal, PlanActionMeta, "save meta of cluster %s without '%s'", u.cluster, strings.Join(u.deletedNodesID, "','"))
+}
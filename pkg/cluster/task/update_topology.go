@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
@@ -23,9 +24,22 @@ func (u *UpdateTopology) String() string {
 	return fmt.Sprintf("UpdateTopology: cluster=%s", u.cluster)
 }
 
+// Plan implements the Planner interface
+func (u *UpdateTopology) Plan(p *Plan) {
+	if len(u.deletedNodesID) == 0 {
+		p.Add(PlanHostLocal, PlanActionAPI, "update the topology of cluster %s stored in PD", u.cluster)
+		return
+	}
+	p.Add(PlanHostLocal, PlanActionAPI, "update the topology of cluster %s stored in PD without '%s'", u.cluster, strings.Join(u.deletedNodesID, "','"))
+}
+
 // Execute implements the Task interface
 func (u *UpdateTopology) Execute(ctx *Context) error {
-	client, err := u.metadata.Topology.GetEtcdClient()
+	tlsCfg, err := u.metadata.Topology.TLSConfig(meta.ClusterPath(u.cluster, meta.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+	client, err := u.metadata.Topology.GetEtcdClient(tlsCfg)
 	if err != nil {
 		return err
 	}
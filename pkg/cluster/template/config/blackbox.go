@@ -14,23 +14,37 @@
 package config
 
 import (
+	"bytes"
 	"io/ioutil"
 	"path"
+	"text/template"
 
 	"github.com/pingcap/tiup/pkg/cluster/embed"
 )
 
-// BlackboxConfig represent the data to generate AlertManager config
-type BlackboxConfig struct{}
+// BlackboxConfig represent the data to generate Blackbox config
+type BlackboxConfig struct {
+	DeployDir  string
+	TLSEnabled bool
+}
 
 // NewBlackboxConfig returns a BlackboxConfig
-func NewBlackboxConfig() *BlackboxConfig {
-	return &BlackboxConfig{}
+func NewBlackboxConfig(deployDir string) *BlackboxConfig {
+	return &BlackboxConfig{
+		DeployDir: deployDir,
+	}
+}
+
+// WithTLSEnabled set TLSEnabled field of BlackboxConfig, the CA and the client
+// certificate of the cluster are placed under the tls sub directory of DeployDir
+func (c *BlackboxConfig) WithTLSEnabled(enabled bool) *BlackboxConfig {
+	c.TLSEnabled = enabled
+	return c
 }
 
 // Config generate the config file data.
 func (c *BlackboxConfig) Config() ([]byte, error) {
-	fp := path.Join("/templates", "config", "blackbox.yml")
+	fp := path.Join("/templates", "config", "blackbox.yml.tpl")
 	tpl, err := embed.ReadFile(fp)
 	if err != nil {
 		return nil, err
@@ -47,7 +61,17 @@ func (c *BlackboxConfig) ConfigToFile(file string) error {
 	return ioutil.WriteFile(file, config, 0755)
 }
 
-// ConfigWithTemplate generate the AlertManager config content by tpl
+// ConfigWithTemplate generate the Blackbox config content by tpl
 func (c *BlackboxConfig) ConfigWithTemplate(tpl string) ([]byte, error) {
-	return []byte(tpl), nil
+	tmpl, err := template.New("Blackbox").Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	content := bytes.NewBufferString("")
+	if err := tmpl.Execute(content, c); err != nil {
+		return nil, err
+	}
+
+	return content.Bytes(), nil
 }
@@ -45,6 +45,9 @@ type PrometheusConfig struct {
 	BlackboxAddr              string
 	KafkaExporterAddr         string
 	GrafanaAddr               string
+
+	TLSEnabled bool
+	DeployDir  string
 }
 
 // NewPrometheusConfig returns a PrometheusConfig
@@ -54,6 +57,14 @@ func NewPrometheusConfig(cluster string) *PrometheusConfig {
 	}
 }
 
+// WithTLSEnabled set TLSEnabled field of PrometheusConfig, the certificates
+// are placed under the tls sub directory of deployDir
+func (c *PrometheusConfig) WithTLSEnabled(enabled bool, deployDir string) *PrometheusConfig {
+	c.TLSEnabled = enabled
+	c.DeployDir = deployDir
+	return c
+}
+
 // AddKafka add a kafka address
 func (c *PrometheusConfig) AddKafka(ip string, port uint64) *PrometheusConfig {
 	c.KafkaAddrs = append(c.KafkaAddrs, fmt.Sprintf("%s:%d", ip, port))
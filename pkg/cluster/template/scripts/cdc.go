@@ -24,12 +24,13 @@ import (
 
 // CDCScript represent the data to generate cdc config
 type CDCScript struct {
-	IP        string
-	Port      int
-	DeployDir string
-	LogDir    string
-	NumaNode  string
-	Endpoints []*PDScript
+	IP         string
+	Port       int
+	DeployDir  string
+	LogDir     string
+	NumaNode   string
+	TLSEnabled bool
+	Endpoints  []*PDScript
 }
 
 // NewCDCScript returns a CDCScript with given arguments
@@ -54,6 +55,12 @@ func (c *CDCScript) WithNumaNode(numa string) *CDCScript {
 	return c
 }
 
+// WithTLSEnabled set TLSEnabled field of CDCScript
+func (c *CDCScript) WithTLSEnabled(enabled bool) *CDCScript {
+	c.TLSEnabled = enabled
+	return c
+}
+
 // Config generate the config file data.
 func (c *CDCScript) Config() ([]byte, error) {
 	fp := path.Join("/templates", "scripts", "run_cdc.sh.tpl")
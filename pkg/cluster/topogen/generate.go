@@ -0,0 +1,412 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topogen
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"gopkg.in/yaml.v2"
+)
+
+// rolePorts are the default ports of the roles, in the order of the port
+// fields of the specs, the first one is the main port
+var rolePorts = map[string][]int{
+	meta.ComponentPD:           {2379, 2380},
+	meta.ComponentTiKV:         {20160, 20180},
+	meta.ComponentTiDB:         {4000, 10080},
+	meta.ComponentTiFlash:      {9000, 8123, 3930, 20170, 20292, 8234},
+	meta.ComponentCDC:          {8300},
+	meta.ComponentPrometheus:   {9090},
+	meta.ComponentGrafana:      {3000},
+	meta.ComponentAlertManager: {9093, 9094},
+}
+
+// the default ports of node_exporter and blackbox_exporter running on all the hosts
+var monitoredPorts = []int{9100, 9115}
+
+// requiredRoles are the roles a cluster can not work without
+var requiredRoles = []string{meta.ComponentPD, meta.ComponentTiKV, meta.ComponentTiDB}
+
+// isDataRole returns true if the role stores the data of the cluster, they
+// are placed on the hosts with more disks and get the disks of their own
+func isDataRole(role string) bool {
+	return role == meta.ComponentTiKV || role == meta.ComponentTiFlash
+}
+
+// isHeavyRole returns true if the instances of the role are bound to the
+// NUMA nodes when they are placed on the same host
+func isHeavyRole(role string) bool {
+	return isDataRole(role) || role == meta.ComponentTiDB
+}
+
+// portSet is the ports used on a host
+type portSet map[int]struct{}
+
+// allocate returns the ports with the least offset to the default ports
+// that are all not used, and marks them used
+func (s portSet) allocate(defaults []int) []int {
+	for offset := 0; ; offset++ {
+		free := true
+		for _, port := range defaults {
+			if _, used := s[port+offset]; used {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		ports := make([]int, 0, len(defaults))
+		for _, port := range defaults {
+			s[port+offset] = struct{}{}
+			ports = append(ports, port+offset)
+		}
+		return ports
+	}
+}
+
+// Place returns the roles placed on each of the hosts of the spec
+func Place(spec *Spec) ([][]string, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	roles := make([][]string, len(spec.Hosts))
+	for i, h := range spec.Hosts {
+		roles[i] = append([]string{}, h.Roles...)
+	}
+
+	auto := spec.autoHosts()
+	if len(auto) > 0 {
+		counts := DefaultRoleCounts(len(auto))
+		for role, count := range spec.Roles {
+			counts[role] = count
+		}
+		for _, role := range Roles {
+			for n := 0; n < counts[role]; n++ {
+				best := auto[0]
+				for _, i := range auto[1:] {
+					if better(spec, roles, role, i, best) {
+						best = i
+					}
+				}
+				roles[best] = append(roles[best], role)
+			}
+		}
+	} else {
+		for role, count := range spec.Roles {
+			if count > 0 {
+				return nil, errors.Errorf("no host to place %d %s instances, the roles of all the hosts are listed", count, role)
+			}
+		}
+	}
+
+	for _, role := range requiredRoles {
+		found := false
+		for _, rs := range roles {
+			found = found || countRole(rs, role) > 0
+		}
+		if !found {
+			return nil, errors.Errorf("at least one %s instance is required", role)
+		}
+	}
+	return roles, nil
+}
+
+// better returns true if host i is better than host j to place an instance
+// of the role: spread the instances of the role first, then place the data
+// roles on the hosts with more free disks, then prefer the hosts with less
+// instances and the hosts with more resources at last
+func better(spec *Spec, roles [][]string, role string, i, j int) bool {
+	if ci, cj := countRole(roles[i], role), countRole(roles[j], role); ci != cj {
+		return ci < cj
+	}
+
+	if isDataRole(role) {
+		fi := len(spec.Hosts[i].Info.dataDisks()) - countDataRoles(roles[i])
+		fj := len(spec.Hosts[j].Info.dataDisks()) - countDataRoles(roles[j])
+		if fi != fj {
+			return fi > fj
+		}
+	}
+	if len(roles[i]) != len(roles[j]) {
+		return len(roles[i]) < len(roles[j])
+	}
+
+	hi, hj := spec.Hosts[i].Info, spec.Hosts[j].Info
+	if hi == nil || hj == nil {
+		return false
+	}
+	if role == meta.ComponentTiDB && hi.CPUThreads != hj.CPUThreads {
+		return hi.CPUThreads > hj.CPUThreads
+	}
+	return hi.MemoryMB > hj.MemoryMB
+}
+
+func countRole(roles []string, role string) int {
+	n := 0
+	for _, r := range roles {
+		if r == role {
+			n++
+		}
+	}
+	return n
+}
+
+func countDataRoles(roles []string) int {
+	n := 0
+	for _, r := range roles {
+		if isDataRole(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// Generate places the instances on the hosts of the spec and returns the
+// topology, the ports used by other clusters are avoided
+func Generate(spec *Spec) (*meta.TopologySpecification, error) {
+	spec.fillDefaults()
+	roles, err := Place(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	topo := &meta.TopologySpecification{
+		GlobalOptions: meta.GlobalOptions{
+			User:      spec.User,
+			SSHPort:   spec.SSHPort,
+			DeployDir: spec.DeployDir,
+			DataDir:   spec.DataDir,
+			Arch:      spec.Arch,
+		},
+	}
+
+	// the monitored ports are the same on all the hosts
+	hostPorts := make([]portSet, len(spec.Hosts))
+	all := portSet{}
+	for i, h := range spec.Hosts {
+		hostPorts[i] = portSet{}
+		for _, port := range h.UsedPorts {
+			hostPorts[i][port] = struct{}{}
+			all[port] = struct{}{}
+		}
+	}
+	ports := all.allocate(monitoredPorts)
+	topo.MonitoredOptions.NodeExporterPort = ports[0]
+	topo.MonitoredOptions.BlackboxExporterPort = ports[1]
+
+	for i, h := range spec.Hosts {
+		for _, port := range ports {
+			hostPorts[i][port] = struct{}{}
+		}
+
+		var (
+			disks    = h.Info.dataDisks()
+			heavy    = 0
+			tikvs    = countRole(roles[i], meta.ComponentTiKV)
+			dataIdx  = 0
+			numaIdx  = 0
+			numaNode = func(role string) string {
+				if h.Info == nil || h.Info.NUMANodes < 2 || heavy < 2 || !isHeavyRole(role) {
+					return ""
+				}
+				node := strconv.Itoa(numaIdx % h.Info.NUMANodes)
+				numaIdx++
+				return node
+			}
+		)
+		for _, role := range roles[i] {
+			if isHeavyRole(role) {
+				heavy++
+			}
+		}
+
+		for _, role := range roles[i] {
+			opt := instanceOptions{
+				host:    h.Host,
+				sshPort: h.SSHPort,
+				ports:   hostPorts[i].allocate(rolePorts[role]),
+				numa:    numaNode(role),
+			}
+			if h.Info != nil && h.Info.Arch != "" && h.Info.Arch != spec.Arch {
+				opt.arch = h.Info.Arch
+			}
+			if isDataRole(role) && len(disks) > 0 {
+				disk := disks[dataIdx%len(disks)]
+				dataIdx++
+				opt.dataDir = filepath.Join(disk.MountPoint, "tidb-data", fmt.Sprintf("%s-%d", role, opt.ports[0]))
+			}
+			if role == meta.ComponentTiKV && tikvs > 1 && h.Info != nil && h.Info.MemoryMB > 0 {
+				// share the block cache of the instances on the same host
+				capacity := h.Info.MemoryMB * 45 / 100 / tikvs / 1024
+				if capacity < 1 {
+					capacity = 1
+				}
+				opt.config = map[string]interface{}{
+					"storage.block-cache.capacity": fmt.Sprintf("%dGB", capacity),
+				}
+			}
+			addInstance(topo, role, opt)
+		}
+	}
+	return topo, nil
+}
+
+// instanceOptions are the fields of the instance spec set by the generator
+type instanceOptions struct {
+	host    string
+	sshPort int
+	ports   []int
+	dataDir string
+	numa    string
+	arch    string
+	config  map[string]interface{}
+}
+
+func addInstance(topo *meta.TopologySpecification, role string, opt instanceOptions) {
+	switch role {
+	case meta.ComponentPD:
+		topo.PDServers = append(topo.PDServers, meta.PDSpec{
+			Host:       opt.host,
+			SSHPort:    opt.sshPort,
+			ClientPort: opt.ports[0],
+			PeerPort:   opt.ports[1],
+			DataDir:    opt.dataDir,
+			NumaNode:   opt.numa,
+			Arch:       opt.arch,
+		})
+	case meta.ComponentTiKV:
+		topo.TiKVServers = append(topo.TiKVServers, meta.TiKVSpec{
+			Host:       opt.host,
+			SSHPort:    opt.sshPort,
+			Port:       opt.ports[0],
+			StatusPort: opt.ports[1],
+			DataDir:    opt.dataDir,
+			NumaNode:   opt.numa,
+			Config:     opt.config,
+			Arch:       opt.arch,
+		})
+	case meta.ComponentTiDB:
+		topo.TiDBServers = append(topo.TiDBServers, meta.TiDBSpec{
+			Host:       opt.host,
+			SSHPort:    opt.sshPort,
+			Port:       opt.ports[0],
+			StatusPort: opt.ports[1],
+			NumaNode:   opt.numa,
+			Arch:       opt.arch,
+		})
+	case meta.ComponentTiFlash:
+		topo.TiFlashServers = append(topo.TiFlashServers, meta.TiFlashSpec{
+			Host:                 opt.host,
+			SSHPort:              opt.sshPort,
+			TCPPort:              opt.ports[0],
+			HTTPPort:             opt.ports[1],
+			FlashServicePort:     opt.ports[2],
+			FlashProxyPort:       opt.ports[3],
+			FlashProxyStatusPort: opt.ports[4],
+			StatusPort:           opt.ports[5],
+			DataDir:              opt.dataDir,
+			NumaNode:             opt.numa,
+			Arch:                 opt.arch,
+		})
+	case meta.ComponentCDC:
+		topo.CDCServers = append(topo.CDCServers, meta.CDCSpec{
+			Host:    opt.host,
+			SSHPort: opt.sshPort,
+			Port:    opt.ports[0],
+			Arch:    opt.arch,
+		})
+	case meta.ComponentPrometheus:
+		topo.Monitors = append(topo.Monitors, meta.PrometheusSpec{
+			Host:    opt.host,
+			SSHPort: opt.sshPort,
+			Port:    opt.ports[0],
+			Arch:    opt.arch,
+		})
+	case meta.ComponentGrafana:
+		topo.Grafana = append(topo.Grafana, meta.GrafanaSpec{
+			Host:    opt.host,
+			SSHPort: opt.sshPort,
+			Port:    opt.ports[0],
+			Arch:    opt.arch,
+		})
+	case meta.ComponentAlertManager:
+		topo.Alertmanager = append(topo.Alertmanager, meta.AlertManagerSpec{
+			Host:        opt.host,
+			SSHPort:     opt.sshPort,
+			WebPort:     opt.ports[0],
+			ClusterPort: opt.ports[1],
+			Arch:        opt.arch,
+		})
+	}
+}
+
+// Encode returns the topology.yaml of the topology with the empty fields
+// omitted, it is validated by being parsed as a topology file
+func Encode(topo *meta.TopologySpecification) ([]byte, error) {
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var tree yaml.MapSlice
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	pruned, _ := prune(tree)
+	if data, err = yaml.Marshal(pruned); err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, &meta.TopologySpecification{}); err != nil {
+		return nil, errors.Annotate(err, "the topology generated is invalid")
+	}
+	return data, nil
+}
+
+// prune removes the empty values of the yaml tree, it returns false if the
+// value itself is empty
+func prune(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil:
+		return nil, false
+	case yaml.MapSlice:
+		pruned := yaml.MapSlice{}
+		for _, item := range v {
+			if value, ok := prune(item.Value); ok {
+				pruned = append(pruned, yaml.MapItem{Key: item.Key, Value: value})
+			}
+		}
+		return pruned, len(pruned) > 0
+	case []interface{}:
+		pruned := []interface{}{}
+		for _, item := range v {
+			if value, ok := prune(item); ok {
+				pruned = append(pruned, value)
+			}
+		}
+		return pruned, len(pruned) > 0
+	case string:
+		return v, v != ""
+	case int:
+		return v, v != 0
+	case bool:
+		return v, v
+	}
+	return v, true
+}
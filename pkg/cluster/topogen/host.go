@@ -0,0 +1,144 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topogen
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb-insight/collector/insight"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// NUMAProbeCommand prints the number of NUMA nodes of the host, and 0 if
+// numactl is not installed as the instances can not be bound without it
+const NUMAProbeCommand = "command -v numactl >/dev/null 2>&1 && ls -d /sys/devices/system/node/node[0-9]* 2>/dev/null | wc -l || echo 0"
+
+// systemMountPoints are not suggested to store the data
+var systemMountPoints = set.NewStringSet("/boot", "/boot/efi")
+
+// HostInfo is the hardware of a host
+type HostInfo struct {
+	Arch       string // amd64 or arm64
+	CPUThreads int
+	MemoryMB   int
+	NUMANodes  int    // 0 if the instances can not be bound to the NUMA nodes
+	Disks      []Disk // the disks to store the data, the better ones first
+}
+
+// Disk is a mounted disk of a host
+type Disk struct {
+	MountPoint string
+	FSType     string
+	Size       uint64 // in bytes
+	Rotational bool
+}
+
+// String implements the fmt.Stringer interface
+func (d Disk) String() string {
+	kind := "SSD"
+	if d.Rotational {
+		kind = "HDD"
+	}
+	return fmt.Sprintf("%s (%s %s, %dGB)", d.MountPoint, d.FSType, kind, d.Size>>30)
+}
+
+// String implements the fmt.Stringer interface
+func (h *HostInfo) String() string {
+	disks := make([]string, 0, len(h.Disks))
+	for _, d := range h.Disks {
+		disks = append(disks, d.String())
+	}
+	return fmt.Sprintf("%s, %d threads, %dMB memory, %d NUMA nodes, disks: %s",
+		h.Arch, h.CPUThreads, h.MemoryMB, h.NUMANodes, strings.Join(disks, ", "))
+}
+
+// ParseHostInfo parses the output of the insight collector run on the host
+func ParseHostInfo(output []byte) (*HostInfo, error) {
+	var info insight.InsightInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, errors.Annotate(err, "failed to parse the system info")
+	}
+
+	h := &HostInfo{
+		Arch:       meta.NormalizeArch(info.SysInfo.Kernel.Architecture),
+		CPUThreads: int(info.SysInfo.CPU.Threads),
+		MemoryMB:   int(info.SysInfo.Memory.Size),
+	}
+	var collect func(devs []insight.BlockDev, rotational string)
+	collect = func(devs []insight.BlockDev, rotational string) {
+		for _, dev := range devs {
+			// partitions inherit the rotational flag of the disk
+			r := dev.Rotational
+			if r == "" {
+				r = rotational
+			}
+			collect(dev.SubDev, r)
+
+			switch dev.Mount.FSType {
+			case "ext4", "xfs":
+			default:
+				continue
+			}
+			if systemMountPoints.Exist(dev.Mount.MountPoint) {
+				continue
+			}
+			h.Disks = append(h.Disks, Disk{
+				MountPoint: dev.Mount.MountPoint,
+				FSType:     dev.Mount.FSType,
+				Size:       dev.Size,
+				Rotational: r == "1",
+			})
+		}
+	}
+	collect(info.Partitions, "")
+
+	// SSDs first, and then the larger ones
+	sort.SliceStable(h.Disks, func(i, j int) bool {
+		if h.Disks[i].Rotational != h.Disks[j].Rotational {
+			return !h.Disks[i].Rotational
+		}
+		return h.Disks[i].Size > h.Disks[j].Size
+	})
+	return h, nil
+}
+
+// ParseNUMANodes parses the output of NUMAProbeCommand
+func ParseNUMANodes(output []byte) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, errors.Annotatef(err, "failed to parse the number of NUMA nodes")
+	}
+	return n, nil
+}
+
+// dataDisks returns the disks suggested to store the data, the root disk is
+// excluded and the global data_dir is used if there is no other disk
+func (h *HostInfo) dataDisks() []Disk {
+	if h == nil {
+		return nil
+	}
+	var disks []Disk
+	for _, d := range h.Disks {
+		if d.MountPoint != "/" {
+			disks = append(disks, d)
+		}
+	}
+	return disks
+}
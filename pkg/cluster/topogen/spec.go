@@ -0,0 +1,178 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topogen
+
+import (
+	"io/ioutil"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/set"
+	"gopkg.in/yaml.v2"
+)
+
+// Roles are the components the generator places, in the order they are placed
+var Roles = []string{
+	meta.ComponentPD,
+	meta.ComponentTiKV,
+	meta.ComponentTiDB,
+	meta.ComponentTiFlash,
+	meta.ComponentCDC,
+	meta.ComponentPrometheus,
+	meta.ComponentGrafana,
+	meta.ComponentAlertManager,
+}
+
+// Spec is the small specification a topology is generated from, e.g.
+//
+//	user: tidb
+//	hosts:
+//	  - host: 10.0.1.1
+//	  - host: 10.0.1.2
+//	  - host: 10.0.1.3
+//	    roles: [tidb, prometheus, grafana]
+//	roles:
+//	  tikv: 3
+//
+// The roles of a host are placed as listed, a role can be listed multiple
+// times to place multiple instances on the host. The instances counted in
+// roles are placed on the hosts without roles listed, and the default
+// counts are used for the roles not counted.
+type Spec struct {
+	User      string         `yaml:"user,omitempty"`
+	SSHPort   int            `yaml:"ssh_port,omitempty"`
+	DeployDir string         `yaml:"deploy_dir,omitempty"`
+	DataDir   string         `yaml:"data_dir,omitempty"`
+	Arch      string         `yaml:"arch,omitempty"`
+	Hosts     []HostSpec     `yaml:"hosts"`
+	Roles     map[string]int `yaml:"roles,omitempty"`
+}
+
+// HostSpec is a host of the Spec
+type HostSpec struct {
+	Host      string    `yaml:"host"`
+	SSHPort   int       `yaml:"ssh_port,omitempty"`
+	Roles     []string  `yaml:"roles,omitempty"`
+	Info      *HostInfo `yaml:"-"` // the hardware of the host, nil if not probed
+	UsedPorts []int     `yaml:"-"` // the ports used by other clusters on the host
+}
+
+// LoadSpec reads the spec from the file
+func LoadSpec(file string) (*Spec, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to read the spec %s", file)
+	}
+	spec := &Spec{}
+	if err := yaml.UnmarshalStrict(data, spec); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse the spec %s", file)
+	}
+	spec.fillDefaults()
+	return spec, nil
+}
+
+// DefaultSpec returns the spec of the hosts with the default global options
+func DefaultSpec(hosts ...string) *Spec {
+	spec := &Spec{}
+	for _, host := range hosts {
+		spec.Hosts = append(spec.Hosts, HostSpec{Host: host})
+	}
+	spec.fillDefaults()
+	return spec
+}
+
+// fillDefaults sets the default values of the global options
+func (s *Spec) fillDefaults() {
+	if s.User == "" {
+		s.User = "tidb"
+	}
+	if s.SSHPort == 0 {
+		s.SSHPort = 22
+	}
+	if s.DeployDir == "" {
+		s.DeployDir = "/tidb-deploy"
+	}
+	if s.DataDir == "" {
+		s.DataDir = "/tidb-data"
+	}
+	if s.Arch == "" {
+		s.Arch = "amd64"
+	}
+}
+
+// Validate checks the hosts and the roles of the spec
+func (s *Spec) Validate() error {
+	if len(s.Hosts) == 0 {
+		return errors.New("no host is specified")
+	}
+
+	roles := set.NewStringSet(Roles...)
+	hosts := set.NewStringSet()
+	for _, h := range s.Hosts {
+		if h.Host == "" {
+			return errors.New("the host should not be empty")
+		}
+		if hosts.Exist(h.Host) {
+			return errors.Errorf("host %s is specified more than once", h.Host)
+		}
+		hosts.Insert(h.Host)
+		for _, role := range h.Roles {
+			if !roles.Exist(role) {
+				return errors.Errorf("unknown role '%s' of host %s, the supported roles are %v", role, h.Host, Roles)
+			}
+		}
+	}
+
+	for role, count := range s.Roles {
+		if !roles.Exist(role) {
+			return errors.Errorf("unknown role '%s', the supported roles are %v", role, Roles)
+		}
+		if count < 0 {
+			return errors.Errorf("the count of %s should not be negative", role)
+		}
+	}
+	return nil
+}
+
+// autoHosts returns the indexes of the hosts whose roles are placed automatically
+func (s *Spec) autoHosts() []int {
+	var idx []int
+	for i, h := range s.Hosts {
+		if len(h.Roles) == 0 {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// DefaultRoleCounts returns the default number of instances of each role
+// placed on the n hosts
+func DefaultRoleCounts(n int) map[string]int {
+	counts := map[string]int{
+		meta.ComponentPD:           1,
+		meta.ComponentTiKV:         3,
+		meta.ComponentTiDB:         1,
+		meta.ComponentTiFlash:      0,
+		meta.ComponentCDC:          0,
+		meta.ComponentPrometheus:   1,
+		meta.ComponentGrafana:      1,
+		meta.ComponentAlertManager: 1,
+	}
+	if n >= 3 {
+		counts[meta.ComponentPD] = 3
+		counts[meta.ComponentTiKV] = n
+		counts[meta.ComponentTiDB] = 2
+	}
+	return counts
+}
@@ -0,0 +1,250 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topogen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/meta"
+	"github.com/pingcap/tiup/pkg/tui"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTopogen(t *testing.T) {
+	check.TestingT(t)
+}
+
+type topogenSuite struct{}
+
+var _ = check.Suite(&topogenSuite{})
+
+// generate generates and parses the topology of the spec
+func generate(c *check.C, spec *Spec) *meta.TopologySpecification {
+	topo, err := Generate(spec)
+	c.Assert(err, check.IsNil)
+	data, err := Encode(topo)
+	c.Assert(err, check.IsNil)
+
+	parsed := &meta.TopologySpecification{}
+	c.Assert(yaml.UnmarshalStrict(data, parsed), check.IsNil)
+	return parsed
+}
+
+func (s *topogenSuite) TestDefaultSpec(c *check.C) {
+	topo := generate(c, DefaultSpec("172.16.5.1", "172.16.5.2", "172.16.5.3", "172.16.5.4"))
+	c.Assert(topo.GlobalOptions.User, check.Equals, "tidb")
+	c.Assert(topo.GlobalOptions.DeployDir, check.Equals, "/tidb-deploy")
+	c.Assert(topo.PDServers, check.HasLen, 3)
+	c.Assert(topo.TiKVServers, check.HasLen, 4)
+	c.Assert(topo.TiDBServers, check.HasLen, 2)
+	c.Assert(topo.TiFlashServers, check.HasLen, 0)
+	c.Assert(topo.Monitors, check.HasLen, 1)
+	c.Assert(topo.Grafana, check.HasLen, 1)
+	c.Assert(topo.Alertmanager, check.HasLen, 1)
+
+	// the instances are spread over the hosts
+	hosts := map[string]int{}
+	topo.IterInstance(func(inst meta.Instance) {
+		hosts[inst.GetHost()]++
+	})
+	c.Assert(hosts, check.DeepEquals, map[string]int{
+		"172.16.5.1": 3, "172.16.5.2": 3, "172.16.5.3": 3, "172.16.5.4": 3,
+	})
+
+	// less than 3 hosts
+	topo = generate(c, DefaultSpec("172.16.5.1"))
+	c.Assert(topo.PDServers, check.HasLen, 1)
+	c.Assert(topo.TiKVServers, check.HasLen, 3)
+	c.Assert(topo.TiKVServers[1].Port, check.Equals, 20161)
+	c.Assert(topo.TiKVServers[2].StatusPort, check.Equals, 20182)
+}
+
+func (s *topogenSuite) TestColocatedRoles(c *check.C) {
+	spec := DefaultSpec("172.16.5.1", "172.16.5.2")
+	spec.Hosts[0].Roles = []string{"pd", "tikv", "tikv", "tiflash", "tiflash", "tidb", "tidb", "cdc", "prometheus", "grafana", "alertmanager"}
+	spec.Roles = map[string]int{"tikv": 2}
+	topo := generate(c, spec)
+
+	c.Assert(topo.TiKVServers, check.HasLen, 4)
+	c.Assert(topo.TiKVServers[0].Port, check.Equals, 20160)
+	c.Assert(topo.TiKVServers[1].Port, check.Equals, 20161)
+	// the second TiFlash instance conflicts with 20170 used by the first
+	c.Assert(topo.TiFlashServers[1].TCPPort, check.Equals, 9001)
+	c.Assert(topo.TiFlashServers[1].FlashProxyPort, check.Equals, 20171)
+	c.Assert(topo.TiDBServers[1].Port, check.Equals, 4001)
+
+	// all the roles are listed but there are instances to place
+	spec = DefaultSpec("172.16.5.1")
+	spec.Hosts[0].Roles = []string{"pd", "tikv", "tidb"}
+	spec.Roles = map[string]int{"tikv": 2}
+	_, err := Generate(spec)
+	c.Assert(err, check.ErrorMatches, "no host to place 2 tikv instances.*")
+}
+
+func (s *topogenSuite) TestProbedHosts(c *check.C) {
+	spec := DefaultSpec("172.16.5.1", "172.16.5.2", "172.16.5.3")
+	for i := range spec.Hosts {
+		spec.Hosts[i].Info = &HostInfo{Arch: "amd64", CPUThreads: 16, MemoryMB: 32768}
+	}
+	spec.Hosts[0].Info = &HostInfo{
+		Arch:       "arm64",
+		CPUThreads: 64,
+		MemoryMB:   131072,
+		NUMANodes:  2,
+		Disks: []Disk{
+			{MountPoint: "/data1", FSType: "ext4"},
+			{MountPoint: "/data2", FSType: "xfs"},
+			{MountPoint: "/", FSType: "ext4"},
+		},
+	}
+	spec.Hosts[2].Info.CPUThreads = 32
+	spec.Roles = map[string]int{"tikv": 4, "tidb": 1}
+	spec.Hosts[1].UsedPorts = []int{9100, 2379}
+	topo := generate(c, spec)
+
+	// the host with two data disks gets two TiKV instances
+	var tikvs []meta.TiKVSpec
+	for _, tikv := range topo.TiKVServers {
+		if tikv.Host == "172.16.5.1" {
+			tikvs = append(tikvs, tikv)
+		}
+	}
+	c.Assert(tikvs, check.HasLen, 2)
+	c.Assert(tikvs[0].DataDir, check.Equals, "/data1/tidb-data/tikv-20160")
+	c.Assert(tikvs[1].DataDir, check.Equals, "/data2/tidb-data/tikv-20161")
+	c.Assert(tikvs[0].NumaNode, check.Equals, "0")
+	c.Assert(tikvs[1].NumaNode, check.Equals, "1")
+	c.Assert(tikvs[0].Config["storage.block-cache.capacity"], check.Equals, "28GB")
+	c.Assert(tikvs[0].Arch, check.Equals, "arm64")
+
+	// TiDB is placed on the less loaded host with more CPU threads
+	c.Assert(topo.TiDBServers, check.HasLen, 1)
+	c.Assert(topo.TiDBServers[0].Host, check.Equals, "172.16.5.3")
+	c.Assert(topo.TiDBServers[0].NumaNode, check.Equals, "")
+
+	// the ports used by the other clusters are avoided
+	c.Assert(topo.MonitoredOptions.NodeExporterPort, check.Equals, 9101)
+	for _, pd := range topo.PDServers {
+		if pd.Host == "172.16.5.2" {
+			c.Assert(pd.ClientPort, check.Equals, 2380)
+			c.Assert(pd.PeerPort, check.Equals, 2381)
+		}
+	}
+}
+
+func (s *topogenSuite) TestInvalidSpec(c *check.C) {
+	cases := []struct {
+		spec *Spec
+		err  string
+	}{
+		{&Spec{}, "no host is specified"},
+		{&Spec{Hosts: []HostSpec{{Host: "h1"}, {Host: "h1"}}}, "host h1 is specified more than once"},
+		{&Spec{Hosts: []HostSpec{{Host: "h1", Roles: []string{"tiproxy"}}}}, "unknown role 'tiproxy' of host h1.*"},
+		{&Spec{Hosts: []HostSpec{{Host: "h1"}}, Roles: map[string]int{"pd": -1}}, "the count of pd should not be negative"},
+		{&Spec{Hosts: []HostSpec{{Host: "h1"}}, Roles: map[string]int{"pd": 0}}, "at least one pd instance is required"},
+		{&Spec{Hosts: []HostSpec{{Host: "h1", Roles: []string{"pd", "tikv"}}}}, "at least one tidb instance is required"},
+	}
+	for _, t := range cases {
+		_, err := Generate(t.spec)
+		c.Assert(err, check.ErrorMatches, t.err)
+	}
+}
+
+func (s *topogenSuite) TestParseHostInfo(c *check.C) {
+	info, err := ParseHostInfo([]byte(`{
+  "sysinfo": {
+    "kernel": {"architecture": "x86_64"},
+    "cpu": {"threads": 48},
+    "memory": {"size": 192000}
+  },
+  "partitions": [
+    {"name": "sda", "rotational": "1", "subdev": [
+      {"name": "sda1", "mount": {"mount_point": "/boot", "filesystem": "ext4"}, "size": 1073741824},
+      {"name": "sda2", "mount": {"mount_point": "/", "filesystem": "xfs"}, "size": 107374182400},
+      {"name": "sda3", "mount": {"mount_point": "/data3", "filesystem": "ext4"}, "size": 4398046511104}
+    ]},
+    {"name": "nvme0n1", "rotational": "0", "mount": {"mount_point": "/data1", "filesystem": "ext4"}, "size": 1099511627776},
+    {"name": "nvme1n1", "rotational": "0", "mount": {"mount_point": "/data2", "filesystem": "xfs"}, "size": 2199023255552},
+    {"name": "tmp", "mount": {"mount_point": "/tmp", "filesystem": "tmpfs"}}
+  ]
+}`))
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Arch, check.Equals, "amd64")
+	c.Assert(info.CPUThreads, check.Equals, 48)
+	c.Assert(info.MemoryMB, check.Equals, 192000)
+
+	var mounts []string
+	for _, d := range info.Disks {
+		mounts = append(mounts, d.MountPoint)
+	}
+	c.Assert(mounts, check.DeepEquals, []string{"/data2", "/data1", "/data3", "/"})
+	c.Assert(info.Disks[2].Rotational, check.IsTrue)
+	c.Assert(info.Disks[0].String(), check.Equals, "/data2 (xfs SSD, 2048GB)")
+
+	_, err = ParseHostInfo([]byte("not json"))
+	c.Assert(err, check.NotNil)
+
+	n, err := ParseNUMANodes([]byte("2\n"))
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 2)
+}
+
+func (s *topogenSuite) TestWizard(c *check.C) {
+	input := strings.Join([]string{
+		"172.16.5.1, 172.16.5.2 172.16.5.2",
+		"",       // user
+		"abc",    // invalid ssh port
+		"2222",   // ssh port
+		"deploy", // not absolute
+		"/deploy",
+		"", // data dir
+		"n",
+		"pd,tikv,tidb",
+		"tikv,unknown", // unknown role
+		"tikv,prometheus",
+		// ask again with the automatic placement
+		"",
+		"1", "", "", "", "", "", "0", "0",
+	}, "\n") + "\n"
+	out := &bytes.Buffer{}
+	w := NewWizard(tui.NewPrompter(strings.NewReader(input), out))
+
+	c.Assert(w.AskHosts(), check.IsNil)
+	c.Assert(w.Spec.Hosts, check.DeepEquals, []HostSpec{{Host: "172.16.5.1"}, {Host: "172.16.5.2"}})
+	c.Assert(w.Spec.User, check.Equals, "tidb")
+	c.Assert(w.Spec.SSHPort, check.Equals, 2222)
+	c.Assert(w.Spec.DeployDir, check.Equals, "/deploy")
+	c.Assert(w.Spec.DataDir, check.Equals, "/tidb-data")
+
+	c.Assert(w.AskRoles(), check.IsNil)
+	c.Assert(w.Spec.Hosts[0].Roles, check.DeepEquals, []string{"pd", "tikv", "tidb"})
+	c.Assert(w.Spec.Hosts[1].Roles, check.DeepEquals, []string{"tikv", "prometheus"})
+	c.Assert(out.String(), check.Matches, "(?s).*'abc' is not a non-negative integer.*unknown role 'unknown'.*")
+	generate(c, w.Spec)
+
+	c.Assert(w.AskRoles(), check.IsNil)
+	c.Assert(w.Spec.Hosts[0].Roles, check.IsNil)
+	c.Assert(w.Spec.Roles, check.DeepEquals, map[string]int{
+		"pd": 1, "tikv": 3, "tidb": 1, "tiflash": 0, "cdc": 0, "prometheus": 1, "grafana": 0, "alertmanager": 0,
+	})
+	topo := generate(c, w.Spec)
+	c.Assert(topo.TiKVServers, check.HasLen, 3)
+	c.Assert(topo.GlobalOptions.SSHPort, check.Equals, 2222)
+
+	// no more input
+	c.Assert(w.AskRoles(), check.NotNil)
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topogen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// Wizard fills the spec with the answers of the questions, the hosts are
+// asked first so that they can be probed before the roles are placed
+type Wizard struct {
+	prompter *tui.Prompter
+	Spec     *Spec
+}
+
+// NewWizard returns a Wizard asking the questions with the prompter
+func NewWizard(prompter *tui.Prompter) *Wizard {
+	spec := &Spec{}
+	spec.fillDefaults()
+	return &Wizard{prompter: prompter, Spec: spec}
+}
+
+// AskHosts asks for the hosts and the global options
+func (w *Wizard) AskHosts() error {
+	p := w.prompter
+	hosts, err := p.List("Hosts of the cluster, separated by commas", nil, nil)
+	if err != nil {
+		return err
+	}
+	uniq := set.NewStringSet()
+	for _, host := range hosts {
+		if uniq.Exist(host) {
+			continue
+		}
+		uniq.Insert(host)
+		w.Spec.Hosts = append(w.Spec.Hosts, HostSpec{Host: host})
+	}
+
+	if w.Spec.User, err = p.Input("User to deploy and run the cluster", w.Spec.User, nil); err != nil {
+		return err
+	}
+	if w.Spec.SSHPort, err = p.Int("SSH port of the hosts", w.Spec.SSHPort); err != nil {
+		return err
+	}
+	if w.Spec.DeployDir, err = p.Input("Directory to deploy the components", w.Spec.DeployDir, absolute); err != nil {
+		return err
+	}
+	if w.Spec.DataDir, err = p.Input("Directory to store the data if there is no data disk", w.Spec.DataDir, absolute); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AskRoles asks for the roles of the hosts, or the number of instances of
+// each role to place them automatically, the previous answers are dropped
+func (w *Wizard) AskRoles() error {
+	p := w.prompter
+	w.Spec.Roles = nil
+	for i := range w.Spec.Hosts {
+		w.Spec.Hosts[i].Roles = nil
+	}
+
+	auto, err := p.Confirm("Place the components on the hosts automatically?", true)
+	if err != nil {
+		return err
+	}
+
+	roles := set.NewStringSet(Roles...)
+	validateRole := func(role string) error {
+		if !roles.Exist(role) {
+			return errors.Errorf("unknown role '%s', the supported roles are %s", role, strings.Join(Roles, ", "))
+		}
+		return nil
+	}
+
+	if !auto {
+		for i := range w.Spec.Hosts {
+			h := &w.Spec.Hosts[i]
+			if h.Roles, err = p.List(fmt.Sprintf("Roles of %s, e.g. pd,tikv,tidb", h.Host), nil, validateRole); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	counts := DefaultRoleCounts(len(w.Spec.Hosts))
+	w.Spec.Roles = make(map[string]int)
+	for _, role := range Roles {
+		if w.Spec.Roles[role], err = p.Int(fmt.Sprintf("Number of %s instances", role), counts[role]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func absolute(dir string) error {
+	if !strings.HasPrefix(dir, "/") {
+		return errors.Errorf("'%s' is not an absolute path", dir)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+const (
+	// RSAKeyLength is the length of RSA keys generated for certificates
+	RSAKeyLength = 2048
+
+	// caValidity is how long a generated CA stays valid
+	caValidity = 10 * 365 * 24 * time.Hour
+	// certValidity is how long a certificate signed by the CA stays valid
+	certValidity = 10 * 365 * 24 * time.Hour
+)
+
+var (
+	// ErrorDecodePEM means the content is not a valid PEM block
+	ErrorDecodePEM = errors.New("failed to decode PEM block")
+)
+
+// CertificateAuthority holds the CA of a cluster
+type CertificateAuthority struct {
+	ClusterName string
+	Cert        *x509.Certificate
+	Key         *rsa.PrivateKey
+}
+
+// NewPrivateKey generates a new RSA private key
+func NewPrivateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, RSAKeyLength)
+}
+
+// NewCA generates a self-signed CA for the cluster
+func NewCA(clsName string) (*CertificateAuthority, error) {
+	key, err := NewPrivateKey()
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization:       []string{"PingCAP"},
+			OrganizationalUnit: []string{"TiUP"},
+			CommonName:         clsName,
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	return &CertificateAuthority{
+		ClusterName: clsName,
+		Cert:        cert,
+		Key:         key,
+	}, nil
+}
+
+// ReadCA reads an existing CA certificate and its private key from PEM files
+func ReadCA(clsName, certPath, keyPath string) (*CertificateAuthority, error) {
+	certData, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	cert, err := ParseCertificate(certData)
+	if err != nil {
+		return nil, err
+	}
+
+	keyData, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	key, err := ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateAuthority{
+		ClusterName: clsName,
+		Cert:        cert,
+		Key:         key,
+	}, nil
+}
+
+// Sign issues a certificate for the hosts (IP addresses or DNS names) with the
+// public key, the certificate is valid for both server and client usage
+func (ca *CertificateAuthority) Sign(commonName string, hosts []string, pub *rsa.PublicKey) ([]byte, error) {
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization:       []string{"PingCAP"},
+			OrganizationalUnit: []string{"TiUP"},
+			CommonName:         commonName,
+		},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(certValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if h != "" {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, pub, ca.Key)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return EncodeCertificate(der), nil
+}
+
+// CertPEM returns the CA certificate in PEM format
+func (ca *CertificateAuthority) CertPEM() []byte {
+	return EncodeCertificate(ca.Cert.Raw)
+}
+
+// KeyPEM returns the private key of the CA in PEM format
+func (ca *CertificateAuthority) KeyPEM() []byte {
+	return EncodePrivateKey(ca.Key)
+}
+
+// EncodeCertificate encodes a DER certificate to PEM format
+func EncodeCertificate(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: der,
+	})
+}
+
+// EncodePrivateKey encodes a RSA private key to PEM format
+func EncodePrivateKey(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+// ParseCertificate decodes a PEM encoded certificate
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrorDecodePEM
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return cert, nil
+}
+
+// ParsePrivateKey decodes a PEM encoded RSA private key
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrorDecodePEM
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return key, nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serial, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return serial, nil
+}
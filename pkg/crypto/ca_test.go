@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCASign(t *testing.T) {
+	ca, err := NewCA("test-cluster")
+	assert.Nil(t, err)
+	assert.True(t, ca.Cert.IsCA)
+
+	key, err := NewPrivateKey()
+	assert.Nil(t, err)
+	certPEM, err := ca.Sign("tikv", []string{"172.16.5.1", "tikv.local"}, &key.PublicKey)
+	assert.Nil(t, err)
+
+	cert, err := ParseCertificate(certPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, "tikv", cert.Subject.CommonName)
+	assert.Equal(t, "172.16.5.1", cert.IPAddresses[0].String())
+	assert.Equal(t, []string{"tikv.local"}, cert.DNSNames)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	assert.Nil(t, err)
+}
+
+func TestReadCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-ca")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, err := NewCA("test-cluster")
+	assert.Nil(t, err)
+	certPath := filepath.Join(dir, "ca.crt")
+	keyPath := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, ioutil.WriteFile(certPath, ca.CertPEM(), 0644))
+	assert.Nil(t, ioutil.WriteFile(keyPath, ca.KeyPEM(), 0600))
+
+	loaded, err := ReadCA("test-cluster", certPath, keyPath)
+	assert.Nil(t, err)
+	assert.True(t, loaded.Cert.Equal(ca.Cert))
+	assert.Equal(t, ca.Key.N, loaded.Key.N)
+
+	_, err = ParseCertificate([]byte("not a pem"))
+	assert.Equal(t, ErrorDecodePEM, err)
+}
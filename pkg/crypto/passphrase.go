@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// the layout of the encrypted data is: magic | salt | nonce | sealed data,
+// the key is derived from the passphrase and salt with scrypt, and the data
+// is sealed with AES-256-GCM
+var passphraseMagic = []byte("TIUPENC1")
+
+const (
+	passphraseSaltLength = 16
+	passphraseKeyLength  = 32
+)
+
+var (
+	// ErrorWrongPassphrase means the passphrase is wrong or the data is corrupted
+	ErrorWrongPassphrase = errors.New("wrong passphrase or corrupted data")
+	// ErrorNotEncrypted means the data is not encrypted by EncryptWithPassphrase
+	ErrorNotEncrypted = errors.New("the data is not encrypted by tiup")
+)
+
+func passphraseCipher(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, passphraseKeyLength)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptWithPassphrase encrypts the data with a key derived from the passphrase
+func EncryptWithPassphrase(data, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, passphraseSaltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, errors.Trace(err)
+	}
+	aead, err := passphraseCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	out := append(append(append([]byte{}, passphraseMagic...), salt...), nonce...)
+	return aead.Seal(out, nonce, data, passphraseMagic), nil
+}
+
+// DecryptWithPassphrase decrypts the data encrypted by EncryptWithPassphrase
+func DecryptWithPassphrase(data, passphrase []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, passphraseMagic) {
+		return nil, ErrorNotEncrypted
+	}
+	data = data[len(passphraseMagic):]
+	if len(data) < passphraseSaltLength {
+		return nil, ErrorWrongPassphrase
+	}
+	aead, err := passphraseCipher(passphrase, data[:passphraseSaltLength])
+	if err != nil {
+		return nil, err
+	}
+	data = data[passphraseSaltLength:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrorWrongPassphrase
+	}
+
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], passphraseMagic)
+	if err != nil {
+		return nil, ErrorWrongPassphrase
+	}
+	return plain, nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassphrase(t *testing.T) {
+	data := []byte("meta of the cluster")
+	sealed, err := EncryptWithPassphrase(data, []byte("secret"))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(sealed), string(data))
+
+	plain, err := DecryptWithPassphrase(sealed, []byte("secret"))
+	assert.Nil(t, err)
+	assert.Equal(t, data, plain)
+
+	_, err = DecryptWithPassphrase(sealed, []byte("wrong"))
+	assert.Equal(t, ErrorWrongPassphrase, err)
+
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = DecryptWithPassphrase(sealed, []byte("secret"))
+	assert.Equal(t, ErrorWrongPassphrase, err)
+
+	_, err = DecryptWithPassphrase(data, []byte("secret"))
+	assert.Equal(t, ErrorNotEncrypted, err)
+}
@@ -15,15 +15,14 @@ package logger
 
 import (
 	"bytes"
-	"io/ioutil"
 	"os"
+	"os/user"
 	"strings"
 	"time"
 
-	utils2 "github.com/pingcap/tiup/pkg/utils"
-
-	"github.com/pingcap/tiup/pkg/base52"
+	"github.com/pingcap/tiup/pkg/cluster/audit"
 	"github.com/pingcap/tiup/pkg/cluster/meta"
+	utils2 "github.com/pingcap/tiup/pkg/utils"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -31,6 +30,7 @@ import (
 
 var auditEnabled atomic.Bool
 var auditBuffer *bytes.Buffer
+var auditStartTime time.Time
 
 // EnableAuditLog enables audit log.
 func EnableAuditLog() {
@@ -43,25 +43,35 @@ func DisableAuditLog() {
 }
 
 func newAuditLogCore() zapcore.Core {
-	auditBuffer = bytes.NewBufferString(strings.Join(os.Args, " ") + "\n")
+	auditStartTime = time.Now()
+	auditBuffer = new(bytes.Buffer)
 	encoder := zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
 	return zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(auditBuffer)), zapcore.InfoLevel)
 }
 
-// OutputAuditLogIfEnabled outputs audit log if enabled.
-func OutputAuditLogIfEnabled() {
+// OutputAuditLogIfEnabled outputs audit log if enabled. The record is filled
+// by the caller with the result of the command, and the command line, user
+// and time are filled here.
+func OutputAuditLogIfEnabled(record *audit.Record) {
 	if !auditEnabled.Load() {
 		return
 	}
 	auditDir := meta.ProfilePath(meta.TiOpsAuditDir)
 	if err := utils2.CreateDir(auditDir); err != nil {
 		zap.L().Warn("Create audit directory failed", zap.Error(err))
-	} else {
-		auditFilePath := meta.ProfilePath(meta.TiOpsAuditDir, base52.Encode(time.Now().Unix()))
-		err := ioutil.WriteFile(auditFilePath, auditBuffer.Bytes(), os.ModePerm)
-		if err != nil {
-			zap.L().Warn("Write audit log file failed", zap.Error(err))
-		}
-		auditBuffer.Reset()
+		return
+	}
+
+	record.Command = strings.Join(os.Args, " ")
+	record.Args = os.Args[1:]
+	record.Dir, _ = os.Getwd()
+	if u, err := user.Current(); err == nil {
+		record.User = u.Username
+	}
+	record.StartTime = auditStartTime
+	record.EndTime = time.Now()
+	if err := audit.Write(auditDir, record, auditBuffer.Bytes()); err != nil {
+		zap.L().Warn("Write audit log file failed", zap.Error(err))
 	}
+	auditBuffer.Reset()
 }
@@ -34,11 +34,12 @@ func newDebugLogCore() zapcore.Core {
 	return zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(debugBuffer)), zapcore.DebugLevel)
 }
 
-// OutputDebugLog outputs debug log in the current working directory.
-func OutputDebugLog() {
+// OutputDebugLog outputs debug log in the current working directory, the
+// path of the log is returned if it is written.
+func OutputDebugLog() string {
 	if err := os.MkdirAll("./logs", 0755); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "\nCreate debug logs directory failed %v.\n", err)
-		return
+		return ""
 	}
 
 	// FIXME: Stupid go does not allow writing fraction seconds without a leading dot.
@@ -49,10 +50,11 @@ func OutputDebugLog() {
 	}
 
 	err = ioutil.WriteFile(filePath, debugBuffer.Bytes(), 0644)
+	debugBuffer.Reset()
 	if err != nil {
 		_, _ = colorutil.ColorWarningMsg.Fprint(os.Stderr, "\nWarn: Failed to write error debug log.\n")
-	} else {
-		_, _ = fmt.Fprintf(os.Stderr, "\nVerbose debug logs has been written to %s.\n", colorutil.ColorKeyword.Sprint(filePath))
+		return ""
 	}
-	debugBuffer.Reset()
+	_, _ = fmt.Fprintf(os.Stderr, "\nVerbose debug logs has been written to %s.\n", colorutil.ColorKeyword.Sprint(filePath))
+	return filePath
 }
@@ -15,11 +15,21 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"go.uber.org/zap"
 )
 
+// output is the writer of the info messages, the other messages are always
+// written to stderr
+var output io.Writer = os.Stdout
+
+// SetOutput sets the writer of the info messages
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 // Debugf output the debug message to console
 // Deprecated: Use zap.L().Debug() instead
 func Debugf(format string, args ...interface{}) {
@@ -31,7 +41,7 @@ func Debugf(format string, args ...interface{}) {
 // Deprecated: Use zap.L().Info() instead
 func Infof(format string, args ...interface{}) {
 	zap.L().Info(fmt.Sprintf(format, args...))
-	fmt.Printf(format+"\n", args...)
+	_, _ = fmt.Fprintf(output, format+"\n", args...)
 }
 
 // Warnf output the warning message to console
@@ -13,6 +13,8 @@
 
 package set
 
+import "sort"
+
 // StringSet is a string set.
 type StringSet map[string]struct{}
 
@@ -62,3 +64,13 @@ func (s StringSet) Difference(rhs StringSet) StringSet {
 	}
 	return newSet
 }
+
+// Slice converts the set to a sorted slice
+func (s StringSet) Slice() []string {
+	res := make([]string, 0, len(s))
+	for val := range s {
+		res = append(res, val)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -65,4 +65,7 @@ func (s *stringSetTestSuite) TestStringSet(c *check.C) {
 
 	s6 := NewStringSet()
 	c.Assert(s3.Intersection(s6), check.DeepEquals, NewStringSet())
+
+	c.Assert(NewStringSet("b", "c", "a").Slice(), check.DeepEquals, []string{"a", "b", "c"})
+	c.Assert(NewStringSet().Slice(), check.HasLen, 0)
 }
@@ -0,0 +1,149 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// Prompter asks questions and reads the answers line by line
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter returns a Prompter reading from in and writing the questions to out
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// NewStdPrompter returns a Prompter of the console, the questions are written
+// to stderr like the other prompts so that stdout is kept for the results
+func NewStdPrompter() *Prompter {
+	return NewPrompter(os.Stdin, os.Stderr)
+}
+
+// readLine reads a line, io.EOF is returned if there is no more input
+func (p *Prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Input asks the question until the answer passes the validation, the
+// default value is used if the answer is empty, validate can be nil
+func (p *Prompter) Input(question, def string, validate func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+		answer, err := p.readLine()
+		if err != nil {
+			return "", errors.Annotatef(err, "failed to read the answer of '%s'", question)
+		}
+		if answer == "" {
+			answer = def
+		}
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(p.out, "  %s\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// Int asks for a non-negative integer
+func (p *Prompter) Int(question string, def int) (int, error) {
+	answer, err := p.Input(question, strconv.Itoa(def), func(s string) error {
+		if n, err := strconv.Atoi(s); err != nil || n < 0 {
+			return errors.Errorf("'%s' is not a non-negative integer", s)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(answer)
+}
+
+// List asks for a comma or space separated list, validate is called on
+// each of the items and can be nil
+func (p *Prompter) List(question string, def []string, validate func(string) error) ([]string, error) {
+	answer, err := p.Input(question, strings.Join(def, ","), func(s string) error {
+		items := SplitList(s)
+		if len(items) == 0 {
+			return errors.New("the list should not be empty")
+		}
+		if validate == nil {
+			return nil
+		}
+		for _, item := range items {
+			if err := validate(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return SplitList(answer), nil
+}
+
+// Confirm asks a yes / no question
+func (p *Prompter) Confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, hint)
+		answer, err := p.readLine()
+		if err != nil {
+			return false, errors.Annotatef(err, "failed to read the answer of '%s'", question)
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// SplitList splits the comma or space separated list
+func SplitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "strings"
+
 // RebuildArgs move "--help" or "-h" flag to the end of the arg list
 func RebuildArgs(args []string) []string {
 	helpFlag := "--help"
@@ -14,3 +16,8 @@ func RebuildArgs(args []string) []string {
 	argList = append(argList, helpFlag)
 	return argList
 }
+
+// ShellQuote quotes the string as a single word of the shell
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"os/exec"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&TestArgsSuite{})
+
+type TestArgsSuite struct{}
+
+func (s *TestArgsSuite) TestShellQuote(c *C) {
+	for _, str := range []string{"", "plain", "with space", `it's "quoted" $HOME`} {
+		output, err := exec.Command("sh", "-c", "printf %s "+ShellQuote(str)).Output()
+		c.Assert(err, IsNil)
+		c.Assert(string(output), Equals, str)
+	}
+}
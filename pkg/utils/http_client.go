@@ -19,6 +19,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -64,6 +65,17 @@ func (c *HTTPClient) Post(url string, body io.Reader) ([]byte, error) {
 	return checkHTTPResponse(res)
 }
 
+// PostForm send a POST request of the form to the url and returns the response
+func (c *HTTPClient) PostForm(url string, data url.Values) ([]byte, error) {
+	res, err := c.client.PostForm(url, data)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return checkHTTPResponse(res)
+}
+
 // Delete send a DELETE request to the url and returns the response and status code.
 func (c *HTTPClient) Delete(url string, body io.Reader) ([]byte, int, error) {
 	var statusCode int
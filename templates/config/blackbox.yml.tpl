@@ -35,4 +35,15 @@ modules:
       prober: icmp
       timeout: 5s
       icmp:
-        preferred_ip_protocol: "ip4"
\ No newline at end of file
+        preferred_ip_protocol: "ip4"
+{{- if .TLSEnabled}}
+    tls_connect:
+      prober: tcp
+      timeout: 5s
+      tcp:
+        tls: true
+        tls_config:
+          ca_file: {{.DeployDir}}/tls/ca.crt
+          cert_file: {{.DeployDir}}/tls/client.crt
+          key_file: {{.DeployDir}}/tls/client.pem
+{{- end}}
@@ -1,4 +1,39 @@
 ---
+{{- define "TLSConfig"}}
+    scheme: https
+    tls_config:
+      ca_file: {{.DeployDir}}/tls/ca.crt
+      cert_file: {{.DeployDir}}/tls/prometheus.crt
+      key_file: {{.DeployDir}}/tls/prometheus.pem
+{{- end}}
+{{- define "TiDBPortProbeTargets"}}
+    - targets:
+    {{- range .TiDBStatusAddrs}}
+      - '{{.}}'
+    {{- end}}
+      labels:
+        group: 'tidb'
+    - targets:
+    {{- range .TiKVStatusAddrs}}
+      - '{{.}}'
+    {{- end}}
+      labels:
+        group: 'tikv'
+    - targets:
+    {{- range .PDAddrs}}
+      - '{{.}}'
+    {{- end}}
+      labels:
+        group: 'pd'
+{{- if .TiFlashStatusAddrs}}
+    - targets:
+    {{- range .TiFlashStatusAddrs}}
+       - '{{.}}'
+    {{- end}}
+      labels:
+        group: 'tiflash'
+{{- end}}
+{{- end}}
 global:
   scrape_interval:     15s # By default, scrape targets every 15 seconds.
   evaluation_interval: 15s # By default, scrape targets every 15 seconds.
@@ -80,6 +115,9 @@ scrape_configs:
 {{- end}}
   - job_name: "tidb"
     honor_labels: true # don't overwrite job & instance labels
+{{- if .TLSEnabled}}
+{{- template "TLSConfig" .}}
+{{- end}}
     static_configs:
     - targets:
 {{- range .TiDBStatusAddrs}}
@@ -87,6 +125,9 @@ scrape_configs:
 {{- end}}
   - job_name: "tikv"
     honor_labels: true # don't overwrite job & instance labels
+{{- if .TLSEnabled}}
+{{- template "TLSConfig" .}}
+{{- end}}
     static_configs:
     - targets:
 {{- range .TiKVStatusAddrs}}
@@ -94,6 +135,9 @@ scrape_configs:
 {{- end}}
   - job_name: "pd"
     honor_labels: true # don't overwrite job & instance labels
+{{- if .TLSEnabled}}
+{{- template "TLSConfig" .}}
+{{- end}}
     static_configs:
     - targets:
 {{- range .PDAddrs}}
@@ -102,6 +146,9 @@ scrape_configs:
 {{- if .TiFlashStatusAddrs}}
   - job_name: "tiflash"
     honor_labels: true # don't overwrite job & instance labels
+{{- if .TLSEnabled}}
+{{- template "TLSConfig" .}}
+{{- end}}
     static_configs:
     - targets:
     {{- range .TiFlashStatusAddrs}}
@@ -121,6 +168,9 @@ scrape_configs:
 {{- end}}
   - job_name: 'pump'
     honor_labels: true # don't overwrite job & instance labels
+{{- if .TLSEnabled}}
+{{- template "TLSConfig" .}}
+{{- end}}
     static_configs:
     - targets:
     {{- range .PumpAddrs}}
@@ -128,6 +178,9 @@ scrape_configs:
     {{- end}}
   - job_name: 'drainer'
     honor_labels: true # don't overwrite job & instance labels
+{{- if .TLSEnabled}}
+{{- template "TLSConfig" .}}
+{{- end}}
     static_configs:
     - targets:
     {{- range .DrainerAddrs}}
@@ -184,11 +237,30 @@ scrape_configs:
 {{- if .CDCAddrs}}
   - job_name: "ticdc"
     honor_labels: true # don't overwrite job & instance labels
+{{- if .TLSEnabled}}
+{{- template "TLSConfig" .}}
+{{- end}}
     static_configs:
     - targets:
 {{- range .CDCAddrs}}
       - '{{.}}'
 {{- end}}
+{{- end}}
+{{- if .TLSEnabled}}
+  - job_name: "tidb_tls_port_probe"
+    scrape_interval: 30s
+    metrics_path: /probe
+    params:
+      module: [tls_connect]
+    static_configs:
+{{- template "TiDBPortProbeTargets" .}}
+    relabel_configs:
+      - source_labels: [__address__]
+        target_label: __param_target
+      - source_labels: [__param_target]
+        target_label: instance
+      - target_label: __address__
+        replacement: {{.BlackboxAddr}}
 {{- end}}
   - job_name: "tidb_port_probe"
     scrape_interval: 30s
@@ -196,31 +268,8 @@ scrape_configs:
     params:
       module: [tcp_connect]
     static_configs:
-    - targets:
-    {{- range .TiDBStatusAddrs}}
-      - '{{.}}' 
-    {{- end}}
-      labels:
-        group: 'tidb'
-    - targets:
-    {{- range .TiKVStatusAddrs}}
-      - '{{.}}'
-    {{- end}}
-      labels:
-        group: 'tikv'
-    - targets:
-    {{- range .PDAddrs}}
-      - '{{.}}'
-    {{- end}}
-      labels:
-        group: 'pd'
-{{- if .TiFlashStatusAddrs}}
-    - targets:
-    {{- range .TiFlashStatusAddrs}}
-       - '{{.}}'
-    {{- end}}
-      labels:
-        group: 'tiflash'
+{{- if not .TLSEnabled}}
+{{- template "TiDBPortProbeTargets" .}}
 {{- end}}
 {{- if .PushgatewayAddr}}
     - targets: